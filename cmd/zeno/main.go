@@ -7,25 +7,52 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"Zeno/internal/api"
+	"Zeno/internal/clock"
 	"Zeno/internal/config"
 	"Zeno/internal/controller"
 	"Zeno/internal/github"
 	"Zeno/internal/leaderelection"
 	"Zeno/internal/metrics"
+	"Zeno/internal/notify"
 	"Zeno/internal/provider"
-	"Zeno/internal/provider/docker"
-	"Zeno/internal/provider/ec2"
+	"Zeno/internal/provider/breaker"
+	"Zeno/internal/provider/spillover"
+	"Zeno/internal/reload"
 	"Zeno/internal/store"
 
+	// Providers register themselves with the provider registry
+	_ "Zeno/internal/provider/azure"
+	_ "Zeno/internal/provider/docker"
+	_ "Zeno/internal/provider/ec2"
+	_ "Zeno/internal/provider/gce"
+	_ "Zeno/internal/provider/kubernetes"
+	_ "Zeno/internal/provider/plugin"
+	_ "Zeno/internal/provider/process"
+	_ "Zeno/internal/provider/ssh"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 const version = "2.0.0"
 
+// notificationFlushTimeout bounds how long shutdown waits for queued
+// notifications, such as those about draining runners, to be delivered
+const notificationFlushTimeout = 30 * time.Second
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "simulate" {
+		if err := runSimulate(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	configPath := flag.String("config", "", "Path to configuration file (optional)")
 	flag.Parse()
 
@@ -64,30 +91,59 @@ func run(configPath string) error {
 	met.ControllerInfo.WithLabelValues(version, cfg.Provider.Type, modeString(cfg.DryRun)).Set(1)
 
 	// Initialize GitHub client
-	ghClient := github.NewClient(cfg.GitHub, logger)
+	clk := clock.Real()
+	ghClient := github.NewClient(cfg.GitHub, clk, logger)
 
 	// Initialize provider
-	prov, err := createProvider(cfg, logger)
+	prov, err := provider.New(cfg.Provider.Type, cfg.Provider, logger)
 	if err != nil {
 		return fmt.Errorf("failed to create provider: %w", err)
 	}
+	if cfg.Provider.CircuitBreaker.Enabled {
+		wrap := func(p provider.Provider) provider.Provider {
+			return breaker.Wrap(p, cfg.Provider.CircuitBreaker, met, logger)
+		}
+		// Spillover needs a circuit per provider to tell when to spill
+		if sp, ok := prov.(*spillover.SpilloverProvider); ok {
+			sp.WrapMembers(wrap)
+		} else {
+			prov = wrap(prov)
+		}
+	}
 	defer prov.Close()
 
 	// Initialize store
 	st, err := store.New(store.StoreConfig{
-		Enabled:   cfg.Store.Enabled,
-		Path:      cfg.Store.Path,
-		MaxEvents: cfg.Store.MaxEvents,
+		Enabled:      cfg.Store.Enabled,
+		Path:         cfg.Store.Path,
+		MaxEvents:    cfg.Store.MaxEvents,
+		MaxDecisions: cfg.Store.MaxDecisions,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create store: %w", err)
 	}
 
+	// Initialize notifications. The notifier outlives ctx, so that events
+	// raised while applying the shutdown policy are still delivered.
+	notifier, err := notify.New(cfg.Notifications, clk, met, logger)
+	if err != nil {
+		return fmt.Errorf("failed to create notifier: %w", err)
+	}
+	notifyCtx, stopNotifier := context.WithCancel(context.Background())
+	defer stopNotifier()
+	go notifier.Run(notifyCtx)
+
 	// Initialize controller
-	ctrl := controller.New(cfg, ghClient, prov, st, met, logger)
+	ctrl := controller.New(cfg, ghClient, prov, st, met, notifier, clk, logger)
+
+	// Reload configuration on SIGHUP and when the config file changes
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	reloader := reload.New(configPath, cfg, ctrl, prov, notifier, met, logger)
+	go reloader.Run(ctx, hupCh)
 
 	// Initialize API server
-	apiServer := api.New(cfg, prov, st, met, logger)
+	apiServer := api.New(cfg, ctrl, prov, st, met, logger)
 
 	// Start API server
 	go func() {
@@ -103,15 +159,18 @@ func run(configPath string) error {
 		LeaseDuration: cfg.LeaderElection.LeaseDuration,
 		RenewDeadline: cfg.LeaderElection.RenewDeadline,
 		RetryPeriod:   cfg.LeaderElection.RetryPeriod,
-	}, logger)
+	}, clk, logger)
 
-	// Start controller with leader election
+	// Start controller with leader election. Only the leader applies the
+	// shutdown policy; leadership lost to another instance is not a shutdown.
+	var leading atomic.Bool
 	errCh := make(chan error, 1)
 	go func() {
 		errCh <- le.Run(ctx,
 			func(ctx context.Context) {
 				logger.Info("became leader, starting controller")
 				met.LeaderElection.Set(1)
+				leading.Store(true)
 				if err := ctrl.Run(ctx); err != nil {
 					logger.Error("controller error", "error", err)
 				}
@@ -119,6 +178,9 @@ func run(configPath string) error {
 			func(ctx context.Context) {
 				logger.Info("stopped being leader")
 				met.LeaderElection.Set(0)
+				if ctx.Err() == nil {
+					leading.Store(false)
+				}
 			},
 		)
 	}()
@@ -128,25 +190,39 @@ func run(configPath string) error {
 	case <-sigCh:
 		logger.Info("received shutdown signal")
 		cancel()
+		<-errCh
 	case err := <-errCh:
 		if err != nil {
 			return err
 		}
 	}
 
-	logger.Info("shutdown complete")
-	return nil
-}
+	if leading.Load() {
+		// A second signal abandons the shutdown policy
+		shutdownCtx, stop := context.WithCancel(context.Background())
+		defer stop()
+		go func() {
+			select {
+			case <-sigCh:
+				logger.Warn("received second signal, abandoning shutdown policy")
+				stop()
+			case <-shutdownCtx.Done():
+			}
+		}()
 
-func createProvider(cfg *config.Config, logger *slog.Logger) (provider.Provider, error) {
-	switch cfg.Provider.Type {
-	case "docker":
-		return docker.New(cfg.Provider.Docker, logger)
-	case "ec2":
-		return ec2.New(cfg.Provider.AWS, logger)
-	default:
-		return nil, fmt.Errorf("unknown provider type: %s", cfg.Provider.Type)
+		if err := ctrl.Shutdown(shutdownCtx); err != nil {
+			logger.Error("shutdown policy incomplete", "policy", cfg.Shutdown.Policy, "error", err)
+		}
 	}
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), notificationFlushTimeout)
+	defer cancelFlush()
+	if err := notifier.Flush(flushCtx); err != nil {
+		logger.Warn("notifications left undelivered on shutdown", "error", err)
+	}
+
+	logger.Info("shutdown complete")
+	return nil
 }
 
 func setupLogger(level string) *slog.Logger {
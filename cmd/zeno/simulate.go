@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"Zeno/internal/config"
+	"Zeno/internal/controller"
+	"Zeno/internal/store"
+)
+
+// stringList collects a repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+type simulationResult struct {
+	Config string                       `json:"config"`
+	Report *controller.SimulationReport `json:"report"`
+}
+
+// runSimulate implements `zeno simulate`, replaying a queue trace against
+// one or more configuration files and reporting the results side by side
+func runSimulate(args []string) error {
+	fs := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	var configs stringList
+	fs.Var(&configs, "config", "Configuration file to simulate (repeat to compare)")
+	tracePath := fs.String("trace", "", "Queue depth trace: CSV (timestamp,queue_depth) or a store file")
+	bootLatency := fs.Duration("boot-latency", 60*time.Second, "Time for a simulated runner to become ready")
+	step := fs.Duration("step", 0, "Virtual reconcile interval (default: scaling.check_interval)")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *tracePath == "" {
+		return fmt.Errorf("-trace is required")
+	}
+	if len(configs) == 0 {
+		// Defaults and ZENO_* environment only
+		configs = append(configs, "")
+	}
+
+	samples, err := loadTrace(*tracePath)
+	if err != nil {
+		return fmt.Errorf("failed to load trace: %w", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	opts := controller.SimulationOptions{
+		BootLatency: *bootLatency,
+		Step:        *step,
+	}
+
+	results := make([]simulationResult, 0, len(configs))
+	for _, path := range configs {
+		cfg, err := config.LoadScaling(path)
+		if err != nil {
+			return fmt.Errorf("failed to load config %q: %w", path, err)
+		}
+
+		report, err := controller.Simulate(context.Background(), cfg, samples, opts, logger)
+		if err != nil {
+			return fmt.Errorf("simulation of %q failed: %w", path, err)
+		}
+
+		name := path
+		if name == "" {
+			name = "defaults"
+		}
+		results = append(results, simulationResult{Config: name, Report: report})
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+
+	printSimulationResults(os.Stdout, results)
+	return nil
+}
+
+func printSimulationResults(w io.Writer, results []simulationResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	rows := []struct {
+		label string
+		value func(r *controller.SimulationReport) string
+	}{
+		{"runner-minutes", func(r *controller.SimulationReport) string { return fmt.Sprintf("%.1f", r.RunnerMinutes) }},
+		{"queued-job-minutes", func(r *controller.SimulationReport) string { return fmt.Sprintf("%.1f", r.QueuedJobMinutes) }},
+		{"scale-up events", func(r *controller.SimulationReport) string { return strconv.Itoa(r.ScaleUpEvents) }},
+		{"scale-down events", func(r *controller.SimulationReport) string { return strconv.Itoa(r.ScaleDownEvents) }},
+		{"runners created", func(r *controller.SimulationReport) string { return strconv.Itoa(r.RunnersCreated) }},
+		{"runners removed", func(r *controller.SimulationReport) string { return strconv.Itoa(r.RunnersRemoved) }},
+		{"peak runners", func(r *controller.SimulationReport) string { return strconv.Itoa(r.PeakRunners) }},
+		{"hysteresis holds", func(r *controller.SimulationReport) string { return strconv.Itoa(r.HysteresisHolds) }},
+	}
+
+	header := []string{""}
+	for _, res := range results {
+		header = append(header, res.Config)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	for _, row := range rows {
+		cols := []string{row.label}
+		for _, res := range results {
+			cols = append(cols, row.value(res.Report))
+		}
+		fmt.Fprintln(tw, strings.Join(cols, "\t"))
+	}
+
+	if len(results) > 0 {
+		r := results[0].Report
+		fmt.Fprintf(tw, "\ntrace %s to %s, %d ticks\n",
+			r.Start.Format(time.RFC3339), r.End.Format(time.RFC3339), r.Ticks)
+	}
+
+	tw.Flush()
+}
+
+// loadTrace reads a CSV trace or, for any other extension, a store file
+func loadTrace(path string) ([]controller.QueueSample, error) {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return parseCSVTrace(f)
+	}
+	return loadStoreTrace(path)
+}
+
+// parseCSVTrace reads timestamp,queue_depth rows. Timestamps are RFC3339 or
+// unix seconds; a header row is skipped.
+func parseCSVTrace(r io.Reader) ([]controller.QueueSample, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []controller.QueueSample
+	for i, record := range records {
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: want timestamp,queue_depth", i+1)
+		}
+
+		depth, err := strconv.Atoi(record[1])
+		if err != nil {
+			if i == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: invalid queue depth %q", i+1, record[1])
+		}
+
+		ts, err := parseTraceTime(record[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		samples = append(samples, controller.QueueSample{Time: ts, QueueDepth: depth})
+	}
+
+	return samples, nil
+}
+
+func parseTraceTime(value string) (time.Time, error) {
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), nil
+	}
+	ts, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+	}
+	return ts, nil
+}
+
+// loadStoreTrace uses recorded decisions when present, since every
+// reconcile is recorded, and falls back to the sparser scale events
+func loadStoreTrace(path string) ([]controller.QueueSample, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	st, err := store.New(store.StoreConfig{Enabled: true, Path: path})
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []controller.QueueSample
+	for _, d := range st.QueryDecisions(store.DecisionQuery{}) {
+		samples = append(samples, controller.QueueSample{Time: d.Timestamp, QueueDepth: d.QueueDepth})
+	}
+	if len(samples) > 0 {
+		return samples, nil
+	}
+
+	for _, e := range st.GetAllEvents() {
+		samples = append(samples, controller.QueueSample{Time: e.Timestamp, QueueDepth: e.QueueDepth})
+	}
+	return samples, nil
+}
@@ -0,0 +1,137 @@
+// Command provider-plugin is a sample Zeno provider plugin. It keeps its
+// runners in memory, so it is useful for trying out the plugin protocol and
+// as a starting point for a real plugin. Point Zeno at it with:
+//
+//	provider:
+//	  type: "plugin"
+//	  plugin:
+//	    command: "/usr/local/bin/provider-plugin"
+//	    settings:
+//	      max_runners: 10
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"Zeno/pkg/providerplugin"
+)
+
+// Settings come from provider.plugin.settings in the Zeno configuration
+type Settings struct {
+	// MaxRunners caps the number of runners; 0 means no cap
+	MaxRunners int `json:"max_runners"`
+}
+
+type memoryProvider struct {
+	settings Settings
+	logger   *log.Logger
+
+	mu      sync.Mutex
+	nextID  int
+	runners map[string]*providerplugin.Runner
+}
+
+func newMemoryProvider(raw json.RawMessage) (providerplugin.Provider, error) {
+	var settings Settings
+	if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &settings); err != nil {
+			return nil, fmt.Errorf("invalid settings: %w", err)
+		}
+	}
+	if settings.MaxRunners < 0 {
+		return nil, fmt.Errorf("max_runners must be >= 0")
+	}
+
+	return &memoryProvider{
+		settings: settings,
+		// stdout belongs to the protocol; log to stderr, which Zeno
+		// forwards to its own log
+		logger:  log.New(os.Stderr, "", 0),
+		runners: make(map[string]*providerplugin.Runner),
+	}, nil
+}
+
+func (p *memoryProvider) Name() string {
+	return "memory"
+}
+
+func (p *memoryProvider) ListRunners(ctx context.Context) ([]*providerplugin.Runner, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	runners := make([]*providerplugin.Runner, 0, len(p.runners))
+	for _, r := range p.runners {
+		runners = append(runners, r)
+	}
+	sort.Slice(runners, func(i, j int) bool { return runners[i].CreatedAt.Before(runners[j].CreatedAt) })
+	return runners, nil
+}
+
+func (p *memoryProvider) GetRunner(ctx context.Context, id string) (*providerplugin.Runner, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r, ok := p.runners[id]
+	if !ok {
+		return nil, fmt.Errorf("runner not found: %s", id)
+	}
+	return r, nil
+}
+
+func (p *memoryProvider) CreateRunner(ctx context.Context, req *providerplugin.CreateRunnerRequest) (*providerplugin.Runner, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.settings.MaxRunners > 0 && len(p.runners) >= p.settings.MaxRunners {
+		return nil, fmt.Errorf("runner limit of %d reached", p.settings.MaxRunners)
+	}
+
+	p.nextID++
+	now := time.Now()
+	r := &providerplugin.Runner{
+		ID:         fmt.Sprintf("memory-%d", p.nextID),
+		Name:       req.Name,
+		Status:     providerplugin.StatusRunning,
+		Labels:     req.Labels,
+		ProviderID: fmt.Sprintf("memory-%d", p.nextID),
+		CreatedAt:  now,
+		LastSeen:   now,
+		Metadata:   req.Metadata,
+	}
+	p.runners[r.ID] = r
+	p.logger.Printf("created runner %s (%s)", r.ID, r.Name)
+	return r, nil
+}
+
+func (p *memoryProvider) RemoveRunner(ctx context.Context, id string, graceful bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.runners[id]; !ok {
+		return fmt.Errorf("runner not found: %s", id)
+	}
+	delete(p.runners, id)
+	p.logger.Printf("removed runner %s", id)
+	return nil
+}
+
+func (p *memoryProvider) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+func (p *memoryProvider) Close() error {
+	return nil
+}
+
+func main() {
+	if err := providerplugin.Serve(newMemoryProvider); err != nil {
+		log.Fatal(err)
+	}
+}
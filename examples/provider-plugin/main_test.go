@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"testing"
+
+	"Zeno/pkg/providerplugin/conformance"
+)
+
+// TestMain lets the test binary stand in for the plugin: the conformance
+// suite runs it again with ZENO_RUN_AS_PLUGIN set.
+func TestMain(m *testing.M) {
+	if os.Getenv("ZENO_RUN_AS_PLUGIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func() *exec.Cmd {
+		cmd := exec.Command(os.Args[0])
+		cmd.Env = append(os.Environ(), "ZENO_RUN_AS_PLUGIN=1")
+		return cmd
+	}, json.RawMessage(`{"max_runners": 10}`))
+}
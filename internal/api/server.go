@@ -2,15 +2,19 @@ package api
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"Zeno/internal/config"
+	"Zeno/internal/controller"
 	"Zeno/internal/metrics"
 	"Zeno/internal/provider"
+	"Zeno/internal/provider/breaker"
 	"Zeno/internal/store"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -18,6 +22,7 @@ import (
 
 type Server struct {
 	config      *config.Config
+	controller  *controller.Controller
 	provider    provider.Provider
 	store       *store.Store
 	metrics     *metrics.Metrics
@@ -28,17 +33,19 @@ type Server struct {
 // New creates a new API server
 func New(
 	cfg *config.Config,
+	ctrl *controller.Controller,
 	prov provider.Provider,
 	st *store.Store,
 	met *metrics.Metrics,
 	logger *slog.Logger,
 ) *Server {
 	return &Server{
-		config:   cfg,
-		provider: prov,
-		store:    st,
-		metrics:  met,
-		logger:   logger.With("component", "api-server"),
+		config:     cfg,
+		controller: ctrl,
+		provider:   prov,
+		store:      st,
+		metrics:    met,
+		logger:     logger.With("component", "api-server"),
 	}
 }
 
@@ -59,6 +66,17 @@ func (s *Server) Start(ctx context.Context) error {
 	mux.HandleFunc("/api/v1/status", s.authMiddleware(s.handleStatus))
 	mux.HandleFunc("/api/v1/runners", s.authMiddleware(s.handleRunners))
 	mux.HandleFunc("/api/v1/events", s.authMiddleware(s.handleEvents))
+	mux.HandleFunc("GET /api/v1/decisions", s.authMiddleware(s.handleDecisions))
+
+	// Operator overrides
+	mux.HandleFunc("GET /api/v1/overrides", s.requireAuth(s.handleGetOverrides))
+	mux.HandleFunc("POST /api/v1/overrides/pause", s.requireAuth(s.handlePause))
+	mux.HandleFunc("POST /api/v1/overrides/resume", s.requireAuth(s.handleResume))
+	mux.HandleFunc("POST /api/v1/overrides/pin", s.requireAuth(s.handlePin))
+	mux.HandleFunc("DELETE /api/v1/overrides/pin", s.requireAuth(s.handleClearPin))
+	mux.HandleFunc("POST /api/v1/overrides/boost", s.requireAuth(s.handleBoost))
+	mux.HandleFunc("DELETE /api/v1/overrides/boost", s.requireAuth(s.handleClearBoost))
+	mux.HandleFunc("POST /api/v1/reconcile", s.requireAuth(s.handleReconcile))
 
 	addr := fmt.Sprintf("%s:%d", s.config.Server.Address, s.config.Server.Port)
 	s.httpServer = &http.Server{
@@ -100,20 +118,40 @@ func (s *Server) handleReadiness(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
+	// Circuit breaker state, if the provider is guarded by one
+	var circuits map[string]breaker.State
+	if reporter, ok := s.provider.(breaker.Reporter); ok {
+		circuits = reporter.CircuitStates()
+	}
+
 	if err := s.provider.HealthCheck(ctx); err != nil {
 		s.logger.Error("readiness check failed", "error", err)
 		w.WriteHeader(http.StatusServiceUnavailable)
-		json.NewEncoder(w).Encode(map[string]string{
-			"status": "not ready",
-			"error":  err.Error(),
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":   "not ready",
+			"error":    err.Error(),
+			"circuits": circuits,
 		})
 		return
 	}
 
+	for op, state := range circuits {
+		if state == breaker.StateOpen {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"status":   "not ready",
+				"error":    fmt.Sprintf("provider circuit open for %s", op),
+				"circuits": circuits,
+			})
+			return
+		}
+	}
+
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"status": "ready",
-		"time":   time.Now().Format(time.RFC3339),
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "ready",
+		"time":     time.Now().Format(time.RFC3339),
+		"circuits": circuits,
 	})
 }
 
@@ -126,18 +164,70 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The controller holds the latest reloaded scaling settings
+	cfg := s.config
+	if s.controller != nil {
+		cfg = s.controller.Config()
+	}
+
 	response := map[string]interface{}{
 		"timestamp":     time.Now().Format(time.RFC3339),
 		"runner_count":  len(runners),
-		"min_runners":   s.config.Scaling.MinRunners,
-		"max_runners":   s.config.Scaling.MaxRunners,
+		"min_runners":   cfg.Scaling.MinRunners,
+		"max_runners":   cfg.Scaling.MaxRunners,
 		"provider":      s.provider.Name(),
 		"dry_run":       s.config.DryRun,
 	}
 
+	if s.controller != nil {
+		response["budget"] = s.controller.BudgetStatus()
+		response["overrides"] = s.controller.Overrides()
+	}
+
+	if mix, ok := lifecycleMix(runners, cfg.Provider); ok {
+		response["lifecycle_mix"] = mix
+	}
+
 	s.writeJSON(w, http.StatusOK, response)
 }
 
+// lifecycleMix counts active runners on spot and on-demand capacity, with
+// the EC2 spot mix policy when it is enabled. It reports false when no
+// runner has a lifecycle and no policy is set.
+func lifecycleMix(runners []*provider.Runner, cfg config.ProviderConfig) (map[string]interface{}, bool) {
+	spot, onDemand := 0, 0
+	for _, r := range runners {
+		if r.Status == provider.StatusTerminated || r.Status == provider.StatusDraining {
+			continue
+		}
+		switch r.Metadata[provider.MetadataLifecycle] {
+		case provider.LifecycleSpot:
+			spot++
+		case provider.LifecycleOnDemand:
+			onDemand++
+		}
+	}
+
+	policy := cfg.AWS.SpotMix
+	enabled := policy.Enabled && cfg.Uses("ec2")
+	if spot+onDemand == 0 && !enabled {
+		return nil, false
+	}
+
+	mix := map[string]interface{}{
+		"spot":      spot,
+		"on_demand": onDemand,
+	}
+	if enabled {
+		mix["policy"] = map[string]interface{}{
+			"on_demand_base":  policy.OnDemandBase,
+			"spot_percentage": policy.SpotPercentage,
+			"target_spot":     policy.SpotRunners(spot + onDemand),
+		}
+	}
+	return mix, true
+}
+
 func (s *Server) handleRunners(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -169,6 +259,165 @@ func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleDecisions returns recorded scaling decisions, optionally filtered
+// by time range (RFC3339 since/until) and action
+func (s *Server) handleDecisions(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil || !s.config.Store.Enabled {
+		s.writeError(w, http.StatusNotFound, "store not enabled", nil)
+		return
+	}
+
+	params := r.URL.Query()
+	query := store.DecisionQuery{
+		Action: params.Get("action"),
+		Limit:  100,
+	}
+
+	for name, dst := range map[string]*time.Time{"since": &query.Since, "until": &query.Until} {
+		value := params.Get(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "invalid "+name+" timestamp", err)
+			return
+		}
+		*dst = t
+	}
+
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			s.writeError(w, http.StatusBadRequest, "invalid limit", err)
+			return
+		}
+		query.Limit = limit
+	}
+
+	decisions := s.store.QueryDecisions(query)
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"timestamp": time.Now().Format(time.RFC3339),
+		"count":     len(decisions),
+		"decisions": decisions,
+	})
+}
+
+type pinRequest struct {
+	Count    int    `json:"count"`
+	Duration string `json:"duration"`
+}
+
+type boostRequest struct {
+	MinRunners int    `json:"min_runners"`
+	Duration   string `json:"duration"`
+}
+
+func (s *Server) handleGetOverrides(w http.ResponseWriter, r *http.Request) {
+	s.writeOverrides(w)
+}
+
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	if err := s.controller.Pause(); err != nil {
+		s.writeError(w, http.StatusInternalServerError, "failed to pause autoscaling", err)
+		return
+	}
+	s.logger.Warn("autoscaling paused by operator", "remote_addr", r.RemoteAddr)
+	s.writeOverrides(w)
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	if err := s.controller.Resume(); err != nil {
+		s.writeError(w, http.StatusInternalServerError, "failed to resume autoscaling", err)
+		return
+	}
+	s.logger.Info("autoscaling resumed by operator", "remote_addr", r.RemoteAddr)
+	s.writeOverrides(w)
+}
+
+func (s *Server) handlePin(w http.ResponseWriter, r *http.Request) {
+	var req pinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid duration", err)
+		return
+	}
+
+	if err := s.controller.PinDesiredCount(req.Count, time.Now().Add(duration)); err != nil {
+		s.writeError(w, http.StatusBadRequest, "failed to pin desired count", err)
+		return
+	}
+	s.logger.Warn("desired count pinned by operator",
+		"count", req.Count,
+		"duration", duration,
+		"remote_addr", r.RemoteAddr,
+	)
+	s.writeOverrides(w)
+}
+
+func (s *Server) handleClearPin(w http.ResponseWriter, r *http.Request) {
+	if err := s.controller.ClearPin(); err != nil {
+		s.writeError(w, http.StatusInternalServerError, "failed to clear pin", err)
+		return
+	}
+	s.writeOverrides(w)
+}
+
+func (s *Server) handleBoost(w http.ResponseWriter, r *http.Request) {
+	var req boostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid duration", err)
+		return
+	}
+
+	if err := s.controller.BoostMinRunners(req.MinRunners, time.Now().Add(duration)); err != nil {
+		s.writeError(w, http.StatusBadRequest, "failed to boost min runners", err)
+		return
+	}
+	s.logger.Warn("min runners boosted by operator",
+		"min_runners", req.MinRunners,
+		"duration", duration,
+		"remote_addr", r.RemoteAddr,
+	)
+	s.writeOverrides(w)
+}
+
+func (s *Server) handleClearBoost(w http.ResponseWriter, r *http.Request) {
+	if err := s.controller.ClearBoost(); err != nil {
+		s.writeError(w, http.StatusInternalServerError, "failed to clear boost", err)
+		return
+	}
+	s.writeOverrides(w)
+}
+
+func (s *Server) handleReconcile(w http.ResponseWriter, r *http.Request) {
+	triggered := s.controller.TriggerReconcile()
+	s.writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"timestamp": time.Now().Format(time.RFC3339),
+		"triggered": triggered,
+		"pending":   !triggered,
+	})
+}
+
+func (s *Server) writeOverrides(w http.ResponseWriter) {
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"timestamp": time.Now().Format(time.RFC3339),
+		"overrides": s.controller.Overrides(),
+	})
+}
+
 func (s *Server) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if !s.config.Server.EnableAuth {
@@ -176,23 +425,50 @@ func (s *Server) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		apiKey := r.Header.Get("X-API-Key")
-		if apiKey == "" {
-			apiKey = r.Header.Get("Authorization")
-			if len(apiKey) > 7 && apiKey[:7] == "Bearer " {
-				apiKey = apiKey[7:]
-			}
+		if !s.authorized(r) {
+			s.writeError(w, http.StatusUnauthorized, "unauthorized", nil)
+			return
 		}
 
-		if apiKey != s.config.Server.APIKey {
+		next(w, r)
+	}
+}
+
+// requireAuth guards endpoints that change controller behaviour. Unlike
+// authMiddleware it refuses requests when authentication is disabled.
+func (s *Server) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.config.Server.EnableAuth {
+			s.writeError(w, http.StatusForbidden, "server.enable_auth must be true to use this endpoint", nil)
+			return
+		}
+
+		if !s.authorized(r) {
 			s.writeError(w, http.StatusUnauthorized, "unauthorized", nil)
 			return
 		}
 
+		if s.controller == nil {
+			s.writeError(w, http.StatusServiceUnavailable, "controller not available", nil)
+			return
+		}
+
 		next(w, r)
 	}
 }
 
+func (s *Server) authorized(r *http.Request) bool {
+	apiKey := r.Header.Get("X-API-Key")
+	if apiKey == "" {
+		apiKey = r.Header.Get("Authorization")
+		if len(apiKey) > 7 && apiKey[:7] == "Bearer " {
+			apiKey = apiKey[7:]
+		}
+	}
+
+	return subtle.ConstantTimeCompare([]byte(apiKey), []byte(s.config.Server.APIKey)) == 1
+}
+
 func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -0,0 +1,216 @@
+package budget
+
+import (
+	"sync"
+	"time"
+
+	"Zeno/internal/config"
+	"Zeno/internal/provider"
+)
+
+// Tracker accrues runner cost from hourly prices and enforces spend limits.
+// Spend is tracked in calendar windows: the current UTC hour and day.
+type Tracker struct {
+	config config.BudgetConfig
+	mu     sync.RWMutex
+
+	// lastAccrued is the time up to which each runner has been charged
+	lastAccrued map[string]time.Time
+
+	hourStart time.Time
+	dayStart  time.Time
+	hourly    float64
+	daily     float64
+	total     float64
+}
+
+// Status is a point-in-time view of accrued spend
+type Status struct {
+	Enabled        bool    `json:"enabled"`
+	HourlySpend    float64 `json:"hourly_spend"`
+	DailySpend     float64 `json:"daily_spend"`
+	TotalSpend     float64 `json:"total_spend"`
+	MaxHourlySpend float64 `json:"max_hourly_spend"`
+	MaxDailySpend  float64 `json:"max_daily_spend"`
+	Exhausted      bool    `json:"exhausted"`
+	ExhaustedBy    string  `json:"exhausted_by,omitempty"`
+}
+
+// Spend is the accrued spend of a tracker, saved so a restarted controller
+// does not start its budget windows from zero
+type Spend struct {
+	HourStart time.Time
+	DayStart  time.Time
+	Hourly    float64
+	Daily     float64
+	Total     float64
+
+	// LastAccrued is the time up to which each runner has been charged
+	LastAccrued map[string]time.Time
+}
+
+// New creates a new budget tracker
+func New(cfg config.BudgetConfig) *Tracker {
+	return &Tracker{
+		config:      cfg,
+		lastAccrued: make(map[string]time.Time),
+	}
+}
+
+// Observe charges every live runner for the time since it was last observed,
+// or since its CreatedAt if it is new, and returns the cost added.
+func (t *Tracker) Observe(runners []*provider.Runner, now time.Time) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rollWindows(now)
+
+	var added float64
+	seen := make(map[string]time.Time, len(runners))
+
+	for _, r := range runners {
+		from, ok := t.lastAccrued[r.ID]
+		if !ok {
+			from = r.CreatedAt
+			if from.IsZero() || from.After(now) {
+				from = now
+			}
+		}
+		seen[r.ID] = now
+
+		// Exited containers and stopped instances are not billed
+		if r.Status == provider.StatusTerminated {
+			continue
+		}
+
+		price := t.PriceFor(r)
+		if price <= 0 || !now.After(from) {
+			continue
+		}
+
+		cost := price * now.Sub(from).Hours()
+		added += cost
+		t.total += cost
+		t.hourly += price * now.Sub(latest(from, t.hourStart)).Hours()
+		t.daily += price * now.Sub(latest(from, t.dayStart)).Hours()
+	}
+
+	// Runners that disappeared stop accruing
+	t.lastAccrued = seen
+
+	return added
+}
+
+// Spend returns the accrued spend
+func (t *Tracker) Spend() Spend {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	accrued := make(map[string]time.Time, len(t.lastAccrued))
+	for id, at := range t.lastAccrued {
+		accrued[id] = at
+	}
+	return Spend{
+		HourStart:   t.hourStart,
+		DayStart:    t.dayStart,
+		Hourly:      t.hourly,
+		Daily:       t.daily,
+		Total:       t.total,
+		LastAccrued: accrued,
+	}
+}
+
+// Restore picks up spend saved by a previous tracker. Windows that have
+// passed since are reset by the next Observe, and runners still running
+// are charged from where they were left.
+func (t *Tracker) Restore(spend Spend) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.hourStart = spend.HourStart
+	t.dayStart = spend.DayStart
+	t.hourly = spend.Hourly
+	t.daily = spend.Daily
+	t.total = spend.Total
+	t.lastAccrued = make(map[string]time.Time, len(spend.LastAccrued))
+	for id, at := range spend.LastAccrued {
+		t.lastAccrued[id] = at
+	}
+}
+
+// Exhausted reports whether the hourly or daily limit has been reached and,
+// if so, which one
+func (t *Tracker) Exhausted() (bool, string) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.exhausted()
+}
+
+// Status returns the current spend against the configured limits
+func (t *Tracker) Status() Status {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	exhausted, by := t.exhausted()
+	return Status{
+		Enabled:        t.config.Enabled,
+		HourlySpend:    t.hourly,
+		DailySpend:     t.daily,
+		TotalSpend:     t.total,
+		MaxHourlySpend: t.config.MaxHourlySpend,
+		MaxDailySpend:  t.config.MaxDailySpend,
+		Exhausted:      exhausted,
+		ExhaustedBy:    by,
+	}
+}
+
+// PriceFor returns the hourly price of a runner. EC2 runners are priced by
+// instance type, Docker runners by pool; anything else uses the default.
+func (t *Tracker) PriceFor(r *provider.Runner) float64 {
+	for _, key := range []string{r.Metadata["instance_type"], r.Metadata["pool"]} {
+		if key == "" {
+			continue
+		}
+		if price, ok := t.config.Prices[key]; ok {
+			return price
+		}
+	}
+	return t.config.DefaultPrice
+}
+
+func (t *Tracker) exhausted() (bool, string) {
+	if !t.config.Enabled {
+		return false, ""
+	}
+	if t.config.MaxHourlySpend > 0 && t.hourly >= t.config.MaxHourlySpend {
+		return true, "hourly"
+	}
+	if t.config.MaxDailySpend > 0 && t.daily >= t.config.MaxDailySpend {
+		return true, "daily"
+	}
+	return false, ""
+}
+
+func (t *Tracker) rollWindows(now time.Time) {
+	now = now.UTC()
+
+	hourStart := now.Truncate(time.Hour)
+	if !hourStart.Equal(t.hourStart) {
+		t.hourStart = hourStart
+		t.hourly = 0
+	}
+
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if !dayStart.Equal(t.dayStart) {
+		t.dayStart = dayStart
+		t.daily = 0
+	}
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
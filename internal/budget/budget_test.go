@@ -0,0 +1,140 @@
+package budget
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"Zeno/internal/config"
+	"Zeno/internal/provider"
+)
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestObserveAccruesFromCreatedAt(t *testing.T) {
+	tracker := New(config.BudgetConfig{
+		Enabled:        true,
+		MaxHourlySpend: 10,
+		Prices:         map[string]float64{"t3.medium": 2, "docker": 1},
+	})
+
+	now := time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC)
+	runners := []*provider.Runner{
+		{ID: "ec2", CreatedAt: now.Add(-15 * time.Minute), Metadata: map[string]string{"instance_type": "t3.medium"}},
+		{ID: "docker", CreatedAt: now.Add(-30 * time.Minute), Metadata: map[string]string{"pool": "docker"}},
+	}
+
+	added := tracker.Observe(runners, now)
+
+	// 0.25h * $2 + 0.5h * $1
+	if !approxEqual(added, 1.0) {
+		t.Errorf("Observe() = %v, want 1.0", added)
+	}
+
+	// Second observation only charges the elapsed interval
+	added = tracker.Observe(runners, now.Add(6*time.Minute))
+	if !approxEqual(added, 0.3) {
+		t.Errorf("Observe() = %v, want 0.3", added)
+	}
+
+	status := tracker.Status()
+	if !approxEqual(status.TotalSpend, 1.3) {
+		t.Errorf("TotalSpend = %v, want 1.3", status.TotalSpend)
+	}
+	if !approxEqual(status.HourlySpend, 1.3) {
+		t.Errorf("HourlySpend = %v, want 1.3", status.HourlySpend)
+	}
+}
+
+func TestObserveRollsHourlyWindow(t *testing.T) {
+	tracker := New(config.BudgetConfig{
+		Enabled:      true,
+		DefaultPrice: 1,
+	})
+
+	start := time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC)
+	runners := []*provider.Runner{{ID: "r1", CreatedAt: start}}
+
+	tracker.Observe(runners, start)
+	tracker.Observe(runners, start.Add(time.Hour))
+
+	status := tracker.Status()
+
+	// Only the half hour since 13:00 counts towards the current hour
+	if !approxEqual(status.HourlySpend, 0.5) {
+		t.Errorf("HourlySpend = %v, want 0.5", status.HourlySpend)
+	}
+	if !approxEqual(status.DailySpend, 1.0) {
+		t.Errorf("DailySpend = %v, want 1.0", status.DailySpend)
+	}
+}
+
+func TestObserveSkipsTerminatedRunners(t *testing.T) {
+	tracker := New(config.BudgetConfig{DefaultPrice: 1})
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	runners := []*provider.Runner{
+		{ID: "r1", Status: provider.StatusTerminated, CreatedAt: now.Add(-time.Hour)},
+	}
+
+	if added := tracker.Observe(runners, now); added != 0 {
+		t.Errorf("Observe() = %v, want 0 for terminated runner", added)
+	}
+}
+
+func TestExhausted(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.BudgetConfig
+		hours  float64
+		want   bool
+		wantBy string
+	}{
+		{
+			name:  "under limits",
+			cfg:   config.BudgetConfig{Enabled: true, MaxHourlySpend: 2, DefaultPrice: 1},
+			hours: 0.5,
+			want:  false,
+		},
+		{
+			name:   "hourly limit reached",
+			cfg:    config.BudgetConfig{Enabled: true, MaxHourlySpend: 0.5, DefaultPrice: 1},
+			hours:  0.5,
+			want:   true,
+			wantBy: "hourly",
+		},
+		{
+			name:   "daily limit reached",
+			cfg:    config.BudgetConfig{Enabled: true, MaxDailySpend: 0.25, DefaultPrice: 1},
+			hours:  0.5,
+			want:   true,
+			wantBy: "daily",
+		},
+		{
+			name:  "disabled never exhausts",
+			cfg:   config.BudgetConfig{Enabled: false, MaxHourlySpend: 0.1, DefaultPrice: 1},
+			hours: 0.5,
+			want:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := New(tt.cfg)
+			now := time.Date(2024, 6, 1, 12, 59, 0, 0, time.UTC)
+			created := now.Add(-time.Duration(tt.hours * float64(time.Hour)))
+
+			tracker.Observe([]*provider.Runner{{ID: "r1", CreatedAt: created}}, now)
+
+			got, by := tracker.Exhausted()
+			if got != tt.want {
+				t.Errorf("Exhausted() = %v, want %v", got, tt.want)
+			}
+			if by != tt.wantBy {
+				t.Errorf("Exhausted() by = %q, want %q", by, tt.wantBy)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock that only moves when told to. Timers and tickers fire
+// as Advance or Set moves the clock past their deadlines.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+	changed chan struct{}
+}
+
+type waiter struct {
+	at      time.Time
+	period  time.Duration
+	ch      chan time.Time
+	stopped bool
+}
+
+// NewFake returns a fake clock set to now
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now, changed: make(chan struct{})}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+func (f *Fake) Until(t time.Time) time.Duration {
+	return t.Sub(f.Now())
+}
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w := &waiter{at: f.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.ch <- f.now
+		return w.ch
+	}
+	f.addLocked(w)
+	return w.ch
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w := &waiter{at: f.now.Add(d), period: d, ch: make(chan time.Time, 1)}
+	f.addLocked(w)
+	return &fakeTicker{clock: f, w: w}
+}
+
+// Advance moves the clock forward by d, firing due timers and tickers in
+// deadline order
+func (f *Fake) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set moves the clock to t. Moving backwards fires nothing.
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for {
+		next := f.nextDueLocked(t)
+		if next == nil {
+			break
+		}
+
+		f.now = next.at
+		// Like time.Ticker, a tick is dropped if the last one is unread
+		select {
+		case next.ch <- next.at:
+		default:
+		}
+
+		if next.period > 0 {
+			next.at = next.at.Add(next.period)
+		} else {
+			f.removeLocked(next)
+		}
+	}
+
+	if t.After(f.now) {
+		f.now = t
+	}
+}
+
+// BlockUntil waits until at least n timers or tickers are pending. Tests
+// use it to know a goroutine has started waiting on the clock.
+func (f *Fake) BlockUntil(n int) {
+	for {
+		f.mu.Lock()
+		pending := len(f.waiters)
+		changed := f.changed
+		f.mu.Unlock()
+
+		if pending >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+func (f *Fake) nextDueLocked(t time.Time) *waiter {
+	var next *waiter
+	for _, w := range f.waiters {
+		if w.at.After(t) {
+			continue
+		}
+		if next == nil || w.at.Before(next.at) {
+			next = w
+		}
+	}
+	return next
+}
+
+func (f *Fake) addLocked(w *waiter) {
+	f.waiters = append(f.waiters, w)
+	f.notifyLocked()
+}
+
+func (f *Fake) removeLocked(w *waiter) {
+	for i, other := range f.waiters {
+		if other == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			break
+		}
+	}
+	f.notifyLocked()
+}
+
+func (f *Fake) notifyLocked() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+type fakeTicker struct {
+	clock *Fake
+	w     *waiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.w.ch
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	if !t.w.stopped {
+		t.w.stopped = true
+		t.clock.removeLocked(t.w)
+	}
+}
@@ -0,0 +1,80 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestFakeTicker(t *testing.T) {
+	c := NewFake(epoch)
+	ticker := c.NewTicker(time.Minute)
+
+	c.Advance(59 * time.Second)
+	select {
+	case <-ticker.C():
+		t.Fatal("ticker fired before its interval")
+	default:
+	}
+
+	c.Advance(time.Second)
+	select {
+	case tick := <-ticker.C():
+		if !tick.Equal(epoch.Add(time.Minute)) {
+			t.Errorf("tick = %v, want %v", tick, epoch.Add(time.Minute))
+		}
+	default:
+		t.Fatal("ticker did not fire after its interval")
+	}
+
+	// Unread ticks are dropped rather than queued
+	c.Advance(5 * time.Minute)
+	<-ticker.C()
+	select {
+	case <-ticker.C():
+		t.Error("ticker queued more than one tick")
+	default:
+	}
+
+	ticker.Stop()
+	c.Advance(time.Hour)
+	select {
+	case <-ticker.C():
+		t.Error("stopped ticker fired")
+	default:
+	}
+}
+
+func TestFakeAfter(t *testing.T) {
+	c := NewFake(epoch)
+	ch := c.After(30 * time.Second)
+
+	c.Advance(time.Minute)
+	select {
+	case fired := <-ch:
+		if !fired.Equal(epoch.Add(30 * time.Second)) {
+			t.Errorf("fired at %v, want %v", fired, epoch.Add(30*time.Second))
+		}
+	default:
+		t.Fatal("After did not fire")
+	}
+
+	if got := c.Since(epoch); got != time.Minute {
+		t.Errorf("Since() = %v, want 1m", got)
+	}
+}
+
+func TestFakeBlockUntil(t *testing.T) {
+	c := NewFake(epoch)
+	done := make(chan struct{})
+
+	go func() {
+		<-c.After(time.Second)
+		close(done)
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(time.Second)
+	<-done
+}
@@ -2,9 +2,13 @@ package config
 
 import (
 	"fmt"
+	"path"
+	"reflect"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 )
 
@@ -16,6 +20,9 @@ type Config struct {
 	Observability  ObservabilityConfig  `mapstructure:"observability"`
 	LeaderElection LeaderElectionConfig `mapstructure:"leader_election"`
 	Store          StoreConfig          `mapstructure:"store"`
+	Budget         BudgetConfig         `mapstructure:"budget"`
+	Notifications  NotificationsConfig  `mapstructure:"notifications"`
+	Shutdown       ShutdownConfig       `mapstructure:"shutdown"`
 	DryRun         bool                 `mapstructure:"dry_run"`
 	LogLevel       string               `mapstructure:"log_level"`
 }
@@ -44,24 +51,115 @@ type GitHubConfig struct {
 }
 
 type ScalingConfig struct {
-	MinRunners              int           `mapstructure:"min_runners"`
-	MaxRunners              int           `mapstructure:"max_runners"`
-	ScaleUpThreshold        int           `mapstructure:"scale_up_threshold"`
-	ScaleDownThreshold      int           `mapstructure:"scale_down_threshold"`
-	ScaleUpHysteresis       int           `mapstructure:"scale_up_hysteresis"`
-	ScaleDownHysteresis     int           `mapstructure:"scale_down_hysteresis"`
-	CheckInterval           time.Duration `mapstructure:"check_interval"`
-	CooldownPeriod          time.Duration `mapstructure:"cooldown_period"`
-	EnablePredictiveScaling bool          `mapstructure:"enable_predictive_scaling"`
-	PredictionWindow        time.Duration `mapstructure:"prediction_window"`
-	GracefulTermination     bool          `mapstructure:"graceful_termination"`
-	TerminationTimeout      time.Duration `mapstructure:"termination_timeout"`
+	MinRunners              int             `mapstructure:"min_runners"`
+	MaxRunners              int             `mapstructure:"max_runners"`
+	ScaleUpThreshold        int             `mapstructure:"scale_up_threshold"`
+	ScaleDownThreshold      int             `mapstructure:"scale_down_threshold"`
+	ScaleUpHysteresis       int             `mapstructure:"scale_up_hysteresis"`
+	ScaleDownHysteresis     int             `mapstructure:"scale_down_hysteresis"`
+	CheckInterval           time.Duration   `mapstructure:"check_interval"`
+	CooldownPeriod          time.Duration   `mapstructure:"cooldown_period"`
+	EnablePredictiveScaling bool            `mapstructure:"enable_predictive_scaling"`
+	PredictionWindow        time.Duration   `mapstructure:"prediction_window"`
+	GracefulTermination     bool            `mapstructure:"graceful_termination"`
+	TerminationTimeout      time.Duration   `mapstructure:"termination_timeout"`
+	Ephemeral               bool            `mapstructure:"ephemeral"`
+	RegistrationTimeout     time.Duration   `mapstructure:"registration_timeout"`
+	FairShare               FairShareConfig `mapstructure:"fair_share"`
+	Priority                PriorityConfig  `mapstructure:"priority"`
+}
+
+// PriorityConfig sorts queued jobs into high, normal and low priority
+// classes. max_runners is a soft maximum that normal and low priority jobs
+// share; high priority jobs may take the pool up to hard_max_runners
+// (0 = no higher than max_runners).
+type PriorityConfig struct {
+	Enabled        bool           `mapstructure:"enabled"`
+	HardMaxRunners int            `mapstructure:"hard_max_runners"`
+	Rules          []PriorityRule `mapstructure:"rules"`
+}
+
+// PriorityRule puts jobs in a class when their workflow name, repository or
+// one of their labels matches. Patterns use path.Match syntax; the first
+// matching rule wins and unmatched jobs are normal priority.
+type PriorityRule struct {
+	Class        string   `mapstructure:"class"`
+	Workflows    []string `mapstructure:"workflows"`
+	Repositories []string `mapstructure:"repositories"`
+	Labels       []string `mapstructure:"labels"`
+}
+
+// FairShareConfig divides max_runners between the repositories of an
+// organization while the pool is contended. Repositories without an entry
+// get the default weight and no minimum or cap.
+type FairShareConfig struct {
+	Enabled       bool              `mapstructure:"enabled"`
+	DefaultWeight int               `mapstructure:"default_weight"`
+	Repositories  []RepositoryQuota `mapstructure:"repositories"`
+}
+
+// RepositoryQuota is the share of runners one repository is entitled to.
+// Min runners are handed out before weights are applied; Max (0 = none) is
+// a hard cap.
+type RepositoryQuota struct {
+	Name   string `mapstructure:"name"`
+	Weight int    `mapstructure:"weight"`
+	Min    int    `mapstructure:"min"`
+	Max    int    `mapstructure:"max"`
 }
 
 type ProviderConfig struct {
-	Type   string        `mapstructure:"type"`
-	Docker DockerConfig  `mapstructure:"docker"`
-	AWS    AWSConfig     `mapstructure:"aws"`
+	Type           string               `mapstructure:"type"`
+	Docker         DockerConfig         `mapstructure:"docker"`
+	AWS            AWSConfig            `mapstructure:"aws"`
+	Kubernetes     KubernetesConfig     `mapstructure:"kubernetes"`
+	Process        ProcessConfig        `mapstructure:"process"`
+	SSH            SSHConfig            `mapstructure:"ssh"`
+	GCE            GCEConfig            `mapstructure:"gce"`
+	Azure          AzureConfig          `mapstructure:"azure"`
+	Plugin         PluginConfig         `mapstructure:"plugin"`
+	Spillover      SpilloverConfig      `mapstructure:"spillover"`
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+}
+
+// Uses reports whether typ is the provider type or one of the spillover
+// providers
+func (c ProviderConfig) Uses(typ string) bool {
+	if c.Type == typ {
+		return true
+	}
+	if c.Type != "spillover" {
+		return false
+	}
+	for _, m := range c.Spillover.Providers {
+		if m.Type == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// SpilloverConfig combines providers in priority order. New runners go to
+// the first provider below its maximum whose circuit is not open; scale-down
+// removes runners from the most expensive provider first. Each provider is
+// configured in its own section, as when it is used alone.
+type SpilloverConfig struct {
+	Providers []SpilloverProviderConfig `mapstructure:"providers"`
+}
+
+// SpilloverProviderConfig is one provider of a spillover pool. Cost is the
+// hourly price of one of its runners and only orders scale-down.
+type SpilloverProviderConfig struct {
+	Type       string  `mapstructure:"type"`
+	MaxRunners int     `mapstructure:"max_runners"`
+	Cost       float64 `mapstructure:"cost"`
+}
+
+type CircuitBreakerConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	FailureThreshold int           `mapstructure:"failure_threshold"`
+	BaseBackoff      time.Duration `mapstructure:"base_backoff"`
+	MaxBackoff       time.Duration `mapstructure:"max_backoff"`
 }
 
 type DockerConfig struct {
@@ -75,22 +173,214 @@ type DockerConfig struct {
 	Volumes            []string          `mapstructure:"volumes"`
 	RegistryAuth       string            `mapstructure:"registry_auth"`
 	PullPolicy         string            `mapstructure:"pull_policy"`
+	Pool               string            `mapstructure:"pool"`
 }
 
 type AWSConfig struct {
-	Region                string            `mapstructure:"region"`
-	InstanceType          string            `mapstructure:"instance_type"`
-	AMI                   string            `mapstructure:"ami"`
-	SubnetID              string            `mapstructure:"subnet_id"`
-	SecurityGroupIDs      []string          `mapstructure:"security_group_ids"`
-	KeyName               string            `mapstructure:"key_name"`
-	IAMInstanceProfile    string            `mapstructure:"iam_instance_profile"`
-	UseSpot               bool              `mapstructure:"use_spot"`
-	SpotMaxPrice          string            `mapstructure:"spot_max_price"`
-	Tags                  map[string]string `mapstructure:"tags"`
-	UserDataScript        string            `mapstructure:"user_data_script"`
-	VolumeSize            int32             `mapstructure:"volume_size"`
-	VolumeType            string            `mapstructure:"volume_type"`
+	Region             string               `mapstructure:"region"`
+	InstanceType       string               `mapstructure:"instance_type"`
+	AMI                string               `mapstructure:"ami"`
+	SubnetID           string               `mapstructure:"subnet_id"`
+	SecurityGroupIDs   []string             `mapstructure:"security_group_ids"`
+	KeyName            string               `mapstructure:"key_name"`
+	IAMInstanceProfile string               `mapstructure:"iam_instance_profile"`
+	UseSpot            bool                 `mapstructure:"use_spot"`
+	SpotMaxPrice       string               `mapstructure:"spot_max_price"`
+	SpotMix            SpotMixConfig        `mapstructure:"spot_mix"`
+	Tags               map[string]string    `mapstructure:"tags"`
+	UserDataScript     string               `mapstructure:"user_data_script"`
+	VolumeSize         int32                `mapstructure:"volume_size"`
+	VolumeType         string               `mapstructure:"volume_type"`
+	LaunchTemplate     LaunchTemplateConfig `mapstructure:"launch_template"`
+	Fleet              FleetConfig          `mapstructure:"fleet"`
+	WarmPool           WarmPoolConfig       `mapstructure:"warm_pool"`
+	Interruptions      InterruptionConfig   `mapstructure:"interruptions"`
+}
+
+// SpotMixConfig splits runners between on-demand and spot capacity instead
+// of use_spot's all-or-nothing choice. The first OnDemandBase runners are
+// on-demand; of the runners above that, SpotPercentage percent are spot,
+// rounded down. A spot launch that fails is retried as on-demand.
+type SpotMixConfig struct {
+	Enabled        bool `mapstructure:"enabled"`
+	OnDemandBase   int  `mapstructure:"on_demand_base"`
+	SpotPercentage int  `mapstructure:"spot_percentage"`
+}
+
+// SpotRunners returns how many of total runners should be spot
+func (m SpotMixConfig) SpotRunners(total int) int {
+	if total <= m.OnDemandBase {
+		return 0
+	}
+	return (total - m.OnDemandBase) * m.SpotPercentage / 100
+}
+
+// LaunchTemplateConfig references an EC2 launch template by ID or name.
+// Version is a version number, "$Latest" or "$Default"; empty uses the
+// template's default version. With a template, the AMI, network, storage,
+// key and instance profile come from the template, and Zeno only sets the
+// user data, its tags and, if instance_type is set, the instance type. The
+// t3.medium default only applies without a template.
+type LaunchTemplateConfig struct {
+	ID      string `mapstructure:"id"`
+	Name    string `mapstructure:"name"`
+	Version string `mapstructure:"version"`
+}
+
+// FleetConfig launches runners with an instant CreateFleet request, so EC2
+// can pick any of several instance types and subnets instead of failing on
+// one that is out of capacity. CreateFleet only launches from launch
+// templates, so fleet mode needs launch_template. AllocationStrategy is
+// "price-capacity-optimized" or "lowest-price" and applies to spot;
+// on-demand always uses lowest-price. With OnDemandFallback, a spot request
+// that gets no capacity is retried as on-demand.
+type FleetConfig struct {
+	Enabled            bool     `mapstructure:"enabled"`
+	InstanceTypes      []string `mapstructure:"instance_types"`
+	SubnetIDs          []string `mapstructure:"subnet_ids"`
+	AllocationStrategy string   `mapstructure:"allocation_strategy"`
+	OnDemandFallback   bool     `mapstructure:"on_demand_fallback"`
+}
+
+// WarmPoolConfig keeps pre-provisioned, stopped instances ready to start
+type WarmPoolConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	Size           int           `mapstructure:"size"`
+	RefillInterval time.Duration `mapstructure:"refill_interval"`
+}
+
+// InterruptionConfig watches for spot interruption warnings and rebalance
+// recommendations, so runners can be replaced before EC2 reclaims them.
+// Besides instance state reasons, notices arrive as EventBridge events,
+// either posted to an HTTP endpoint on ListenAddress or read from the SQS
+// queue at QueueURL. An address without a host, such as ":8090", listens on
+// localhost only; posts must carry Secret as a bearer token. Endpoint
+// overrides the SQS endpoint, for queues that only speak the SQS API.
+// WaitTime is the SQS long poll wait, at most 20s.
+type InterruptionConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	ListenAddress string        `mapstructure:"listen_address"`
+	Path          string        `mapstructure:"path"`
+	Secret        string        `mapstructure:"secret"`
+	QueueURL      string        `mapstructure:"queue_url"`
+	Endpoint      string        `mapstructure:"endpoint"`
+	WaitTime      time.Duration `mapstructure:"wait_time"`
+}
+
+// KubernetesConfig is the template for runner pods. Resource requests and
+// limits use Kubernetes quantities such as "500m" or "2Gi".
+type KubernetesConfig struct {
+	Kubeconfig             string             `mapstructure:"kubeconfig"`
+	Namespace              string             `mapstructure:"namespace"`
+	Image                  string             `mapstructure:"image"`
+	ServiceAccount         string             `mapstructure:"service_account"`
+	RunnerWorkDir          string             `mapstructure:"runner_work_dir"`
+	CPURequest             string             `mapstructure:"cpu_request"`
+	CPULimit               string             `mapstructure:"cpu_limit"`
+	MemoryRequest          string             `mapstructure:"memory_request"`
+	MemoryLimit            string             `mapstructure:"memory_limit"`
+	NodeSelector           map[string]string  `mapstructure:"node_selector"`
+	Tolerations            []TolerationConfig `mapstructure:"tolerations"`
+	Labels                 map[string]string  `mapstructure:"labels"`
+	TerminationGracePeriod time.Duration      `mapstructure:"termination_grace_period"`
+	Pool                   string             `mapstructure:"pool"`
+}
+
+type TolerationConfig struct {
+	Key      string `mapstructure:"key"`
+	Operator string `mapstructure:"operator"`
+	Value    string `mapstructure:"value"`
+	Effect   string `mapstructure:"effect"`
+}
+
+// ProcessConfig runs runners as child processes of Zeno. Each runner gets a
+// copy of RunnerDir, an unpacked actions-runner release, under WorkRoot.
+type ProcessConfig struct {
+	RunnerDir   string        `mapstructure:"runner_dir"`
+	WorkRoot    string        `mapstructure:"work_root"`
+	StopTimeout time.Duration `mapstructure:"stop_timeout"`
+	Pool        string        `mapstructure:"pool"`
+}
+
+// SSHConfig runs runners on a static pool of machines over SSH. Each host
+// needs an unpacked actions-runner release at RunnerDir; WorkRoot is
+// relative to the login user's home directory unless absolute.
+type SSHConfig struct {
+	User                  string          `mapstructure:"user"`
+	PrivateKeyPath        string          `mapstructure:"private_key_path"`
+	KnownHostsPath        string          `mapstructure:"known_hosts_path"`
+	InsecureIgnoreHostKey bool            `mapstructure:"insecure_ignore_host_key"`
+	Hosts                 []SSHHostConfig `mapstructure:"hosts"`
+	RunnerDir             string          `mapstructure:"runner_dir"`
+	WorkRoot              string          `mapstructure:"work_root"`
+	ConnectTimeout        time.Duration   `mapstructure:"connect_timeout"`
+	StopTimeout           time.Duration   `mapstructure:"stop_timeout"`
+	Pool                  string          `mapstructure:"pool"`
+}
+
+// SSHHostConfig is one machine and the number of runners it can hold
+type SSHHostConfig struct {
+	Address string `mapstructure:"address"`
+	User    string `mapstructure:"user"`
+	Slots   int    `mapstructure:"slots"`
+}
+
+// GCEConfig creates runners as Compute Engine instances, either from an
+// instance template or from a machine type and boot image. Settings given
+// alongside a template override the template's. Endpoint replaces the
+// Compute API base URL, for example "http://localhost:8080/compute/v1/".
+type GCEConfig struct {
+	Project          string            `mapstructure:"project"`
+	Zone             string            `mapstructure:"zone"`
+	CredentialsFile  string            `mapstructure:"credentials_file"`
+	Endpoint         string            `mapstructure:"endpoint"`
+	InstanceTemplate string            `mapstructure:"instance_template"`
+	MachineType      string            `mapstructure:"machine_type"`
+	Image            string            `mapstructure:"image"`
+	DiskSizeGB       int64             `mapstructure:"disk_size_gb"`
+	DiskType         string            `mapstructure:"disk_type"`
+	Network          string            `mapstructure:"network"`
+	Subnetwork       string            `mapstructure:"subnetwork"`
+	ServiceAccount   string            `mapstructure:"service_account"`
+	Provisioning     string            `mapstructure:"provisioning"`
+	Labels           map[string]string `mapstructure:"labels"`
+	StartupScript    string            `mapstructure:"startup_script"`
+	Pool             string            `mapstructure:"pool"`
+}
+
+// AzureConfig creates runners as virtual machines in one resource group,
+// each with its own network interface in SubnetID. Image is an image
+// resource ID or a "publisher:offer:sku:version" URN. Endpoint replaces the
+// Azure Resource Manager URL, for example to test against a local fake.
+type AzureConfig struct {
+	SubscriptionID   string            `mapstructure:"subscription_id"`
+	ResourceGroup    string            `mapstructure:"resource_group"`
+	Location         string            `mapstructure:"location"`
+	Endpoint         string            `mapstructure:"endpoint"`
+	SubnetID         string            `mapstructure:"subnet_id"`
+	VMSize           string            `mapstructure:"vm_size"`
+	Image            string            `mapstructure:"image"`
+	OSDiskSizeGB     int32             `mapstructure:"os_disk_size_gb"`
+	OSDiskType       string            `mapstructure:"os_disk_type"`
+	AdminUsername    string            `mapstructure:"admin_username"`
+	SSHPublicKey     string            `mapstructure:"ssh_public_key"`
+	UseSpot          bool              `mapstructure:"use_spot"`
+	SpotMaxPrice     float64           `mapstructure:"spot_max_price"`
+	Tags             map[string]string `mapstructure:"tags"`
+	CustomDataScript string            `mapstructure:"custom_data_script"`
+	Pool             string            `mapstructure:"pool"`
+}
+
+// PluginConfig runs a provider as a separate program. Zeno starts Command
+// and speaks the provider plugin protocol with it over stdin and stdout;
+// Settings is handed to the plugin as JSON when it starts. Viper lowercases
+// the keys of Settings.
+type PluginConfig struct {
+	Command      string                 `mapstructure:"command"`
+	Args         []string               `mapstructure:"args"`
+	Env          map[string]string      `mapstructure:"env"`
+	Settings     map[string]interface{} `mapstructure:"settings"`
+	StartTimeout time.Duration          `mapstructure:"start_timeout"`
 }
 
 type ObservabilityConfig struct {
@@ -111,14 +401,81 @@ type LeaderElectionConfig struct {
 }
 
 type StoreConfig struct {
-	Enabled   bool   `mapstructure:"enabled"`
-	Type      string `mapstructure:"type"`
-	Path      string `mapstructure:"path"`
-	MaxEvents int    `mapstructure:"max_events"`
+	Enabled      bool          `mapstructure:"enabled"`
+	Type         string        `mapstructure:"type"`
+	Path         string        `mapstructure:"path"`
+	MaxEvents    int           `mapstructure:"max_events"`
+	MaxDecisions int           `mapstructure:"max_decisions"`
+	StateMaxAge  time.Duration `mapstructure:"state_max_age"`
+}
+
+type BudgetConfig struct {
+	Enabled        bool               `mapstructure:"enabled"`
+	MaxHourlySpend float64            `mapstructure:"max_hourly_spend"`
+	MaxDailySpend  float64            `mapstructure:"max_daily_spend"`
+	Prices         map[string]float64 `mapstructure:"prices"`
+	DefaultPrice   float64            `mapstructure:"default_price"`
+}
+
+// ShutdownConfig decides what happens to runners when Zeno stops.
+// Teardown is bounded by scaling.termination_timeout.
+type ShutdownConfig struct {
+	Policy       string        `mapstructure:"policy"`
+	DrainTimeout time.Duration `mapstructure:"drain_timeout"`
+}
+
+type NotificationsConfig struct {
+	Enabled                  bool                     `mapstructure:"enabled"`
+	Webhooks                 []WebhookConfig          `mapstructure:"webhooks"`
+	DedupWindow              time.Duration            `mapstructure:"dedup_window"`
+	DedupWindows             map[string]time.Duration `mapstructure:"dedup_windows"`
+	MaxRetries               int                      `mapstructure:"max_retries"`
+	RetryBackoff             time.Duration            `mapstructure:"retry_backoff"`
+	Timeout                  time.Duration            `mapstructure:"timeout"`
+	QueueSize                int                      `mapstructure:"queue_size"`
+	CreationFailureThreshold int                      `mapstructure:"creation_failure_threshold"`
+}
+
+type WebhookConfig struct {
+	Name     string   `mapstructure:"name"`
+	URL      string   `mapstructure:"url"`
+	Format   string   `mapstructure:"format"`
+	Secret   string   `mapstructure:"secret"`
+	Events   []string `mapstructure:"events"`
+	Template string   `mapstructure:"template"`
 }
 
 // Load reads configuration from environment variables and optional config file
 func Load(configPath string) (*Config, error) {
+	cfg, err := read(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// LoadScaling loads a configuration for offline use such as simulation,
+// validating only the scaling settings. GitHub credentials and provider
+// settings are not required.
+func LoadScaling(configPath string) (*Config, error) {
+	cfg, err := read(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.ValidateScaling(); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	return cfg, nil
+}
+
+func read(configPath string) (*Config, error) {
 	v := viper.New()
 
 	// Set defaults
@@ -138,17 +495,57 @@ func Load(configPath string) (*Config, error) {
 	}
 
 	var cfg Config
-	if err := v.Unmarshal(&cfg); err != nil {
+	if err := v.Unmarshal(&cfg, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		flattenDottedKeysHook,
+	))); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
-	if err := cfg.Validate(); err != nil {
-		return nil, fmt.Errorf("config validation failed: %w", err)
+	// A launch template brings its own instance type, which a default would
+	// override
+	if aws := &cfg.Provider.AWS; aws.InstanceType == "" && aws.LaunchTemplate.ID == "" && aws.LaunchTemplate.Name == "" {
+		aws.InstanceType = defaultAWSInstanceType
 	}
 
 	return &cfg, nil
 }
 
+// flattenDottedKeysHook undoes viper splitting keys on dots for every
+// map[string]float64 and map[string]string in the config, so that keys such
+// as the "t3.medium" budget price or the "kubernetes.io/arch" label survive
+// decoding. Values that are themselves maps cannot be expressed in these
+// types, so any nesting found there came from a dotted key.
+func flattenDottedKeysHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if to != reflect.TypeOf(map[string]float64{}) && to != reflect.TypeOf(map[string]string{}) {
+		return data, nil
+	}
+	nested, ok := data.(map[string]interface{})
+	if !ok {
+		return data, nil
+	}
+
+	flat := make(map[string]interface{})
+	var walk func(prefix string, m map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		for k, v := range m {
+			if child, ok := v.(map[string]interface{}); ok {
+				walk(prefix+k+".", child)
+				continue
+			}
+			flat[prefix+k] = v
+		}
+	}
+	walk("", nested)
+
+	return flat, nil
+}
+
+// defaultAWSInstanceType is used when neither provider.aws.instance_type nor
+// a launch template picks one
+const defaultAWSInstanceType = "t3.medium"
+
 func setDefaults(v *viper.Viper) {
 	// Server defaults
 	v.SetDefault("server.address", "0.0.0.0")
@@ -180,6 +577,11 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("scaling.prediction_window", 5*time.Minute)
 	v.SetDefault("scaling.graceful_termination", true)
 	v.SetDefault("scaling.termination_timeout", 60*time.Second)
+	v.SetDefault("scaling.ephemeral", false)
+	v.SetDefault("scaling.registration_timeout", 10*time.Minute)
+	v.SetDefault("scaling.fair_share.enabled", false)
+	v.SetDefault("scaling.fair_share.default_weight", 1)
+	v.SetDefault("scaling.priority.enabled", false)
 
 	// Provider defaults
 	v.SetDefault("provider.type", "docker")
@@ -190,11 +592,54 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("provider.docker.cpu_limit", 1.0)
 	v.SetDefault("provider.docker.memory_limit", 2147483648) // 2GB
 	v.SetDefault("provider.docker.pull_policy", "always")
+	v.SetDefault("provider.docker.pool", "docker")
+	v.SetDefault("provider.kubernetes.namespace", "default")
+	v.SetDefault("provider.kubernetes.image", "myoung34/github-runner:latest")
+	v.SetDefault("provider.kubernetes.runner_work_dir", "/runner/_work")
+	v.SetDefault("provider.kubernetes.cpu_request", "500m")
+	v.SetDefault("provider.kubernetes.cpu_limit", "1")
+	v.SetDefault("provider.kubernetes.memory_request", "1Gi")
+	v.SetDefault("provider.kubernetes.memory_limit", "2Gi")
+	v.SetDefault("provider.kubernetes.termination_grace_period", 30*time.Second)
+	v.SetDefault("provider.kubernetes.pool", "kubernetes")
+	v.SetDefault("provider.process.work_root", "/var/lib/zeno/runners")
+	v.SetDefault("provider.process.stop_timeout", 30*time.Second)
+	v.SetDefault("provider.process.pool", "process")
+	v.SetDefault("provider.ssh.work_root", "zeno-runners")
+	v.SetDefault("provider.ssh.connect_timeout", 10*time.Second)
+	v.SetDefault("provider.ssh.stop_timeout", 30*time.Second)
+	v.SetDefault("provider.ssh.pool", "ssh")
+	v.SetDefault("provider.gce.disk_size_gb", 30)
+	v.SetDefault("provider.gce.disk_type", "pd-balanced")
+	v.SetDefault("provider.gce.pool", "gce")
+	v.SetDefault("provider.azure.vm_size", "Standard_D2s_v5")
+	v.SetDefault("provider.azure.image", "Canonical:0001-com-ubuntu-server-jammy:22_04-lts-gen2:latest")
+	v.SetDefault("provider.azure.os_disk_size_gb", 30)
+	v.SetDefault("provider.azure.os_disk_type", "StandardSSD_LRS")
+	v.SetDefault("provider.azure.admin_username", "zeno")
+	v.SetDefault("provider.azure.spot_max_price", -1)
+	v.SetDefault("provider.azure.pool", "azure")
+	v.SetDefault("provider.plugin.start_timeout", 30*time.Second)
 	v.SetDefault("provider.aws.region", "us-east-1")
-	v.SetDefault("provider.aws.instance_type", "t3.medium")
 	v.SetDefault("provider.aws.use_spot", true)
+	v.SetDefault("provider.aws.spot_mix.enabled", false)
+	v.SetDefault("provider.aws.spot_mix.on_demand_base", 0)
+	v.SetDefault("provider.aws.spot_mix.spot_percentage", 100)
 	v.SetDefault("provider.aws.volume_size", 30)
 	v.SetDefault("provider.aws.volume_type", "gp3")
+	v.SetDefault("provider.aws.fleet.enabled", false)
+	v.SetDefault("provider.aws.fleet.allocation_strategy", "price-capacity-optimized")
+	v.SetDefault("provider.aws.fleet.on_demand_fallback", true)
+	v.SetDefault("provider.aws.warm_pool.enabled", false)
+	v.SetDefault("provider.aws.warm_pool.size", 2)
+	v.SetDefault("provider.aws.warm_pool.refill_interval", time.Minute)
+	v.SetDefault("provider.aws.interruptions.enabled", false)
+	v.SetDefault("provider.aws.interruptions.path", "/events")
+	v.SetDefault("provider.aws.interruptions.wait_time", 20*time.Second)
+	v.SetDefault("provider.circuit_breaker.enabled", true)
+	v.SetDefault("provider.circuit_breaker.failure_threshold", 5)
+	v.SetDefault("provider.circuit_breaker.base_backoff", 30*time.Second)
+	v.SetDefault("provider.circuit_breaker.max_backoff", 10*time.Minute)
 
 	// Observability defaults
 	v.SetDefault("observability.enable_metrics", true)
@@ -203,6 +648,19 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("observability.health_check_path", "/health")
 	v.SetDefault("observability.readiness_path", "/ready")
 
+	// Notification defaults
+	v.SetDefault("notifications.enabled", false)
+	v.SetDefault("notifications.dedup_window", 15*time.Minute)
+	v.SetDefault("notifications.max_retries", 3)
+	v.SetDefault("notifications.retry_backoff", 2*time.Second)
+	v.SetDefault("notifications.timeout", 10*time.Second)
+	v.SetDefault("notifications.queue_size", 100)
+	v.SetDefault("notifications.creation_failure_threshold", 3)
+
+	// Shutdown defaults
+	v.SetDefault("shutdown.policy", "leave")
+	v.SetDefault("shutdown.drain_timeout", 30*time.Minute)
+
 	// Leader election defaults
 	v.SetDefault("leader_election.enabled", false)
 	v.SetDefault("leader_election.lock_file_path", "/tmp/zeno-leader.lock")
@@ -215,12 +673,65 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("store.type", "file")
 	v.SetDefault("store.path", "/tmp/zeno-events.json")
 	v.SetDefault("store.max_events", 1000)
+	v.SetDefault("store.max_decisions", 5000)
+	v.SetDefault("store.state_max_age", 10*time.Minute)
+
+	// Budget defaults
+	v.SetDefault("budget.enabled", false)
+	v.SetDefault("budget.max_hourly_spend", 0)
+	v.SetDefault("budget.max_daily_spend", 0)
+	v.SetDefault("budget.default_price", 0)
 
 	// General defaults
 	v.SetDefault("dry_run", false)
 	v.SetDefault("log_level", "info")
 }
 
+// providerTypes returns the provider types Validate accepts. The provider
+// registry installs it, so only types compiled into the binary pass.
+var providerTypes func() []string
+
+// SetProviderTypes makes Validate accept the provider types types returns
+func SetProviderTypes(types func() []string) {
+	providerTypes = types
+}
+
+// validProviderType reports an error naming the accepted types when name
+// is not one of them. Without registered providers any non-empty type
+// passes, and an unknown one fails when the provider is created.
+func validProviderType(field, name string, exclude ...string) error {
+	var registered []string
+	if providerTypes != nil {
+		registered = providerTypes()
+	}
+	if len(registered) == 0 {
+		if name == "" {
+			return fmt.Errorf("%s is required", field)
+		}
+		return nil
+	}
+
+	var accepted []string
+	for _, t := range registered {
+		if !slices.Contains(exclude, t) {
+			accepted = append(accepted, t)
+		}
+	}
+	if slices.Contains(accepted, name) {
+		return nil
+	}
+
+	quoted := make([]string, len(accepted))
+	for i, t := range accepted {
+		quoted[i] = "'" + t + "'"
+	}
+	list := strings.Join(quoted, ", ")
+	if n := len(quoted); n > 1 {
+		list = strings.Join(quoted[:n-1], ", ") + " or " + quoted[n-1]
+	}
+	return fmt.Errorf("%s must be one of %s", field, list)
+}
+
 func (c *Config) Validate() error {
 	// GitHub validation
 	if c.GitHub.Token == "" {
@@ -236,52 +747,254 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("github.cache_ttl must be >= 0")
 	}
 
-	// Scaling validation
-	if c.Scaling.MinRunners < 0 {
-		return fmt.Errorf("scaling.min_runners must be >= 0")
-	}
-	if c.Scaling.MaxRunners < c.Scaling.MinRunners {
-		return fmt.Errorf("scaling.max_runners must be >= scaling.min_runners")
-	}
-	if c.Scaling.ScaleDownThreshold < 0 {
-		return fmt.Errorf("scaling.scale_down_threshold must be >= 0")
-	}
-	if c.Scaling.ScaleUpThreshold <= c.Scaling.ScaleDownThreshold {
-		return fmt.Errorf("scaling.scale_up_threshold must be > scaling.scale_down_threshold")
-	}
-	if c.Scaling.CheckInterval <= 0 {
-		return fmt.Errorf("scaling.check_interval must be > 0")
-	}
-	if c.Scaling.ScaleUpHysteresis < 0 {
-		return fmt.Errorf("scaling.scale_up_hysteresis must be >= 0")
+	if c.Scaling.FairShare.Enabled && c.GitHub.Organization == "" {
+		return fmt.Errorf("scaling.fair_share requires github.organization")
 	}
-	if c.Scaling.ScaleDownHysteresis < 0 {
-		return fmt.Errorf("scaling.scale_down_hysteresis must be >= 0")
+
+	if err := c.ValidateScaling(); err != nil {
+		return err
 	}
 
 	// Provider validation
-	if c.Provider.Type != "docker" && c.Provider.Type != "ec2" {
-		return fmt.Errorf("provider.type must be either 'docker' or 'ec2'")
+	if err := validProviderType("provider.type", c.Provider.Type); err != nil {
+		return err
+	}
+
+	if c.Provider.Type == "spillover" {
+		if len(c.Provider.Spillover.Providers) == 0 {
+			return fmt.Errorf("provider.spillover.providers is required when using spillover provider")
+		}
+		seen := make(map[string]bool)
+		for i, m := range c.Provider.Spillover.Providers {
+			if err := validProviderType(fmt.Sprintf("provider.spillover.providers[%d].type", i), m.Type, "spillover"); err != nil {
+				return err
+			}
+			if seen[m.Type] {
+				return fmt.Errorf("provider.spillover.providers[%d].type %q is listed twice", i, m.Type)
+			}
+			seen[m.Type] = true
+			if m.MaxRunners < 1 {
+				return fmt.Errorf("provider.spillover.providers[%d].max_runners must be >= 1", i)
+			}
+			if m.Cost < 0 {
+				return fmt.Errorf("provider.spillover.providers[%d].cost must be >= 0", i)
+			}
+		}
 	}
 
-	if c.Provider.Type == "docker" {
+	if c.Provider.Uses("docker") {
 		if c.Provider.Docker.Image == "" {
 			return fmt.Errorf("provider.docker.image is required when using docker provider")
 		}
 	}
 
-	if c.Provider.Type == "ec2" {
+	if c.Provider.Uses("ec2") {
 		if c.Provider.AWS.Region == "" {
 			return fmt.Errorf("provider.aws.region is required when using ec2 provider")
 		}
-		if c.Provider.AWS.AMI == "" {
-			return fmt.Errorf("provider.aws.ami is required when using ec2 provider")
+		lt := c.Provider.AWS.LaunchTemplate
+		if lt.ID != "" && lt.Name != "" {
+			return fmt.Errorf("provider.aws.launch_template needs id or name, not both")
+		}
+		// A launch template supplies the image and network
+		if lt.ID == "" && lt.Name == "" {
+			if c.Provider.AWS.AMI == "" {
+				return fmt.Errorf("provider.aws.ami is required when using ec2 provider")
+			}
+			if c.Provider.AWS.SubnetID == "" {
+				return fmt.Errorf("provider.aws.subnet_id is required when using ec2 provider")
+			}
+			if len(c.Provider.AWS.SecurityGroupIDs) == 0 {
+				return fmt.Errorf("provider.aws.security_group_ids is required when using ec2 provider")
+			}
+		}
+		if mix := c.Provider.AWS.SpotMix; mix.Enabled {
+			if mix.OnDemandBase < 0 {
+				return fmt.Errorf("provider.aws.spot_mix.on_demand_base must be >= 0")
+			}
+			if mix.SpotPercentage < 0 || mix.SpotPercentage > 100 {
+				return fmt.Errorf("provider.aws.spot_mix.spot_percentage must be between 0 and 100")
+			}
+		}
+		if fleet := c.Provider.AWS.Fleet; fleet.Enabled {
+			if lt.ID == "" && lt.Name == "" {
+				return fmt.Errorf("provider.aws.fleet needs provider.aws.launch_template, since CreateFleet only launches from templates")
+			}
+			if len(fleet.InstanceTypes) == 0 {
+				return fmt.Errorf("provider.aws.fleet.instance_types is required when fleet is enabled")
+			}
+			switch fleet.AllocationStrategy {
+			case "price-capacity-optimized", "lowest-price":
+			default:
+				return fmt.Errorf("provider.aws.fleet.allocation_strategy must be 'price-capacity-optimized' or 'lowest-price'")
+			}
+		}
+		if c.Provider.AWS.WarmPool.Enabled {
+			if c.Provider.AWS.WarmPool.Size < 1 {
+				return fmt.Errorf("provider.aws.warm_pool.size must be >= 1")
+			}
+			if c.Provider.AWS.WarmPool.RefillInterval <= 0 {
+				return fmt.Errorf("provider.aws.warm_pool.refill_interval must be > 0")
+			}
+		}
+		if in := c.Provider.AWS.Interruptions; in.Enabled {
+			if in.ListenAddress != "" && !strings.HasPrefix(in.Path, "/") {
+				return fmt.Errorf("provider.aws.interruptions.path must start with '/'")
+			}
+			if in.ListenAddress != "" && in.Secret == "" {
+				return fmt.Errorf("provider.aws.interruptions.secret is required when listen_address is set")
+			}
+			if in.QueueURL != "" && (in.WaitTime < time.Second || in.WaitTime > 20*time.Second) {
+				return fmt.Errorf("provider.aws.interruptions.wait_time must be between 1s and 20s")
+			}
+		}
+	}
+
+	if c.Provider.Uses("kubernetes") {
+		if c.Provider.Kubernetes.Namespace == "" {
+			return fmt.Errorf("provider.kubernetes.namespace is required when using kubernetes provider")
+		}
+		if c.Provider.Kubernetes.Image == "" {
+			return fmt.Errorf("provider.kubernetes.image is required when using kubernetes provider")
+		}
+		if c.Provider.Kubernetes.TerminationGracePeriod < 0 {
+			return fmt.Errorf("provider.kubernetes.termination_grace_period must be >= 0")
+		}
+		for i, t := range c.Provider.Kubernetes.Tolerations {
+			switch t.Operator {
+			case "", "Equal":
+			case "Exists":
+				if t.Value != "" {
+					return fmt.Errorf("provider.kubernetes.tolerations[%d].value must be empty with operator Exists", i)
+				}
+			default:
+				return fmt.Errorf("provider.kubernetes.tolerations[%d].operator must be Equal or Exists", i)
+			}
+			switch t.Effect {
+			case "", "NoSchedule", "PreferNoSchedule", "NoExecute":
+			default:
+				return fmt.Errorf("provider.kubernetes.tolerations[%d].effect must be NoSchedule, PreferNoSchedule or NoExecute", i)
+			}
+		}
+	}
+
+	if c.Provider.Uses("process") {
+		if c.Provider.Process.RunnerDir == "" {
+			return fmt.Errorf("provider.process.runner_dir is required when using process provider")
+		}
+		if c.Provider.Process.WorkRoot == "" {
+			return fmt.Errorf("provider.process.work_root is required when using process provider")
+		}
+		if c.Provider.Process.StopTimeout <= 0 {
+			return fmt.Errorf("provider.process.stop_timeout must be > 0")
+		}
+	}
+
+	if c.Provider.Uses("ssh") {
+		ssh := c.Provider.SSH
+		if len(ssh.Hosts) == 0 {
+			return fmt.Errorf("provider.ssh.hosts is required when using ssh provider")
+		}
+		addresses := make(map[string]bool, len(ssh.Hosts))
+		for i, h := range ssh.Hosts {
+			if h.Address == "" {
+				return fmt.Errorf("provider.ssh.hosts[%d].address is required", i)
+			}
+			if addresses[h.Address] {
+				return fmt.Errorf("provider.ssh.hosts[%d].address %q is listed twice", i, h.Address)
+			}
+			addresses[h.Address] = true
+			if h.Slots < 1 {
+				return fmt.Errorf("provider.ssh.hosts[%d].slots must be >= 1", i)
+			}
+			if h.User == "" && ssh.User == "" {
+				return fmt.Errorf("provider.ssh.hosts[%d].user or provider.ssh.user is required", i)
+			}
+		}
+		if ssh.PrivateKeyPath == "" {
+			return fmt.Errorf("provider.ssh.private_key_path is required when using ssh provider")
+		}
+		if ssh.KnownHostsPath == "" && !ssh.InsecureIgnoreHostKey {
+			return fmt.Errorf("provider.ssh.known_hosts_path is required unless insecure_ignore_host_key is set")
+		}
+		if ssh.RunnerDir == "" {
+			return fmt.Errorf("provider.ssh.runner_dir is required when using ssh provider")
+		}
+		if ssh.WorkRoot == "" {
+			return fmt.Errorf("provider.ssh.work_root is required when using ssh provider")
+		}
+		if ssh.StopTimeout <= 0 {
+			return fmt.Errorf("provider.ssh.stop_timeout must be > 0")
+		}
+	}
+
+	if c.Provider.Uses("gce") {
+		gce := c.Provider.GCE
+		if gce.Project == "" {
+			return fmt.Errorf("provider.gce.project is required when using gce provider")
+		}
+		if gce.Zone == "" {
+			return fmt.Errorf("provider.gce.zone is required when using gce provider")
+		}
+		if gce.InstanceTemplate == "" && (gce.MachineType == "" || gce.Image == "") {
+			return fmt.Errorf("provider.gce.instance_template or both provider.gce.machine_type and provider.gce.image are required")
+		}
+		if gce.Image != "" && gce.DiskSizeGB < 1 {
+			return fmt.Errorf("provider.gce.disk_size_gb must be >= 1")
+		}
+		switch gce.Provisioning {
+		case "", "standard", "preemptible", "spot":
+		default:
+			return fmt.Errorf("provider.gce.provisioning must be one of 'standard', 'preemptible' or 'spot'")
+		}
+	}
+
+	if c.Provider.Uses("azure") {
+		az := c.Provider.Azure
+		if az.SubscriptionID == "" {
+			return fmt.Errorf("provider.azure.subscription_id is required when using azure provider")
+		}
+		if az.ResourceGroup == "" {
+			return fmt.Errorf("provider.azure.resource_group is required when using azure provider")
+		}
+		if az.Location == "" {
+			return fmt.Errorf("provider.azure.location is required when using azure provider")
+		}
+		if az.SubnetID == "" {
+			return fmt.Errorf("provider.azure.subnet_id is required when using azure provider")
+		}
+		if az.Image == "" {
+			return fmt.Errorf("provider.azure.image is required when using azure provider")
+		}
+		if !strings.HasPrefix(az.Image, "/") && len(strings.Split(az.Image, ":")) != 4 {
+			return fmt.Errorf("provider.azure.image must be an image resource ID or a publisher:offer:sku:version URN")
+		}
+		if az.AdminUsername == "" || az.SSHPublicKey == "" {
+			return fmt.Errorf("provider.azure.admin_username and provider.azure.ssh_public_key are required when using azure provider")
+		}
+		if az.UseSpot && az.SpotMaxPrice < 0 && az.SpotMaxPrice != -1 {
+			return fmt.Errorf("provider.azure.spot_max_price must be -1 (up to the pay-as-you-go price) or >= 0")
 		}
-		if c.Provider.AWS.SubnetID == "" {
-			return fmt.Errorf("provider.aws.subnet_id is required when using ec2 provider")
+	}
+
+	if c.Provider.Uses("plugin") {
+		if c.Provider.Plugin.Command == "" {
+			return fmt.Errorf("provider.plugin.command is required when using plugin provider")
+		}
+		if c.Provider.Plugin.StartTimeout <= 0 {
+			return fmt.Errorf("provider.plugin.start_timeout must be > 0")
+		}
+	}
+
+	if c.Provider.CircuitBreaker.Enabled {
+		if c.Provider.CircuitBreaker.FailureThreshold < 1 {
+			return fmt.Errorf("provider.circuit_breaker.failure_threshold must be >= 1")
+		}
+		if c.Provider.CircuitBreaker.BaseBackoff <= 0 {
+			return fmt.Errorf("provider.circuit_breaker.base_backoff must be > 0")
 		}
-		if len(c.Provider.AWS.SecurityGroupIDs) == 0 {
-			return fmt.Errorf("provider.aws.security_group_ids is required when using ec2 provider")
+		if c.Provider.CircuitBreaker.MaxBackoff < c.Provider.CircuitBreaker.BaseBackoff {
+			return fmt.Errorf("provider.circuit_breaker.max_backoff must be >= base_backoff")
 		}
 	}
 
@@ -293,6 +1006,57 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("server.api_key is required when server.enable_auth is true")
 	}
 
+	// Budget validation
+	if c.Budget.MaxHourlySpend < 0 || c.Budget.MaxDailySpend < 0 {
+		return fmt.Errorf("budget.max_hourly_spend and budget.max_daily_spend must be >= 0")
+	}
+	for key, price := range c.Budget.Prices {
+		if price < 0 {
+			return fmt.Errorf("budget.prices.%s must be >= 0", key)
+		}
+	}
+	if c.Budget.Enabled && c.Budget.MaxHourlySpend == 0 && c.Budget.MaxDailySpend == 0 {
+		return fmt.Errorf("budget.max_hourly_spend or budget.max_daily_spend is required when budget is enabled")
+	}
+
+	// Shutdown validation
+	switch c.Shutdown.Policy {
+	case "", "leave":
+	case "drain":
+		if c.Shutdown.DrainTimeout <= 0 {
+			return fmt.Errorf("shutdown.drain_timeout must be > 0 when shutdown.policy is drain")
+		}
+	case "teardown":
+		if c.Scaling.TerminationTimeout <= 0 {
+			return fmt.Errorf("scaling.termination_timeout must be > 0 when shutdown.policy is teardown")
+		}
+	default:
+		return fmt.Errorf("shutdown.policy must be one of leave, drain or teardown")
+	}
+
+	// Notification validation
+	if c.Notifications.Enabled {
+		if len(c.Notifications.Webhooks) == 0 {
+			return fmt.Errorf("notifications.webhooks is required when notifications are enabled")
+		}
+		for i, hook := range c.Notifications.Webhooks {
+			if hook.URL == "" {
+				return fmt.Errorf("notifications.webhooks[%d].url is required", i)
+			}
+			switch hook.Format {
+			case "", "json", "slack", "teams":
+			default:
+				return fmt.Errorf("notifications.webhooks[%d].format must be one of json, slack or teams", i)
+			}
+		}
+		if c.Notifications.MaxRetries < 0 {
+			return fmt.Errorf("notifications.max_retries must be >= 0")
+		}
+		if c.Notifications.CreationFailureThreshold < 1 {
+			return fmt.Errorf("notifications.creation_failure_threshold must be >= 1")
+		}
+	}
+
 	// Leader election validation
 	if c.LeaderElection.Enabled {
 		if c.LeaderElection.LockFilePath == "" {
@@ -311,3 +1075,80 @@ func (c *Config) Validate() error {
 
 	return nil
 }
+
+// ValidateScaling checks the scaling settings on their own
+func (c *Config) ValidateScaling() error {
+	if c.Scaling.MinRunners < 0 {
+		return fmt.Errorf("scaling.min_runners must be >= 0")
+	}
+	if c.Scaling.MaxRunners < c.Scaling.MinRunners {
+		return fmt.Errorf("scaling.max_runners must be >= scaling.min_runners")
+	}
+	if c.Scaling.ScaleDownThreshold < 0 {
+		return fmt.Errorf("scaling.scale_down_threshold must be >= 0")
+	}
+	if c.Scaling.ScaleUpThreshold <= c.Scaling.ScaleDownThreshold {
+		return fmt.Errorf("scaling.scale_up_threshold must be > scaling.scale_down_threshold")
+	}
+	if c.Scaling.CheckInterval <= 0 {
+		return fmt.Errorf("scaling.check_interval must be > 0")
+	}
+	if c.Scaling.ScaleUpHysteresis < 0 {
+		return fmt.Errorf("scaling.scale_up_hysteresis must be >= 0")
+	}
+	if c.Scaling.ScaleDownHysteresis < 0 {
+		return fmt.Errorf("scaling.scale_down_hysteresis must be >= 0")
+	}
+
+	if c.Scaling.FairShare.Enabled {
+		if c.Scaling.FairShare.DefaultWeight < 1 {
+			return fmt.Errorf("scaling.fair_share.default_weight must be >= 1")
+		}
+		seen := make(map[string]bool)
+		guaranteed := 0
+		for i, quota := range c.Scaling.FairShare.Repositories {
+			if quota.Name == "" {
+				return fmt.Errorf("scaling.fair_share.repositories[%d].name is required", i)
+			}
+			if seen[quota.Name] {
+				return fmt.Errorf("scaling.fair_share.repositories[%d]: duplicate repository %s", i, quota.Name)
+			}
+			seen[quota.Name] = true
+			if quota.Weight < 0 || quota.Min < 0 || quota.Max < 0 {
+				return fmt.Errorf("scaling.fair_share.repositories[%d]: weight, min and max must be >= 0", i)
+			}
+			if quota.Max > 0 && quota.Min > quota.Max {
+				return fmt.Errorf("scaling.fair_share.repositories[%d].min must be <= max", i)
+			}
+			guaranteed += quota.Min
+		}
+		if guaranteed > c.Scaling.MaxRunners {
+			return fmt.Errorf("scaling.fair_share repository minimums add up to %d, more than scaling.max_runners", guaranteed)
+		}
+	}
+
+	if c.Scaling.Priority.Enabled {
+		if c.Scaling.Priority.HardMaxRunners != 0 && c.Scaling.Priority.HardMaxRunners < c.Scaling.MaxRunners {
+			return fmt.Errorf("scaling.priority.hard_max_runners must be 0 or >= scaling.max_runners")
+		}
+		for i, rule := range c.Scaling.Priority.Rules {
+			switch rule.Class {
+			case "high", "normal", "low":
+			default:
+				return fmt.Errorf("scaling.priority.rules[%d].class must be one of high, normal or low", i)
+			}
+			if len(rule.Workflows)+len(rule.Repositories)+len(rule.Labels) == 0 {
+				return fmt.Errorf("scaling.priority.rules[%d] must match workflows, repositories or labels", i)
+			}
+			for _, patterns := range [][]string{rule.Workflows, rule.Repositories, rule.Labels} {
+				for _, pattern := range patterns {
+					if _, err := path.Match(pattern, ""); err != nil {
+						return fmt.Errorf("scaling.priority.rules[%d]: invalid pattern %q", i, pattern)
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -7,6 +7,13 @@ import (
 	"time"
 )
 
+func init() {
+	// Stands in for the provider registry, which imports this package
+	SetProviderTypes(func() []string {
+		return []string{"azure", "docker", "ec2", "gce", "kubernetes", "plugin", "process", "spillover", "ssh"}
+	})
+}
+
 func TestLoad(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -46,7 +53,7 @@ func TestLoad(t *testing.T) {
 				"ZENO_PROVIDER_TYPE":       "invalid",
 			},
 			wantErr:     true,
-			errContains: "provider.type must be either 'docker' or 'ec2'",
+			errContains: "provider.type must be one of 'azure', 'docker', 'ec2', 'gce', 'kubernetes', 'plugin', 'process', 'spillover' or 'ssh'",
 		},
 		{
 			name: "invalid scaling config",
@@ -128,6 +135,27 @@ func TestConfigDefaults(t *testing.T) {
 	}
 }
 
+func TestValidateSpilloverMemberTypes(t *testing.T) {
+	cfg := &Config{
+		GitHub:  GitHubConfig{Token: "token", Organization: "org"},
+		Scaling: ScalingConfig{MinRunners: 1, MaxRunners: 10, ScaleUpThreshold: 5, CheckInterval: 30 * time.Second},
+		Provider: ProviderConfig{
+			Type:   "spillover",
+			Docker: DockerConfig{Image: "test-image"},
+			Spillover: SpilloverConfig{Providers: []SpilloverProviderConfig{
+				{Type: "docker", MaxRunners: 2},
+				{Type: "spillover", MaxRunners: 2},
+			}},
+		},
+		Server: ServerConfig{Port: 8080},
+	}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "provider.spillover.providers[1].type must be one of") || strings.Contains(err.Error(), "'spillover'") {
+		t.Errorf("Validate() error = %v, want spillover rejected as a member type", err)
+	}
+}
+
 func TestValidate(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -161,6 +189,127 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "ec2 launch template replaces ami, subnet and security groups",
+			cfg: &Config{
+				GitHub: GitHubConfig{
+					Token:        "token",
+					Organization: "org",
+				},
+				Scaling: ScalingConfig{
+					MinRunners:         1,
+					MaxRunners:         10,
+					ScaleUpThreshold:   5,
+					ScaleDownThreshold: 0,
+					CheckInterval:      30 * time.Second,
+				},
+				Provider: ProviderConfig{
+					Type: "ec2",
+					AWS: AWSConfig{
+						Region:         "us-east-1",
+						LaunchTemplate: LaunchTemplateConfig{Name: "ci-runners", Version: "$Latest"},
+					},
+				},
+				Server: ServerConfig{
+					Port: 8080,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "ec2 launch template with id and name",
+			cfg: &Config{
+				GitHub: GitHubConfig{
+					Token:        "token",
+					Organization: "org",
+				},
+				Scaling: ScalingConfig{
+					MinRunners:         1,
+					MaxRunners:         10,
+					ScaleUpThreshold:   5,
+					ScaleDownThreshold: 0,
+					CheckInterval:      30 * time.Second,
+				},
+				Provider: ProviderConfig{
+					Type: "ec2",
+					AWS: AWSConfig{
+						Region:         "us-east-1",
+						LaunchTemplate: LaunchTemplateConfig{ID: "lt-0123456789abcdef0", Name: "ci-runners"},
+					},
+				},
+				Server: ServerConfig{
+					Port: 8080,
+				},
+			},
+			wantErr:     true,
+			errContains: "provider.aws.launch_template needs id or name, not both",
+		},
+		{
+			name: "ec2 interruption queue wait time too long",
+			cfg: &Config{
+				GitHub: GitHubConfig{
+					Token:        "token",
+					Organization: "org",
+				},
+				Scaling: ScalingConfig{
+					MinRunners:         1,
+					MaxRunners:         10,
+					ScaleUpThreshold:   5,
+					ScaleDownThreshold: 0,
+					CheckInterval:      30 * time.Second,
+				},
+				Provider: ProviderConfig{
+					Type: "ec2",
+					AWS: AWSConfig{
+						Region:         "us-east-1",
+						LaunchTemplate: LaunchTemplateConfig{Name: "ci-runners"},
+						Interruptions: InterruptionConfig{
+							Enabled:  true,
+							QueueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/zeno-interruptions",
+							WaitTime: time.Minute,
+						},
+					},
+				},
+				Server: ServerConfig{
+					Port: 8080,
+				},
+			},
+			wantErr:     true,
+			errContains: "provider.aws.interruptions.wait_time must be between 1s and 20s",
+		},
+		{
+			name: "ec2 interruption endpoint without secret",
+			cfg: &Config{
+				GitHub: GitHubConfig{
+					Token:        "token",
+					Organization: "org",
+				},
+				Scaling: ScalingConfig{
+					MinRunners:         1,
+					MaxRunners:         10,
+					ScaleUpThreshold:   5,
+					ScaleDownThreshold: 0,
+					CheckInterval:      30 * time.Second,
+				},
+				Provider: ProviderConfig{
+					Type: "ec2",
+					AWS: AWSConfig{
+						Region:         "us-east-1",
+						LaunchTemplate: LaunchTemplateConfig{Name: "ci-runners"},
+						Interruptions: InterruptionConfig{
+							Enabled:       true,
+							ListenAddress: "127.0.0.1:8090",
+							Path:          "/events",
+						},
+					},
+				},
+				Server: ServerConfig{
+					Port: 8080,
+				},
+			},
+			wantErr:     true,
+			errContains: "provider.aws.interruptions.secret is required when listen_address is set",
+		},
 		{
 			name: "leader election invalid config",
 			cfg: &Config{
@@ -210,3 +359,254 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadBudgetPricesWithDottedKeys(t *testing.T) {
+	path := t.TempDir() + "/config.yaml"
+	data := "github:\n  token: test-token\n  organization: test-org\n" +
+		"budget:\n  prices:\n    t3.medium: 0.0416\n    docker: 0.01\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if got := cfg.Budget.Prices["t3.medium"]; got != 0.0416 {
+		t.Errorf("Prices[t3.medium] = %v, want 0.0416", got)
+	}
+	if got := cfg.Budget.Prices["docker"]; got != 0.01 {
+		t.Errorf("Prices[docker] = %v, want 0.01", got)
+	}
+}
+
+func TestDefaultInstanceTypeLeavesLaunchTemplateAlone(t *testing.T) {
+	tests := []struct {
+		name string
+		aws  string
+		want string
+	}{
+		{"no template", "    ami: ami-123\n", "t3.medium"},
+		{"template", "    launch_template:\n      name: ci-runners\n", ""},
+		{"template with override", "    instance_type: c6i.large\n    launch_template:\n      name: ci-runners\n", "c6i.large"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := t.TempDir() + "/config.yaml"
+			if err := os.WriteFile(path, []byte("provider:\n  aws:\n"+tt.aws), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := LoadScaling(path)
+			if err != nil {
+				t.Fatalf("LoadScaling() error = %v", err)
+			}
+			if got := cfg.Provider.AWS.InstanceType; got != tt.want {
+				t.Errorf("instance_type = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadScalingKubernetesNodeSelectorWithDottedKeys(t *testing.T) {
+	path := t.TempDir() + "/config.yaml"
+	data := "provider:\n  kubernetes:\n    node_selector:\n      kubernetes.io/arch: amd64\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadScaling(path)
+	if err != nil {
+		t.Fatalf("LoadScaling() error = %v", err)
+	}
+
+	if got := cfg.Provider.Kubernetes.NodeSelector["kubernetes.io/arch"]; got != "amd64" {
+		t.Errorf("NodeSelector = %v, want kubernetes.io/arch=amd64", cfg.Provider.Kubernetes.NodeSelector)
+	}
+	if cfg.Provider.Kubernetes.Namespace != "default" {
+		t.Errorf("Namespace = %q, want the default namespace", cfg.Provider.Kubernetes.Namespace)
+	}
+}
+
+func TestLoadScalingNotifications(t *testing.T) {
+	path := t.TempDir() + "/config.yaml"
+	data := `notifications:
+  enabled: true
+  dedup_windows:
+    scale_up: 0
+    provider_error: 1h
+  webhooks:
+    - name: ops
+      url: http://localhost:9000/hook
+      format: slack
+      events: [creation_failures]
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadScaling(path)
+	if err != nil {
+		t.Fatalf("LoadScaling() error = %v", err)
+	}
+
+	n := cfg.Notifications
+	if n.DedupWindow != 15*time.Minute || n.MaxRetries != 3 || n.CreationFailureThreshold != 3 {
+		t.Errorf("defaults = %+v", n)
+	}
+	if w, ok := n.DedupWindows["scale_up"]; !ok || w != 0 {
+		t.Errorf("DedupWindows[scale_up] = %v, %v, want 0, true", w, ok)
+	}
+	if got := n.DedupWindows["provider_error"]; got != time.Hour {
+		t.Errorf("DedupWindows[provider_error] = %v, want 1h", got)
+	}
+	if len(n.Webhooks) != 1 || n.Webhooks[0].Format != "slack" || len(n.Webhooks[0].Events) != 1 {
+		t.Errorf("Webhooks = %+v", n.Webhooks)
+	}
+}
+
+func TestLoadScalingFairShare(t *testing.T) {
+	path := t.TempDir() + "/config.yaml"
+	data := `scaling:
+  max_runners: 4
+  fair_share:
+    enabled: true
+    repositories:
+      - name: acme/web.site
+        weight: 3
+        min: 1
+      - name: acme/docs
+        min: 4
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadScaling(path)
+	if err == nil || !strings.Contains(err.Error(), "minimums add up to 5") {
+		t.Fatalf("LoadScaling() error = %v, want minimums above max_runners rejected", err)
+	}
+
+	data = strings.Replace(data, "min: 4", "min: 2", 1)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadScaling(path)
+	if err != nil {
+		t.Fatalf("LoadScaling() error = %v", err)
+	}
+
+	fs := cfg.Scaling.FairShare
+	if fs.DefaultWeight != 1 || len(fs.Repositories) != 2 {
+		t.Fatalf("FairShare = %+v", fs)
+	}
+	if repo := fs.Repositories[0]; repo.Name != "acme/web.site" || repo.Weight != 3 || repo.Min != 1 {
+		t.Errorf("Repositories[0] = %+v", repo)
+	}
+}
+
+func TestLoadScalingPriority(t *testing.T) {
+	path := t.TempDir() + "/config.yaml"
+	data := `scaling:
+  max_runners: 10
+  priority:
+    enabled: true
+    hard_max_runners: 5
+    rules:
+      - class: high
+        workflows: ["Release*"]
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadScaling(path)
+	if err == nil || !strings.Contains(err.Error(), "hard_max_runners") {
+		t.Fatalf("LoadScaling() error = %v, want hard maximum below max_runners rejected", err)
+	}
+
+	data = strings.Replace(data, "hard_max_runners: 5", "hard_max_runners: 15", 1)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadScaling(path)
+	if err != nil {
+		t.Fatalf("LoadScaling() error = %v", err)
+	}
+	if p := cfg.Scaling.Priority; p.HardMaxRunners != 15 || len(p.Rules) != 1 || p.Rules[0].Workflows[0] != "Release*" {
+		t.Errorf("Priority = %+v", p)
+	}
+}
+
+func TestReloadable(t *testing.T) {
+	current := &Config{
+		Server:  ServerConfig{Port: 8080},
+		GitHub:  GitHubConfig{Token: "token", Organization: "acme", RunnerLabels: []string{"linux"}},
+		Scaling: ScalingConfig{MinRunners: 1, MaxRunners: 5},
+		Provider: ProviderConfig{
+			Type:   "docker",
+			Docker: DockerConfig{Host: "unix:///var/run/docker.sock", Image: "runner:1", Pool: "blue"},
+		},
+		Notifications: NotificationsConfig{Enabled: true, QueueSize: 10, MaxRetries: 1},
+	}
+
+	next := *current
+	next.Server.Port = 9090
+	next.GitHub.RunnerLabels = []string{"linux", "gpu"}
+	next.Scaling.MaxRunners = 10
+	next.Scaling.Ephemeral = true
+	next.Provider.Type = "process"
+	next.Provider.Docker.Image = "runner:2"
+	next.Notifications.MaxRetries = 5
+
+	merged, restart := current.Reloadable(&next)
+
+	if merged.Scaling.MaxRunners != 10 || merged.Scaling.Ephemeral {
+		t.Errorf("scaling = %+v, want max_runners reloaded and ephemeral unchanged", merged.Scaling)
+	}
+	if len(merged.GitHub.RunnerLabels) != 2 {
+		t.Errorf("runner labels = %v, want the reloaded labels", merged.GitHub.RunnerLabels)
+	}
+	if merged.Provider.Type != "docker" || merged.Provider.Docker.Image != "runner:2" {
+		t.Errorf("provider = %+v, want the new image on the running provider type", merged.Provider)
+	}
+	if merged.Notifications.MaxRetries != 5 {
+		t.Errorf("notifications max_retries = %d, want 5", merged.Notifications.MaxRetries)
+	}
+	if merged.Server.Port != 8080 {
+		t.Errorf("server port = %d, want the running port", merged.Server.Port)
+	}
+
+	want := []string{"scaling.ephemeral", "provider.type", "server"}
+	if strings.Join(restart, ",") != strings.Join(want, ",") {
+		t.Errorf("restart = %v, want %v", restart, want)
+	}
+	if current.Scaling.MaxRunners != 5 {
+		t.Error("Reloadable() modified the running configuration")
+	}
+}
+
+func TestSpotMixSpotRunners(t *testing.T) {
+	tests := []struct {
+		mix   SpotMixConfig
+		total int
+		want  int
+	}{
+		{SpotMixConfig{OnDemandBase: 2, SpotPercentage: 100}, 2, 0},
+		{SpotMixConfig{OnDemandBase: 2, SpotPercentage: 100}, 5, 3},
+		{SpotMixConfig{OnDemandBase: 1, SpotPercentage: 50}, 4, 1},
+		{SpotMixConfig{OnDemandBase: 1, SpotPercentage: 50}, 5, 2},
+		{SpotMixConfig{OnDemandBase: 0, SpotPercentage: 0}, 10, 0},
+		{SpotMixConfig{OnDemandBase: 0, SpotPercentage: 75}, 10, 7},
+	}
+
+	for _, tt := range tests {
+		if got := tt.mix.SpotRunners(tt.total); got != tt.want {
+			t.Errorf("%+v.SpotRunners(%d) = %d, want %d", tt.mix, tt.total, got, tt.want)
+		}
+	}
+}
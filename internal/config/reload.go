@@ -0,0 +1,68 @@
+package config
+
+import "reflect"
+
+// Reloadable returns a copy of c with the settings that can change while
+// Zeno is running taken from next: scaling, runner labels, provider settings
+// and notifications. It also returns the sections of next that differ from c
+// but only take effect after a restart. Provider settings are taken as a
+// whole; the provider's Reconfigure reports which of them it cannot change.
+func (c *Config) Reloadable(next *Config) (*Config, []string) {
+	merged := *c
+	var restart []string
+
+	merged.Scaling = next.Scaling
+	if next.Scaling.Ephemeral != c.Scaling.Ephemeral {
+		// Runners already running were created for the other mode
+		merged.Scaling.Ephemeral = c.Scaling.Ephemeral
+		restart = append(restart, "scaling.ephemeral")
+	}
+
+	merged.GitHub.RunnerLabels = next.GitHub.RunnerLabels
+	gh := next.GitHub
+	gh.RunnerLabels = c.GitHub.RunnerLabels
+	if !reflect.DeepEqual(gh, c.GitHub) {
+		restart = append(restart, "github")
+	}
+
+	// Each provider decides which of its own settings it can apply at
+	// runtime; the provider type and circuit breaker wrap it from outside
+	merged.Provider = next.Provider
+	merged.Provider.Type = c.Provider.Type
+	merged.Provider.CircuitBreaker = c.Provider.CircuitBreaker
+	if next.Provider.Type != c.Provider.Type {
+		restart = append(restart, "provider.type")
+	}
+	if next.Provider.CircuitBreaker != c.Provider.CircuitBreaker {
+		restart = append(restart, "provider.circuit_breaker")
+	}
+
+	// The delivery queue is sized once, and a disabled notifier is never
+	// started
+	if next.Notifications.Enabled == c.Notifications.Enabled && next.Notifications.QueueSize == c.Notifications.QueueSize {
+		merged.Notifications = next.Notifications
+	} else {
+		restart = append(restart, "notifications")
+	}
+
+	sections := []struct {
+		name        string
+		old, latest interface{}
+	}{
+		{"server", c.Server, next.Server},
+		{"observability", c.Observability, next.Observability},
+		{"leader_election", c.LeaderElection, next.LeaderElection},
+		{"store", c.Store, next.Store},
+		{"budget", c.Budget, next.Budget},
+		{"shutdown", c.Shutdown, next.Shutdown},
+		{"dry_run", c.DryRun, next.DryRun},
+		{"log_level", c.LogLevel, next.LogLevel},
+	}
+	for _, s := range sections {
+		if !reflect.DeepEqual(s.old, s.latest) {
+			restart = append(restart, s.name)
+		}
+	}
+
+	return &merged, restart
+}
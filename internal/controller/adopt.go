@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"context"
+
+	"Zeno/internal/github"
+	"Zeno/internal/provider"
+	"Zeno/internal/store"
+)
+
+// RunnerRegistry lists and removes runner registrations on GitHub. GitHub
+// clients that implement it let the controller check the provider inventory
+// against registrations when it starts.
+type RunnerRegistry interface {
+	ListRegisteredRunners(ctx context.Context) ([]github.RegisteredRunner, error)
+	RemoveRegisteredRunner(ctx context.Context, id int64) error
+}
+
+var _ RunnerRegistry = (*github.Client)(nil)
+
+// adoptRunners takes over the runners a previous process or leader left
+// behind, so they are counted once and keep the origin recorded when they
+// were created. With a RunnerRegistry, runners that never registered within
+// the registration timeout are removed as orphans, and offline registrations
+// of known runners whose instances are gone are deleted.
+func (c *Controller) adoptRunners(ctx context.Context) {
+	runners, err := c.provider.ListRunners(ctx)
+	if err != nil {
+		c.logger.Warn("failed to list runners for adoption", "error", err)
+		return
+	}
+
+	var registrations []github.RegisteredRunner
+	registry, _ := c.ghClient.(RunnerRegistry)
+	if registry != nil {
+		registrations, err = registry.ListRegisteredRunners(ctx)
+		if err != nil {
+			c.logger.Warn("failed to list GitHub registrations, adopting every provider runner", "error", err)
+			registry = nil
+		}
+	}
+
+	registered := make(map[string]bool, len(registrations))
+	for _, reg := range registrations {
+		registered[reg.Name] = true
+	}
+
+	listed := make(map[string]bool, len(runners))
+	var adopted, orphans int
+	for _, r := range runners {
+		if registry != nil && c.isOrphan(r, registered) {
+			if c.removeOrphan(ctx, r) {
+				orphans++
+				continue
+			}
+		}
+
+		listed[r.Name] = true
+		c.adopt(r)
+		adopted++
+	}
+
+	var stale int
+	if registry != nil {
+		stale = c.removeStaleRegistrations(ctx, registry, registrations, listed)
+	}
+
+	c.pruneOrigins(runners)
+
+	c.logger.Info("adopted existing runners",
+		"adopted", adopted,
+		"orphans_removed", orphans,
+		"registrations_removed", stale,
+		"checked_registrations", registry != nil,
+	)
+}
+
+// isOrphan reports whether a runner should have registered with GitHub by
+// now but has not. Runners scoped to a repository register there rather
+// than with the organization, so their registration cannot be checked.
+func (c *Controller) isOrphan(r *provider.Runner, registered map[string]bool) bool {
+	timeout := c.cfg.Scaling.RegistrationTimeout
+	if timeout <= 0 || registered[r.Name] || r.Metadata[provider.MetadataRepository] != "" {
+		return false
+	}
+	return c.clock.Since(r.CreatedAt) > timeout
+}
+
+// removeOrphan removes an unregistered runner, reporting whether it is gone
+func (c *Controller) removeOrphan(ctx context.Context, r *provider.Runner) bool {
+	if c.cfg.DryRun {
+		c.logger.Info("dry-run mode: would remove orphaned runner",
+			"id", r.ID,
+			"name", r.Name,
+			"created_at", r.CreatedAt,
+		)
+		return false
+	}
+
+	if err := c.provider.RemoveRunner(ctx, r.ID, false); err != nil {
+		c.logger.Error("failed to remove orphaned runner",
+			"id", r.ID,
+			"error", err,
+		)
+		c.providerError("remove", "orphan_error", err)
+		return false
+	}
+
+	c.logger.Info("removed orphaned runner, never registered with GitHub",
+		"id", r.ID,
+		"name", r.Name,
+		"created_at", r.CreatedAt,
+	)
+	c.metrics.RunnersAdopted.WithLabelValues("orphan_removed").Inc()
+	c.forgetOrigin(r.ID)
+	return true
+}
+
+// adopt records an origin for runners this controller has no record of
+func (c *Controller) adopt(r *provider.Runner) {
+	c.metrics.RunnersAdopted.WithLabelValues("adopted").Inc()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.origins[r.ID]; ok {
+		return
+	}
+	c.origins[r.ID] = store.RunnerOrigin{
+		Name:      r.Name,
+		Reason:    "adopted",
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+// removeStaleRegistrations deletes offline registrations of runners this
+// controller created whose instances no longer exist. Registrations of
+// runners it has no record of are left alone, since they may belong to
+// another installation.
+func (c *Controller) removeStaleRegistrations(
+	ctx context.Context,
+	registry RunnerRegistry,
+	registrations []github.RegisteredRunner,
+	listed map[string]bool,
+) int {
+	known := make(map[string]string)
+	for id, origin := range c.RunnerOrigins() {
+		known[origin.Name] = id
+	}
+
+	removed := 0
+	for _, reg := range registrations {
+		id, ok := known[reg.Name]
+		if !ok || listed[reg.Name] || reg.Status != "offline" || reg.Busy {
+			continue
+		}
+
+		if c.cfg.DryRun {
+			c.logger.Info("dry-run mode: would remove stale registration", "name", reg.Name, "registration_id", reg.ID)
+			continue
+		}
+
+		if err := registry.RemoveRegisteredRunner(ctx, reg.ID); err != nil {
+			c.logger.Warn("failed to remove stale registration",
+				"name", reg.Name,
+				"error", err,
+			)
+			continue
+		}
+
+		c.logger.Info("removed stale registration", "name", reg.Name, "registration_id", reg.ID)
+		c.metrics.RunnersAdopted.WithLabelValues("registration_removed").Inc()
+		c.forgetOrigin(id)
+		removed++
+	}
+
+	return removed
+}
@@ -0,0 +1,244 @@
+package controller
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"Zeno/internal/clock"
+	"Zeno/internal/config"
+	"Zeno/internal/github"
+	"Zeno/internal/metrics"
+	"Zeno/internal/provider"
+	"Zeno/internal/store"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// registryGitHubClient also reports runner registrations
+type registryGitHubClient struct {
+	mockGitHubClient
+	registrations []github.RegisteredRunner
+	removed       []int64
+}
+
+func (r *registryGitHubClient) ListRegisteredRunners(ctx context.Context) ([]github.RegisteredRunner, error) {
+	return r.registrations, nil
+}
+
+func (r *registryGitHubClient) RemoveRegisteredRunner(ctx context.Context, id int64) error {
+	r.removed = append(r.removed, id)
+	return nil
+}
+
+var stateStart = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newStateTestStore(t *testing.T, path string) *store.Store {
+	t.Helper()
+
+	st, err := store.New(store.StoreConfig{
+		Enabled:      true,
+		Path:         path,
+		MaxEvents:    100,
+		MaxDecisions: 100,
+	})
+	if err != nil {
+		t.Fatalf("store.New() error = %v", err)
+	}
+	return st
+}
+
+func stateTestConfig() *config.Config {
+	return &config.Config{
+		Scaling: config.ScalingConfig{
+			MinRunners:          1,
+			MaxRunners:          10,
+			ScaleUpThreshold:    3,
+			ScaleUpHysteresis:   3,
+			ScaleDownHysteresis: 3,
+			CooldownPeriod:      5 * time.Minute,
+			RegistrationTimeout: 10 * time.Minute,
+		},
+		Store: config.StoreConfig{StateMaxAge: 10 * time.Minute},
+	}
+}
+
+func TestStateSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(stateStart)
+	gh := &mockGitHubClient{queueDepth: 6}
+	prov := &mockProvider{runners: []*provider.Runner{{ID: "r1", Name: "zeno-runner-1", Status: provider.StatusIdle, CreatedAt: stateStart}}}
+
+	// Two of the three observations needed to scale up
+	first := New(stateTestConfig(), gh, prov, newStateTestStore(t, path), metrics.NewMetrics(prometheus.NewRegistry()), nil, clk, logger)
+	for i := 0; i < 2; i++ {
+		if err := first.reconcile(context.Background()); err != nil {
+			t.Fatalf("reconcile() error = %v", err)
+		}
+		clk.Advance(30 * time.Second)
+	}
+	if prov.created != 0 {
+		t.Fatalf("scaled up before hysteresis was met")
+	}
+
+	// A new process continues the count instead of starting over
+	second := New(stateTestConfig(), gh, prov, newStateTestStore(t, path), metrics.NewMetrics(prometheus.NewRegistry()), nil, clk, logger)
+	second.restoreState()
+	if err := second.reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile() error = %v", err)
+	}
+	if prov.created != 6-1 {
+		t.Fatalf("created %d runners after restart, want 5", prov.created)
+	}
+
+	// The cooldown started by the scale up holds across another restart
+	clk.Advance(time.Minute)
+	third := New(stateTestConfig(), gh, prov, newStateTestStore(t, path), metrics.NewMetrics(prometheus.NewRegistry()), nil, clk, logger)
+	third.restoreState()
+	if !third.inCooldownPeriod() {
+		t.Error("restored controller is not in cooldown a minute after scaling up")
+	}
+
+	origins := third.RunnerOrigins()
+	if len(origins) != 5 {
+		t.Fatalf("restored %d runner origins, want 5", len(origins))
+	}
+	for id, origin := range origins {
+		if origin.Reason != "queue_above_threshold" || !origin.CreatedAt.Equal(stateStart.Add(time.Minute)) {
+			t.Errorf("origin of %s = %+v", id, origin)
+		}
+	}
+}
+
+func TestBudgetSpendSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(stateStart)
+	cfg := stateTestConfig()
+	cfg.Budget = config.BudgetConfig{Enabled: true, MaxDailySpend: 1, DefaultPrice: 1}
+	prov := &mockProvider{runners: []*provider.Runner{
+		{ID: "r1", Name: "zeno-runner-1", Status: provider.StatusIdle, CreatedAt: stateStart.Add(-2 * time.Hour)},
+	}}
+
+	first := New(cfg, &mockGitHubClient{}, prov, newStateTestStore(t, path), metrics.NewMetrics(prometheus.NewRegistry()), nil, clk, logger)
+	if err := first.reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile() error = %v", err)
+	}
+	if status := first.BudgetStatus(); !status.Exhausted {
+		t.Fatalf("budget status = %+v, want the daily limit exhausted", status)
+	}
+
+	// A restart does not hand out a fresh daily budget
+	clk.Advance(30 * time.Minute)
+	second := New(cfg, &mockGitHubClient{}, prov, newStateTestStore(t, path), metrics.NewMetrics(prometheus.NewRegistry()), nil, clk, logger)
+	second.restoreState()
+	if err := second.reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile() error = %v", err)
+	}
+	status := second.BudgetStatus()
+	if !status.Exhausted || math.Abs(status.DailySpend-2.5) > 1e-9 {
+		t.Errorf("budget status after restart = %+v, want $2.50 spent today and exhausted", status)
+	}
+}
+
+func TestStaleStateResetsHysteresis(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	st := newStateTestStore(t, path)
+	if err := st.SaveControllerState(store.ControllerState{
+		SavedAt:         stateStart,
+		LastScaleUpTime: stateStart.Add(-time.Minute),
+		ScaleUpCounter:  2,
+		QueueHistory:    []int{6, 6},
+	}); err != nil {
+		t.Fatalf("SaveControllerState() error = %v", err)
+	}
+
+	clk := clock.NewFake(stateStart.Add(time.Hour))
+	ctrl := New(stateTestConfig(), &mockGitHubClient{}, &mockProvider{}, st,
+		metrics.NewMetrics(prometheus.NewRegistry()), nil, clk, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctrl.restoreState()
+
+	if ctrl.scaleUpCounter != 0 || len(ctrl.queueHistory) != 0 {
+		t.Errorf("restored counter %d and history %v from hour-old state", ctrl.scaleUpCounter, ctrl.queueHistory)
+	}
+	if !ctrl.lastScaleUpTime.Equal(stateStart.Add(-time.Minute)) {
+		t.Errorf("lastScaleUpTime = %s, want it restored regardless of age", ctrl.lastScaleUpTime)
+	}
+}
+
+func TestAdoptRunnersAgainstRegistrations(t *testing.T) {
+	st := newStateTestStore(t, filepath.Join(t.TempDir(), "store.json"))
+	if err := st.SaveControllerState(store.ControllerState{
+		SavedAt: stateStart,
+		Runners: map[string]store.RunnerOrigin{
+			"r1":   {Name: "zeno-runner-1", Reason: "queue_above_threshold", CreatedAt: stateStart.Add(-time.Hour)},
+			"gone": {Name: "zeno-runner-gone", Reason: "queue_above_threshold", CreatedAt: stateStart.Add(-time.Hour)},
+		},
+	}); err != nil {
+		t.Fatalf("SaveControllerState() error = %v", err)
+	}
+
+	prov := &mockProvider{runners: []*provider.Runner{
+		// Registered and known from saved state
+		{ID: "r1", Name: "zeno-runner-1", Status: provider.StatusBusy, CreatedAt: stateStart.Add(-time.Hour)},
+		// Created by the previous process but never registered
+		{ID: "r2", Name: "zeno-runner-2", Status: provider.StatusRunning, CreatedAt: stateStart.Add(-time.Hour)},
+		// Still booting
+		{ID: "r3", Name: "zeno-runner-3", Status: provider.StatusProvisioning, CreatedAt: stateStart.Add(-2 * time.Minute)},
+	}}
+
+	gh := &registryGitHubClient{registrations: []github.RegisteredRunner{
+		{ID: 1, Name: "zeno-runner-1", Status: "online", Busy: true},
+		{ID: 7, Name: "zeno-runner-gone", Status: "offline"},
+		{ID: 9, Name: "someone-elses-runner", Status: "offline"},
+	}}
+
+	clk := clock.NewFake(stateStart)
+	met := metrics.NewMetrics(prometheus.NewRegistry())
+	ctrl := New(stateTestConfig(), gh, prov, st, met, nil, clk, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctrl.restoreState()
+	ctrl.adoptRunners(context.Background())
+
+	if len(prov.removed) != 1 || prov.removed[0] != "r2" {
+		t.Errorf("removed runners = %v, want only the unregistered r2", prov.removed)
+	}
+	if len(gh.removed) != 1 || gh.removed[0] != 7 {
+		t.Errorf("removed registrations = %v, want only the known, vanished runner 7", gh.removed)
+	}
+
+	origins := ctrl.RunnerOrigins()
+	if len(origins) != 2 {
+		t.Fatalf("origins = %+v, want r1 and r3", origins)
+	}
+	if origins["r1"].Reason != "queue_above_threshold" {
+		t.Errorf("origins[r1] = %+v, want the saved origin kept", origins["r1"])
+	}
+	if origins["r3"].Reason != "adopted" {
+		t.Errorf("origins[r3] = %+v, want adopted", origins["r3"])
+	}
+}
+
+func TestAdoptRunnersDryRunRemovesNothing(t *testing.T) {
+	prov := &mockProvider{runners: []*provider.Runner{
+		{ID: "r1", Name: "zeno-runner-1", Status: provider.StatusRunning, CreatedAt: stateStart.Add(-time.Hour)},
+	}}
+	gh := &registryGitHubClient{}
+
+	cfg := stateTestConfig()
+	cfg.DryRun = true
+	ctrl := New(cfg, gh, prov, nil, metrics.NewMetrics(prometheus.NewRegistry()), nil,
+		clock.NewFake(stateStart), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctrl.adoptRunners(context.Background())
+
+	if len(prov.removed) != 0 {
+		t.Errorf("dry run removed %v", prov.removed)
+	}
+	if _, ok := ctrl.RunnerOrigins()["r1"]; !ok {
+		t.Error("dry run did not adopt the unregistered runner")
+	}
+}
@@ -2,25 +2,46 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
+	"Zeno/internal/budget"
+	"Zeno/internal/clock"
 	"Zeno/internal/config"
+	"Zeno/internal/fairshare"
 	"Zeno/internal/github"
 	"Zeno/internal/metrics"
+	"Zeno/internal/notify"
+	"Zeno/internal/priority"
 	"Zeno/internal/provider"
+	"Zeno/internal/provider/breaker"
 	"Zeno/internal/store"
 )
 
+// GitHubClient is the subset of the GitHub API the controller depends on
+type GitHubClient interface {
+	GetQueuedWorkflowJobs(ctx context.Context) (int, error)
+	GetQueuedJobs(ctx context.Context) ([]github.QueuedJob, error)
+	GetRateLimitInfo() github.RateLimitInfo
+}
+
 type Controller struct {
-	cfg      *config.Config
-	ghClient *github.Client
-	provider provider.Provider
-	store    *store.Store
-	metrics  *metrics.Metrics
-	logger   *slog.Logger
+	cfg       *config.Config
+	ghClient  GitHubClient
+	provider  provider.Provider
+	store     *store.Store
+	metrics   *metrics.Metrics
+	logger    *slog.Logger
+	budget    *budget.Tracker
+	notifier  *notify.Notifier
+	fairShare *fairshare.Quotas
+	priority  *priority.Classifier
+	clock     clock.Clock
 
 	// Scaling state
 	lastScaleUpTime   time.Time
@@ -28,17 +49,44 @@ type Controller struct {
 	scaleUpCounter    int
 	scaleDownCounter  int
 	queueHistory      []int
+	createFailures    int
+
+	// origins records why each runner was created, keyed by runner ID
+	origins map[string]store.RunnerOrigin
+
+	// replacements maps draining runners to the runner started in their
+	// place, or to "" while none could be started yet
+	replacements map[string]string
+
+	// Operator overrides and on-demand reconcile requests
+	overrides   store.Overrides
+	reconcileCh chan struct{}
+
+	// Reloaded configuration waiting to be applied between reconciles
+	pendingCfg *config.Config
+	configCh   chan struct{}
 
-	mu sync.RWMutex
+	// runMu is held while Run is running, so Shutdown waits for it
+	runMu sync.Mutex
+	mu    sync.RWMutex
 }
 
 type ScaleDecision struct {
-	Action        ScaleAction
-	Reason        string
-	CurrentCount  int
-	DesiredCount  int
-	QueueDepth    int
-	HysteresisHit bool
+	Action              ScaleAction
+	Reason              string
+	CurrentCount        int
+	DesiredCount        int
+	QueueDepth          int
+	PredictedQueueDepth int
+	HysteresisHit       bool
+	JobIDs              []int64
+	// Repositories maps job IDs to the repository their runner is scoped
+	// to, when runners are shared out between repositories
+	Repositories map[int64]string
+	// PriorityClass is the class of queued jobs that drove the decision
+	PriorityClass string
+	// Overridden is set when operator overrides dictated the decision
+	Overridden bool
 }
 
 type ScaleAction string
@@ -52,45 +100,91 @@ const (
 // New creates a new controller instance
 func New(
 	cfg *config.Config,
-	ghClient *github.Client,
+	ghClient GitHubClient,
 	prov provider.Provider,
 	st *store.Store,
 	met *metrics.Metrics,
+	notifier *notify.Notifier,
+	clk clock.Clock,
 	logger *slog.Logger,
 ) *Controller {
-	return &Controller{
+	c := &Controller{
 		cfg:          cfg,
 		ghClient:     ghClient,
 		provider:     prov,
 		store:        st,
 		metrics:      met,
 		logger:       logger.With("component", "controller"),
+		budget:       budget.New(cfg.Budget),
+		fairShare:    fairshare.New(cfg.Scaling.FairShare),
+		priority:     priority.New(cfg.Scaling.Priority),
+		notifier:     notifier,
+		clock:        clk,
 		queueHistory: make([]int, 0, 100),
+		origins:      make(map[string]store.RunnerOrigin),
+		replacements: make(map[string]string),
+		reconcileCh:  make(chan struct{}, 1),
+		configCh:     make(chan struct{}, 1),
 	}
+
+	// Overrides survive restarts through the store
+	if st != nil {
+		c.overrides = st.GetOverrides()
+	}
+
+	return c
+}
+
+// BudgetStatus returns accrued runner cost against the configured limits
+func (c *Controller) BudgetStatus() budget.Status {
+	return c.budget.Status()
 }
 
 // Run starts the controller reconciliation loop
 func (c *Controller) Run(ctx context.Context) error {
+	c.runMu.Lock()
+	defer c.runMu.Unlock()
+
+	c.applyPendingConfig()
+
 	c.logger.Info("controller starting",
 		"check_interval", c.cfg.Scaling.CheckInterval,
 		"min_runners", c.cfg.Scaling.MinRunners,
 		"max_runners", c.cfg.Scaling.MaxRunners,
 	)
 
+	// Pick up where a previous process or leader left off
+	c.restoreState()
+	c.adoptRunners(ctx)
+
 	// Initial reconcile
 	if err := c.reconcile(ctx); err != nil {
 		c.logger.Error("initial reconcile failed", "error", err)
 	}
 
-	ticker := time.NewTicker(c.cfg.Scaling.CheckInterval)
-	defer ticker.Stop()
+	interval := c.cfg.Scaling.CheckInterval
+	ticker := c.clock.NewTicker(interval)
+	defer func() { ticker.Stop() }()
 
 	for {
 		select {
 		case <-ctx.Done():
 			c.logger.Info("controller stopped")
 			return ctx.Err()
-		case <-ticker.C:
+		case <-c.configCh:
+			if !c.applyPendingConfig() || c.cfg.Scaling.CheckInterval == interval {
+				continue
+			}
+			interval = c.cfg.Scaling.CheckInterval
+			ticker.Stop()
+			ticker = c.clock.NewTicker(interval)
+		case <-ticker.C():
+			if err := c.reconcile(ctx); err != nil {
+				c.logger.Error("reconcile failed", "error", err)
+				c.metrics.ReconcileErrors.WithLabelValues("reconcile_error").Inc()
+			}
+		case <-c.reconcileCh:
+			c.logger.Info("reconcile triggered by operator")
 			if err := c.reconcile(ctx); err != nil {
 				c.logger.Error("reconcile failed", "error", err)
 				c.metrics.ReconcileErrors.WithLabelValues("reconcile_error").Inc()
@@ -100,17 +194,31 @@ func (c *Controller) Run(ctx context.Context) error {
 }
 
 func (c *Controller) reconcile(ctx context.Context) error {
-	startTime := time.Now()
+	startTime := c.clock.Now()
 	defer func() {
-		duration := time.Since(startTime)
+		duration := c.clock.Since(startTime)
 		c.metrics.ReconcileDuration.WithLabelValues("success").Observe(duration.Seconds())
 	}()
 
 	c.logger.Debug("starting reconciliation")
-
-	// Get queue depth
-	queueDepth, err := c.ghClient.GetQueuedWorkflowJobs(ctx)
+	defer c.saveState()
+
+	// Get queue depth; ephemeral mode, fair share and priority classes need
+	// the individual jobs
+	var queuedJobs []github.QueuedJob
+	var queueDepth int
+	var err error
+	if c.cfg.Scaling.Ephemeral || c.fairShare.Enabled() || c.priority.Enabled() {
+		queuedJobs, err = c.ghClient.GetQueuedJobs(ctx)
+		queueDepth = len(queuedJobs)
+	} else {
+		queueDepth, err = c.ghClient.GetQueuedWorkflowJobs(ctx)
+	}
 	if err != nil {
+		var rateLimitErr *github.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			c.notifyRateLimitExhausted(rateLimitErr.ResetTime)
+		}
 		return fmt.Errorf("failed to get queue depth: %w", err)
 	}
 
@@ -126,14 +234,34 @@ func (c *Controller) reconcile(ctx context.Context) error {
 		return fmt.Errorf("failed to list runners: %w", err)
 	}
 
-	currentCount := len(runners)
-	c.metrics.RunnersCurrent.Set(float64(currentCount))
+	// Ephemeral runners that have exited are consumed and no longer capacity
+	if c.cfg.Scaling.Ephemeral {
+		runners = c.collectConsumedRunners(ctx, runners)
+	}
+
+	c.pruneOrigins(runners)
 
 	// Update runner status metrics
 	c.updateRunnerStatusMetrics(runners)
 
+	// Accrue runner cost
+	c.updateBudget(runners)
+
+	// Draining runners no longer count as capacity, their replacements do
+	runners = c.replaceDrainingRunners(ctx, runners)
+
+	currentCount := len(runners)
+	c.metrics.RunnersCurrent.Set(float64(currentCount))
+
 	// Make scaling decision
-	decision := c.makeScalingDecision(queueDepth, currentCount)
+	var decision ScaleDecision
+	if c.cfg.Scaling.Ephemeral {
+		decision = c.makeEphemeralDecision(queuedJobs, runners)
+	} else if c.fairShare.Enabled() || c.priority.Enabled() {
+		decision = c.makeJobsDecision(queuedJobs, currentCount)
+	} else {
+		decision = c.makeScalingDecision(queueDepth, currentCount)
+	}
 
 	c.logger.Info("scaling decision",
 		"action", decision.Action,
@@ -142,12 +270,30 @@ func (c *Controller) reconcile(ctx context.Context) error {
 		"desired", decision.DesiredCount,
 		"queue_depth", decision.QueueDepth,
 		"hysteresis_hit", decision.HysteresisHit,
+		"jobs", len(decision.JobIDs),
+		"priority_class", decision.PriorityClass,
 	)
 
 	c.metrics.RunnersDesired.Set(float64(decision.DesiredCount))
 
+	if c.atMaxRunners(decision) {
+		c.notifier.Notify(notify.Event{
+			Type:     notify.EventMaxRunnersReached,
+			Severity: notify.SeverityWarning,
+			Title:    "Runner limit reached",
+			Message: fmt.Sprintf("%d jobs are queued but scaling is capped at %d runners",
+				decision.QueueDepth, c.cfg.Scaling.MaxRunners),
+			Fields: map[string]string{
+				"queue_depth": strconv.Itoa(decision.QueueDepth),
+				"max_runners": strconv.Itoa(c.cfg.Scaling.MaxRunners),
+			},
+		})
+	}
+
 	// Execute scaling action
-	if err := c.executeScaling(ctx, decision); err != nil {
+	changed, err := c.executeScaling(ctx, decision)
+	c.recordDecision(decision, runners, changed, err)
+	if err != nil {
 		return fmt.Errorf("failed to execute scaling: %w", err)
 	}
 
@@ -156,6 +302,9 @@ func (c *Controller) reconcile(ctx context.Context) error {
 	c.metrics.GitHubAPIRateLimit.Set(float64(rateLimitInfo.Remaining))
 	if !rateLimitInfo.Reset.IsZero() {
 		c.metrics.GitHubAPIRateLimitReset.Set(float64(rateLimitInfo.Reset.Unix()))
+		if rateLimitInfo.Remaining == 0 {
+			c.notifyRateLimitExhausted(rateLimitInfo.Reset)
+		}
 	}
 
 	c.metrics.ReconcileTotal.WithLabelValues("success").Inc()
@@ -170,6 +319,19 @@ func (c *Controller) makeScalingDecision(queueDepth, currentCount int) ScaleDeci
 		QueueDepth:   queueDepth,
 	}
 
+	// Operator overrides take precedence over queue-driven scaling
+	if overridden, ok := c.applyOverrides(decision); ok {
+		overridden.Overridden = true
+		if overridden.Action == ScaleActionUp {
+			if reason := c.scaleUpBlockedReason(); reason != "" {
+				overridden.Action = ScaleActionNone
+				overridden.DesiredCount = currentCount
+				overridden.Reason = reason
+			}
+		}
+		return overridden
+	}
+
 	// Check if in cooldown period
 	if c.inCooldownPeriod() {
 		decision.Reason = "in_cooldown_period"
@@ -179,6 +341,7 @@ func (c *Controller) makeScalingDecision(queueDepth, currentCount int) ScaleDeci
 	// Predictive scaling
 	if c.cfg.Scaling.EnablePredictiveScaling {
 		predictedQueue := c.predictQueueGrowth()
+		decision.PredictedQueueDepth = predictedQueue
 		if predictedQueue > queueDepth {
 			c.logger.Debug("predictive scaling",
 				"current_queue", queueDepth,
@@ -195,9 +358,16 @@ func (c *Controller) makeScalingDecision(queueDepth, currentCount int) ScaleDeci
 	if queueDepth >= c.cfg.Scaling.ScaleUpThreshold {
 		// Simple strategy: one runner per queued job, up to max
 		desiredCount = min(queueDepth, c.cfg.Scaling.MaxRunners)
-		desiredCount = max(desiredCount, c.cfg.Scaling.MinRunners)
+		desiredCount = max(desiredCount, c.minRunners())
 
+		var blockedReason string
 		if desiredCount > currentCount {
+			blockedReason = c.scaleUpBlockedReason()
+		}
+
+		if blockedReason != "" {
+			decision.Reason = blockedReason
+		} else if desiredCount > currentCount {
 			// Check hysteresis
 			c.mu.Lock()
 			c.scaleUpCounter++
@@ -216,9 +386,11 @@ func (c *Controller) makeScalingDecision(queueDepth, currentCount int) ScaleDeci
 		}
 	} else if queueDepth <= c.cfg.Scaling.ScaleDownThreshold {
 		// Scale down logic
-		desiredCount = max(queueDepth, c.cfg.Scaling.MinRunners)
+		desiredCount = max(queueDepth, c.minRunners())
 
-		if desiredCount < currentCount {
+		if desiredCount < currentCount && c.circuitOpen(breaker.OpRemove) {
+			decision.Reason = "circuit_open"
+		} else if desiredCount < currentCount {
 			// Check hysteresis
 			c.mu.Lock()
 			c.scaleDownCounter++
@@ -247,9 +419,11 @@ func (c *Controller) makeScalingDecision(queueDepth, currentCount int) ScaleDeci
 	return decision
 }
 
-func (c *Controller) executeScaling(ctx context.Context, decision ScaleDecision) error {
+// executeScaling acts on a decision and returns how many runners were
+// created or removed
+func (c *Controller) executeScaling(ctx context.Context, decision ScaleDecision) (int, error) {
 	if decision.Action == ScaleActionNone {
-		return nil
+		return 0, nil
 	}
 
 	if c.cfg.DryRun {
@@ -258,7 +432,7 @@ func (c *Controller) executeScaling(ctx context.Context, decision ScaleDecision)
 			"from", decision.CurrentCount,
 			"to", decision.DesiredCount,
 		)
-		return nil
+		return 0, nil
 	}
 
 	switch decision.Action {
@@ -268,65 +442,102 @@ func (c *Controller) executeScaling(ctx context.Context, decision ScaleDecision)
 		return c.scaleDown(ctx, decision)
 	}
 
-	return nil
+	return 0, nil
 }
 
-func (c *Controller) scaleUp(ctx context.Context, decision ScaleDecision) error {
-	startTime := time.Now()
+func (c *Controller) scaleUp(ctx context.Context, decision ScaleDecision) (int, error) {
+	startTime := c.clock.Now()
 	defer func() {
-		c.metrics.ScaleUpDuration.Observe(time.Since(startTime).Seconds())
+		c.metrics.ScaleUpDuration.Observe(c.clock.Since(startTime).Seconds())
 	}()
 
 	count := decision.DesiredCount - decision.CurrentCount
 	c.logger.Info("scaling up", "count", count)
 
+	created := 0
 	for i := 0; i < count; i++ {
-		req := &provider.CreateRunnerRequest{
-			Name:        fmt.Sprintf("zeno-runner-%d", time.Now().UnixNano()),
-			Labels:      c.cfg.GitHub.RunnerLabels,
-			GitHubToken: c.cfg.GitHub.Token,
-			GitHubOrg:   c.cfg.GitHub.Organization,
-			GitHubRepo:  c.cfg.GitHub.Repository,
+		var jobID int64
+		if i < len(decision.JobIDs) {
+			jobID = decision.JobIDs[i]
 		}
+		req := c.runnerRequest(jobID, decision.Repositories[jobID])
 
 		runner, err := c.provider.CreateRunner(ctx, req)
+		if errors.Is(err, breaker.ErrOpen) {
+			c.logger.Warn("provider circuit open, aborting scale up",
+				"created", i,
+				"requested", count,
+			)
+			c.providerError("create", "circuit_open", err)
+			break
+		}
 		if err != nil {
 			c.logger.Error("failed to create runner", "error", err)
-			c.metrics.ProviderErrors.WithLabelValues(
-				c.provider.Name(),
-				"create",
-				"creation_error",
-			).Inc()
+			c.providerError("create", "creation_error", err)
 			continue
 		}
 
-		c.logger.Info("runner created", "id", runner.ID, "name", runner.Name)
+		c.logger.Info("runner created", "id", runner.ID, "name", runner.Name, "job_id", jobID)
 		c.metrics.ScaleUpEvents.WithLabelValues(decision.Reason).Inc()
+		created++
+		c.recordOrigin(runner, decision.Reason, jobID)
+
+		c.mu.Lock()
+		c.createFailures = 0
+		c.mu.Unlock()
 
 		// Record event
-		if c.store != nil {
-			_ = c.store.RecordScaleEvent(store.ScaleEvent{
-				Timestamp:    time.Now(),
-				Action:       "scale_up",
-				Reason:       decision.Reason,
-				QueueDepth:   decision.QueueDepth,
-				RunnersBefore: decision.CurrentCount,
-				RunnersAfter: decision.CurrentCount + i + 1,
-			})
-		}
+		c.recordScaleEvent(store.ScaleEvent{
+			Timestamp:     c.clock.Now(),
+			Action:        "scale_up",
+			Reason:        decision.Reason,
+			QueueDepth:    decision.QueueDepth,
+			RunnersBefore: decision.CurrentCount,
+			RunnersAfter:  decision.CurrentCount + created,
+			JobID:         jobID,
+		})
 	}
 
 	c.mu.Lock()
-	c.lastScaleUpTime = time.Now()
+	c.lastScaleUpTime = c.clock.Now()
 	c.mu.Unlock()
 
-	return nil
+	return created, nil
 }
 
-func (c *Controller) scaleDown(ctx context.Context, decision ScaleDecision) error {
-	startTime := time.Now()
+// runnerRequest describes a new runner, for a job when jobID is not zero
+func (c *Controller) runnerRequest(jobID int64, repo string) *provider.CreateRunnerRequest {
+	req := &provider.CreateRunnerRequest{
+		// Wall time keeps names unique even under a fake clock
+		Name:        fmt.Sprintf("zeno-runner-%d", time.Now().UnixNano()),
+		Labels:      c.cfg.GitHub.RunnerLabels,
+		GitHubToken: c.cfg.GitHub.Token,
+		GitHubOrg:   c.cfg.GitHub.Organization,
+		GitHubRepo:  c.cfg.GitHub.Repository,
+		Ephemeral:   c.cfg.Scaling.Ephemeral,
+	}
+	if jobID == 0 {
+		return req
+	}
+
+	req.Metadata = map[string]string{
+		provider.MetadataJobID: strconv.FormatInt(jobID, 10),
+	}
+
+	// Register to the job's repository so the runner counts against that
+	// repository's share
+	if repo != "" {
+		req.GitHubOrg = ""
+		req.GitHubRepo = repo
+		req.Metadata[provider.MetadataRepository] = repo
+	}
+	return req
+}
+
+func (c *Controller) scaleDown(ctx context.Context, decision ScaleDecision) (int, error) {
+	startTime := c.clock.Now()
 	defer func() {
-		c.metrics.ScaleDownDuration.Observe(time.Since(startTime).Seconds())
+		c.metrics.ScaleDownDuration.Observe(c.clock.Since(startTime).Seconds())
 	}()
 
 	count := decision.CurrentCount - decision.DesiredCount
@@ -335,10 +546,10 @@ func (c *Controller) scaleDown(ctx context.Context, decision ScaleDecision) erro
 	// Get current runners
 	runners, err := c.provider.ListRunners(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to list runners: %w", err)
+		return 0, fmt.Errorf("failed to list runners: %w", err)
 	}
 
-	// Remove oldest idle runners first
+	removalOrder(runners)
 	removed := 0
 	for _, runner := range runners {
 		if removed >= count {
@@ -348,49 +559,213 @@ func (c *Controller) scaleDown(ctx context.Context, decision ScaleDecision) erro
 		if runner.Status == provider.StatusIdle || runner.Status == provider.StatusRunning {
 			graceful := c.cfg.Scaling.GracefulTermination
 
-			if err := c.provider.RemoveRunner(ctx, runner.ID, graceful); err != nil {
+			err := c.provider.RemoveRunner(ctx, runner.ID, graceful)
+			if errors.Is(err, breaker.ErrOpen) {
+				c.logger.Warn("provider circuit open, aborting scale down", "removed", removed)
+				c.providerError("remove", "circuit_open", err)
+				break
+			}
+			if err != nil {
 				c.logger.Error("failed to remove runner",
 					"id", runner.ID,
 					"error", err,
 				)
-				c.metrics.ProviderErrors.WithLabelValues(
-					c.provider.Name(),
-					"remove",
-					"removal_error",
-				).Inc()
+				c.providerError("remove", "removal_error", err)
 				continue
 			}
 
 			c.logger.Info("runner removed", "id", runner.ID, "name", runner.Name)
 			c.metrics.ScaleDownEvents.WithLabelValues(decision.Reason).Inc()
 			removed++
+			c.forgetOrigin(runner.ID)
 
 			// Record event
-			if c.store != nil {
-				_ = c.store.RecordScaleEvent(store.ScaleEvent{
-					Timestamp:     time.Now(),
-					Action:        "scale_down",
-					Reason:        decision.Reason,
-					QueueDepth:    decision.QueueDepth,
-					RunnersBefore: decision.CurrentCount,
-					RunnersAfter:  decision.CurrentCount - removed,
-				})
-			}
+			c.recordScaleEvent(store.ScaleEvent{
+				Timestamp:     c.clock.Now(),
+				Action:        "scale_down",
+				Reason:        decision.Reason,
+				QueueDepth:    decision.QueueDepth,
+				RunnersBefore: decision.CurrentCount,
+				RunnersAfter:  decision.CurrentCount - removed,
+			})
 		}
 	}
 
 	c.mu.Lock()
-	c.lastScaleDownTime = time.Now()
+	c.lastScaleDownTime = c.clock.Now()
 	c.mu.Unlock()
 
-	return nil
+	return removed, nil
+}
+
+// removalOrder sorts runners into the order scale-down removes them. The
+// provider's list order is kept, as Provider.ListRunners asks, except that
+// spot runners move ahead of the on-demand runners of the same provider so
+// on-demand capacity is kept. Runners never move ahead of another
+// provider's, so spillover still empties its most expensive provider first.
+func removalOrder(runners []*provider.Runner) {
+	rank := make(map[string]int)
+	for _, r := range runners {
+		if _, ok := rank[r.Provider]; !ok {
+			rank[r.Provider] = len(rank)
+		}
+	}
+
+	spot := func(r *provider.Runner) bool {
+		return r.Metadata[provider.MetadataLifecycle] == provider.LifecycleSpot
+	}
+	sort.SliceStable(runners, func(i, j int) bool {
+		if ri, rj := rank[runners[i].Provider], rank[runners[j].Provider]; ri != rj {
+			return ri < rj
+		}
+		return spot(runners[i]) && !spot(runners[j])
+	})
+}
+
+// makeJobsDecision scales for the queued jobs that count once fair share
+// and priority classes are applied
+func (c *Controller) makeJobsDecision(jobs []github.QueuedJob, currentCount int) ScaleDecision {
+	if c.fairShare.Enabled() {
+		jobs = c.fairShareJobs(jobs)
+	}
+	if c.priority.Enabled() {
+		return c.makePriorityDecision(c.priority.Count(jobs), currentCount)
+	}
+	return c.makeScalingDecision(len(jobs), currentCount)
+}
+
+// makeEphemeralDecision plans one runner for every queued job that does not
+// have one yet. Ephemeral mode never scales down by count: runners leave the
+// pool only once they have exited after their job.
+func (c *Controller) makeEphemeralDecision(jobs []github.QueuedJob, runners []*provider.Runner) ScaleDecision {
+	currentCount := len(runners)
+	decision := ScaleDecision{
+		Action:       ScaleActionNone,
+		CurrentCount: currentCount,
+		DesiredCount: currentCount,
+		QueueDepth:   len(jobs),
+	}
+
+	// Count-based overrides do not apply to ephemeral runners, pausing does
+	if c.Overrides().Paused {
+		decision.Reason = "paused"
+		return decision
+	}
+
+	assigned := make(map[string]bool, len(runners))
+	for _, r := range runners {
+		if jobID := r.Metadata[provider.MetadataJobID]; jobID != "" {
+			assigned[jobID] = true
+		}
+	}
+
+	var unassigned []github.QueuedJob
+	for _, job := range jobs {
+		if !assigned[strconv.FormatInt(job.ID, 10)] {
+			unassigned = append(unassigned, job)
+		}
+	}
+
+	if len(unassigned) == 0 {
+		decision.Reason = "all_jobs_assigned"
+		return decision
+	}
+
+	room := c.cfg.Scaling.MaxRunners - currentCount
+	if c.priority.Enabled() {
+		// High priority jobs may use room up to the hard maximum
+		room = c.priority.HardMax(c.cfg.Scaling.MaxRunners) - currentCount
+	}
+	if room <= 0 {
+		decision.Reason = "max_runners_reached"
+		return decision
+	}
+	if reason := c.scaleUpBlockedReason(); reason != "" {
+		decision.Reason = reason
+		return decision
+	}
+	if c.fairShare.Enabled() {
+		unassigned = c.selectFairShareJobs(unassigned, runners)
+		if len(unassigned) == 0 {
+			decision.Reason = "repository_share_reached"
+			return decision
+		}
+		decision.Repositories = make(map[int64]string, len(unassigned))
+	}
+	if c.priority.Enabled() {
+		unassigned, decision.PriorityClass = c.prioritiseJobs(unassigned, currentCount)
+	} else if len(unassigned) > room {
+		unassigned = unassigned[:room]
+	}
+	if len(unassigned) == 0 {
+		decision.Reason = "max_runners_reached"
+		return decision
+	}
+
+	for _, job := range unassigned {
+		decision.JobIDs = append(decision.JobIDs, job.ID)
+		if decision.Repositories != nil {
+			decision.Repositories[job.ID] = job.Repository
+		}
+	}
+
+	decision.Action = ScaleActionUp
+	decision.DesiredCount = currentCount + len(unassigned)
+	decision.Reason = "queued_jobs_unassigned"
+
+	return decision
+}
+
+// collectConsumedRunners removes ephemeral runners whose container or
+// instance has exited and returns the runners that are still alive
+func (c *Controller) collectConsumedRunners(ctx context.Context, runners []*provider.Runner) []*provider.Runner {
+	live := make([]*provider.Runner, 0, len(runners))
+	collected := 0
+
+	for _, runner := range runners {
+		if runner.Status != provider.StatusTerminated {
+			live = append(live, runner)
+			continue
+		}
+
+		if c.cfg.DryRun {
+			c.logger.Info("dry-run mode: would collect consumed runner", "id", runner.ID)
+			continue
+		}
+
+		if err := c.provider.RemoveRunner(ctx, runner.ID, false); err != nil {
+			c.logger.Error("failed to collect consumed runner",
+				"id", runner.ID,
+				"error", err,
+			)
+			c.providerError("remove", "collection_error", err)
+			continue
+		}
+
+		jobID, _ := strconv.ParseInt(runner.Metadata[provider.MetadataJobID], 10, 64)
+		c.logger.Info("consumed runner collected", "id", runner.ID, "name", runner.Name, "job_id", jobID)
+		c.metrics.ScaleDownEvents.WithLabelValues("runner_consumed").Inc()
+		collected++
+		c.forgetOrigin(runner.ID)
+
+		c.recordScaleEvent(store.ScaleEvent{
+			Timestamp:     c.clock.Now(),
+			Action:        "collect",
+			Reason:        "runner_consumed",
+			RunnersBefore: len(runners) - collected + 1,
+			RunnersAfter:  len(runners) - collected,
+			JobID:         jobID,
+		})
+	}
+
+	return live
 }
 
 func (c *Controller) inCooldownPeriod() bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	now := time.Now()
+	now := c.clock.Now()
 	if now.Sub(c.lastScaleUpTime) < c.cfg.Scaling.CooldownPeriod {
 		return true
 	}
@@ -440,6 +815,146 @@ func (c *Controller) predictQueueGrowth() int {
 	return max(0, int(predicted))
 }
 
+func (c *Controller) updateBudget(runners []*provider.Runner) {
+	added := c.budget.Observe(runners, c.clock.Now())
+	status := c.budget.Status()
+
+	c.metrics.BudgetSpendTotal.Add(added)
+	c.metrics.BudgetHourlySpend.Set(status.HourlySpend)
+	c.metrics.BudgetDailySpend.Set(status.DailySpend)
+	if status.Exhausted {
+		c.metrics.BudgetExhausted.Set(1)
+	} else {
+		c.metrics.BudgetExhausted.Set(0)
+	}
+}
+
+// scaleUpBlockedReason returns why scale-up must not happen right now, or
+// an empty string if nothing blocks it
+func (c *Controller) scaleUpBlockedReason() string {
+	if c.circuitOpen(breaker.OpCreate) {
+		return "circuit_open"
+	}
+	if c.budgetExhausted() {
+		return "budget_exceeded"
+	}
+	return ""
+}
+
+// recordScaleEvent stores a scale event and sends it to the notifier
+func (c *Controller) recordScaleEvent(event store.ScaleEvent) {
+	if c.store != nil {
+		_ = c.store.RecordScaleEvent(event)
+	}
+
+	fields := map[string]string{
+		"reason":         event.Reason,
+		"runners_before": strconv.Itoa(event.RunnersBefore),
+		"runners_after":  strconv.Itoa(event.RunnersAfter),
+	}
+	if event.JobID != 0 {
+		fields["job_id"] = strconv.FormatInt(event.JobID, 10)
+	}
+
+	c.notifier.Notify(notify.Event{
+		Type:      event.Action,
+		Severity:  notify.SeverityInfo,
+		Title:     fmt.Sprintf("Runners %s: %d -> %d", event.Action, event.RunnersBefore, event.RunnersAfter),
+		Message:   fmt.Sprintf("%s (queue depth %d)", event.Reason, event.QueueDepth),
+		Timestamp: event.Timestamp,
+		Fields:    fields,
+		// Every scale event is a separate action, not a repeat of the last
+		Key: fmt.Sprintf("%d/%d/%d/%d", event.Timestamp.UnixNano(), event.RunnersBefore, event.RunnersAfter, event.JobID),
+	})
+}
+
+// providerError counts a failed provider call and notifies about it.
+// Consecutive creation failures raise a separate, critical notification
+// once they reach the configured threshold.
+func (c *Controller) providerError(operation, errorType string, err error) {
+	name := c.provider.Name()
+	c.metrics.ProviderErrors.WithLabelValues(name, operation, errorType).Inc()
+
+	c.notifier.Notify(notify.Event{
+		Type:     notify.EventProviderError,
+		Severity: notify.SeverityWarning,
+		Title:    fmt.Sprintf("Provider %s failed to %s a runner", name, operation),
+		Message:  err.Error(),
+		Fields: map[string]string{
+			"provider":   name,
+			"operation":  operation,
+			"error_type": errorType,
+		},
+		Key: operation + "/" + errorType,
+	})
+
+	if operation != "create" {
+		return
+	}
+
+	c.mu.Lock()
+	c.createFailures++
+	failures := c.createFailures
+	c.mu.Unlock()
+
+	if threshold := c.cfg.Notifications.CreationFailureThreshold; threshold > 0 && failures >= threshold {
+		c.notifier.Notify(notify.Event{
+			Type:     notify.EventCreationFailures,
+			Severity: notify.SeverityCritical,
+			Title:    fmt.Sprintf("%d consecutive runner creation failures", failures),
+			Message:  err.Error(),
+			Fields: map[string]string{
+				"provider": name,
+				"failures": strconv.Itoa(failures),
+			},
+		})
+	}
+}
+
+// atMaxRunners reports whether demand exceeds what MaxRunners allows
+func (c *Controller) atMaxRunners(decision ScaleDecision) bool {
+	if decision.Reason == "max_runners_reached" {
+		return true
+	}
+	limit := c.cfg.Scaling.MaxRunners
+	return decision.QueueDepth > limit && max(decision.CurrentCount, decision.DesiredCount) >= limit
+}
+
+func (c *Controller) notifyRateLimitExhausted(reset time.Time) {
+	c.notifier.Notify(notify.Event{
+		Type:     notify.EventRateLimitExhausted,
+		Severity: notify.SeverityCritical,
+		Title:    "GitHub API rate limit exhausted",
+		Message:  fmt.Sprintf("Scaling is blind until the limit resets at %s", reset.UTC().Format(time.RFC3339)),
+		Fields: map[string]string{
+			"reset": reset.UTC().Format(time.RFC3339),
+		},
+	})
+}
+
+// circuitOpen reports whether the provider's circuit breaker currently
+// rejects op. Providers without a breaker never do.
+func (c *Controller) circuitOpen(op string) bool {
+	reporter, ok := c.provider.(breaker.Reporter)
+	if !ok {
+		return false
+	}
+	return reporter.CircuitStates()[op] == breaker.StateOpen
+}
+
+// budgetExhausted reports whether scale-up must be refused for spend reasons
+func (c *Controller) budgetExhausted() bool {
+	if c.budget == nil {
+		return false
+	}
+
+	exhausted, by := c.budget.Exhausted()
+	if exhausted {
+		c.logger.Warn("budget exhausted, refusing scale-up", "limit", by)
+	}
+	return exhausted
+}
+
 func (c *Controller) updateRunnerStatusMetrics(runners []*provider.Runner) {
 	var provisioning, running, terminating, failed int
 
@@ -449,7 +964,7 @@ func (c *Controller) updateRunnerStatusMetrics(runners []*provider.Runner) {
 			provisioning++
 		case provider.StatusRunning, provider.StatusIdle, provider.StatusBusy:
 			running++
-		case provider.StatusTerminating:
+		case provider.StatusDraining, provider.StatusTerminating:
 			terminating++
 		case provider.StatusFailed:
 			failed++
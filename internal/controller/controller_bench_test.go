@@ -2,10 +2,16 @@ package controller
 
 import (
 	"context"
+	"io"
+	"log/slog"
 	"testing"
 	"time"
 
+	"Zeno/internal/clock"
 	"Zeno/internal/config"
+	"Zeno/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func BenchmarkReconcile(b *testing.B) {
@@ -14,19 +20,19 @@ func BenchmarkReconcile(b *testing.B) {
 			Token:        "test-token",
 			Organization: "test-org",
 		},
-		Runner: config.RunnerConfig{
+		Scaling: config.ScalingConfig{
 			MinRunners:         1,
 			MaxRunners:         10,
 			ScaleUpThreshold:   5,
 			ScaleDownThreshold: 0,
 			CheckInterval:      30 * time.Second,
 		},
+		DryRun: true,
 	}
 
-	ctrl, err := New(cfg)
-	if err != nil {
-		b.Fatalf("failed to create controller: %v", err)
-	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	met := metrics.NewMetrics(prometheus.NewRegistry())
+	ctrl := New(cfg, &mockGitHubClient{queueDepth: 7}, &mockProvider{}, nil, met, nil, clock.Real(), logger)
 
 	ctx := context.Background()
 
@@ -2,23 +2,36 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
+	"Zeno/internal/budget"
+	"Zeno/internal/clock"
 	"Zeno/internal/config"
 	"Zeno/internal/github"
 	"Zeno/internal/metrics"
+	"Zeno/internal/notify"
 	"Zeno/internal/provider"
+	"Zeno/internal/provider/breaker"
 	"Zeno/internal/store"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 // Mock provider for testing
 type mockProvider struct {
 	runners []*provider.Runner
+	created int
+	removed []string
 }
 
 func (m *mockProvider) Name() string {
@@ -26,7 +39,8 @@ func (m *mockProvider) Name() string {
 }
 
 func (m *mockProvider) ListRunners(ctx context.Context) ([]*provider.Runner, error) {
-	return m.runners, nil
+	// A copy, as providers return, so removals do not shift the caller's list
+	return append([]*provider.Runner(nil), m.runners...), nil
 }
 
 func (m *mockProvider) GetRunner(ctx context.Context, id string) (*provider.Runner, error) {
@@ -39,12 +53,14 @@ func (m *mockProvider) GetRunner(ctx context.Context, id string) (*provider.Runn
 }
 
 func (m *mockProvider) CreateRunner(ctx context.Context, req *provider.CreateRunnerRequest) (*provider.Runner, error) {
+	m.created++
 	runner := &provider.Runner{
-		ID:         "test-" + time.Now().Format("20060102150405"),
-		Name:       req.Name,
-		Status:     provider.StatusRunning,
-		Provider:   "mock",
-		CreatedAt:  time.Now(),
+		ID:        fmt.Sprintf("test-%d", m.created),
+		Name:      req.Name,
+		Status:    provider.StatusRunning,
+		Provider:  "mock",
+		CreatedAt: time.Now(),
+		Metadata:  req.Metadata,
 	}
 	m.runners = append(m.runners, runner)
 	return runner, nil
@@ -54,6 +70,7 @@ func (m *mockProvider) RemoveRunner(ctx context.Context, id string, graceful boo
 	for i, r := range m.runners {
 		if r.ID == id {
 			m.runners = append(m.runners[:i], m.runners[i+1:]...)
+			m.removed = append(m.removed, id)
 			return nil
 		}
 	}
@@ -71,12 +88,17 @@ func (m *mockProvider) Close() error {
 // Mock GitHub client for testing
 type mockGitHubClient struct {
 	queueDepth int
+	jobs       []github.QueuedJob
 }
 
 func (m *mockGitHubClient) GetQueuedWorkflowJobs(ctx context.Context) (int, error) {
 	return m.queueDepth, nil
 }
 
+func (m *mockGitHubClient) GetQueuedJobs(ctx context.Context) ([]github.QueuedJob, error) {
+	return m.jobs, nil
+}
+
 func (m *mockGitHubClient) GetRateLimitInfo() github.RateLimitInfo {
 	return github.RateLimitInfo{
 		Remaining: 5000,
@@ -189,6 +211,7 @@ func TestMakeScalingDecision(t *testing.T) {
 				provider: &mockProvider{},
 				metrics:  met,
 				logger:   logger,
+				clock:    clock.Real(),
 			}
 
 			decision := ctrl.makeScalingDecision(tt.queueDepth, tt.currentCount)
@@ -224,6 +247,7 @@ func TestScaleUpHysteresis(t *testing.T) {
 		provider: &mockProvider{},
 		metrics:  met,
 		logger:   logger,
+		clock:    clock.Real(),
 	}
 
 	// First check should not trigger scale up
@@ -263,6 +287,7 @@ func TestPredictQueueGrowth(t *testing.T) {
 		provider:     &mockProvider{},
 		metrics:      met,
 		logger:       logger,
+		clock:        clock.Real(),
 		queueHistory: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
 	}
 
@@ -277,6 +302,7 @@ func TestPredictQueueGrowth(t *testing.T) {
 func TestCooldownPeriod(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
 
+	clk := clock.NewFake(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
 	ctrl := &Controller{
 		cfg: &config.Config{
 			Scaling: config.ScalingConfig{
@@ -284,19 +310,482 @@ func TestCooldownPeriod(t *testing.T) {
 			},
 		},
 		logger:          logger,
-		lastScaleUpTime: time.Now().Add(-3 * time.Minute),
+		clock:           clk,
+		lastScaleUpTime: clk.Now(),
 	}
 
 	// Should be in cooldown
+	clk.Advance(3 * time.Minute)
 	if !ctrl.inCooldownPeriod() {
 		t.Error("inCooldownPeriod() = false, want true (recent scale up)")
 	}
 
-	// Set last scale up to past cooldown period
-	ctrl.lastScaleUpTime = time.Now().Add(-10 * time.Minute)
+	// Move past the cooldown period
+	clk.Advance(2 * time.Minute)
 
 	// Should not be in cooldown
 	if ctrl.inCooldownPeriod() {
 		t.Error("inCooldownPeriod() = true, want false (cooldown expired)")
 	}
 }
+
+func TestMakeEphemeralDecision(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	jobRunner := func(id, jobID string) *provider.Runner {
+		return &provider.Runner{
+			ID:       id,
+			Status:   provider.StatusRunning,
+			Metadata: map[string]string{provider.MetadataJobID: jobID},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		maxRunners int
+		jobs       []github.QueuedJob
+		runners    []*provider.Runner
+		wantAction ScaleAction
+		wantJobIDs []int64
+		wantReason string
+	}{
+		{
+			name:       "one runner per unassigned job",
+			maxRunners: 10,
+			jobs:       []github.QueuedJob{{ID: 1}, {ID: 2}, {ID: 3}},
+			runners:    []*provider.Runner{jobRunner("r1", "2")},
+			wantAction: ScaleActionUp,
+			wantJobIDs: []int64{1, 3},
+			wantReason: "queued_jobs_unassigned",
+		},
+		{
+			name:       "never scales down with empty queue",
+			maxRunners: 10,
+			runners:    []*provider.Runner{jobRunner("r1", "1"), jobRunner("r2", "2")},
+			wantAction: ScaleActionNone,
+			wantReason: "all_jobs_assigned",
+		},
+		{
+			name:       "capped at max runners",
+			maxRunners: 2,
+			jobs:       []github.QueuedJob{{ID: 1}, {ID: 2}, {ID: 3}},
+			runners:    []*provider.Runner{jobRunner("r1", "9")},
+			wantAction: ScaleActionUp,
+			wantJobIDs: []int64{1},
+			wantReason: "queued_jobs_unassigned",
+		},
+		{
+			name:       "max runners reached",
+			maxRunners: 1,
+			jobs:       []github.QueuedJob{{ID: 1}},
+			runners:    []*provider.Runner{jobRunner("r1", "9")},
+			wantAction: ScaleActionNone,
+			wantReason: "max_runners_reached",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := &Controller{
+				cfg: &config.Config{
+					Scaling: config.ScalingConfig{
+						MaxRunners: tt.maxRunners,
+						Ephemeral:  true,
+					},
+				},
+				logger: logger,
+				clock:  clock.Real(),
+			}
+
+			decision := ctrl.makeEphemeralDecision(tt.jobs, tt.runners)
+
+			if decision.Action != tt.wantAction {
+				t.Errorf("Action = %v, want %v", decision.Action, tt.wantAction)
+			}
+			if decision.Reason != tt.wantReason {
+				t.Errorf("Reason = %s, want %s", decision.Reason, tt.wantReason)
+			}
+			if fmt.Sprint(decision.JobIDs) != fmt.Sprint(tt.wantJobIDs) {
+				t.Errorf("JobIDs = %v, want %v", decision.JobIDs, tt.wantJobIDs)
+			}
+			if decision.DesiredCount != len(tt.runners)+len(tt.wantJobIDs) {
+				t.Errorf("DesiredCount = %d, want %d", decision.DesiredCount, len(tt.runners)+len(tt.wantJobIDs))
+			}
+		})
+	}
+}
+
+func TestEphemeralReconcile(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	registry := prometheus.NewRegistry()
+	met := metrics.NewMetrics(registry)
+
+	st, err := store.New(store.StoreConfig{
+		Enabled:   true,
+		Path:      filepath.Join(t.TempDir(), "events.json"),
+		MaxEvents: 100,
+	})
+	if err != nil {
+		t.Fatalf("store.New() error = %v", err)
+	}
+
+	prov := &mockProvider{
+		runners: []*provider.Runner{
+			{
+				ID:       "consumed",
+				Status:   provider.StatusTerminated,
+				Metadata: map[string]string{provider.MetadataJobID: "41"},
+			},
+		},
+	}
+	gh := &mockGitHubClient{jobs: []github.QueuedJob{{ID: 42}}}
+
+	ctrl := New(&config.Config{
+		Scaling: config.ScalingConfig{
+			MinRunners:        1,
+			MaxRunners:        5,
+			ScaleUpThreshold:  5,
+			ScaleUpHysteresis: 3,
+			CooldownPeriod:    time.Hour,
+			Ephemeral:         true,
+		},
+	}, gh, prov, st, met, nil, clock.Real(), logger)
+
+	if err := ctrl.reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile() error = %v", err)
+	}
+
+	if len(prov.removed) != 1 || prov.removed[0] != "consumed" {
+		t.Errorf("removed = %v, want [consumed]", prov.removed)
+	}
+	if len(prov.runners) != 1 || prov.runners[0].Metadata[provider.MetadataJobID] != "42" {
+		t.Fatalf("runners = %+v, want one runner for job 42", prov.runners)
+	}
+
+	events := st.GetAllEvents()
+	if len(events) != 2 {
+		t.Fatalf("recorded %d events, want 2", len(events))
+	}
+	if events[0].Action != "collect" || events[0].JobID != 41 {
+		t.Errorf("events[0] = %+v, want collect for job 41", events[0])
+	}
+	if events[1].Action != "scale_up" || events[1].JobID != 42 {
+		t.Errorf("events[1] = %+v, want scale_up for job 42", events[1])
+	}
+
+	// A second pass with the job still queued must not create another runner
+	if err := ctrl.reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile() error = %v", err)
+	}
+	if prov.created != 1 {
+		t.Errorf("created %d runners, want 1", prov.created)
+	}
+}
+
+func TestScaleUpRefusedWhenBudgetExhausted(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	ctrl := &Controller{
+		cfg: &config.Config{
+			Scaling: config.ScalingConfig{
+				MinRunners:        1,
+				MaxRunners:        10,
+				ScaleUpThreshold:  5,
+				ScaleUpHysteresis: 1,
+			},
+		},
+		logger: logger,
+		clock:  clock.Real(),
+		budget: budget.New(config.BudgetConfig{
+			Enabled:        true,
+			MaxHourlySpend: 1,
+			DefaultPrice:   10,
+		}),
+	}
+
+	now := time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC)
+	ctrl.budget.Observe([]*provider.Runner{
+		{ID: "r1", CreatedAt: now.Add(-10 * time.Minute)},
+	}, now)
+
+	decision := ctrl.makeScalingDecision(8, 2)
+	if decision.Action != ScaleActionNone {
+		t.Errorf("Action = %v, want %v", decision.Action, ScaleActionNone)
+	}
+	if decision.Reason != "budget_exceeded" {
+		t.Errorf("Reason = %s, want budget_exceeded", decision.Reason)
+	}
+}
+
+// failingProvider fails every runner creation
+type failingProvider struct {
+	mockProvider
+	createCalls int
+}
+
+func (f *failingProvider) CreateRunner(ctx context.Context, req *provider.CreateRunnerRequest) (*provider.Runner, error) {
+	f.createCalls++
+	return nil, fmt.Errorf("insufficient capacity")
+}
+
+func TestScaleUpStopsWhenCircuitOpens(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	met := metrics.NewMetrics(prometheus.NewRegistry())
+
+	prov := &failingProvider{}
+	guarded := breaker.Wrap(prov, config.CircuitBreakerConfig{
+		Enabled:          true,
+		FailureThreshold: 2,
+		BaseBackoff:      time.Hour,
+		MaxBackoff:       time.Hour,
+	}, met, logger)
+
+	ctrl := New(&config.Config{
+		Scaling: config.ScalingConfig{
+			MinRunners:        1,
+			MaxRunners:        10,
+			ScaleUpThreshold:  5,
+			ScaleUpHysteresis: 1,
+		},
+	}, &mockGitHubClient{queueDepth: 8}, guarded, nil, met, nil, clock.Real(), logger)
+
+	if err := ctrl.reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile() error = %v", err)
+	}
+
+	// Creation stops as soon as the circuit opens instead of trying all 8
+	if prov.createCalls != 2 {
+		t.Errorf("CreateRunner called %d times, want 2", prov.createCalls)
+	}
+
+	decision := ctrl.makeScalingDecision(8, 0)
+	if decision.Action != ScaleActionNone || decision.Reason != "circuit_open" {
+		t.Errorf("decision = %s/%s, want none/circuit_open", decision.Action, decision.Reason)
+	}
+}
+
+func TestDecisionsRecordedForHoldsAndFailures(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	met := metrics.NewMetrics(prometheus.NewRegistry())
+
+	st, err := store.New(store.StoreConfig{
+		Enabled:      true,
+		Path:         filepath.Join(t.TempDir(), "store.json"),
+		MaxEvents:    100,
+		MaxDecisions: 100,
+	})
+	if err != nil {
+		t.Fatalf("store.New() error = %v", err)
+	}
+
+	prov := &failingProvider{
+		mockProvider: mockProvider{
+			runners: []*provider.Runner{{ID: "r1", Status: provider.StatusIdle}},
+		},
+	}
+
+	ctrl := New(&config.Config{
+		Scaling: config.ScalingConfig{
+			MinRunners:        1,
+			MaxRunners:        4,
+			ScaleUpThreshold:  3,
+			ScaleUpHysteresis: 2,
+			CooldownPeriod:    time.Minute,
+		},
+	}, &mockGitHubClient{queueDepth: 6}, prov, st, met, nil, clock.Real(), logger)
+
+	// First pass is held by hysteresis, second tries and fails to scale up
+	for i := 0; i < 2; i++ {
+		if err := ctrl.reconcile(context.Background()); err != nil {
+			t.Fatalf("reconcile() error = %v", err)
+		}
+	}
+
+	decisions := st.QueryDecisions(store.DecisionQuery{})
+	if len(decisions) != 2 {
+		t.Fatalf("recorded %d decisions, want 2", len(decisions))
+	}
+
+	hold := decisions[0]
+	if hold.Action != string(ScaleActionNone) || !hold.HysteresisHit || !hold.Succeeded || hold.Executed {
+		t.Errorf("decisions[0] = %+v, want a successful hysteresis hold", hold)
+	}
+	if hold.QueueDepth != 6 || hold.RunnersByStatus["idle"] != 1 {
+		t.Errorf("decisions[0] inputs = queue %d, runners %v", hold.QueueDepth, hold.RunnersByStatus)
+	}
+	if hold.Thresholds.MaxRunners != 4 || hold.Thresholds.ScaleUpHysteresis != 2 || hold.Thresholds.CooldownPeriod != "1m0s" {
+		t.Errorf("decisions[0].Thresholds = %+v, want thresholds in effect", hold.Thresholds)
+	}
+
+	failed := decisions[1]
+	if failed.Action != string(ScaleActionUp) || !failed.Executed || failed.Succeeded {
+		t.Errorf("decisions[1] = %+v, want an executed, failed scale up", failed)
+	}
+	if failed.RunnersChanged != 0 || failed.Error == "" {
+		t.Errorf("decisions[1] outcome = changed %d, error %q", failed.RunnersChanged, failed.Error)
+	}
+
+	if ups := st.QueryDecisions(store.DecisionQuery{Action: "up"}); len(ups) != 1 {
+		t.Errorf("QueryDecisions(action=up) returned %d, want 1", len(ups))
+	}
+}
+
+func TestNotifiesOnCreationFailures(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	met := metrics.NewMetrics(prometheus.NewRegistry())
+
+	var mu sync.Mutex
+	received := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received[r.Header.Get("X-Zeno-Event")]++
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	notifyCfg := config.NotificationsConfig{
+		Enabled:                  true,
+		Webhooks:                 []config.WebhookConfig{{Name: "ops", URL: srv.URL}},
+		DedupWindow:              15 * time.Minute,
+		Timeout:                  5 * time.Second,
+		QueueSize:                10,
+		CreationFailureThreshold: 3,
+	}
+	notifier, err := notify.New(notifyCfg, clock.Real(), met, logger)
+	if err != nil {
+		t.Fatalf("notify.New() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go notifier.Run(ctx)
+
+	prov := &failingProvider{
+		mockProvider: mockProvider{
+			runners: []*provider.Runner{{ID: "r1", Status: provider.StatusIdle}},
+		},
+	}
+
+	ctrl := New(&config.Config{
+		Scaling: config.ScalingConfig{
+			MinRunners:        1,
+			MaxRunners:        4,
+			ScaleUpThreshold:  3,
+			ScaleUpHysteresis: 1,
+		},
+		Notifications: notifyCfg,
+	}, &mockGitHubClient{queueDepth: 6}, prov, nil, met, notifier, clock.Real(), logger)
+
+	if err := ctrl.reconcile(ctx); err != nil {
+		t.Fatalf("reconcile() error = %v", err)
+	}
+	if prov.createCalls != 3 {
+		t.Fatalf("createCalls = %d, want 3", prov.createCalls)
+	}
+
+	// Repeated provider errors collapse into one inside the dedup window
+	want := map[string]int{
+		notify.EventMaxRunnersReached: 1,
+		notify.EventProviderError:     1,
+		notify.EventCreationFailures:  1,
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		got := fmt.Sprint(received)
+		mu.Unlock()
+		if got == fmt.Sprint(want) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("received notifications %s, want %s", got, fmt.Sprint(want))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestScaleEventsAreNotDeduplicated(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	met := metrics.NewMetrics(prometheus.NewRegistry())
+	clk := clock.NewFake(stateStart)
+
+	notifier, err := notify.New(config.NotificationsConfig{
+		Enabled:     true,
+		DedupWindow: 15 * time.Minute,
+		QueueSize:   10,
+	}, clk, met, logger)
+	if err != nil {
+		t.Fatalf("notify.New() error = %v", err)
+	}
+
+	ctrl := New(stateTestConfig(), &mockGitHubClient{}, &mockProvider{}, nil, met, notifier, clk, logger)
+	for i := 0; i < 3; i++ {
+		clk.Advance(time.Minute)
+		ctrl.recordScaleEvent(store.ScaleEvent{
+			Timestamp:     clk.Now(),
+			Action:        notify.EventScaleUp,
+			Reason:        "queue_above_threshold",
+			RunnersBefore: 1,
+			RunnersAfter:  2,
+		})
+	}
+
+	if got := testutil.ToFloat64(met.NotificationsTotal.WithLabelValues("", notify.EventScaleUp, "deduplicated")); got != 0 {
+		t.Errorf("deduplicated scale events = %v, want 0", got)
+	}
+}
+
+func TestScaleDownRemovesSpotFirst(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	lifecycle := func(l string) map[string]string {
+		return map[string]string{provider.MetadataLifecycle: l}
+	}
+	prov := &mockProvider{runners: []*provider.Runner{
+		{ID: "od-1", Status: provider.StatusIdle, Metadata: lifecycle(provider.LifecycleOnDemand)},
+		{ID: "spot-1", Status: provider.StatusIdle, Metadata: lifecycle(provider.LifecycleSpot)},
+		{ID: "od-2", Status: provider.StatusIdle, Metadata: lifecycle(provider.LifecycleOnDemand)},
+		{ID: "spot-2", Status: provider.StatusIdle, Metadata: lifecycle(provider.LifecycleSpot)},
+	}}
+	ctrl := New(stateTestConfig(), &mockGitHubClient{}, prov, nil, metrics.NewMetrics(prometheus.NewRegistry()), nil, clock.Real(), logger)
+
+	removed, err := ctrl.scaleDown(context.Background(), ScaleDecision{
+		Action:       ScaleActionDown,
+		Reason:       "queue_below_threshold",
+		CurrentCount: 4,
+		DesiredCount: 1,
+	})
+	if err != nil {
+		t.Fatalf("scaleDown() error = %v", err)
+	}
+	if removed != 3 || fmt.Sprint(prov.removed) != "[spot-1 spot-2 od-1]" {
+		t.Errorf("removed %d runners %v, want spot-1, spot-2, then od-1", removed, prov.removed)
+	}
+}
+
+func TestScaleDownKeepsProviderOrder(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	lifecycle := func(l string) map[string]string {
+		return map[string]string{provider.MetadataLifecycle: l}
+	}
+	// Spillover lists its most expensive provider's runners first
+	prov := &mockProvider{runners: []*provider.Runner{
+		{ID: "od-1", Provider: "on-demand-pool", Status: provider.StatusIdle, Metadata: lifecycle(provider.LifecycleOnDemand)},
+		{ID: "spot-1", Provider: "on-demand-pool", Status: provider.StatusIdle, Metadata: lifecycle(provider.LifecycleSpot)},
+		{ID: "od-2", Provider: "spot-pool", Status: provider.StatusIdle, Metadata: lifecycle(provider.LifecycleOnDemand)},
+		{ID: "spot-2", Provider: "spot-pool", Status: provider.StatusIdle, Metadata: lifecycle(provider.LifecycleSpot)},
+	}}
+	ctrl := New(stateTestConfig(), &mockGitHubClient{}, prov, nil, metrics.NewMetrics(prometheus.NewRegistry()), nil, clock.Real(), logger)
+
+	removed, err := ctrl.scaleDown(context.Background(), ScaleDecision{
+		Action:       ScaleActionDown,
+		Reason:       "queue_below_threshold",
+		CurrentCount: 4,
+		DesiredCount: 1,
+	})
+	if err != nil {
+		t.Fatalf("scaleDown() error = %v", err)
+	}
+	if removed != 3 || fmt.Sprint(prov.removed) != "[spot-1 od-1 spot-2]" {
+		t.Errorf("removed %d runners %v, want spot-1, od-1, then spot-2", removed, prov.removed)
+	}
+}
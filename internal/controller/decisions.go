@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"fmt"
+
+	"Zeno/internal/provider"
+	"Zeno/internal/store"
+)
+
+// recordDecision stores a scaling decision along with the inputs it was
+// based on and the outcome of executing it. Holds, cooldowns and dry runs
+// are recorded too, so operators can see why Zeno did not act.
+func (c *Controller) recordDecision(decision ScaleDecision, runners []*provider.Runner, changed int, execErr error) {
+	if c.store == nil {
+		return
+	}
+
+	byStatus := make(map[string]int)
+	for _, r := range runners {
+		byStatus[string(r.Status)]++
+	}
+
+	record := store.DecisionRecord{
+		Timestamp:           c.clock.Now(),
+		Action:              string(decision.Action),
+		Reason:              decision.Reason,
+		QueueDepth:          decision.QueueDepth,
+		PredictedQueueDepth: decision.PredictedQueueDepth,
+		CurrentCount:        decision.CurrentCount,
+		DesiredCount:        decision.DesiredCount,
+		RunnersByStatus:     byStatus,
+		HysteresisHit:       decision.HysteresisHit,
+		JobIDs:              decision.JobIDs,
+		PriorityClass:       decision.PriorityClass,
+		Thresholds: store.DecisionThresholds{
+			MinRunners:          c.minRunners(),
+			MaxRunners:          c.cfg.Scaling.MaxRunners,
+			ScaleUpThreshold:    c.cfg.Scaling.ScaleUpThreshold,
+			ScaleDownThreshold:  c.cfg.Scaling.ScaleDownThreshold,
+			ScaleUpHysteresis:   c.cfg.Scaling.ScaleUpHysteresis,
+			ScaleDownHysteresis: c.cfg.Scaling.ScaleDownHysteresis,
+			CooldownPeriod:      c.cfg.Scaling.CooldownPeriod.String(),
+			Ephemeral:           c.cfg.Scaling.Ephemeral,
+		},
+		DryRun:         c.cfg.DryRun,
+		Executed:       decision.Action != ScaleActionNone && !c.cfg.DryRun,
+		RunnersChanged: changed,
+	}
+
+	want := decision.DesiredCount - decision.CurrentCount
+	if want < 0 {
+		want = -want
+	}
+
+	switch {
+	case execErr != nil:
+		record.Error = execErr.Error()
+	case record.Executed && changed < want:
+		record.Error = fmt.Sprintf("changed %d of %d runners", changed, want)
+	default:
+		record.Succeeded = true
+	}
+
+	if err := c.store.RecordDecision(record); err != nil {
+		c.logger.Error("failed to record scaling decision", "error", err)
+	}
+}
@@ -0,0 +1,314 @@
+package controller
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"Zeno/internal/clock"
+	"Zeno/internal/config"
+	"Zeno/internal/github"
+	"Zeno/internal/metrics"
+	"Zeno/internal/provider"
+	"Zeno/internal/store"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var e2eStart = time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)
+
+// scriptedGitHubClient reports a queue depth that follows the fake clock
+type scriptedGitHubClient struct {
+	clock   clock.Clock
+	depthAt func(elapsed time.Duration) int
+	done    chan struct{}
+}
+
+func (s *scriptedGitHubClient) GetQueuedWorkflowJobs(ctx context.Context) (int, error) {
+	return s.depthAt(s.clock.Since(e2eStart)), nil
+}
+
+func (s *scriptedGitHubClient) GetQueuedJobs(ctx context.Context) ([]github.QueuedJob, error) {
+	return nil, nil
+}
+
+// GetRateLimitInfo is the last call of a reconcile, so it marks completion
+func (s *scriptedGitHubClient) GetRateLimitInfo() github.RateLimitInfo {
+	if s.done != nil {
+		s.done <- struct{}{}
+	}
+	return github.RateLimitInfo{Remaining: 5000}
+}
+
+// clockedProvider stamps runners with the fake clock's time
+type clockedProvider struct {
+	mockProvider
+	clock clock.Clock
+	mu    sync.Mutex
+}
+
+func (p *clockedProvider) ListRunners(ctx context.Context) ([]*provider.Runner, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]*provider.Runner(nil), p.runners...), nil
+}
+
+func (p *clockedProvider) CreateRunner(ctx context.Context, req *provider.CreateRunnerRequest) (*provider.Runner, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	runner, err := p.mockProvider.CreateRunner(ctx, req)
+	if err == nil {
+		runner.Status = provider.StatusIdle
+		runner.CreatedAt = p.clock.Now()
+	}
+	return runner, err
+}
+
+func (p *clockedProvider) RemoveRunner(ctx context.Context, id string, graceful bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.mockProvider.RemoveRunner(ctx, id, graceful)
+}
+
+func (p *clockedProvider) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.runners)
+}
+
+type e2eHarness struct {
+	t     *testing.T
+	ctrl  *Controller
+	clock *clock.Fake
+	gh    *scriptedGitHubClient
+	prov  *clockedProvider
+	store *store.Store
+}
+
+func newE2EHarness(t *testing.T, cfg *config.Config, initialRunners int, depthAt func(time.Duration) int) *e2eHarness {
+	t.Helper()
+
+	clk := clock.NewFake(e2eStart)
+	prov := &clockedProvider{clock: clk}
+	for i := 0; i < initialRunners; i++ {
+		_, _ = prov.CreateRunner(context.Background(), &provider.CreateRunnerRequest{})
+	}
+
+	st, err := store.New(store.StoreConfig{
+		Enabled:      true,
+		Path:         filepath.Join(t.TempDir(), "store.json"),
+		MaxEvents:    10000,
+		MaxDecisions: 10000,
+	})
+	if err != nil {
+		t.Fatalf("store.New() error = %v", err)
+	}
+
+	gh := &scriptedGitHubClient{clock: clk, depthAt: depthAt}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctrl := New(cfg, gh, prov, st, metrics.NewMetrics(prometheus.NewRegistry()), nil, clk, logger)
+
+	return &e2eHarness{t: t, ctrl: ctrl, clock: clk, gh: gh, prov: prov, store: st}
+}
+
+// runFor reconciles once per check interval until d has passed, calling
+// check after every cycle
+func (h *e2eHarness) runFor(d time.Duration, check func(elapsed time.Duration)) {
+	h.t.Helper()
+
+	interval := h.ctrl.cfg.Scaling.CheckInterval
+	end := h.clock.Now().Add(d)
+	for h.clock.Now().Before(end) {
+		if err := h.ctrl.reconcile(context.Background()); err != nil {
+			h.t.Fatalf("reconcile() at %s error = %v", h.clock.Since(e2eStart), err)
+		}
+		if check != nil {
+			check(h.clock.Since(e2eStart))
+		}
+		h.clock.Advance(interval)
+	}
+}
+
+func e2eConfig() *config.Config {
+	return &config.Config{
+		Scaling: config.ScalingConfig{
+			MinRunners:          1,
+			MaxRunners:          8,
+			ScaleUpThreshold:    3,
+			ScaleDownThreshold:  0,
+			ScaleUpHysteresis:   2,
+			ScaleDownHysteresis: 4,
+			CheckInterval:       30 * time.Second,
+			CooldownPeriod:      5 * time.Minute,
+		},
+	}
+}
+
+func TestE2EWorkdayTraffic(t *testing.T) {
+	// Quiet first hour, steady load, a spike past max runners, then quiet
+	h := newE2EHarness(t, e2eConfig(), 1, func(elapsed time.Duration) int {
+		switch {
+		case elapsed < time.Hour:
+			return 0
+		case elapsed < 2*time.Hour:
+			return 5
+		case elapsed < 3*time.Hour:
+			return 20
+		default:
+			return 0
+		}
+	})
+
+	peak := 0
+	h.runFor(4*time.Hour, func(elapsed time.Duration) {
+		count := h.prov.count()
+		if count > 8 {
+			t.Fatalf("%d runners at %s, above max runners", count, elapsed)
+		}
+		if elapsed < time.Hour && count != 1 {
+			t.Fatalf("%d runners at %s during the quiet hour, want 1", count, elapsed)
+		}
+		peak = max(peak, count)
+	})
+
+	if peak != 8 {
+		t.Errorf("peak runners = %d, want 8", peak)
+	}
+	if got := h.prov.count(); got != 1 {
+		t.Errorf("runners after quiet hour = %d, want min runners", got)
+	}
+
+	ups := h.store.QueryDecisions(store.DecisionQuery{Action: string(ScaleActionUp)})
+	if len(ups) != 2 {
+		t.Fatalf("scale ups = %d, want 2 (load, then spike)", len(ups))
+	}
+	// Hysteresis needs two consecutive observations above threshold
+	if want := e2eStart.Add(time.Hour + 30*time.Second); !ups[0].Timestamp.Equal(want) {
+		t.Errorf("first scale up at %s, want %s", ups[0].Timestamp, want)
+	}
+
+	// Executed actions are never closer together than the cooldown
+	var last time.Time
+	for _, d := range h.store.QueryDecisions(store.DecisionQuery{}) {
+		if !d.Executed {
+			continue
+		}
+		if !last.IsZero() && d.Timestamp.Sub(last) < 5*time.Minute {
+			t.Errorf("%s at %s only %s after the previous action", d.Action, d.Timestamp, d.Timestamp.Sub(last))
+		}
+		last = d.Timestamp
+	}
+}
+
+func TestE2EPinHoldsUntilExpiry(t *testing.T) {
+	h := newE2EHarness(t, e2eConfig(), 1, func(time.Duration) int { return 0 })
+
+	if err := h.ctrl.PinDesiredCount(4, e2eStart.Add(45*time.Minute)); err != nil {
+		t.Fatalf("PinDesiredCount() error = %v", err)
+	}
+
+	h.runFor(45*time.Minute, func(elapsed time.Duration) {
+		if got := h.prov.count(); got != 4 {
+			t.Fatalf("%d runners at %s while pinned, want 4", got, elapsed)
+		}
+	})
+
+	// Back to autoscaling: the idle queue scales down after hysteresis
+	h.runFor(2*time.Minute, nil)
+	if got := h.prov.count(); got != 1 {
+		t.Errorf("runners after pin expiry = %d, want 1", got)
+	}
+
+	downs := h.store.QueryDecisions(store.DecisionQuery{Action: string(ScaleActionDown)})
+	if len(downs) != 1 || downs[0].Reason != "queue_below_threshold" {
+		t.Fatalf("scale downs = %+v, want one queue_below_threshold", downs)
+	}
+	if want := e2eStart.Add(45*time.Minute + 90*time.Second); !downs[0].Timestamp.Equal(want) {
+		t.Errorf("scaled down at %s, want %s", downs[0].Timestamp, want)
+	}
+}
+
+func TestE2EBudgetResetsOnTheHour(t *testing.T) {
+	cfg := e2eConfig()
+	cfg.Scaling.MinRunners = 3
+	cfg.Budget = config.BudgetConfig{
+		Enabled:        true,
+		MaxHourlySpend: 1,
+		DefaultPrice:   1,
+	}
+
+	// Three runners at 1/hour spend the hourly budget in 20 minutes
+	h := newE2EHarness(t, cfg, 3, func(elapsed time.Duration) int {
+		if elapsed < 30*time.Minute {
+			return 0
+		}
+		return 6
+	})
+
+	h.runFor(time.Hour, nil)
+
+	if got := h.prov.count(); got != 3 {
+		t.Errorf("runners before the hour = %d, want 3", got)
+	}
+	blocked := h.store.QueryDecisions(store.DecisionQuery{Since: e2eStart.Add(30 * time.Minute)})
+	if len(blocked) == 0 || blocked[len(blocked)-1].Reason != "budget_exceeded" {
+		t.Fatalf("last decision before the hour = %+v, want budget_exceeded", blocked[len(blocked)-1])
+	}
+
+	// The hourly window resets at 10:00 and scale-up resumes
+	h.runFor(2*time.Minute, nil)
+	if got := h.prov.count(); got != 6 {
+		t.Errorf("runners after the hour = %d, want 6", got)
+	}
+}
+
+func TestE2ERunLoopFollowsClock(t *testing.T) {
+	h := newE2EHarness(t, e2eConfig(), 1, func(time.Duration) int { return 0 })
+	h.gh.done = make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- h.ctrl.Run(ctx)
+	}()
+
+	waitReconcile := func() {
+		t.Helper()
+		select {
+		case <-h.gh.done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("reconcile did not run")
+		}
+	}
+
+	// Initial reconcile, then one per tick of the fake clock
+	waitReconcile()
+	h.clock.BlockUntil(1)
+	for i := 0; i < 120; i++ {
+		h.clock.Advance(30 * time.Second)
+		waitReconcile()
+	}
+
+	cancel()
+	if err := <-errCh; err != context.Canceled {
+		t.Errorf("Run() error = %v, want context.Canceled", err)
+	}
+
+	decisions := h.store.QueryDecisions(store.DecisionQuery{})
+	if len(decisions) != 121 {
+		t.Fatalf("recorded %d decisions over one simulated hour, want 121", len(decisions))
+	}
+	if got := decisions[len(decisions)-1].Timestamp; !got.Equal(e2eStart.Add(time.Hour)) {
+		t.Errorf("last decision at %s, want %s", got, e2eStart.Add(time.Hour))
+	}
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"Zeno/internal/github"
+	"Zeno/internal/provider"
+)
+
+// fairShareJobs limits each repository's queued jobs to its share of
+// max_runners, keeping queue order. Shared runners are not tied to a
+// repository, so this bounds how far one backlog can grow the pool rather
+// than which jobs the runners pick up.
+func (c *Controller) fairShareJobs(jobs []github.QueuedJob) []github.QueuedJob {
+	demand := github.QueueDepthByRepository(jobs)
+	alloc := c.fairShare.Allocate(c.cfg.Scaling.MaxRunners, demand)
+	c.updateFairShareMetrics(demand, alloc)
+
+	var counted []github.QueuedJob
+	for _, job := range jobs {
+		if alloc[job.Repository] > 0 {
+			counted = append(counted, job)
+			alloc[job.Repository]--
+		}
+	}
+	return counted
+}
+
+// selectFairShareJobs returns the unassigned jobs whose repositories are
+// below their share of the pool, in queue order. Runners are scoped to a
+// repository, so a repository's runners count against its share until they
+// are consumed; runners without a repository take up pool space only.
+func (c *Controller) selectFairShareJobs(unassigned []github.QueuedJob, runners []*provider.Runner) []github.QueuedJob {
+	held := make(map[string]int)
+	pool := c.cfg.Scaling.MaxRunners
+	for _, r := range runners {
+		if repo := r.Metadata[provider.MetadataRepository]; repo != "" {
+			held[repo]++
+		} else {
+			pool--
+		}
+	}
+
+	demand := github.QueueDepthByRepository(unassigned)
+	queued := make(map[string]int, len(demand))
+	for repo, n := range demand {
+		queued[repo] = n
+		demand[repo] = n + held[repo]
+	}
+	for repo, n := range held {
+		if _, ok := demand[repo]; !ok {
+			demand[repo] = n
+		}
+	}
+
+	alloc := c.fairShare.Allocate(max(pool, 0), demand)
+	c.updateFairShareMetrics(queued, alloc)
+
+	var selected []github.QueuedJob
+	for _, job := range unassigned {
+		if held[job.Repository] < alloc[job.Repository] {
+			selected = append(selected, job)
+			held[job.Repository]++
+		}
+	}
+	return selected
+}
+
+func (c *Controller) updateFairShareMetrics(queued, alloc map[string]int) {
+	c.metrics.RepositoryQueueDepth.Reset()
+	c.metrics.RepositoryAllocation.Reset()
+	for repo, n := range queued {
+		c.metrics.RepositoryQueueDepth.WithLabelValues(repo).Set(float64(n))
+	}
+	for repo, n := range alloc {
+		c.metrics.RepositoryAllocation.WithLabelValues(repo).Set(float64(n))
+	}
+}
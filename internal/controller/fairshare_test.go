@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"Zeno/internal/clock"
+	"Zeno/internal/config"
+	"Zeno/internal/github"
+	"Zeno/internal/metrics"
+	"Zeno/internal/provider"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// requestRecordingProvider keeps every create request
+type requestRecordingProvider struct {
+	mockProvider
+	requests []*provider.CreateRunnerRequest
+}
+
+func (p *requestRecordingProvider) CreateRunner(ctx context.Context, req *provider.CreateRunnerRequest) (*provider.Runner, error) {
+	p.requests = append(p.requests, req)
+	return p.mockProvider.CreateRunner(ctx, req)
+}
+
+func fairShareTestController(gh GitHubClient, prov provider.Provider, ephemeral bool, quotas ...config.RepositoryQuota) *Controller {
+	cfg := stateTestConfig()
+	cfg.GitHub.Organization = "acme"
+	cfg.Scaling.MaxRunners = 4
+	cfg.Scaling.Ephemeral = ephemeral
+	cfg.Scaling.FairShare = config.FairShareConfig{
+		Enabled:       true,
+		DefaultWeight: 1,
+		Repositories:  quotas,
+	}
+
+	return New(cfg, gh, prov, nil, metrics.NewMetrics(prometheus.NewRegistry()), nil,
+		clock.NewFake(stateStart), slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func repoJobs(repo string, ids ...int64) []github.QueuedJob {
+	jobs := make([]github.QueuedJob, 0, len(ids))
+	for _, id := range ids {
+		jobs = append(jobs, github.QueuedJob{ID: id, Repository: repo})
+	}
+	return jobs
+}
+
+func TestFairShareScopesEphemeralRunners(t *testing.T) {
+	// The monorepo is first in the queue with a large matrix
+	jobs := append(repoJobs("acme/monorepo", 1, 2, 3, 4, 5, 6), repoJobs("acme/docs", 7, 8)...)
+	prov := &requestRecordingProvider{}
+	ctrl := fairShareTestController(&mockGitHubClient{jobs: jobs}, prov, true,
+		config.RepositoryQuota{Name: "acme/docs", Min: 1})
+
+	if err := ctrl.reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile() error = %v", err)
+	}
+
+	perRepo := make(map[string]int)
+	for _, req := range prov.requests {
+		if req.GitHubOrg != "" {
+			t.Errorf("runner %s registered with the organization, want it scoped to %s", req.Name, req.GitHubRepo)
+		}
+		if req.Metadata[provider.MetadataRepository] != req.GitHubRepo {
+			t.Errorf("runner metadata repository = %q, want %q", req.Metadata[provider.MetadataRepository], req.GitHubRepo)
+		}
+		perRepo[req.GitHubRepo]++
+	}
+
+	want := map[string]int{"acme/monorepo": 2, "acme/docs": 2}
+	if !reflect.DeepEqual(perRepo, want) {
+		t.Errorf("runners per repository = %v, want %v", perRepo, want)
+	}
+}
+
+func TestFairShareCountsHeldRunners(t *testing.T) {
+	held := func(id, jobID string) *provider.Runner {
+		return &provider.Runner{ID: id, Status: provider.StatusBusy, Metadata: map[string]string{
+			provider.MetadataJobID:      jobID,
+			provider.MetadataRepository: "acme/monorepo",
+		}}
+	}
+	runners := []*provider.Runner{held("r1", "1"), held("r2", "2"), held("r3", "3")}
+	jobs := append(repoJobs("acme/monorepo", 4, 5, 6), repoJobs("acme/api", 7)...)
+
+	ctrl := fairShareTestController(&mockGitHubClient{}, &mockProvider{}, true)
+	decision := ctrl.makeEphemeralDecision(jobs, runners)
+
+	if decision.Action != ScaleActionUp || !reflect.DeepEqual(decision.JobIDs, []int64{7}) {
+		t.Fatalf("decision = %+v, want a runner only for the api job", decision)
+	}
+	if decision.Repositories[7] != "acme/api" {
+		t.Errorf("repositories = %v", decision.Repositories)
+	}
+
+	// Once every repository holds its share nothing more is created
+	decision = ctrl.makeEphemeralDecision(repoJobs("acme/monorepo", 4, 5, 6),
+		append(runners, &provider.Runner{ID: "r4", Metadata: map[string]string{provider.MetadataRepository: "acme/api"}}))
+	if decision.Action != ScaleActionNone {
+		t.Errorf("decision = %+v, want no scale up with the pool full", decision)
+	}
+}
+
+func TestFairShareJobsHonourCaps(t *testing.T) {
+	ctrl := fairShareTestController(&mockGitHubClient{}, &mockProvider{}, false,
+		config.RepositoryQuota{Name: "acme/monorepo", Max: 1})
+
+	jobs := append(repoJobs("acme/monorepo", 1, 2, 3, 4, 5, 6), repoJobs("acme/api", 7, 8)...)
+	got := ctrl.fairShareJobs(jobs)
+	var ids []int64
+	for _, job := range got {
+		ids = append(ids, job.ID)
+	}
+	if !reflect.DeepEqual(ids, []int64{1, 7, 8}) {
+		t.Errorf("fairShareJobs() = %v, want the capped monorepo plus both api jobs", ids)
+	}
+}
@@ -0,0 +1,176 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"Zeno/internal/provider"
+	"Zeno/internal/provider/breaker"
+	"Zeno/internal/store"
+)
+
+// replaceDrainingRunners starts a replacement for every runner the provider
+// reports as draining, before its machine is reclaimed and its job fails.
+// Once a draining runner has a replacement and no job, it is removed, since
+// a rebalance recommendation may never be followed by an interruption. It
+// returns the runners that count as capacity: every runner but the
+// draining ones, plus the replacements started.
+func (c *Controller) replaceDrainingRunners(ctx context.Context, runners []*provider.Runner) []*provider.Runner {
+	capacity := make([]*provider.Runner, 0, len(runners))
+	var draining []*provider.Runner
+	for _, r := range runners {
+		if r.Status == provider.StatusDraining {
+			draining = append(draining, r)
+		} else {
+			capacity = append(capacity, r)
+		}
+	}
+
+	c.pruneReplacements(draining)
+
+	var replaced []*provider.Runner
+	for _, r := range draining {
+		replacement, seen := c.replacement(r.ID)
+		if !seen {
+			c.logger.Warn("runner is draining",
+				"id", r.ID,
+				"name", r.Name,
+				"interruption", r.Metadata[provider.MetadataInterruption],
+			)
+			c.metrics.RunnerInterruptions.WithLabelValues(
+				r.Metadata[provider.MetadataInterruption],
+				r.Metadata["instance_type"],
+				r.Metadata["az"],
+			).Inc()
+			c.setReplacement(r.ID, "")
+		}
+
+		if replacement != "" {
+			replaced = append(replaced, r)
+			continue
+		}
+		if created := c.replaceRunner(ctx, r, len(capacity)); created != nil {
+			capacity = append(capacity, created)
+		}
+	}
+
+	if len(replaced) > 0 && !c.cfg.DryRun {
+		c.removeDrainedRunners(ctx, replaced)
+	}
+
+	return capacity
+}
+
+// replaceRunner starts a runner in place of a draining one, for the same
+// job and repository. It returns nil if none could be started.
+func (c *Controller) replaceRunner(ctx context.Context, r *provider.Runner, current int) *provider.Runner {
+	if c.cfg.DryRun {
+		c.logger.Info("dry-run mode: would replace draining runner", "id", r.ID)
+		return nil
+	}
+	if reason := c.scaleUpBlockedReason(); reason != "" {
+		c.logger.Warn("cannot replace draining runner", "id", r.ID, "reason", reason)
+		return nil
+	}
+
+	jobID, _ := strconv.ParseInt(r.Metadata[provider.MetadataJobID], 10, 64)
+	req := c.runnerRequest(jobID, r.Metadata[provider.MetadataRepository])
+
+	runner, err := c.provider.CreateRunner(ctx, req)
+	if errors.Is(err, breaker.ErrOpen) {
+		c.logger.Warn("provider circuit open, cannot replace draining runner", "id", r.ID)
+		c.providerError("create", "circuit_open", err)
+		return nil
+	}
+	if err != nil {
+		c.logger.Error("failed to replace draining runner", "id", r.ID, "error", err)
+		c.providerError("create", "creation_error", err)
+		return nil
+	}
+
+	reason := "runner_" + r.Metadata[provider.MetadataInterruption]
+	c.logger.Info("draining runner replaced", "id", r.ID, "replacement", runner.ID, "job_id", jobID)
+	c.metrics.ScaleUpEvents.WithLabelValues(reason).Inc()
+	c.recordOrigin(runner, reason, jobID)
+	c.setReplacement(r.ID, runner.ID)
+
+	c.mu.Lock()
+	c.createFailures = 0
+	c.mu.Unlock()
+
+	c.recordScaleEvent(store.ScaleEvent{
+		Timestamp:     c.clock.Now(),
+		Action:        "replace",
+		Reason:        reason,
+		RunnersBefore: current,
+		RunnersAfter:  current + 1,
+		JobID:         jobID,
+	})
+
+	return runner
+}
+
+// removeDrainedRunners gracefully removes replaced draining runners that
+// are not running a job. Draining runners are not removed without a
+// RunnerRegistry, since most providers cannot tell whether they are busy.
+func (c *Controller) removeDrainedRunners(ctx context.Context, runners []*provider.Runner) {
+	if _, ok := c.ghClient.(RunnerRegistry); !ok {
+		return
+	}
+
+	busy, ok := c.busyRunners(ctx, runners)
+	if !ok {
+		return
+	}
+
+	for _, r := range runners {
+		if busy[r.Name] {
+			continue
+		}
+
+		if err := c.provider.RemoveRunner(ctx, r.ID, true); err != nil {
+			c.logger.Error("failed to remove drained runner", "id", r.ID, "error", err)
+			c.providerError("remove", "removal_error", err)
+			continue
+		}
+
+		c.logger.Info("drained runner removed", "id", r.ID, "name", r.Name)
+		c.metrics.ScaleDownEvents.WithLabelValues("runner_drained").Inc()
+		c.forgetOrigin(r.ID)
+	}
+}
+
+// replacement returns the runner started in place of a draining runner and
+// whether the runner was seen draining before
+func (c *Controller) replacement(id string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	replacement, ok := c.replacements[id]
+	return replacement, ok
+}
+
+func (c *Controller) setReplacement(id, replacement string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.replacements[id] = replacement
+}
+
+// pruneReplacements forgets runners that are no longer draining
+func (c *Controller) pruneReplacements(draining []*provider.Runner) {
+	listed := make(map[string]bool, len(draining))
+	for _, r := range draining {
+		listed[r.ID] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id := range c.replacements {
+		if !listed[id] {
+			delete(c.replacements, id)
+		}
+	}
+}
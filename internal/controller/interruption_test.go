@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"Zeno/internal/clock"
+	"Zeno/internal/github"
+	"Zeno/internal/metrics"
+	"Zeno/internal/provider"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDrainingRunnerReplaced(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	path := filepath.Join(t.TempDir(), "store.json")
+	st := newStateTestStore(t, path)
+	met := metrics.NewMetrics(prometheus.NewRegistry())
+	prov := &mockProvider{runners: []*provider.Runner{
+		{ID: "r1", Name: "zeno-runner-1", Status: provider.StatusIdle, CreatedAt: stateStart},
+		{
+			ID:        "r2",
+			Name:      "zeno-runner-2",
+			Status:    provider.StatusDraining,
+			CreatedAt: stateStart,
+			Metadata: map[string]string{
+				provider.MetadataInterruption: "spot-interruption",
+				"instance_type":               "m5.large",
+				"az":                          "us-east-1a",
+			},
+		},
+	}}
+	gh := &registryGitHubClient{registrations: []github.RegisteredRunner{
+		{ID: 1, Name: "zeno-runner-1"},
+		{ID: 2, Name: "zeno-runner-2", Busy: true},
+	}}
+
+	cfg := stateTestConfig()
+	cfg.Scaling.MinRunners = 2
+	ctrl := New(cfg, gh, prov, st, met, nil, clock.NewFake(stateStart), logger)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := ctrl.reconcile(ctx); err != nil {
+			t.Fatalf("reconcile() error = %v", err)
+		}
+	}
+
+	if prov.created != 1 {
+		t.Fatalf("created %d runners, want one replacement", prov.created)
+	}
+	if got := testutil.ToFloat64(met.RunnersCurrent); got != 2 {
+		t.Errorf("current runners = %v, want 2 with the draining runner left out", got)
+	}
+	if got := testutil.ToFloat64(met.RunnerInterruptions.WithLabelValues("spot-interruption", "m5.large", "us-east-1a")); got != 1 {
+		t.Errorf("interruptions counted = %v, want 1", got)
+	}
+	if origin := ctrl.RunnerOrigins()["test-1"]; origin.Reason != "runner_spot-interruption" {
+		t.Errorf("replacement origin = %+v, want runner_spot-interruption", origin)
+	}
+	events := st.GetAllEvents()
+	if len(events) != 1 || events[0].Action != "replace" {
+		t.Errorf("events = %+v, want one replace event", events)
+	}
+
+	// A restarted controller knows the draining runner was replaced
+	restarted := New(cfg, gh, prov, newStateTestStore(t, path), metrics.NewMetrics(prometheus.NewRegistry()), nil, clock.NewFake(stateStart), logger)
+	restarted.restoreState()
+	if err := restarted.reconcile(ctx); err != nil {
+		t.Fatalf("reconcile() error = %v", err)
+	}
+	if prov.created != 1 {
+		t.Fatalf("created %d runners after restart, want no second replacement", prov.created)
+	}
+
+	// The draining runner is removed once its job is done
+	if len(prov.removed) != 0 {
+		t.Fatalf("removed %v while its job was running", prov.removed)
+	}
+	gh.registrations[1].Busy = false
+	if err := restarted.reconcile(ctx); err != nil {
+		t.Fatalf("reconcile() error = %v", err)
+	}
+	if len(prov.removed) != 1 || prov.removed[0] != "r2" {
+		t.Errorf("removed = %v, want [r2]", prov.removed)
+	}
+	if prov.created != 1 {
+		t.Errorf("created %d runners, want no further replacement", prov.created)
+	}
+}
@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"Zeno/internal/store"
+)
+
+// Pause stops autoscaling until Resume is called. Runners are left as they are.
+func (c *Controller) Pause() error {
+	return c.updateOverrides(func(o *store.Overrides) error {
+		if !o.Paused {
+			o.Paused = true
+			o.PausedAt = c.clock.Now()
+		}
+		return nil
+	})
+}
+
+// Resume re-enables autoscaling after Pause
+func (c *Controller) Resume() error {
+	return c.updateOverrides(func(o *store.Overrides) error {
+		o.Paused = false
+		o.PausedAt = time.Time{}
+		return nil
+	})
+}
+
+// PinDesiredCount holds the runner count at count until the given time,
+// bypassing queue thresholds, hysteresis and cooldown
+func (c *Controller) PinDesiredCount(count int, until time.Time) error {
+	maxRunners := c.Config().Scaling.MaxRunners
+	if count < 0 || count > maxRunners {
+		return fmt.Errorf("pinned count must be between 0 and %d", maxRunners)
+	}
+	if !until.After(c.clock.Now()) {
+		return fmt.Errorf("pin expiry must be in the future")
+	}
+
+	return c.updateOverrides(func(o *store.Overrides) error {
+		o.PinnedCount = count
+		o.PinnedUntil = until
+		return nil
+	})
+}
+
+// ClearPin removes a pinned desired count
+func (c *Controller) ClearPin() error {
+	return c.updateOverrides(func(o *store.Overrides) error {
+		o.PinnedCount = 0
+		o.PinnedUntil = time.Time{}
+		return nil
+	})
+}
+
+// BoostMinRunners raises the runner floor to minRunners until the given time
+func (c *Controller) BoostMinRunners(minRunners int, until time.Time) error {
+	maxRunners := c.Config().Scaling.MaxRunners
+	if minRunners < 1 || minRunners > maxRunners {
+		return fmt.Errorf("boosted min runners must be between 1 and %d", maxRunners)
+	}
+	if !until.After(c.clock.Now()) {
+		return fmt.Errorf("boost expiry must be in the future")
+	}
+
+	return c.updateOverrides(func(o *store.Overrides) error {
+		o.BoostedMinRunners = minRunners
+		o.BoostedUntil = until
+		return nil
+	})
+}
+
+// ClearBoost removes a min runners boost
+func (c *Controller) ClearBoost() error {
+	return c.updateOverrides(func(o *store.Overrides) error {
+		o.BoostedMinRunners = 0
+		o.BoostedUntil = time.Time{}
+		return nil
+	})
+}
+
+// Overrides returns the operator overrides currently in effect
+func (c *Controller) Overrides() store.Overrides {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.activeOverridesLocked(c.clock.Now())
+}
+
+// TriggerReconcile asks the run loop to reconcile right away. It returns
+// false if a reconcile is already pending.
+func (c *Controller) TriggerReconcile() bool {
+	select {
+	case c.reconcileCh <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Controller) updateOverrides(update func(o *store.Overrides) error) error {
+	c.mu.Lock()
+	overrides := c.activeOverridesLocked(c.clock.Now())
+	if err := update(&overrides); err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	c.overrides = overrides
+	c.mu.Unlock()
+
+	c.logger.Info("operator overrides updated",
+		"paused", overrides.Paused,
+		"pinned_count", overrides.PinnedCount,
+		"pinned_until", overrides.PinnedUntil,
+		"boosted_min_runners", overrides.BoostedMinRunners,
+		"boosted_until", overrides.BoostedUntil,
+	)
+
+	if c.store != nil {
+		if err := c.store.SetOverrides(overrides); err != nil {
+			return fmt.Errorf("failed to persist overrides: %w", err)
+		}
+	}
+	return nil
+}
+
+// activeOverridesLocked drops expired overrides and returns the rest.
+// c.mu must be held.
+func (c *Controller) activeOverridesLocked(now time.Time) store.Overrides {
+	o := c.overrides
+	if !o.PinnedUntil.IsZero() && !now.Before(o.PinnedUntil) {
+		o.PinnedCount = 0
+		o.PinnedUntil = time.Time{}
+	}
+	if !o.BoostedUntil.IsZero() && !now.Before(o.BoostedUntil) {
+		o.BoostedMinRunners = 0
+		o.BoostedUntil = time.Time{}
+	}
+	c.overrides = o
+	return o
+}
+
+// applyOverrides returns a decision dictated by operator overrides, if any.
+// Pausing holds the current count; a pin or an unmet min boost moves the
+// count straight to its target without hysteresis or cooldown.
+func (c *Controller) applyOverrides(decision ScaleDecision) (ScaleDecision, bool) {
+	o := c.Overrides()
+
+	switch {
+	case o.Paused:
+		decision.Reason = "paused"
+		return decision, true
+
+	case !o.PinnedUntil.IsZero():
+		decision.DesiredCount = o.PinnedCount
+		decision.Reason = "pinned_desired_count"
+		switch {
+		case o.PinnedCount > decision.CurrentCount:
+			decision.Action = ScaleActionUp
+		case o.PinnedCount < decision.CurrentCount:
+			decision.Action = ScaleActionDown
+		}
+		return decision, true
+
+	case o.BoostedMinRunners > decision.CurrentCount:
+		decision.Action = ScaleActionUp
+		decision.DesiredCount = o.BoostedMinRunners
+		decision.Reason = "min_runners_boost"
+		return decision, true
+	}
+
+	return decision, false
+}
+
+// minRunners returns the runner floor, including any active boost
+func (c *Controller) minRunners() int {
+	return max(c.cfg.Scaling.MinRunners, c.Overrides().BoostedMinRunners)
+}
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"Zeno/internal/clock"
+	"Zeno/internal/config"
+	"Zeno/internal/metrics"
+	"Zeno/internal/store"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func newOverridesTestController(t *testing.T, st *store.Store) (*Controller, *clock.Fake) {
+	t.Helper()
+
+	clk := clock.NewFake(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	return New(&config.Config{
+		Scaling: config.ScalingConfig{
+			MinRunners:          1,
+			MaxRunners:          10,
+			ScaleUpThreshold:    5,
+			ScaleDownThreshold:  0,
+			ScaleUpHysteresis:   3,
+			ScaleDownHysteresis: 3,
+			CooldownPeriod:      time.Hour,
+		},
+	}, &mockGitHubClient{}, &mockProvider{}, st, metrics.NewMetrics(prometheus.NewRegistry()),
+		nil, clk, slog.New(slog.NewTextHandler(io.Discard, nil))), clk
+}
+
+func TestPauseHoldsCurrentCount(t *testing.T) {
+	ctrl, _ := newOverridesTestController(t, nil)
+
+	if err := ctrl.Pause(); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+
+	decision := ctrl.makeScalingDecision(50, 2)
+	if decision.Action != ScaleActionNone || decision.Reason != "paused" {
+		t.Errorf("decision = %s/%s, want none/paused", decision.Action, decision.Reason)
+	}
+
+	if err := ctrl.Resume(); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if decision := ctrl.makeScalingDecision(50, 2); decision.Reason == "paused" {
+		t.Error("decision still paused after Resume()")
+	}
+}
+
+func TestPinBypassesHysteresisAndCooldown(t *testing.T) {
+	ctrl, clk := newOverridesTestController(t, nil)
+	ctrl.lastScaleUpTime = clk.Now()
+
+	if err := ctrl.PinDesiredCount(6, clk.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("PinDesiredCount() error = %v", err)
+	}
+
+	decision := ctrl.makeScalingDecision(0, 2)
+	if decision.Action != ScaleActionUp || decision.DesiredCount != 6 {
+		t.Errorf("decision = %s to %d, want up to 6", decision.Action, decision.DesiredCount)
+	}
+
+	decision = ctrl.makeScalingDecision(0, 8)
+	if decision.Action != ScaleActionDown || decision.DesiredCount != 6 {
+		t.Errorf("decision = %s to %d, want down to 6", decision.Action, decision.DesiredCount)
+	}
+
+	if err := ctrl.PinDesiredCount(11, clk.Now().Add(time.Hour)); err == nil {
+		t.Error("PinDesiredCount() above max runners should fail")
+	}
+}
+
+func TestExpiredOverridesAreDropped(t *testing.T) {
+	ctrl, clk := newOverridesTestController(t, nil)
+
+	if err := ctrl.BoostMinRunners(4, clk.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("BoostMinRunners() error = %v", err)
+	}
+
+	decision := ctrl.makeScalingDecision(0, 1)
+	if decision.Action != ScaleActionUp || decision.DesiredCount != 4 || decision.Reason != "min_runners_boost" {
+		t.Errorf("decision = %+v, want up to 4 for min_runners_boost", decision)
+	}
+
+	clk.Advance(time.Hour)
+
+	if got := ctrl.Overrides(); got.BoostedMinRunners != 0 {
+		t.Errorf("BoostedMinRunners = %d after expiry, want 0", got.BoostedMinRunners)
+	}
+}
+
+func TestOverridesSurviveRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	cfg := store.StoreConfig{Enabled: true, Path: path, MaxEvents: 10}
+
+	st, err := store.New(cfg)
+	if err != nil {
+		t.Fatalf("store.New() error = %v", err)
+	}
+	ctrl, clk := newOverridesTestController(t, st)
+
+	until := clk.Now().Add(time.Hour)
+	if err := ctrl.Pause(); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if err := ctrl.PinDesiredCount(3, until); err != nil {
+		t.Fatalf("PinDesiredCount() error = %v", err)
+	}
+
+	reopened, err := store.New(cfg)
+	if err != nil {
+		t.Fatalf("store.New() error = %v", err)
+	}
+	restarted, _ := newOverridesTestController(t, reopened)
+
+	got := restarted.Overrides()
+	if !got.Paused || got.PinnedCount != 3 || !got.PinnedUntil.Equal(until) {
+		t.Errorf("Overrides() after restart = %+v, want paused with pin 3 until %v", got, until)
+	}
+}
@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"sort"
+
+	"Zeno/internal/github"
+	"Zeno/internal/priority"
+)
+
+// makePriorityDecision scales for queued jobs sorted into priority classes.
+// Normal and low priority jobs go through makeScalingDecision, low priority
+// jobs only counting as far as there is room below the soft maximum
+// (max_runners) once high and normal priority jobs are served. One runner
+// per high priority job is then added on top, skipping the threshold,
+// hysteresis and cooldown, up to the hard maximum.
+func (c *Controller) makePriorityDecision(queue priority.Counts, currentCount int) ScaleDecision {
+	c.updatePriorityMetrics(queue)
+
+	softMax := c.cfg.Scaling.MaxRunners
+	low := min(queue.Low, max(softMax-queue.High-queue.Normal, 0))
+
+	decision := c.makeScalingDecision(queue.Normal+low, currentCount)
+	decision.QueueDepth = queue.Total()
+	// Record the class the decision was made for, which is only high once
+	// high priority jobs add runners below
+	switch {
+	case queue.Normal > 0:
+		decision.PriorityClass = priority.Normal
+	case low > 0:
+		decision.PriorityClass = priority.Low
+	}
+	if queue.High == 0 || decision.Overridden {
+		return decision
+	}
+
+	// High priority jobs are not held back by a scale down of the others
+	base := currentCount
+	if decision.Action == ScaleActionUp {
+		base = decision.DesiredCount
+	}
+	desiredCount := min(base+queue.High, c.priority.HardMax(softMax))
+	if desiredCount <= base {
+		return decision
+	}
+
+	if reason := c.scaleUpBlockedReason(); reason != "" {
+		return ScaleDecision{
+			Action:        ScaleActionNone,
+			Reason:        reason,
+			CurrentCount:  currentCount,
+			DesiredCount:  currentCount,
+			QueueDepth:    queue.Total(),
+			PriorityClass: priority.High,
+		}
+	}
+
+	decision.Action = ScaleActionUp
+	decision.DesiredCount = desiredCount
+	decision.Reason = "high_priority_jobs_queued"
+	decision.PriorityClass = priority.High
+	decision.HysteresisHit = false
+	return decision
+}
+
+// prioritiseJobs orders unassigned jobs highest class first and keeps those
+// that fit: high priority jobs up to the hard maximum, others up to the soft
+// maximum. It returns the class of the highest job kept.
+func (c *Controller) prioritiseJobs(unassigned []github.QueuedJob, currentCount int) ([]github.QueuedJob, string) {
+	classes := make(map[int64]string, len(unassigned))
+	for _, job := range unassigned {
+		classes[job.ID] = c.priority.Classify(job)
+	}
+	c.updatePriorityMetrics(c.priority.Count(unassigned))
+
+	sorted := append([]github.QueuedJob(nil), unassigned...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return priority.Rank(classes[sorted[i].ID]) < priority.Rank(classes[sorted[j].ID])
+	})
+
+	softMax := c.cfg.Scaling.MaxRunners
+	hardMax := c.priority.HardMax(softMax)
+
+	var selected []github.QueuedJob
+	for _, job := range sorted {
+		limit := softMax
+		if classes[job.ID] == priority.High {
+			limit = hardMax
+		}
+		if currentCount+len(selected) < limit {
+			selected = append(selected, job)
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, ""
+	}
+	return selected, classes[selected[0].ID]
+}
+
+func (c *Controller) updatePriorityMetrics(queue priority.Counts) {
+	c.metrics.PriorityQueueDepth.WithLabelValues(priority.High).Set(float64(queue.High))
+	c.metrics.PriorityQueueDepth.WithLabelValues(priority.Normal).Set(float64(queue.Normal))
+	c.metrics.PriorityQueueDepth.WithLabelValues(priority.Low).Set(float64(queue.Low))
+}
@@ -0,0 +1,200 @@
+package controller
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"Zeno/internal/clock"
+	"Zeno/internal/config"
+	"Zeno/internal/github"
+	"Zeno/internal/metrics"
+	"Zeno/internal/priority"
+	"Zeno/internal/provider"
+	"Zeno/internal/store"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func priorityTestConfig() *config.Config {
+	cfg := stateTestConfig()
+	cfg.Scaling.MaxRunners = 4
+	cfg.Scaling.Priority = config.PriorityConfig{
+		Enabled:        true,
+		HardMaxRunners: 6,
+		Rules: []config.PriorityRule{
+			{Class: priority.High, Workflows: []string{"Release*"}},
+			{Class: priority.Low, Workflows: []string{"Nightly*"}},
+		},
+	}
+	return cfg
+}
+
+func workflowJobs(workflow string, ids ...int64) []github.QueuedJob {
+	jobs := make([]github.QueuedJob, 0, len(ids))
+	for _, id := range ids {
+		jobs = append(jobs, github.QueuedJob{ID: id, WorkflowName: workflow})
+	}
+	return jobs
+}
+
+func TestHighPriorityBypassesHysteresisAndCooldown(t *testing.T) {
+	runners := make([]*provider.Runner, 0, 4)
+	for _, id := range []string{"r1", "r2", "r3", "r4"} {
+		runners = append(runners, &provider.Runner{ID: id, Status: provider.StatusBusy})
+	}
+	prov := &mockProvider{runners: runners}
+	jobs := append(workflowJobs("CI", 1, 2, 3, 4, 5, 6), workflowJobs("Release v2", 7, 8)...)
+	st := newStateTestStore(t, filepath.Join(t.TempDir(), "store.json"))
+	clk := clock.NewFake(stateStart)
+
+	ctrl := New(priorityTestConfig(), &mockGitHubClient{jobs: jobs}, prov, st,
+		metrics.NewMetrics(prometheus.NewRegistry()), nil, clk, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctrl.lastScaleUpTime = clk.Now()
+
+	if err := ctrl.reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile() error = %v", err)
+	}
+
+	// Normal jobs fill the soft maximum of 4, release jobs go on top
+	if prov.created != 2 {
+		t.Errorf("created %d runners, want 2 above the soft maximum", prov.created)
+	}
+
+	decisions := st.QueryDecisions(store.DecisionQuery{})
+	if len(decisions) != 1 {
+		t.Fatalf("recorded %d decisions, want 1", len(decisions))
+	}
+	if d := decisions[0]; d.PriorityClass != priority.High || d.Reason != "high_priority_jobs_queued" || d.DesiredCount != 6 {
+		t.Errorf("decision = %+v", d)
+	}
+}
+
+func TestHighPriorityCappedAtHardMaximum(t *testing.T) {
+	ctrl := New(priorityTestConfig(), &mockGitHubClient{}, &mockProvider{}, nil,
+		metrics.NewMetrics(prometheus.NewRegistry()), nil, clock.NewFake(stateStart),
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	decision := ctrl.makePriorityDecision(priority.Counts{High: 10, Normal: 10}, 4)
+	if decision.Action != ScaleActionUp || decision.DesiredCount != 6 {
+		t.Errorf("decision = %+v, want scale up to the hard maximum of 6", decision)
+	}
+}
+
+func TestPriorityClassRecordsDrivingClass(t *testing.T) {
+	cfg := priorityTestConfig()
+	cfg.Scaling.ScaleUpHysteresis = 1
+	ctrl := New(cfg, &mockGitHubClient{}, &mockProvider{}, nil,
+		metrics.NewMetrics(prometheus.NewRegistry()), nil, clock.NewFake(stateStart),
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tests := []struct {
+		name    string
+		queue   priority.Counts
+		current int
+		want    string
+	}{
+		// At the hard maximum the high jobs add nothing, normal jobs decide
+		{name: "high at hard maximum", queue: priority.Counts{High: 3, Normal: 2}, current: 6, want: priority.Normal},
+		{name: "high only at hard maximum", queue: priority.Counts{High: 3}, current: 6, want: ""},
+		{name: "high adds runners", queue: priority.Counts{High: 1, Normal: 2}, current: 1, want: priority.High},
+		{name: "low fills soft maximum", queue: priority.Counts{Low: 3}, current: 1, want: priority.Low},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := ctrl.makePriorityDecision(tt.queue, tt.current)
+			if decision.PriorityClass != tt.want {
+				t.Errorf("PriorityClass = %q, want %q (decision = %+v)", decision.PriorityClass, tt.want, decision)
+			}
+		})
+	}
+}
+
+func TestLowPriorityStaysBelowSoftMaximum(t *testing.T) {
+	cfg := priorityTestConfig()
+	cfg.Scaling.ScaleUpHysteresis = 1
+	ctrl := New(cfg, &mockGitHubClient{}, &mockProvider{}, nil,
+		metrics.NewMetrics(prometheus.NewRegistry()), nil, clock.NewFake(stateStart),
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	decision := ctrl.makePriorityDecision(priority.Counts{Low: 20}, 2)
+	if decision.Action != ScaleActionUp || decision.DesiredCount != 4 || decision.PriorityClass != priority.Low {
+		t.Errorf("decision = %+v, want scale up to the soft maximum of 4 driven by low priority", decision)
+	}
+
+	decision = ctrl.makePriorityDecision(priority.Counts{Low: 20}, 4)
+	if decision.Action != ScaleActionNone {
+		t.Errorf("decision = %+v, want no scale up past the soft maximum", decision)
+	}
+}
+
+func TestHighPriorityBypassOnlyCoversHighJobs(t *testing.T) {
+	ctrl := New(priorityTestConfig(), &mockGitHubClient{}, &mockProvider{}, nil,
+		metrics.NewMetrics(prometheus.NewRegistry()), nil, clock.NewFake(stateStart),
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// Normal jobs are held by hysteresis, the high priority job is not
+	decision := ctrl.makePriorityDecision(priority.Counts{High: 1, Normal: 6}, 1)
+	if decision.Action != ScaleActionUp || decision.DesiredCount != 2 || decision.PriorityClass != priority.High {
+		t.Errorf("decision = %+v, want scale up by one for the high priority job", decision)
+	}
+}
+
+func TestLowPriorityYieldsToHigherClasses(t *testing.T) {
+	cfg := priorityTestConfig()
+	cfg.Scaling.ScaleUpHysteresis = 1
+
+	tests := []struct {
+		name  string
+		queue priority.Counts
+		want  int
+	}{
+		// Normal jobs fill the soft maximum of 4, high jobs go on top
+		{name: "normal", queue: priority.Counts{High: 2, Normal: 11}, want: 6},
+		// Only one low job fits below the soft maximum next to the others
+		{name: "low", queue: priority.Counts{High: 2, Normal: 1, Low: 10}, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := New(cfg, &mockGitHubClient{}, &mockProvider{}, nil,
+				metrics.NewMetrics(prometheus.NewRegistry()), nil, clock.NewFake(stateStart),
+				slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			decision := ctrl.makePriorityDecision(tt.queue, 1)
+			if decision.Action != ScaleActionUp || decision.DesiredCount != tt.want {
+				t.Errorf("decision = %+v, want scale up to %d", decision, tt.want)
+			}
+		})
+	}
+}
+
+func TestEphemeralPriorityOrdersJobs(t *testing.T) {
+	cfg := priorityTestConfig()
+	cfg.Scaling.Ephemeral = true
+	ctrl := New(cfg, &mockGitHubClient{}, &mockProvider{}, nil,
+		metrics.NewMetrics(prometheus.NewRegistry()), nil, clock.NewFake(stateStart),
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	runners := []*provider.Runner{
+		{ID: "r1", Status: provider.StatusBusy},
+		{ID: "r2", Status: provider.StatusBusy},
+		{ID: "r3", Status: provider.StatusBusy},
+	}
+	jobs := append(append(workflowJobs("Nightly build", 1), workflowJobs("CI", 2)...), workflowJobs("Release v2", 3, 4, 5)...)
+
+	decision := ctrl.makeEphemeralDecision(jobs, runners)
+
+	// Release jobs go first and take the pool to the hard maximum of 6,
+	// leaving no room below the soft maximum for the others
+	if !reflect.DeepEqual(decision.JobIDs, []int64{3, 4, 5}) {
+		t.Errorf("JobIDs = %v, want the release jobs only", decision.JobIDs)
+	}
+	if decision.PriorityClass != priority.High {
+		t.Errorf("PriorityClass = %q, want high", decision.PriorityClass)
+	}
+}
@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"Zeno/internal/config"
+	"Zeno/internal/fairshare"
+	"Zeno/internal/priority"
+)
+
+// UpdateConfig hands the controller a reloaded configuration. It is applied
+// between reconciles, or when Run or Shutdown next starts, so a reconcile
+// never sees a mix of old and new settings.
+func (c *Controller) UpdateConfig(cfg *config.Config) {
+	c.mu.Lock()
+	c.pendingCfg = cfg
+	c.mu.Unlock()
+
+	select {
+	case c.configCh <- struct{}{}:
+	default:
+	}
+}
+
+// Config returns the configuration the controller is running with
+func (c *Controller) Config() *config.Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.cfg
+}
+
+// applyPendingConfig swaps in a configuration passed to UpdateConfig. It
+// must only be called from the goroutine running reconciles.
+func (c *Controller) applyPendingConfig() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pendingCfg == nil {
+		return false
+	}
+
+	c.cfg = c.pendingCfg
+	c.pendingCfg = nil
+	c.fairShare = fairshare.New(c.cfg.Scaling.FairShare)
+	c.priority = priority.New(c.cfg.Scaling.Priority)
+
+	c.logger.Info("applied reloaded configuration",
+		"check_interval", c.cfg.Scaling.CheckInterval,
+		"min_runners", c.cfg.Scaling.MinRunners,
+		"max_runners", c.cfg.Scaling.MaxRunners,
+	)
+	return true
+}
@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"Zeno/internal/clock"
+	"Zeno/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestUpdateConfigAppliesBetweenReconciles(t *testing.T) {
+	cfg := stateTestConfig()
+	cfg.Scaling.MaxRunners = 4
+	clk := clock.NewFake(stateStart)
+	ctrl := New(cfg, &mockGitHubClient{}, &mockProvider{}, nil,
+		metrics.NewMetrics(prometheus.NewRegistry()), nil, clk, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	next := *cfg
+	next.Scaling.MaxRunners = 8
+	next.Scaling.Priority.Enabled = true
+	ctrl.UpdateConfig(&next)
+
+	if ctrl.Config().Scaling.MaxRunners != 4 {
+		t.Fatal("configuration applied before the reconcile loop picked it up")
+	}
+
+	if !ctrl.applyPendingConfig() {
+		t.Fatal("applyPendingConfig() found nothing to apply")
+	}
+	if ctrl.Config() != &next || !ctrl.priority.Enabled() {
+		t.Error("reloaded configuration not in use")
+	}
+	if err := ctrl.PinDesiredCount(6, clk.Now().Add(time.Hour)); err != nil {
+		t.Errorf("PinDesiredCount(6) error = %v, want the reloaded maximum of 8 to allow it", err)
+	}
+	if ctrl.applyPendingConfig() {
+		t.Error("applyPendingConfig() applied the same configuration twice")
+	}
+}
@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"Zeno/internal/provider"
+	"Zeno/internal/store"
+)
+
+// Shutdown policies decide what happens to runners when Zeno stops
+const (
+	ShutdownLeave    = "leave"
+	ShutdownDrain    = "drain"
+	ShutdownTeardown = "teardown"
+)
+
+// Shutdown applies the configured shutdown policy once Run has returned.
+// Leave keeps every runner for the next process to adopt. Drain removes
+// idle runners and waits for busy ones to finish, up to the drain timeout.
+// Teardown removes every runner straight away, retrying failed removals
+// until the termination timeout. An error means runners were left behind.
+func (c *Controller) Shutdown(ctx context.Context) error {
+	// Wait for the reconcile loop so the two never remove the same runner
+	c.runMu.Lock()
+	defer c.runMu.Unlock()
+
+	c.applyPendingConfig()
+
+	policy := c.cfg.Shutdown.Policy
+	if policy == "" {
+		policy = ShutdownLeave
+	}
+
+	switch policy {
+	case ShutdownDrain:
+		return c.removeAllRunners(ctx, policy, c.cfg.Shutdown.DrainTimeout, true)
+	case ShutdownTeardown:
+		return c.removeAllRunners(ctx, policy, c.cfg.Scaling.TerminationTimeout, false)
+	default:
+		c.logger.Info("leaving runners in place on shutdown")
+		c.recordShutdown(policy, "left", 0, 0)
+		c.saveState()
+		return nil
+	}
+}
+
+// removeAllRunners removes runners until none are left or the timeout
+// passes. Draining leaves runners with a job in progress until they finish.
+func (c *Controller) removeAllRunners(ctx context.Context, policy string, timeout time.Duration, drain bool) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	deadline := c.clock.Now().Add(timeout)
+	poll := c.cfg.Scaling.CheckInterval
+	if poll <= 0 {
+		poll = time.Second
+	}
+
+	c.logger.Info("shutting down runners", "policy", policy, "timeout", timeout)
+
+	started := -1
+	remaining := -1
+	for {
+		runners, err := c.provider.ListRunners(ctx)
+		if err != nil {
+			c.logger.Warn("failed to list runners during shutdown", "error", err)
+		} else {
+			if started < 0 {
+				started = len(runners)
+				c.recordShutdown(policy, "started", started, started)
+			}
+			remaining = c.removeRunnersRound(ctx, policy, runners, drain)
+			if remaining == 0 {
+				c.logger.Info("shutdown complete, all runners removed", "policy", policy, "removed", started)
+				c.recordShutdown(policy, "complete", started, 0)
+				c.saveState()
+				return nil
+			}
+		}
+
+		wait := c.clock.Until(deadline)
+		if wait > poll {
+			wait = poll
+		}
+		if wait <= 0 || ctx.Err() != nil {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-c.clock.After(wait):
+		}
+	}
+
+	c.logger.Warn("shutdown timed out, leaving runners behind", "policy", policy, "remaining", remaining)
+	c.recordShutdown(policy, "timed_out", max(started, 0), max(remaining, 0))
+	c.saveState()
+
+	if remaining < 0 {
+		return fmt.Errorf("failed to %s runners: could not list runners within %s", policy, timeout)
+	}
+	return fmt.Errorf("failed to %s runners: %d left after %s", policy, remaining, timeout)
+}
+
+// removeRunnersRound removes what it can of runners and returns how many
+// are still left
+func (c *Controller) removeRunnersRound(ctx context.Context, policy string, runners []*provider.Runner, drain bool) int {
+	var busy map[string]bool
+	if drain {
+		var ok bool
+		busy, ok = c.busyRunners(ctx, runners)
+		if !ok {
+			// Without registrations a runner may look idle mid-job
+			return len(runners)
+		}
+	}
+
+	remaining := len(runners)
+	for _, runner := range runners {
+		if drain && busy[runner.Name] {
+			continue
+		}
+
+		if c.cfg.DryRun {
+			c.logger.Info("dry-run mode: would remove runner on shutdown", "id", runner.ID, "policy", policy)
+			remaining--
+			continue
+		}
+
+		if err := c.provider.RemoveRunner(ctx, runner.ID, drain); err != nil {
+			c.logger.Error("failed to remove runner on shutdown",
+				"id", runner.ID,
+				"policy", policy,
+				"error", err,
+			)
+			c.providerError("remove", "shutdown_error", err)
+			continue
+		}
+
+		c.logger.Info("runner removed on shutdown", "id", runner.ID, "name", runner.Name, "policy", policy)
+		c.metrics.ScaleDownEvents.WithLabelValues("shutdown_" + policy).Inc()
+		c.forgetOrigin(runner.ID)
+		remaining--
+
+		c.recordScaleEvent(store.ScaleEvent{
+			Timestamp:     c.clock.Now(),
+			Action:        "scale_down",
+			Reason:        "shutdown_" + policy,
+			RunnersBefore: remaining + 1,
+			RunnersAfter:  remaining,
+		})
+	}
+
+	return remaining
+}
+
+// busyRunners returns the names of runners running a job, from the
+// provider status and, with a RunnerRegistry, GitHub registrations. Runners
+// scoped to a repository that are not among the registrations count as
+// busy, since they register with the repository and their job cannot be
+// checked, as in isOrphan. It reports false when registrations could not be
+// listed.
+func (c *Controller) busyRunners(ctx context.Context, runners []*provider.Runner) (map[string]bool, bool) {
+	busy := make(map[string]bool)
+	for _, r := range runners {
+		if r.Status == provider.StatusBusy {
+			busy[r.Name] = true
+		}
+	}
+
+	registry, ok := c.ghClient.(RunnerRegistry)
+	if !ok {
+		return busy, true
+	}
+
+	registrations, err := registry.ListRegisteredRunners(ctx)
+	if err != nil {
+		c.logger.Warn("failed to list GitHub registrations while draining", "error", err)
+		return nil, false
+	}
+	registered := make(map[string]bool, len(registrations))
+	for _, reg := range registrations {
+		registered[reg.Name] = true
+		if reg.Busy {
+			busy[reg.Name] = true
+		}
+	}
+	for _, r := range runners {
+		if !registered[r.Name] && r.Metadata[provider.MetadataRepository] != "" {
+			busy[r.Name] = true
+		}
+	}
+	return busy, true
+}
+
+// recordShutdown stores a shutdown stage, such as drain_started
+func (c *Controller) recordShutdown(policy, stage string, before, after int) {
+	if c.store == nil {
+		return
+	}
+
+	_ = c.store.RecordScaleEvent(store.ScaleEvent{
+		Timestamp:     c.clock.Now(),
+		Action:        "shutdown",
+		Reason:        policy + "_" + stage,
+		RunnersBefore: before,
+		RunnersAfter:  after,
+	})
+}
@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"Zeno/internal/clock"
+	"Zeno/internal/config"
+	"Zeno/internal/github"
+	"Zeno/internal/metrics"
+	"Zeno/internal/provider"
+	"Zeno/internal/store"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func newShutdownController(t *testing.T, policy string, gh GitHubClient, prov provider.Provider) (*Controller, *store.Store, *clock.Fake) {
+	t.Helper()
+
+	cfg := stateTestConfig()
+	cfg.Scaling.CheckInterval = 30 * time.Second
+	cfg.Scaling.TerminationTimeout = time.Minute
+	cfg.Shutdown = config.ShutdownConfig{Policy: policy, DrainTimeout: 10 * time.Minute}
+
+	st := newStateTestStore(t, filepath.Join(t.TempDir(), "store.json"))
+	clk := clock.NewFake(stateStart)
+	ctrl := New(cfg, gh, prov, st, metrics.NewMetrics(prometheus.NewRegistry()), nil, clk,
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	return ctrl, st, clk
+}
+
+func shutdownReasons(st *store.Store) []string {
+	var reasons []string
+	for _, event := range st.GetAllEvents() {
+		if event.Action == "shutdown" {
+			reasons = append(reasons, event.Reason)
+		}
+	}
+	return reasons
+}
+
+func shutdownTestRunners() []*provider.Runner {
+	return []*provider.Runner{
+		{ID: "r1", Name: "zeno-runner-1", Status: provider.StatusIdle},
+		{ID: "r2", Name: "zeno-runner-2", Status: provider.StatusBusy},
+	}
+}
+
+func TestShutdownLeaveKeepsRunners(t *testing.T) {
+	prov := &mockProvider{runners: shutdownTestRunners()}
+	ctrl, st, _ := newShutdownController(t, ShutdownLeave, &mockGitHubClient{}, prov)
+
+	if err := ctrl.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if len(prov.removed) != 0 {
+		t.Errorf("removed %v, want every runner left in place", prov.removed)
+	}
+	if reasons := shutdownReasons(st); len(reasons) != 1 || reasons[0] != "leave_left" {
+		t.Errorf("shutdown events = %v", reasons)
+	}
+}
+
+func TestShutdownDrainWaitsForBusyRunners(t *testing.T) {
+	prov := &mockProvider{runners: shutdownTestRunners()}
+	// GitHub still reports r1 busy after the provider has marked it idle
+	gh := &registryGitHubClient{registrations: []github.RegisteredRunner{
+		{ID: 1, Name: "zeno-runner-1", Status: "online", Busy: true},
+	}}
+	ctrl, st, clk := newShutdownController(t, ShutdownDrain, gh, prov)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- ctrl.Shutdown(context.Background()) }()
+
+	clk.BlockUntil(1)
+	if len(prov.removed) != 0 {
+		t.Fatalf("removed %v while both runners are busy", prov.removed)
+	}
+
+	// r1 finishes its job
+	gh.registrations = nil
+	clk.Advance(30 * time.Second)
+	clk.BlockUntil(1)
+	if len(prov.removed) != 1 || prov.removed[0] != "r1" {
+		t.Fatalf("removed %v, want the finished r1", prov.removed)
+	}
+
+	// r2 finishes its job
+	prov.runners[0].Status = provider.StatusIdle
+	clk.Advance(30 * time.Second)
+	if err := <-errCh; err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if len(prov.removed) != 2 {
+		t.Errorf("removed %v, want both runners", prov.removed)
+	}
+
+	reasons := shutdownReasons(st)
+	if len(reasons) != 2 || reasons[0] != "drain_started" || reasons[1] != "drain_complete" {
+		t.Errorf("shutdown events = %v", reasons)
+	}
+}
+
+func TestShutdownDrainTimesOut(t *testing.T) {
+	prov := &mockProvider{runners: shutdownTestRunners()}
+	ctrl, st, clk := newShutdownController(t, ShutdownDrain, &mockGitHubClient{}, prov)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- ctrl.Shutdown(context.Background()) }()
+
+	for i := 0; i < 20; i++ {
+		clk.BlockUntil(1)
+		clk.Advance(30 * time.Second)
+	}
+	if err := <-errCh; err == nil {
+		t.Fatal("Shutdown() succeeded with a runner still busy")
+	}
+
+	if len(prov.removed) != 1 || prov.removed[0] != "r1" {
+		t.Errorf("removed %v, want only the idle r1", prov.removed)
+	}
+	if reasons := shutdownReasons(st); len(reasons) == 0 || reasons[len(reasons)-1] != "drain_timed_out" {
+		t.Errorf("shutdown events = %v", reasons)
+	}
+}
+
+func TestShutdownDrainKeepsBusyRepositoryRunners(t *testing.T) {
+	// Fair-share runners register with their repository, so the
+	// organization's registrations say nothing about r2's job
+	prov := &mockProvider{runners: []*provider.Runner{
+		{ID: "r1", Name: "zeno-runner-1", Status: provider.StatusIdle},
+		{
+			ID:       "r2",
+			Name:     "zeno-runner-2",
+			Status:   provider.StatusIdle,
+			Metadata: map[string]string{provider.MetadataRepository: "org/app"},
+		},
+	}}
+	gh := &registryGitHubClient{registrations: []github.RegisteredRunner{
+		{ID: 1, Name: "zeno-runner-1", Status: "online"},
+	}}
+	ctrl, _, clk := newShutdownController(t, ShutdownDrain, gh, prov)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- ctrl.Shutdown(context.Background()) }()
+
+	for i := 0; i < 20; i++ {
+		clk.BlockUntil(1)
+		clk.Advance(30 * time.Second)
+	}
+	if err := <-errCh; err == nil {
+		t.Fatal("Shutdown() succeeded with the repository runner's job unknown")
+	}
+	if len(prov.removed) != 1 || prov.removed[0] != "r1" {
+		t.Errorf("removed %v, want only the registered idle r1", prov.removed)
+	}
+}
+
+func TestShutdownTeardownRemovesBusyRunners(t *testing.T) {
+	prov := &mockProvider{runners: shutdownTestRunners()}
+	ctrl, st, _ := newShutdownController(t, ShutdownTeardown, &mockGitHubClient{}, prov)
+
+	if err := ctrl.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if len(prov.removed) != 2 {
+		t.Errorf("removed %v, want every runner", prov.removed)
+	}
+	if len(ctrl.RunnerOrigins()) != 0 {
+		t.Errorf("origins = %v after teardown", ctrl.RunnerOrigins())
+	}
+
+	var scaleDowns int
+	for _, event := range st.GetAllEvents() {
+		if event.Action == "scale_down" && event.Reason == "shutdown_teardown" {
+			scaleDowns++
+		}
+	}
+	if scaleDowns != 2 {
+		t.Errorf("recorded %d shutdown removals, want 2", scaleDowns)
+	}
+}
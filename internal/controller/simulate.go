@@ -0,0 +1,252 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"Zeno/internal/clock"
+	"Zeno/internal/config"
+	"Zeno/internal/metrics"
+	"Zeno/internal/provider"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// QueueSample is one observation of queue depth in a recorded trace
+type QueueSample struct {
+	Time       time.Time
+	QueueDepth int
+}
+
+// SimulationOptions tune how a trace is replayed
+type SimulationOptions struct {
+	// BootLatency is how long a simulated runner takes to become ready
+	BootLatency time.Duration
+	// Step is the virtual reconcile interval, defaulting to scaling.check_interval
+	Step time.Duration
+}
+
+// SimulationReport summarises how a configuration handled a trace
+type SimulationReport struct {
+	Start            time.Time `json:"start"`
+	End              time.Time `json:"end"`
+	Ticks            int       `json:"ticks"`
+	RunnerMinutes    float64   `json:"runner_minutes"`
+	QueuedJobMinutes float64   `json:"queued_job_minutes"`
+	ScaleUpEvents    int       `json:"scale_up_events"`
+	ScaleDownEvents  int       `json:"scale_down_events"`
+	RunnersCreated   int       `json:"runners_created"`
+	RunnersRemoved   int       `json:"runners_removed"`
+	PeakRunners      int       `json:"peak_runners"`
+	HysteresisHolds  int       `json:"hysteresis_holds"`
+}
+
+// Simulate replays a queue depth trace through the scaling logic using a
+// virtual clock and a simulated provider, without touching GitHub or any
+// real infrastructure.
+//
+// The trace is treated as demand: at every step the controller sees the
+// recorded queue depth, and jobs beyond the number of ready runners count
+// as queued for that step.
+func Simulate(ctx context.Context, cfg *config.Config, samples []QueueSample, opts SimulationOptions, logger *slog.Logger) (*SimulationReport, error) {
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("trace is empty")
+	}
+	if cfg.Scaling.Ephemeral {
+		return nil, fmt.Errorf("simulation does not support ephemeral mode")
+	}
+
+	samples = append([]QueueSample(nil), samples...)
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].Time.Before(samples[j].Time)
+	})
+
+	step := opts.Step
+	if step <= 0 {
+		step = cfg.Scaling.CheckInterval
+	}
+	if step <= 0 {
+		return nil, fmt.Errorf("simulation step must be > 0")
+	}
+
+	simCfg := *cfg
+	simCfg.DryRun = false
+
+	now := samples[0].Time
+	clk := clock.NewFake(now)
+
+	prov := &simProvider{now: clk.Now, bootLatency: opts.BootLatency}
+	for i := 0; i < cfg.Scaling.MinRunners; i++ {
+		prov.add(now.Add(-opts.BootLatency))
+	}
+
+	c := New(&simCfg, nil, prov, nil, metrics.NewMetrics(prometheus.NewRegistry()), nil, clk, logger)
+
+	report := &SimulationReport{
+		Start: samples[0].Time,
+		End:   samples[len(samples)-1].Time,
+	}
+
+	next := 0
+	depth := 0
+	for ; !now.After(report.End); now = now.Add(step) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		clk.Set(now)
+
+		// The queue holds its last recorded depth until the next sample
+		for next < len(samples) && !samples[next].Time.After(now) {
+			depth = samples[next].QueueDepth
+			next++
+		}
+
+		prov.assignJobs(depth)
+		c.updateQueueHistory(depth)
+
+		runners, _ := prov.ListRunners(ctx)
+		c.updateBudget(runners)
+
+		decision := c.makeScalingDecision(depth, len(runners))
+		if decision.HysteresisHit {
+			report.HysteresisHolds++
+		}
+
+		changed, err := c.executeScaling(ctx, decision)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute scaling at %s: %w", now.Format(time.RFC3339), err)
+		}
+
+		switch {
+		case decision.Action == ScaleActionUp && changed > 0:
+			report.ScaleUpEvents++
+			report.RunnersCreated += changed
+		case decision.Action == ScaleActionDown && changed > 0:
+			report.ScaleDownEvents++
+			report.RunnersRemoved += changed
+		}
+
+		prov.assignJobs(depth)
+
+		report.Ticks++
+		report.PeakRunners = max(report.PeakRunners, len(prov.runners))
+		report.RunnerMinutes += float64(len(prov.runners)) * step.Minutes()
+		report.QueuedJobMinutes += float64(max(0, depth-prov.ready())) * step.Minutes()
+	}
+
+	return report, nil
+}
+
+// simRunner is a runner in the simulated provider
+type simRunner struct {
+	id      string
+	created time.Time
+	readyAt time.Time
+	busy    bool
+}
+
+// simProvider is an in-memory provider whose runners become ready after a
+// fixed boot latency. Ready runners are marked busy, oldest first, while
+// there are queued jobs for them to run.
+type simProvider struct {
+	now         func() time.Time
+	bootLatency time.Duration
+	runners     []*simRunner
+	nextID      int
+}
+
+func (p *simProvider) add(created time.Time) *simRunner {
+	p.nextID++
+	r := &simRunner{
+		id:      fmt.Sprintf("sim-%d", p.nextID),
+		created: created,
+		readyAt: created.Add(p.bootLatency),
+	}
+	p.runners = append(p.runners, r)
+	return r
+}
+
+func (p *simProvider) ready() int {
+	now := p.now()
+	n := 0
+	for _, r := range p.runners {
+		if !now.Before(r.readyAt) {
+			n++
+		}
+	}
+	return n
+}
+
+func (p *simProvider) assignJobs(queued int) {
+	now := p.now()
+	for _, r := range p.runners {
+		r.busy = queued > 0 && !now.Before(r.readyAt)
+		if r.busy {
+			queued--
+		}
+	}
+}
+
+func (p *simProvider) toRunner(r *simRunner) *provider.Runner {
+	status := provider.StatusIdle
+	switch {
+	case p.now().Before(r.readyAt):
+		status = provider.StatusProvisioning
+	case r.busy:
+		status = provider.StatusBusy
+	}
+
+	return &provider.Runner{
+		ID:        r.id,
+		Name:      r.id,
+		Status:    status,
+		Provider:  "simulated",
+		CreatedAt: r.created,
+	}
+}
+
+func (p *simProvider) Name() string {
+	return "simulated"
+}
+
+func (p *simProvider) ListRunners(ctx context.Context) ([]*provider.Runner, error) {
+	runners := make([]*provider.Runner, 0, len(p.runners))
+	for _, r := range p.runners {
+		runners = append(runners, p.toRunner(r))
+	}
+	return runners, nil
+}
+
+func (p *simProvider) GetRunner(ctx context.Context, id string) (*provider.Runner, error) {
+	for _, r := range p.runners {
+		if r.id == id {
+			return p.toRunner(r), nil
+		}
+	}
+	return nil, fmt.Errorf("runner not found: %s", id)
+}
+
+func (p *simProvider) CreateRunner(ctx context.Context, req *provider.CreateRunnerRequest) (*provider.Runner, error) {
+	return p.toRunner(p.add(p.now())), nil
+}
+
+func (p *simProvider) RemoveRunner(ctx context.Context, id string, graceful bool) error {
+	for i, r := range p.runners {
+		if r.id == id {
+			p.runners = append(p.runners[:i], p.runners[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("runner not found: %s", id)
+}
+
+func (p *simProvider) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+func (p *simProvider) Close() error {
+	return nil
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"Zeno/internal/config"
+)
+
+func simulationConfig() *config.Config {
+	return &config.Config{
+		Scaling: config.ScalingConfig{
+			MinRunners:          1,
+			MaxRunners:          5,
+			ScaleUpThreshold:    2,
+			ScaleDownThreshold:  0,
+			ScaleUpHysteresis:   1,
+			ScaleDownHysteresis: 1,
+			CheckInterval:       time.Minute,
+			CooldownPeriod:      time.Minute,
+		},
+	}
+}
+
+func TestSimulateBurst(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	// Idle, then a burst of 4 queued jobs for 10 minutes, then idle again
+	trace := []QueueSample{
+		{Time: start, QueueDepth: 0},
+		{Time: start.Add(5 * time.Minute), QueueDepth: 4},
+		{Time: start.Add(15 * time.Minute), QueueDepth: 0},
+		{Time: start.Add(30 * time.Minute), QueueDepth: 0},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	fast, err := Simulate(context.Background(), simulationConfig(), trace, SimulationOptions{}, logger)
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	if fast.Ticks != 31 {
+		t.Errorf("Ticks = %d, want 31", fast.Ticks)
+	}
+	if fast.PeakRunners != 4 {
+		t.Errorf("PeakRunners = %d, want 4", fast.PeakRunners)
+	}
+	if fast.ScaleUpEvents != 1 || fast.RunnersCreated != 3 {
+		t.Errorf("scale up = %d events / %d runners, want 1 / 3", fast.ScaleUpEvents, fast.RunnersCreated)
+	}
+	if fast.ScaleDownEvents != 1 || fast.RunnersRemoved != 3 {
+		t.Errorf("scale down = %d events / %d runners, want 1 / 3", fast.ScaleDownEvents, fast.RunnersRemoved)
+	}
+	// Runners boot instantly, so no job waits
+	if fast.QueuedJobMinutes != 0 {
+		t.Errorf("QueuedJobMinutes = %.1f, want 0 with instant boot", fast.QueuedJobMinutes)
+	}
+
+	slow, err := Simulate(context.Background(), simulationConfig(), trace, SimulationOptions{BootLatency: 3 * time.Minute}, logger)
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	// Three new runners take three minutes to boot while three jobs wait
+	if slow.QueuedJobMinutes != 9 {
+		t.Errorf("QueuedJobMinutes = %.1f, want 9 with 3m boot latency", slow.QueuedJobMinutes)
+	}
+	if slow.RunnerMinutes != fast.RunnerMinutes {
+		t.Errorf("RunnerMinutes = %.1f, want %.1f regardless of boot latency", slow.RunnerMinutes, fast.RunnerMinutes)
+	}
+}
+
+func TestSimulateRejectsEmptyTrace(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	if _, err := Simulate(context.Background(), simulationConfig(), nil, SimulationOptions{}, logger); err == nil {
+		t.Error("Simulate() error = nil, want error for empty trace")
+	}
+}
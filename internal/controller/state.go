@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"Zeno/internal/budget"
+	"Zeno/internal/provider"
+	"Zeno/internal/store"
+)
+
+// restoreState picks up the scaling state saved by a previous process or
+// leader. Cooldown timestamps, runner origins, the replacements of draining
+// runners and budget spend are always restored; hysteresis counters and
+// queue history only when the state is recent enough to still describe the
+// current queue.
+func (c *Controller) restoreState() {
+	if c.store == nil {
+		return
+	}
+
+	state := c.store.GetControllerState()
+	if state.SavedAt.IsZero() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastScaleUpTime = state.LastScaleUpTime
+	c.lastScaleDownTime = state.LastScaleDownTime
+	for id, origin := range state.Runners {
+		c.origins[id] = origin
+	}
+	for id, replacement := range state.Replacements {
+		c.replacements[id] = replacement
+	}
+	if spend := state.Budget; spend != nil {
+		c.budget.Restore(budget.Spend{
+			HourStart:   spend.HourStart,
+			DayStart:    spend.DayStart,
+			Hourly:      spend.Hourly,
+			Daily:       spend.Daily,
+			Total:       spend.Total,
+			LastAccrued: spend.LastAccrued,
+		})
+	}
+
+	age := c.clock.Since(state.SavedAt)
+	if maxAge := c.cfg.Store.StateMaxAge; maxAge > 0 && age > maxAge {
+		c.logger.Info("saved controller state is stale, starting hysteresis fresh",
+			"saved_at", state.SavedAt,
+			"age", age,
+		)
+		return
+	}
+
+	c.scaleUpCounter = state.ScaleUpCounter
+	c.scaleDownCounter = state.ScaleDownCounter
+	c.queueHistory = append(c.queueHistory[:0], state.QueueHistory...)
+
+	c.logger.Info("restored controller state",
+		"saved_at", state.SavedAt,
+		"scale_up_counter", state.ScaleUpCounter,
+		"scale_down_counter", state.ScaleDownCounter,
+		"queue_history", len(state.QueueHistory),
+		"runners", len(state.Runners),
+		"replacements", len(state.Replacements),
+	)
+}
+
+// saveState persists the scaling state after a reconcile. This is the one
+// store write per reconcile, and also writes the decisions recorded in it.
+func (c *Controller) saveState() {
+	if c.store == nil {
+		return
+	}
+
+	c.mu.RLock()
+	state := store.ControllerState{
+		SavedAt:           c.clock.Now(),
+		LastScaleUpTime:   c.lastScaleUpTime,
+		LastScaleDownTime: c.lastScaleDownTime,
+		ScaleUpCounter:    c.scaleUpCounter,
+		ScaleDownCounter:  c.scaleDownCounter,
+		QueueHistory:      append([]int(nil), c.queueHistory...),
+		Runners:           make(map[string]store.RunnerOrigin, len(c.origins)),
+		Replacements:      make(map[string]string, len(c.replacements)),
+	}
+	for id, origin := range c.origins {
+		state.Runners[id] = origin
+	}
+	for id, replacement := range c.replacements {
+		state.Replacements[id] = replacement
+	}
+	if c.cfg.Budget.Enabled {
+		spend := c.budget.Spend()
+		state.Budget = &store.BudgetSpend{
+			HourStart:   spend.HourStart,
+			DayStart:    spend.DayStart,
+			Hourly:      spend.Hourly,
+			Daily:       spend.Daily,
+			Total:       spend.Total,
+			LastAccrued: spend.LastAccrued,
+		}
+	}
+	c.mu.RUnlock()
+
+	if err := c.store.SaveControllerState(state); err != nil {
+		c.logger.Warn("failed to save controller state", "error", err)
+	}
+}
+
+// recordOrigin remembers why a runner was created
+func (c *Controller) recordOrigin(runner *provider.Runner, reason string, jobID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.origins[runner.ID] = store.RunnerOrigin{
+		Name:      runner.Name,
+		Reason:    reason,
+		JobID:     jobID,
+		CreatedAt: c.clock.Now(),
+	}
+}
+
+func (c *Controller) forgetOrigin(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.origins, id)
+}
+
+// pruneOrigins drops origins of runners the provider no longer lists. Runners
+// younger than the registration timeout are kept, since providers may not
+// list a runner straight after creating it.
+func (c *Controller) pruneOrigins(runners []*provider.Runner) {
+	listed := make(map[string]bool, len(runners))
+	for _, r := range runners {
+		listed[r.ID] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, origin := range c.origins {
+		if !listed[id] && c.clock.Since(origin.CreatedAt) > c.cfg.Scaling.RegistrationTimeout {
+			delete(c.origins, id)
+		}
+	}
+}
+
+// RunnerOrigins returns why each known runner was created, keyed by runner ID
+func (c *Controller) RunnerOrigins() map[string]store.RunnerOrigin {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	origins := make(map[string]store.RunnerOrigin, len(c.origins))
+	for id, origin := range c.origins {
+		origins[id] = origin
+	}
+	return origins
+}
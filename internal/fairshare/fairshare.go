@@ -0,0 +1,101 @@
+// Package fairshare divides a pool of runners between the repositories of an
+// organization, so that one repository with a large backlog cannot take
+// every runner and starve the others.
+package fairshare
+
+import (
+	"sort"
+
+	"Zeno/internal/config"
+)
+
+// Quotas looks up repository quotas and allocates runners by them
+type Quotas struct {
+	config config.FairShareConfig
+	quotas map[string]config.RepositoryQuota
+}
+
+// New creates quotas from configuration
+func New(cfg config.FairShareConfig) *Quotas {
+	q := &Quotas{
+		config: cfg,
+		quotas: make(map[string]config.RepositoryQuota, len(cfg.Repositories)),
+	}
+	for _, quota := range cfg.Repositories {
+		q.quotas[quota.Name] = quota
+	}
+	return q
+}
+
+// Enabled reports whether runners are divided between repositories. Nil
+// quotas are disabled.
+func (q *Quotas) Enabled() bool {
+	return q != nil && q.config.Enabled
+}
+
+// Quota returns the quota of a repository, with the default weight filled in
+func (q *Quotas) Quota(repo string) config.RepositoryQuota {
+	quota, ok := q.quotas[repo]
+	if !ok {
+		quota = config.RepositoryQuota{Name: repo}
+	}
+	if quota.Weight <= 0 {
+		quota.Weight = q.config.DefaultWeight
+	}
+	if quota.Weight <= 0 {
+		quota.Weight = 1
+	}
+	return quota
+}
+
+// Allocate returns how many of pool runners each repository may hold, given
+// how many it needs. Every repository first gets its minimum, then the rest
+// of the pool goes one runner at a time to the repository furthest below its
+// weighted share. A repository never gets more than it needs or its cap, so
+// while the pool is not contended every need is met.
+func (q *Quotas) Allocate(pool int, demand map[string]int) map[string]int {
+	repos := make([]string, 0, len(demand))
+	for repo, n := range demand {
+		if n > 0 {
+			repos = append(repos, repo)
+		}
+	}
+	sort.Strings(repos)
+
+	limits := make(map[string]int, len(repos))
+	weights := make(map[string]int, len(repos))
+	alloc := make(map[string]int, len(repos))
+
+	for _, repo := range repos {
+		quota := q.Quota(repo)
+		limit := demand[repo]
+		if quota.Max > 0 && limit > quota.Max {
+			limit = quota.Max
+		}
+		limits[repo] = limit
+		weights[repo] = quota.Weight
+
+		guaranteed := min(quota.Min, limit, pool)
+		alloc[repo] = guaranteed
+		pool -= guaranteed
+	}
+
+	for ; pool > 0; pool-- {
+		next := ""
+		for _, repo := range repos {
+			if alloc[repo] >= limits[repo] {
+				continue
+			}
+			// Lowest allocation per unit of weight goes first
+			if next == "" || alloc[repo]*weights[next] < alloc[next]*weights[repo] {
+				next = repo
+			}
+		}
+		if next == "" {
+			break
+		}
+		alloc[next]++
+	}
+
+	return alloc
+}
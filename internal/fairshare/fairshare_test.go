@@ -0,0 +1,86 @@
+package fairshare
+
+import (
+	"reflect"
+	"testing"
+
+	"Zeno/internal/config"
+)
+
+func TestAllocate(t *testing.T) {
+	quotas := New(config.FairShareConfig{
+		Enabled:       true,
+		DefaultWeight: 1,
+		Repositories: []config.RepositoryQuota{
+			{Name: "acme/monorepo", Weight: 3, Max: 8},
+			{Name: "acme/docs", Min: 2},
+		},
+	})
+
+	tests := []struct {
+		name   string
+		pool   int
+		demand map[string]int
+		want   map[string]int
+	}{
+		{
+			name:   "uncontended pool meets every demand",
+			pool:   10,
+			demand: map[string]int{"acme/monorepo": 4, "acme/api": 3},
+			want:   map[string]int{"acme/monorepo": 4, "acme/api": 3},
+		},
+		{
+			name:   "hard cap holds with free runners left",
+			pool:   10,
+			demand: map[string]int{"acme/monorepo": 50},
+			want:   map[string]int{"acme/monorepo": 8},
+		},
+		{
+			name:   "contended pool is split by weight",
+			pool:   8,
+			demand: map[string]int{"acme/monorepo": 50, "acme/api": 50},
+			want:   map[string]int{"acme/monorepo": 6, "acme/api": 2},
+		},
+		{
+			name:   "minimum comes before weighted shares",
+			pool:   4,
+			demand: map[string]int{"acme/monorepo": 50, "acme/docs": 5},
+			want:   map[string]int{"acme/monorepo": 2, "acme/docs": 2},
+		},
+		{
+			name:   "minimum is capped by demand",
+			pool:   10,
+			demand: map[string]int{"acme/monorepo": 50, "acme/docs": 1},
+			want:   map[string]int{"acme/monorepo": 8, "acme/docs": 1},
+		},
+		{
+			name:   "repositories without demand get nothing",
+			pool:   10,
+			demand: map[string]int{"acme/api": 0},
+			want:   map[string]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := quotas.Allocate(tt.pool, tt.demand)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Allocate(%d, %v) = %v, want %v", tt.pool, tt.demand, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuotaDefaultWeight(t *testing.T) {
+	quotas := New(config.FairShareConfig{
+		DefaultWeight: 2,
+		Repositories:  []config.RepositoryQuota{{Name: "acme/docs", Min: 1}},
+	})
+
+	if got := quotas.Quota("acme/api").Weight; got != 2 {
+		t.Errorf("unlisted repository weight = %d, want the default 2", got)
+	}
+	if got := quotas.Quota("acme/docs"); got.Weight != 2 || got.Min != 1 {
+		t.Errorf("listed repository without weight = %+v, want the default weight and its minimum", got)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"sync"
 	"time"
 
+	"Zeno/internal/clock"
 	"Zeno/internal/config"
 )
 
@@ -19,11 +20,15 @@ type Client struct {
 	config     config.GitHubConfig
 	httpClient *http.Client
 	logger     *slog.Logger
+	clock      clock.Clock
 
 	// Cache
 	cache      *queueCache
 	cacheMu    sync.RWMutex
 
+	// baseURL is the GitHub REST API root, overridable in tests
+	baseURL string
+
 	// Rate limit tracking
 	rateLimitRemaining int
 	rateLimitReset     time.Time
@@ -33,6 +38,9 @@ type Client struct {
 type queueCache struct {
 	queuedJobs int
 	timestamp  time.Time
+
+	jobs          []QueuedJob
+	jobsTimestamp time.Time
 }
 
 type WorkflowRunsResponse struct {
@@ -41,9 +49,58 @@ type WorkflowRunsResponse struct {
 }
 
 type WorkflowRun struct {
-	ID     int64  `json:"id"`
-	Status string `json:"status"`
-	Name   string `json:"name"`
+	ID         int64      `json:"id"`
+	Status     string     `json:"status"`
+	Name       string     `json:"name"`
+	JobsURL    string     `json:"jobs_url"`
+	Repository Repository `json:"repository"`
+}
+
+type Repository struct {
+	FullName string `json:"full_name"`
+}
+
+type WorkflowJobsResponse struct {
+	TotalCount int           `json:"total_count"`
+	Jobs       []WorkflowJob `json:"jobs"`
+}
+
+type WorkflowJob struct {
+	ID           int64    `json:"id"`
+	RunID        int64    `json:"run_id"`
+	Status       string   `json:"status"`
+	Name         string   `json:"name"`
+	WorkflowName string   `json:"workflow_name"`
+	Labels       []string `json:"labels"`
+}
+
+// QueuedJob is a single workflow job waiting for a runner
+type QueuedJob struct {
+	ID           int64
+	RunID        int64
+	Name         string
+	WorkflowName string
+	Repository   string
+	Labels       []string
+}
+
+// RegisteredRunner is a self-hosted runner registered with GitHub
+type RegisteredRunner struct {
+	ID     int64         `json:"id"`
+	Name   string        `json:"name"`
+	OS     string        `json:"os"`
+	Status string        `json:"status"`
+	Busy   bool          `json:"busy"`
+	Labels []RunnerLabel `json:"labels"`
+}
+
+type RunnerLabel struct {
+	Name string `json:"name"`
+}
+
+type registeredRunnersResponse struct {
+	TotalCount int                `json:"total_count"`
+	Runners    []RegisteredRunner `json:"runners"`
 }
 
 type RateLimitInfo struct {
@@ -53,16 +110,18 @@ type RateLimitInfo struct {
 }
 
 // NewClient creates a new GitHub API client with retry and caching capabilities
-func NewClient(cfg config.GitHubConfig, logger *slog.Logger) *Client {
+func NewClient(cfg config.GitHubConfig, clk clock.Clock, logger *slog.Logger) *Client {
 	return &Client{
 		config: cfg,
 		httpClient: &http.Client{
 			Timeout: cfg.RequestTimeout,
 		},
 		logger: logger.With("component", "github-client"),
+		clock:  clk,
 		cache: &queueCache{
 			timestamp: time.Time{},
 		},
+		baseURL: "https://api.github.com",
 	}
 }
 
@@ -86,6 +145,74 @@ func (c *Client) GetQueuedWorkflowJobs(ctx context.Context) (int, error) {
 	return queuedJobs, nil
 }
 
+// GetQueuedJobs returns every queued job of every queued or in-progress
+// workflow run.
+// Unlike GetQueuedWorkflowJobs it resolves individual jobs, so callers can
+// track which runner was created for which job.
+func (c *Client) GetQueuedJobs(ctx context.Context) ([]QueuedJob, error) {
+	if cached, ok := c.getCachedJobs(); ok {
+		c.logger.Debug("using cached queued jobs", "queued_jobs", len(cached))
+		return cached, nil
+	}
+
+	var jobs []QueuedJob
+	err := c.withRetry(ctx, func() error {
+		var err error
+		jobs, err = c.fetchQueuedJobList(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.updateJobsCache(jobs)
+
+	return jobs, nil
+}
+
+// QueueDepthByRepository counts queued jobs per repository
+func QueueDepthByRepository(jobs []QueuedJob) map[string]int {
+	depth := make(map[string]int)
+	for _, job := range jobs {
+		depth[job.Repository]++
+	}
+	return depth
+}
+
+// ListRegisteredRunners returns every self-hosted runner registered with
+// the configured organization or repository
+func (c *Client) ListRegisteredRunners(ctx context.Context) ([]RegisteredRunner, error) {
+	var runners []RegisteredRunner
+	for page := 1; ; page++ {
+		var result registeredRunnersResponse
+		err := c.withRetry(ctx, func() error {
+			return c.get(ctx, fmt.Sprintf("%s?per_page=100&page=%d", c.runnersURL(), page), &result)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list registered runners: %w", err)
+		}
+
+		runners = append(runners, result.Runners...)
+		if len(result.Runners) == 0 || len(runners) >= result.TotalCount {
+			break
+		}
+	}
+
+	c.logger.Debug("fetched registered runners", "count", len(runners))
+	return runners, nil
+}
+
+// RemoveRegisteredRunner deletes a runner registration from GitHub
+func (c *Client) RemoveRegisteredRunner(ctx context.Context, id int64) error {
+	err := c.withRetry(ctx, func() error {
+		return c.do(ctx, http.MethodDelete, fmt.Sprintf("%s/%d", c.runnersURL(), id), nil)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove registered runner %d: %w", id, err)
+	}
+	return nil
+}
+
 // GetRateLimitInfo returns current rate limit information
 func (c *Client) GetRateLimitInfo() RateLimitInfo {
 	c.rateLimitMu.RLock()
@@ -98,6 +225,16 @@ func (c *Client) GetRateLimitInfo() RateLimitInfo {
 }
 
 func (c *Client) fetchQueuedJobsWithRetry(ctx context.Context) (int, error) {
+	var queuedJobs int
+	err := c.withRetry(ctx, func() error {
+		var err error
+		queuedJobs, err = c.fetchQueuedJobs(ctx)
+		return err
+	})
+	return queuedJobs, err
+}
+
+func (c *Client) withRetry(ctx context.Context, fn func() error) error {
 	var lastErr error
 
 	for attempt := 0; attempt <= c.config.MaxRetries; attempt++ {
@@ -109,53 +246,171 @@ func (c *Client) fetchQueuedJobsWithRetry(ctx context.Context) (int, error) {
 			)
 
 			select {
-			case <-time.After(backoff):
+			case <-c.clock.After(backoff):
 			case <-ctx.Done():
-				return 0, ctx.Err()
+				return ctx.Err()
 			}
 		}
 
-		queuedJobs, err := c.fetchQueuedJobs(ctx)
+		err := fn()
 		if err == nil {
-			return queuedJobs, nil
+			return nil
 		}
 
 		lastErr = err
 
 		// Don't retry on certain errors
 		if !c.shouldRetry(err) {
-			return 0, err
+			return err
 		}
 	}
 
-	return 0, fmt.Errorf("max retries exceeded: %w", lastErr)
+	return fmt.Errorf("max retries exceeded: %w", lastErr)
 }
 
-func (c *Client) fetchQueuedJobs(ctx context.Context) (int, error) {
-	var url string
+func (c *Client) queuedRunsURL() string {
+	return c.runsURL("queued")
+}
+
+func (c *Client) runsURL(status string) string {
 	if c.config.Organization != "" {
-		url = fmt.Sprintf("https://api.github.com/orgs/%s/actions/runs?status=queued&per_page=100", c.config.Organization)
-	} else {
-		url = fmt.Sprintf("https://api.github.com/repos/%s/actions/runs?status=queued&per_page=100", c.config.Repository)
+		return fmt.Sprintf("%s/orgs/%s/actions/runs?status=%s&per_page=100", c.baseURL, c.config.Organization, status)
+	}
+	return fmt.Sprintf("%s/repos/%s/actions/runs?status=%s&per_page=100", c.baseURL, c.config.Repository, status)
+}
+
+func (c *Client) runnersURL() string {
+	if c.config.Organization != "" {
+		return fmt.Sprintf("%s/orgs/%s/actions/runners", c.baseURL, c.config.Organization)
+	}
+	return fmt.Sprintf("%s/repos/%s/actions/runners", c.baseURL, c.config.Repository)
+}
+
+func (c *Client) fetchQueuedJobs(ctx context.Context) (int, error) {
+	var result WorkflowRunsResponse
+	if err := c.get(ctx, c.queuedRunsURL(), &result); err != nil {
+		return 0, err
+	}
+
+	c.logger.Debug("fetched queued jobs", "count", result.TotalCount)
+	return result.TotalCount, nil
+}
+
+// fetchQueuedJobList lists the queued jobs of queued runs and of runs
+// already in progress, such as a matrix whose first jobs have started
+func (c *Client) fetchQueuedJobList(ctx context.Context) ([]QueuedJob, error) {
+	var runs []WorkflowRun
+	seen := make(map[int64]bool)
+	for _, status := range []string{"queued", "in_progress"} {
+		statusRuns, err := c.listRuns(ctx, status)
+		if err != nil {
+			return nil, err
+		}
+		// A run that started between the two listings shows up in both
+		for _, run := range statusRuns {
+			if !seen[run.ID] {
+				seen[run.ID] = true
+				runs = append(runs, run)
+			}
+		}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	var jobs []QueuedJob
+	for _, run := range runs {
+		if run.JobsURL == "" {
+			continue
+		}
+
+		runJobs, err := c.listRunJobs(ctx, run.JobsURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list jobs for run %d: %w", run.ID, err)
+		}
+
+		repo := run.Repository.FullName
+		if repo == "" {
+			repo = c.config.Repository
+		}
+
+		for _, job := range runJobs {
+			if job.Status != "queued" {
+				continue
+			}
+			workflowName := job.WorkflowName
+			if workflowName == "" {
+				workflowName = run.Name
+			}
+			jobs = append(jobs, QueuedJob{
+				ID:           job.ID,
+				RunID:        run.ID,
+				Name:         job.Name,
+				WorkflowName: workflowName,
+				Repository:   repo,
+				Labels:       job.Labels,
+			})
+		}
+	}
+
+	c.logger.Debug("fetched queued job list", "runs", len(runs), "jobs", len(jobs))
+	return jobs, nil
+}
+
+// listRuns returns every workflow run with the given status
+func (c *Client) listRuns(ctx context.Context, status string) ([]WorkflowRun, error) {
+	var runs []WorkflowRun
+	for page := 1; ; page++ {
+		var result WorkflowRunsResponse
+		if err := c.get(ctx, fmt.Sprintf("%s&page=%d", c.runsURL(status), page), &result); err != nil {
+			return nil, err
+		}
+
+		runs = append(runs, result.WorkflowRuns...)
+		if len(result.WorkflowRuns) == 0 || len(runs) >= result.TotalCount {
+			return runs, nil
+		}
+	}
+}
+
+// listRunJobs returns the latest attempt of every job of a run
+func (c *Client) listRunJobs(ctx context.Context, jobsURL string) ([]WorkflowJob, error) {
+	var jobs []WorkflowJob
+	for page := 1; ; page++ {
+		var result WorkflowJobsResponse
+		if err := c.get(ctx, fmt.Sprintf("%s?filter=latest&per_page=100&page=%d", jobsURL, page), &result); err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, result.Jobs...)
+		if len(result.Jobs) == 0 || len(jobs) >= result.TotalCount {
+			return jobs, nil
+		}
+	}
+}
+
+// get performs an authenticated GET request and decodes the JSON response into v
+func (c *Client) get(ctx context.Context, url string, v interface{}) error {
+	return c.do(ctx, http.MethodGet, url, v)
+}
+
+// do performs an authenticated request and, when v is not nil, decodes the
+// JSON response into it
+func (c *Client) do(ctx context.Context, method, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+c.config.Token)
 	req.Header.Set("Accept", "application/vnd.github+json")
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
-	startTime := time.Now()
+	startTime := c.clock.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return 0, fmt.Errorf("request failed: %w", err)
+		return fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	duration := time.Since(startTime)
+	duration := c.clock.Since(startTime)
 	c.logger.Debug("GitHub API request completed",
 		"status_code", resp.StatusCode,
 		"duration_ms", duration.Milliseconds(),
@@ -167,30 +422,32 @@ func (c *Client) fetchQueuedJobs(ctx context.Context) (int, error) {
 	// Handle rate limiting
 	if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests {
 		resetTime := c.getRateLimitResetTime(resp.Header)
-		waitDuration := time.Until(resetTime)
+		waitDuration := c.clock.Until(resetTime)
 
 		c.logger.Warn("rate limited by GitHub API",
 			"reset_time", resetTime,
 			"wait_duration", waitDuration,
 		)
 
-		return 0, &RateLimitError{
-			ResetTime: resetTime,
+		return &RateLimitError{
+			ResetTime:  resetTime,
 			RetryAfter: waitDuration,
 		}
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	var result WorkflowRunsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return 0, fmt.Errorf("failed to decode response: %w", err)
+	if v == nil {
+		return nil
 	}
 
-	c.logger.Debug("fetched queued jobs", "count", result.TotalCount)
-	return result.TotalCount, nil
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
 }
 
 func (c *Client) calculateBackoff(attempt int) time.Duration {
@@ -228,7 +485,7 @@ func (c *Client) getCachedQueue() (int, bool) {
 	c.cacheMu.RLock()
 	defer c.cacheMu.RUnlock()
 
-	if c.cache == nil || time.Since(c.cache.timestamp) > c.config.CacheTTL {
+	if c.cache == nil || c.clock.Since(c.cache.timestamp) > c.config.CacheTTL {
 		return 0, false
 	}
 
@@ -239,10 +496,27 @@ func (c *Client) updateCache(queuedJobs int) {
 	c.cacheMu.Lock()
 	defer c.cacheMu.Unlock()
 
-	c.cache = &queueCache{
-		queuedJobs: queuedJobs,
-		timestamp:  time.Now(),
+	c.cache.queuedJobs = queuedJobs
+	c.cache.timestamp = c.clock.Now()
+}
+
+func (c *Client) getCachedJobs() ([]QueuedJob, bool) {
+	c.cacheMu.RLock()
+	defer c.cacheMu.RUnlock()
+
+	if c.cache == nil || c.cache.jobsTimestamp.IsZero() || c.clock.Since(c.cache.jobsTimestamp) > c.config.CacheTTL {
+		return nil, false
 	}
+
+	return append([]QueuedJob(nil), c.cache.jobs...), true
+}
+
+func (c *Client) updateJobsCache(jobs []QueuedJob) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+
+	c.cache.jobs = jobs
+	c.cache.jobsTimestamp = c.clock.Now()
 }
 
 func (c *Client) updateRateLimitInfo(headers http.Header) {
@@ -274,7 +548,7 @@ func (c *Client) getRateLimitResetTime(headers http.Header) time.Time {
 	// Check Retry-After header first (for 429 responses)
 	if retryAfter := headers.Get("Retry-After"); retryAfter != "" {
 		if seconds, err := strconv.Atoi(retryAfter); err == nil {
-			return time.Now().Add(time.Duration(seconds) * time.Second)
+			return c.clock.Now().Add(time.Duration(seconds) * time.Second)
 		}
 	}
 
@@ -286,7 +560,7 @@ func (c *Client) getRateLimitResetTime(headers http.Header) time.Time {
 	}
 
 	// Default: retry in 60 seconds
-	return time.Now().Add(60 * time.Second)
+	return c.clock.Now().Add(60 * time.Second)
 }
 
 // RateLimitError represents a rate limiting error
@@ -2,23 +2,41 @@ package github
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
+	"time"
+
+	"Zeno/internal/clock"
+	"Zeno/internal/config"
 )
 
+func newTestClient(org, repo string) *Client {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	return NewClient(config.GitHubConfig{
+		Token:            "test-token",
+		Organization:     org,
+		Repository:       repo,
+		RequestTimeout:   5 * time.Second,
+		RetryBackoffBase: time.Millisecond,
+		RetryBackoffMax:  time.Millisecond,
+	}, clock.Real(), logger)
+}
+
 func TestNewClient(t *testing.T) {
-	client := NewClient("token", "org", "repo")
+	client := newTestClient("org", "repo")
 	if client == nil {
 		t.Fatal("NewClient() returned nil")
 	}
 
-	if client.token != "token" {
-		t.Errorf("expected token='token', got %s", client.token)
+	if client.config.Token != "test-token" {
+		t.Errorf("expected token='test-token', got %s", client.config.Token)
 	}
 
-	if client.org != "org" {
-		t.Errorf("expected org='org', got %s", client.org)
+	if client.config.Organization != "org" {
+		t.Errorf("expected org='org', got %s", client.config.Organization)
 	}
 }
 
@@ -39,11 +57,15 @@ func TestGetQueuedWorkflowJobsSuccess(t *testing.T) {
 	}))
 	defer server.Close()
 
-	// Note: This test would need modification to actually use the test server
-	// For now, it demonstrates the test structure
-	client := NewClient("test-token", "test-org", "")
-	if client == nil {
-		t.Fatal("NewClient() returned nil")
+	client := newTestClient("test-org", "")
+	client.baseURL = server.URL
+
+	got, err := client.GetQueuedWorkflowJobs(context.Background())
+	if err != nil {
+		t.Fatalf("GetQueuedWorkflowJobs() error = %v", err)
+	}
+	if got != 5 {
+		t.Errorf("GetQueuedWorkflowJobs() = %d, want 5", got)
 	}
 }
 
@@ -70,20 +92,23 @@ func TestGetQueuedWorkflowJobsOrgVsRepo(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_ = tt.wantPath // Will use this when we refactor client to be testable
+			var gotPath string
 			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
 				w.WriteHeader(http.StatusOK)
 				w.Write([]byte(`{"total_count": 0}`))
 			}))
 			defer server.Close()
 
-			client := NewClient("token", tt.org, tt.repo)
-			// Modify client to use test server
-			client.client = server.Client()
+			client := newTestClient(tt.org, tt.repo)
+			client.baseURL = server.URL
 
-			// This will fail in actual execution since URL is hardcoded
-			// but demonstrates the test pattern
-			_, _ = client.GetQueuedWorkflowJobs(context.Background())
+			if _, err := client.GetQueuedWorkflowJobs(context.Background()); err != nil {
+				t.Fatalf("GetQueuedWorkflowJobs() error = %v", err)
+			}
+			if gotPath != tt.wantPath {
+				t.Errorf("request path = %s, want %s", gotPath, tt.wantPath)
+			}
 		})
 	}
 }
@@ -94,12 +119,11 @@ func TestGetQueuedWorkflowJobsError(t *testing.T) {
 	}))
 	defer server.Close()
 
-	client := NewClient("bad-token", "test-org", "")
+	client := newTestClient("test-org", "")
+	client.baseURL = server.URL
 
-	// This demonstrates error handling test structure
-	// Actual implementation would need server URL override
-	if client == nil {
-		t.Fatal("NewClient() returned nil")
+	if _, err := client.GetQueuedWorkflowJobs(context.Background()); err == nil {
+		t.Error("GetQueuedWorkflowJobs() expected error for 401 response")
 	}
 }
 
@@ -110,9 +134,168 @@ func TestGetQueuedWorkflowJobsInvalidJSON(t *testing.T) {
 	}))
 	defer server.Close()
 
-	// Test would verify JSON parsing error handling
-	client := NewClient("token", "org", "")
-	if client == nil {
-		t.Fatal("NewClient() returned nil")
+	client := newTestClient("test-org", "")
+	client.baseURL = server.URL
+
+	if _, err := client.GetQueuedWorkflowJobs(context.Background()); err == nil {
+		t.Error("GetQueuedWorkflowJobs() expected error for invalid JSON")
+	}
+}
+
+func TestGetQueuedJobs(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Path {
+		case "/orgs/test-org/actions/runs":
+			switch r.URL.Query().Get("status") {
+			case "queued":
+				w.Write([]byte(`{"total_count": 1, "workflow_runs": [
+					{"id": 10, "status": "queued", "name": "CI",
+					 "jobs_url": "` + server.URL + `/repos/test-org/app/actions/runs/10/jobs",
+					 "repository": {"full_name": "test-org/app"}}
+				]}`))
+			case "in_progress":
+				// A matrix that has started some of its jobs
+				w.Write([]byte(`{"total_count": 1, "workflow_runs": [
+					{"id": 20, "status": "in_progress", "name": "Matrix",
+					 "jobs_url": "` + server.URL + `/repos/test-org/app/actions/runs/20/jobs",
+					 "repository": {"full_name": "test-org/app"}}
+				]}`))
+			default:
+				t.Errorf("unexpected run status: %s", r.URL.RawQuery)
+			}
+		case "/repos/test-org/app/actions/runs/10/jobs":
+			w.Write([]byte(`{"total_count": 2, "jobs": [
+				{"id": 100, "run_id": 10, "status": "queued", "name": "build", "labels": ["self-hosted"]},
+				{"id": 101, "run_id": 10, "status": "in_progress", "name": "lint"}
+			]}`))
+		case "/repos/test-org/app/actions/runs/20/jobs":
+			// Two pages of jobs
+			if r.URL.Query().Get("page") == "1" {
+				w.Write([]byte(`{"total_count": 2, "jobs": [
+					{"id": 200, "run_id": 20, "status": "in_progress", "name": "test (1)"}
+				]}`))
+				return
+			}
+			w.Write([]byte(`{"total_count": 2, "jobs": [
+				{"id": 201, "run_id": 20, "status": "queued", "name": "test (2)"}
+			]}`))
+		default:
+			t.Errorf("unexpected request path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient("test-org", "")
+	client.baseURL = server.URL
+
+	jobs, err := client.GetQueuedJobs(context.Background())
+	if err != nil {
+		t.Fatalf("GetQueuedJobs() error = %v", err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("GetQueuedJobs() returned %d jobs, want 2", len(jobs))
+	}
+	if jobs[1].ID != 201 || jobs[1].RunID != 20 || jobs[1].WorkflowName != "Matrix" {
+		t.Errorf("jobs[1] = %+v, want the queued job 201 from the second page of in-progress run 20", jobs[1])
+	}
+
+	job := jobs[0]
+	if job.ID != 100 || job.RunID != 10 {
+		t.Errorf("job = %+v, want ID 100 in run 10", job)
+	}
+	if job.Repository != "test-org/app" {
+		t.Errorf("job.Repository = %s, want test-org/app", job.Repository)
+	}
+	if job.WorkflowName != "CI" {
+		t.Errorf("job.WorkflowName = %s, want CI", job.WorkflowName)
+	}
+}
+
+func TestQueueCacheExpiresWithClock(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"total_count": 3}`))
+	}))
+	defer server.Close()
+
+	clk := clock.NewFake(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	client := NewClient(config.GitHubConfig{
+		Token:          "test-token",
+		Organization:   "test-org",
+		RequestTimeout: 5 * time.Second,
+		CacheTTL:       30 * time.Second,
+	}, clk, slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError})))
+	client.baseURL = server.URL
+
+	ctx := context.Background()
+	for _, step := range []struct {
+		advance      time.Duration
+		wantRequests int
+	}{
+		{0, 1},
+		{30 * time.Second, 1},
+		{time.Second, 2},
+	} {
+		clk.Advance(step.advance)
+		if _, err := client.GetQueuedWorkflowJobs(ctx); err != nil {
+			t.Fatalf("GetQueuedWorkflowJobs() error = %v", err)
+		}
+		if requests != step.wantRequests {
+			t.Errorf("after %v: %d requests, want %d", step.advance, requests, step.wantRequests)
+		}
+	}
+}
+
+func TestListAndRemoveRegisteredRunners(t *testing.T) {
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/test-org/app/actions/runners":
+			// Two pages of one runner each
+			if r.URL.Query().Get("page") == "1" {
+				w.Write([]byte(`{"total_count": 2, "runners": [
+					{"id": 1, "name": "zeno-runner-1", "os": "linux", "status": "online", "busy": true, "labels": [{"name": "self-hosted"}]}
+				]}`))
+				return
+			}
+			w.Write([]byte(`{"total_count": 2, "runners": [
+				{"id": 2, "name": "zeno-runner-2", "os": "linux", "status": "offline", "busy": false}
+			]}`))
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient("", "test-org/app")
+	client.baseURL = server.URL
+
+	runners, err := client.ListRegisteredRunners(context.Background())
+	if err != nil {
+		t.Fatalf("ListRegisteredRunners() error = %v", err)
+	}
+	if len(runners) != 2 {
+		t.Fatalf("ListRegisteredRunners() returned %d runners, want 2", len(runners))
+	}
+	if r := runners[0]; r.Name != "zeno-runner-1" || !r.Busy || r.Labels[0].Name != "self-hosted" {
+		t.Errorf("runners[0] = %+v", r)
+	}
+	if r := runners[1]; r.ID != 2 || r.Status != "offline" {
+		t.Errorf("runners[1] = %+v", r)
+	}
+
+	if err := client.RemoveRegisteredRunner(context.Background(), 2); err != nil {
+		t.Fatalf("RemoveRegisteredRunner() error = %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != "/repos/test-org/app/actions/runners/2" {
+		t.Errorf("deleted = %v, want the runner 2 registration", deleted)
 	}
 }
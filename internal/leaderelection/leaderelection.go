@@ -7,11 +7,14 @@ import (
 	"os"
 	"syscall"
 	"time"
+
+	"Zeno/internal/clock"
 )
 
 type LeaderElector struct {
 	config LeaderElectionConfig
 	logger *slog.Logger
+	clock  clock.Clock
 	lockFd int
 	isLeader bool
 }
@@ -25,10 +28,11 @@ type LeaderElectionConfig struct {
 }
 
 // New creates a new leader elector
-func New(cfg LeaderElectionConfig, logger *slog.Logger) *LeaderElector {
+func New(cfg LeaderElectionConfig, clk clock.Clock, logger *slog.Logger) *LeaderElector {
 	return &LeaderElector{
 		config:   cfg,
 		logger:   logger.With("component", "leader-election"),
+		clock:    clk,
 		lockFd:   -1,
 		isLeader: false,
 	}
@@ -49,7 +53,7 @@ func (le *LeaderElector) Run(ctx context.Context, onStartLeading, onStopLeading
 		"lease_duration", le.config.LeaseDuration,
 	)
 
-	ticker := time.NewTicker(le.config.RetryPeriod)
+	ticker := le.clock.NewTicker(le.config.RetryPeriod)
 	defer ticker.Stop()
 
 	for {
@@ -61,7 +65,7 @@ func (le *LeaderElector) Run(ctx context.Context, onStartLeading, onStopLeading
 			}
 			return nil
 
-		case <-ticker.C:
+		case <-ticker.C():
 			acquired, err := le.tryAcquireLock()
 			if err != nil {
 				le.logger.Error("failed to acquire lock", "error", err)
@@ -87,6 +91,12 @@ func (le *LeaderElector) IsLeader() bool {
 }
 
 func (le *LeaderElector) tryAcquireLock() (bool, error) {
+	// flock is held for as long as our descriptor stays open. Locking the
+	// file again through a new descriptor would conflict with ourselves.
+	if le.lockFd >= 0 {
+		return true, nil
+	}
+
 	// Try to open/create lock file
 	fd, err := syscall.Open(le.config.LockFilePath, syscall.O_CREAT|syscall.O_RDWR, 0644)
 	if err != nil {
@@ -110,11 +120,6 @@ func (le *LeaderElector) tryAcquireLock() (bool, error) {
 		return false, fmt.Errorf("failed to write PID: %w", err)
 	}
 
-	// Release old lock if we had one
-	if le.lockFd >= 0 {
-		syscall.Close(le.lockFd)
-	}
-
 	le.lockFd = fd
 	return true, nil
 }
@@ -0,0 +1,89 @@
+package leaderelection
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"Zeno/internal/clock"
+)
+
+func newTestElector(t *testing.T, path string, clk clock.Clock) *LeaderElector {
+	t.Helper()
+
+	return New(LeaderElectionConfig{
+		Enabled:       true,
+		LockFilePath:  path,
+		LeaseDuration: 15 * time.Second,
+		RenewDeadline: 10 * time.Second,
+		RetryPeriod:   2 * time.Second,
+	}, clk, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestRunAcquiresLeadershipOnRetryTick(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	le := newTestElector(t, filepath.Join(t.TempDir(), "leader.lock"), clk)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	stopped := make(chan struct{}, 1)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- le.Run(ctx,
+			func(ctx context.Context) { close(started) },
+			func(ctx context.Context) { stopped <- struct{}{} },
+		)
+	}()
+
+	clk.BlockUntil(1)
+	select {
+	case <-started:
+		t.Fatal("became leader before the first retry tick")
+	default:
+	}
+
+	clk.Advance(2 * time.Second)
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("did not become leader after the retry tick")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Error("onStopLeading not called on shutdown")
+	}
+}
+
+func TestLeaderKeepsLockAcrossRenewals(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	path := filepath.Join(t.TempDir(), "leader.lock")
+	leader := newTestElector(t, path, clk)
+	follower := newTestElector(t, path, clk)
+
+	for i := 0; i < 3; i++ {
+		acquired, err := leader.tryAcquireLock()
+		if err != nil || !acquired {
+			t.Fatalf("renewal %d: tryAcquireLock() = %v, %v, want true", i, acquired, err)
+		}
+	}
+
+	if acquired, err := follower.tryAcquireLock(); err != nil || acquired {
+		t.Fatalf("follower tryAcquireLock() = %v, %v, want false", acquired, err)
+	}
+
+	leader.release()
+	if acquired, err := follower.tryAcquireLock(); err != nil || !acquired {
+		t.Errorf("follower tryAcquireLock() after release = %v, %v, want true", acquired, err)
+	}
+	follower.release()
+}
@@ -34,6 +34,9 @@ type Metrics struct {
 	QueueDepth           prometheus.Gauge
 	QueueDepthSamples    prometheus.Histogram
 	WaitingJobs          prometheus.Gauge
+	RepositoryQueueDepth *prometheus.GaugeVec
+	RepositoryAllocation *prometheus.GaugeVec
+	PriorityQueueDepth   *prometheus.GaugeVec
 
 	// GitHub API metrics
 	GitHubAPIRequests    *prometheus.CounterVec
@@ -45,10 +48,23 @@ type Metrics struct {
 	ProviderOperations   *prometheus.CounterVec
 	ProviderDuration     *prometheus.HistogramVec
 	ProviderErrors       *prometheus.CounterVec
+	ProviderCircuitState *prometheus.GaugeVec
+	RunnersAdopted       *prometheus.CounterVec
+	RunnerInterruptions  *prometheus.CounterVec
+
+	// Budget metrics
+	BudgetHourlySpend    prometheus.Gauge
+	BudgetDailySpend     prometheus.Gauge
+	BudgetSpendTotal     prometheus.Counter
+	BudgetExhausted      prometheus.Gauge
+
+	// Notification metrics
+	NotificationsTotal   *prometheus.CounterVec
 
 	// System metrics
 	ControllerInfo       *prometheus.GaugeVec
 	LeaderElection       prometheus.Gauge
+	ConfigReloads        *prometheus.CounterVec
 }
 
 // NewMetrics creates and registers all Prometheus metrics
@@ -184,6 +200,30 @@ func NewMetrics(registry *prometheus.Registry) *Metrics {
 				Help:      "Number of jobs waiting for runners",
 			},
 		),
+		RepositoryQueueDepth: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "repository_queue_depth",
+				Help:      "Queued workflow jobs per repository (fair share only)",
+			},
+			[]string{"repository"},
+		),
+		RepositoryAllocation: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "repository_runners_allocated",
+				Help:      "Runners each repository may hold under its fair share",
+			},
+			[]string{"repository"},
+		),
+		PriorityQueueDepth: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "priority_queue_depth",
+				Help:      "Queued workflow jobs per priority class",
+			},
+			[]string{"class"},
+		),
 
 		// GitHub API metrics
 		GitHubAPIRequests: factory.NewCounterVec(
@@ -243,6 +283,72 @@ func NewMetrics(registry *prometheus.Registry) *Metrics {
 			},
 			[]string{"provider", "operation", "error_type"},
 		),
+		ProviderCircuitState: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "provider_circuit_state",
+				Help:      "Provider circuit breaker state per operation (0 closed, 1 half-open, 2 open)",
+			},
+			[]string{"provider", "operation"},
+		),
+
+		// Budget metrics
+		BudgetHourlySpend: factory.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "budget_hourly_spend_dollars",
+				Help:      "Runner cost accrued in the current UTC hour",
+			},
+		),
+		BudgetDailySpend: factory.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "budget_daily_spend_dollars",
+				Help:      "Runner cost accrued in the current UTC day",
+			},
+		),
+		BudgetSpendTotal: factory.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "budget_spend_dollars_total",
+				Help:      "Total runner cost accrued since start",
+			},
+		),
+		BudgetExhausted: factory.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "budget_exhausted",
+				Help:      "Whether the spend budget is exhausted (1 if exhausted, 0 otherwise)",
+			},
+		),
+
+		RunnersAdopted: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "runners_adopted_total",
+				Help:      "Runners found at startup by outcome: adopted, orphan_removed or registration_removed",
+			},
+			[]string{"result"},
+		),
+
+		RunnerInterruptions: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "runner_interruptions_total",
+				Help:      "Runners flagged as draining because their machine is about to be reclaimed, by signal, instance type and availability zone",
+			},
+			[]string{"signal", "instance_type", "az"},
+		),
+
+		// Notification metrics
+		NotificationsTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "notifications_total",
+				Help:      "Total number of notifications by webhook, event type and result",
+			},
+			[]string{"webhook", "event", "result"},
+		),
 
 		// System metrics
 		ControllerInfo: factory.NewGaugeVec(
@@ -260,6 +366,14 @@ func NewMetrics(registry *prometheus.Registry) *Metrics {
 				Help:      "Leader election status (1 if leader, 0 otherwise)",
 			},
 		),
+		ConfigReloads: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "config_reload_total",
+				Help:      "Configuration reloads by result: applied or rejected",
+			},
+			[]string{"result"},
+		),
 	}
 
 	return m
@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+var templateFuncs = template.FuncMap{
+	// json renders a value as a JSON literal, for embedding event text in
+	// custom payloads safely
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// render builds the request body for a webhook. A custom template wins over
+// the format; the default format is the event itself as JSON.
+func render(hook *webhook, ev Event) ([]byte, error) {
+	if hook.tmpl != nil {
+		var buf bytes.Buffer
+		if err := hook.tmpl.Execute(&buf, ev); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	switch hook.Format {
+	case "slack":
+		return json.Marshal(slackPayload(ev))
+	case "teams":
+		return json.Marshal(teamsPayload(ev))
+	default:
+		return json.Marshal(ev)
+	}
+}
+
+// slackPayload is an incoming webhook message for Slack
+func slackPayload(ev Event) map[string]interface{} {
+	var text strings.Builder
+	fmt.Fprintf(&text, "*[%s] %s*\n%s", strings.ToUpper(ev.Severity), ev.Title, ev.Message)
+	for _, k := range sortedKeys(ev.Fields) {
+		fmt.Fprintf(&text, "\n• %s: %s", k, ev.Fields[k])
+	}
+
+	return map[string]interface{}{
+		"text": text.String(),
+	}
+}
+
+// teamsPayload is a MessageCard for Microsoft Teams incoming webhooks
+func teamsPayload(ev Event) map[string]interface{} {
+	facts := make([]map[string]string, 0, len(ev.Fields))
+	for _, k := range sortedKeys(ev.Fields) {
+		facts = append(facts, map[string]string{"name": k, "value": ev.Fields[k]})
+	}
+
+	return map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    ev.Title,
+		"themeColor": themeColor(ev.Severity),
+		"title":      ev.Title,
+		"text":       ev.Message,
+		"sections": []map[string]interface{}{
+			{"facts": facts},
+		},
+	}
+}
+
+func themeColor(severity string) string {
+	switch severity {
+	case SeverityCritical:
+		return "D13438"
+	case SeverityWarning:
+		return "FFB900"
+	default:
+		return "0078D7"
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
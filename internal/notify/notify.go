@@ -0,0 +1,348 @@
+// Package notify delivers operational events to outbound webhooks.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"Zeno/internal/clock"
+	"Zeno/internal/config"
+	"Zeno/internal/metrics"
+)
+
+// Event types. Scale event types match store.ScaleEvent actions.
+const (
+	EventScaleUp            = "scale_up"
+	EventScaleDown          = "scale_down"
+	EventRunnerCollected    = "collect"
+	EventMaxRunnersReached  = "max_runners_reached"
+	EventProviderError      = "provider_error"
+	EventCreationFailures   = "creation_failures"
+	EventRateLimitExhausted = "rate_limit_exhausted"
+)
+
+// Severities
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the body when a webhook
+// has a secret configured
+const SignatureHeader = "X-Zeno-Signature-256"
+
+// Event is a notification about something Zeno did or ran into
+type Event struct {
+	Type      string            `json:"type"`
+	Severity  string            `json:"severity"`
+	Title     string            `json:"title"`
+	Message   string            `json:"message"`
+	Timestamp time.Time         `json:"timestamp"`
+	Fields    map[string]string `json:"fields,omitempty"`
+
+	// Key distinguishes events of the same type for deduplication.
+	// Events with the same type and key inside the dedup window are dropped.
+	Key string `json:"-"`
+}
+
+type webhook struct {
+	config.WebhookConfig
+	events map[string]bool
+	tmpl   *template.Template
+}
+
+// Notifier fans events out to the configured webhooks in the background.
+// A nil *Notifier is valid and discards every event.
+type Notifier struct {
+	config   config.NotificationsConfig
+	hooks    []*webhook
+	client   *http.Client
+	clock    clock.Clock
+	metrics  *metrics.Metrics
+	logger   *slog.Logger
+	queue    chan Event
+	lastSent map[string]time.Time
+	pending  int           // events queued or being delivered
+	idle     chan struct{} // closed when pending drops to zero
+	mu       sync.Mutex
+}
+
+// New creates a notifier, or returns nil when notifications are disabled
+func New(cfg config.NotificationsConfig, clk clock.Clock, met *metrics.Metrics, logger *slog.Logger) (*Notifier, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	hooks, err := buildHooks(cfg.Webhooks)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Notifier{
+		config:   cfg,
+		hooks:    hooks,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		clock:    clk,
+		metrics:  met,
+		logger:   logger.With("component", "notifier"),
+		queue:    make(chan Event, max(cfg.QueueSize, 1)),
+		lastSent: make(map[string]time.Time),
+	}, nil
+}
+
+// Reconfigure replaces the webhooks, dedup windows, retries and timeout.
+// Events already being delivered finish with the old settings. The queue
+// size is fixed when the notifier is created.
+func (n *Notifier) Reconfigure(cfg config.NotificationsConfig) error {
+	if n == nil {
+		return nil
+	}
+
+	hooks, err := buildHooks(cfg.Webhooks)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.config = cfg
+	n.hooks = hooks
+	n.client = &http.Client{Timeout: cfg.Timeout}
+	return nil
+}
+
+func buildHooks(configs []config.WebhookConfig) ([]*webhook, error) {
+	var hooks []*webhook
+	for i, hc := range configs {
+		hook := &webhook{WebhookConfig: hc}
+		if hook.Name == "" {
+			hook.Name = fmt.Sprintf("webhook-%d", i)
+		}
+		if len(hc.Events) > 0 {
+			hook.events = make(map[string]bool, len(hc.Events))
+			for _, e := range hc.Events {
+				hook.events[e] = true
+			}
+		}
+		if hc.Template != "" {
+			tmpl, err := template.New(hook.Name).Funcs(templateFuncs).Parse(hc.Template)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse template for webhook %s: %w", hook.Name, err)
+			}
+			hook.tmpl = tmpl
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+// Notify queues an event for delivery without blocking. Duplicates within
+// the dedup window and events that do not fit in the queue are dropped.
+func (n *Notifier) Notify(ev Event) {
+	if n == nil {
+		return
+	}
+
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = n.clock.Now()
+	}
+	if ev.Severity == "" {
+		ev.Severity = SeverityInfo
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.duplicateLocked(ev) {
+		n.logger.Debug("notification deduplicated", "type", ev.Type, "key", ev.Key)
+		n.count("", ev.Type, "deduplicated")
+		return
+	}
+
+	select {
+	case n.queue <- ev:
+		n.lastSent[ev.Type+"/"+ev.Key] = ev.Timestamp
+		if n.pending == 0 {
+			n.idle = make(chan struct{})
+		}
+		n.pending++
+	default:
+		n.logger.Warn("notification queue full, dropping event", "type", ev.Type)
+		n.count("", ev.Type, "dropped")
+	}
+}
+
+// Run delivers queued events until ctx is cancelled
+func (n *Notifier) Run(ctx context.Context) {
+	if n == nil {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-n.queue:
+			n.deliver(ctx, ev)
+			n.delivered()
+		}
+	}
+}
+
+// Flush waits until every queued event has been delivered, or has failed,
+// or until ctx ends. Run must keep running until Flush returns.
+func (n *Notifier) Flush(ctx context.Context) error {
+	if n == nil {
+		return nil
+	}
+
+	n.mu.Lock()
+	if n.pending == 0 {
+		n.mu.Unlock()
+		return nil
+	}
+	idle := n.idle
+	n.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (n *Notifier) delivered() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.pending--
+	if n.pending == 0 {
+		close(n.idle)
+	}
+}
+
+// dedupWindow returns the dedup window for an event type
+func (n *Notifier) dedupWindow(eventType string) time.Duration {
+	if w, ok := n.config.DedupWindows[eventType]; ok {
+		return w
+	}
+	return n.config.DedupWindow
+}
+
+// duplicateLocked reports whether an identical event was queued within the
+// dedup window for its type. Entries outside their window are dropped on
+// the way, since keys such as those of scale events are rarely repeated.
+func (n *Notifier) duplicateLocked(ev Event) bool {
+	for key, last := range n.lastSent {
+		eventType, _, _ := strings.Cut(key, "/")
+		if ev.Timestamp.Sub(last) >= n.dedupWindow(eventType) {
+			delete(n.lastSent, key)
+		}
+	}
+
+	_, ok := n.lastSent[ev.Type+"/"+ev.Key]
+	return ok
+}
+
+func (n *Notifier) deliver(ctx context.Context, ev Event) {
+	n.mu.Lock()
+	hooks, cfg, client := n.hooks, n.config, n.client
+	n.mu.Unlock()
+
+	for _, hook := range hooks {
+		if hook.events != nil && !hook.events[ev.Type] {
+			continue
+		}
+
+		if err := n.send(ctx, client, cfg, hook, ev); err != nil {
+			n.logger.Error("failed to deliver notification",
+				"webhook", hook.Name,
+				"type", ev.Type,
+				"error", err,
+			)
+			n.count(hook.Name, ev.Type, "failed")
+			continue
+		}
+		n.count(hook.Name, ev.Type, "sent")
+	}
+}
+
+// send posts ev to a webhook, retrying with exponential backoff
+func (n *Notifier) send(ctx context.Context, client *http.Client, cfg config.NotificationsConfig, hook *webhook, ev Event) error {
+	body, err := render(hook, ev)
+	if err != nil {
+		return fmt.Errorf("failed to render payload: %w", err)
+	}
+
+	var lastErr error
+	backoff := cfg.RetryBackoff
+	for attempt := 0; attempt <= cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-n.clock.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			backoff *= 2
+		}
+
+		lastErr = post(ctx, client, hook, ev, body)
+		if lastErr == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("giving up after %d attempts: %w", cfg.MaxRetries+1, lastErr)
+}
+
+func post(ctx context.Context, client *http.Client, hook *webhook, ev Event, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "zeno-notifier")
+	req.Header.Set("X-Zeno-Event", ev.Type)
+	if hook.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(hook.Secret, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of body keyed with secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (n *Notifier) count(hook, eventType, result string) {
+	if n.metrics == nil {
+		return
+	}
+	n.metrics.NotificationsTotal.WithLabelValues(hook, eventType, result).Inc()
+}
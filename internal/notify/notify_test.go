@@ -0,0 +1,422 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"Zeno/internal/clock"
+	"Zeno/internal/config"
+	"Zeno/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type request struct {
+	header http.Header
+	body   []byte
+}
+
+// recorder is a webhook endpoint that fails the first failures requests
+type recorder struct {
+	mu       sync.Mutex
+	requests []request
+	failures int
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests = append(r.requests, request{header: req.Header.Clone(), body: body})
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *recorder) received() []request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]request(nil), r.requests...)
+}
+
+var testStart = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestNotifier(t *testing.T, cfg config.NotificationsConfig) (*Notifier, *clock.Fake, *metrics.Metrics) {
+	t.Helper()
+
+	cfg.Enabled = true
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.QueueSize == 0 {
+		cfg.QueueSize = 10
+	}
+
+	clk := clock.NewFake(testStart)
+	met := metrics.NewMetrics(prometheus.NewRegistry())
+	n, err := New(cfg, clk, met, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return n, clk, met
+}
+
+// drain delivers everything queued so far on the calling goroutine
+func drain(n *Notifier) {
+	for {
+		select {
+		case ev := <-n.queue:
+			n.deliver(context.Background(), ev)
+			n.delivered()
+		default:
+			return
+		}
+	}
+}
+
+func TestNewDisabledReturnsNil(t *testing.T) {
+	n, err := New(config.NotificationsConfig{}, clock.Real(), nil, slog.Default())
+	if err != nil || n != nil {
+		t.Fatalf("New() = %v, %v, want nil, nil", n, err)
+	}
+
+	// A nil notifier discards events
+	n.Notify(Event{Type: EventScaleUp})
+}
+
+func TestNewRejectsBadTemplate(t *testing.T) {
+	_, err := New(config.NotificationsConfig{
+		Enabled:  true,
+		Webhooks: []config.WebhookConfig{{URL: "http://example.invalid", Template: "{{.Title"}},
+	}, clock.Real(), nil, slog.Default())
+	if err == nil {
+		t.Fatal("New() error = nil, want template parse error")
+	}
+}
+
+func TestDeliverSignsPayload(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	n, _, met := newTestNotifier(t, config.NotificationsConfig{
+		Webhooks: []config.WebhookConfig{{Name: "ops", URL: srv.URL, Secret: "s3cret"}},
+	})
+
+	n.Notify(Event{Type: EventScaleUp, Title: "Runners scale_up: 1 -> 3", Fields: map[string]string{"reason": "queue_above_threshold"}})
+	drain(n)
+
+	reqs := rec.received()
+	if len(reqs) != 1 {
+		t.Fatalf("received %d requests, want 1", len(reqs))
+	}
+
+	if got, want := reqs[0].header.Get(SignatureHeader), "sha256="+Sign("s3cret", reqs[0].body); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	if got := reqs[0].header.Get("X-Zeno-Event"); got != EventScaleUp {
+		t.Errorf("X-Zeno-Event = %q, want %q", got, EventScaleUp)
+	}
+
+	var ev Event
+	if err := json.Unmarshal(reqs[0].body, &ev); err != nil {
+		t.Fatalf("payload is not an event: %v", err)
+	}
+	if ev.Severity != SeverityInfo || !ev.Timestamp.Equal(testStart) || ev.Fields["reason"] != "queue_above_threshold" {
+		t.Errorf("payload = %+v", ev)
+	}
+
+	if got := testutil.ToFloat64(met.NotificationsTotal.WithLabelValues("ops", EventScaleUp, "sent")); got != 1 {
+		t.Errorf("sent notifications = %v, want 1", got)
+	}
+}
+
+func TestRenderFormats(t *testing.T) {
+	ev := Event{
+		Type:     EventCreationFailures,
+		Severity: SeverityCritical,
+		Title:    "3 consecutive runner creation failures",
+		Message:  "quota exceeded",
+		Fields:   map[string]string{"provider": "ec2"},
+	}
+
+	tests := []struct {
+		name  string
+		hook  config.WebhookConfig
+		check func(t *testing.T, payload map[string]interface{})
+	}{
+		{
+			name: "slack",
+			hook: config.WebhookConfig{Format: "slack"},
+			check: func(t *testing.T, payload map[string]interface{}) {
+				want := "*[CRITICAL] 3 consecutive runner creation failures*\nquota exceeded\n• provider: ec2"
+				if payload["text"] != want {
+					t.Errorf("text = %q, want %q", payload["text"], want)
+				}
+			},
+		},
+		{
+			name: "teams",
+			hook: config.WebhookConfig{Format: "teams"},
+			check: func(t *testing.T, payload map[string]interface{}) {
+				if payload["@type"] != "MessageCard" || payload["themeColor"] != "D13438" || payload["text"] != "quota exceeded" {
+					t.Errorf("payload = %v", payload)
+				}
+			},
+		},
+		{
+			name: "template",
+			hook: config.WebhookConfig{Template: `{"summary": {{json .Title}}, "level": "{{.Severity}}"}`},
+			check: func(t *testing.T, payload map[string]interface{}) {
+				if payload["summary"] != ev.Title || payload["level"] != SeverityCritical {
+					t.Errorf("payload = %v", payload)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{}
+			srv := httptest.NewServer(rec)
+			defer srv.Close()
+
+			tt.hook.URL = srv.URL
+			n, _, _ := newTestNotifier(t, config.NotificationsConfig{Webhooks: []config.WebhookConfig{tt.hook}})
+			n.Notify(ev)
+			drain(n)
+
+			reqs := rec.received()
+			if len(reqs) != 1 {
+				t.Fatalf("received %d requests, want 1", len(reqs))
+			}
+			var payload map[string]interface{}
+			if err := json.Unmarshal(reqs[0].body, &payload); err != nil {
+				t.Fatalf("payload is not JSON: %v\n%s", err, reqs[0].body)
+			}
+			tt.check(t, payload)
+		})
+	}
+}
+
+func TestDeliverFiltersEvents(t *testing.T) {
+	all, alerts := &recorder{}, &recorder{}
+	allSrv, alertsSrv := httptest.NewServer(all), httptest.NewServer(alerts)
+	defer allSrv.Close()
+	defer alertsSrv.Close()
+
+	n, _, _ := newTestNotifier(t, config.NotificationsConfig{
+		Webhooks: []config.WebhookConfig{
+			{Name: "all", URL: allSrv.URL},
+			{Name: "alerts", URL: alertsSrv.URL, Events: []string{EventCreationFailures, EventRateLimitExhausted}},
+		},
+	})
+
+	n.Notify(Event{Type: EventScaleUp})
+	n.Notify(Event{Type: EventRateLimitExhausted})
+	drain(n)
+
+	if got := len(all.received()); got != 2 {
+		t.Errorf("unfiltered webhook received %d events, want 2", got)
+	}
+	reqs := alerts.received()
+	if len(reqs) != 1 || reqs[0].header.Get("X-Zeno-Event") != EventRateLimitExhausted {
+		t.Errorf("filtered webhook received %d events, want only %s", len(reqs), EventRateLimitExhausted)
+	}
+}
+
+func TestReconfigureSwapsWebhooks(t *testing.T) {
+	old, replacement := &recorder{}, &recorder{}
+	oldSrv, replacementSrv := httptest.NewServer(old), httptest.NewServer(replacement)
+	defer oldSrv.Close()
+	defer replacementSrv.Close()
+
+	n, _, _ := newTestNotifier(t, config.NotificationsConfig{
+		Webhooks: []config.WebhookConfig{{Name: "old", URL: oldSrv.URL}},
+	})
+
+	if err := n.Reconfigure(config.NotificationsConfig{
+		Webhooks: []config.WebhookConfig{{Name: "bad", URL: replacementSrv.URL, Template: "{{"}},
+	}); err == nil {
+		t.Fatal("Reconfigure() accepted an invalid template")
+	}
+
+	n.Notify(Event{Type: EventScaleUp})
+	drain(n)
+	if got := len(old.received()); got != 1 {
+		t.Fatalf("old webhook received %d events after a rejected reconfigure, want 1", got)
+	}
+
+	if err := n.Reconfigure(config.NotificationsConfig{
+		Timeout:  time.Second,
+		Webhooks: []config.WebhookConfig{{Name: "new", URL: replacementSrv.URL}},
+	}); err != nil {
+		t.Fatalf("Reconfigure() error = %v", err)
+	}
+
+	n.Notify(Event{Type: EventRateLimitExhausted})
+	drain(n)
+	if got := len(old.received()); got != 1 {
+		t.Errorf("old webhook received %d events, want none after reconfigure", got-1)
+	}
+	if got := len(replacement.received()); got != 1 {
+		t.Errorf("new webhook received %d events, want 1", got)
+	}
+}
+
+func TestNotifyDeduplicatesWithinWindow(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	n, clk, met := newTestNotifier(t, config.NotificationsConfig{
+		Webhooks:     []config.WebhookConfig{{Name: "ops", URL: srv.URL}},
+		DedupWindow:  10 * time.Minute,
+		DedupWindows: map[string]time.Duration{EventScaleUp: 0},
+	})
+
+	notify := func() {
+		n.Notify(Event{Type: EventProviderError, Key: "create/creation_error"})
+		n.Notify(Event{Type: EventProviderError, Key: "remove/removal_error"})
+		n.Notify(Event{Type: EventScaleUp})
+		drain(n)
+	}
+
+	notify()
+	clk.Advance(5 * time.Minute)
+	notify()
+	if got := len(rec.received()); got != 4 {
+		t.Fatalf("received %d requests inside the window, want 4 (two keys, two scale ups)", got)
+	}
+
+	clk.Advance(5 * time.Minute)
+	notify()
+	if got := len(rec.received()); got != 7 {
+		t.Errorf("received %d requests after the window, want 7", got)
+	}
+
+	if got := testutil.ToFloat64(met.NotificationsTotal.WithLabelValues("", EventProviderError, "deduplicated")); got != 2 {
+		t.Errorf("deduplicated notifications = %v, want 2", got)
+	}
+}
+
+func TestDroppedEventIsNotDeduplicated(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	n, _, met := newTestNotifier(t, config.NotificationsConfig{
+		Webhooks:    []config.WebhookConfig{{Name: "ops", URL: srv.URL}},
+		DedupWindow: 10 * time.Minute,
+		QueueSize:   1,
+	})
+
+	n.Notify(Event{Type: EventProviderError, Key: "create/creation_error"})
+	n.Notify(Event{Type: EventProviderError, Key: "remove/removal_error"})
+	if got := testutil.ToFloat64(met.NotificationsTotal.WithLabelValues("", EventProviderError, "dropped")); got != 1 {
+		t.Fatalf("dropped notifications = %v, want 1 with the queue full", got)
+	}
+
+	drain(n)
+	n.Notify(Event{Type: EventProviderError, Key: "remove/removal_error"})
+	drain(n)
+	if got := len(rec.received()); got != 2 {
+		t.Errorf("received %d requests, want the dropped event delivered once there was room", got)
+	}
+}
+
+func TestFlushWaitsForQueuedEvents(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	n, _, _ := newTestNotifier(t, config.NotificationsConfig{
+		Webhooks: []config.WebhookConfig{{Name: "ops", URL: srv.URL}},
+	})
+
+	n.Notify(Event{Type: EventScaleDown, Key: "drain"})
+	n.Notify(Event{Type: EventScaleDown, Key: "teardown"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go n.Run(ctx)
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+	if err := n.Flush(flushCtx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := len(rec.received()); got != 2 {
+		t.Errorf("received %d requests after Flush, want 2", got)
+	}
+}
+
+func TestSendRetriesWithBackoff(t *testing.T) {
+	rec := &recorder{failures: 2}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	n, clk, met := newTestNotifier(t, config.NotificationsConfig{
+		Webhooks:     []config.WebhookConfig{{Name: "ops", URL: srv.URL}},
+		MaxRetries:   3,
+		RetryBackoff: time.Second,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go n.Run(ctx)
+
+	n.Notify(Event{Type: EventCreationFailures})
+
+	// Backoff doubles: 1s after the first failure, 2s after the second
+	for _, wait := range []time.Duration{time.Second, 2 * time.Second} {
+		clk.BlockUntil(1)
+		clk.Advance(wait)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(met.NotificationsTotal.WithLabelValues("ops", EventCreationFailures, "sent")) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("notification was not delivered after retries")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got := len(rec.received()); got != 3 {
+		t.Errorf("received %d requests, want 3", got)
+	}
+}
+
+func TestSendGivesUpAfterMaxRetries(t *testing.T) {
+	rec := &recorder{failures: 10}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	n, _, met := newTestNotifier(t, config.NotificationsConfig{
+		Webhooks: []config.WebhookConfig{{Name: "ops", URL: srv.URL}},
+	})
+
+	n.Notify(Event{Type: EventProviderError})
+	drain(n)
+
+	if got := len(rec.received()); got != 1 {
+		t.Errorf("received %d requests with no retries, want 1", got)
+	}
+	if got := testutil.ToFloat64(met.NotificationsTotal.WithLabelValues("ops", EventProviderError, "failed")); got != 1 {
+		t.Errorf("failed notifications = %v, want 1", got)
+	}
+}
@@ -0,0 +1,121 @@
+// Package priority sorts queued jobs into priority classes, so release and
+// hotfix workflows can get runners ahead of nightly jobs.
+package priority
+
+import (
+	"path"
+
+	"Zeno/internal/config"
+	"Zeno/internal/github"
+)
+
+// Priority classes, highest first
+const (
+	High   = "high"
+	Normal = "normal"
+	Low    = "low"
+)
+
+// Classifier puts jobs into classes by configured rules
+type Classifier struct {
+	config config.PriorityConfig
+}
+
+// Counts is the number of queued jobs in each class
+type Counts struct {
+	High   int
+	Normal int
+	Low    int
+}
+
+// New creates a classifier from configuration
+func New(cfg config.PriorityConfig) *Classifier {
+	return &Classifier{config: cfg}
+}
+
+// Enabled reports whether jobs are classified. A nil classifier is disabled.
+func (c *Classifier) Enabled() bool {
+	return c != nil && c.config.Enabled
+}
+
+// HardMax returns the most runners high priority jobs may take the pool to
+func (c *Classifier) HardMax(softMax int) int {
+	return max(c.config.HardMaxRunners, softMax)
+}
+
+// Classify returns the class of the first rule matching job, or Normal
+func (c *Classifier) Classify(job github.QueuedJob) string {
+	for _, rule := range c.config.Rules {
+		if matches(rule, job) {
+			return rule.Class
+		}
+	}
+	return Normal
+}
+
+// Count classifies every job
+func (c *Classifier) Count(jobs []github.QueuedJob) Counts {
+	var counts Counts
+	for _, job := range jobs {
+		switch c.Classify(job) {
+		case High:
+			counts.High++
+		case Low:
+			counts.Low++
+		default:
+			counts.Normal++
+		}
+	}
+	return counts
+}
+
+// Rank orders classes for sorting, highest priority first
+func Rank(class string) int {
+	switch class {
+	case High:
+		return 0
+	case Low:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// Total returns the number of jobs in every class
+func (c Counts) Total() int {
+	return c.High + c.Normal + c.Low
+}
+
+// Highest returns the highest class with queued jobs, or "" for none
+func (c Counts) Highest() string {
+	switch {
+	case c.High > 0:
+		return High
+	case c.Normal > 0:
+		return Normal
+	case c.Low > 0:
+		return Low
+	}
+	return ""
+}
+
+func matches(rule config.PriorityRule, job github.QueuedJob) bool {
+	if matchAny(rule.Workflows, job.WorkflowName) || matchAny(rule.Repositories, job.Repository) {
+		return true
+	}
+	for _, label := range job.Labels {
+		if matchAny(rule.Labels, label) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package priority
+
+import (
+	"testing"
+
+	"Zeno/internal/config"
+	"Zeno/internal/github"
+)
+
+func TestClassify(t *testing.T) {
+	c := New(config.PriorityConfig{
+		Enabled: true,
+		Rules: []config.PriorityRule{
+			{Class: High, Workflows: []string{"Release*", "Hotfix"}},
+			{Class: High, Labels: []string{"urgent"}},
+			{Class: Low, Workflows: []string{"Nightly*"}},
+			{Class: Low, Repositories: []string{"acme/sandbox-*"}},
+		},
+	})
+
+	tests := []struct {
+		name string
+		job  github.QueuedJob
+		want string
+	}{
+		{"workflow pattern", github.QueuedJob{WorkflowName: "Release v2"}, High},
+		{"exact workflow", github.QueuedJob{WorkflowName: "Hotfix"}, High},
+		{"label", github.QueuedJob{WorkflowName: "CI", Labels: []string{"self-hosted", "urgent"}}, High},
+		{"low workflow", github.QueuedJob{WorkflowName: "Nightly build"}, Low},
+		{"repository", github.QueuedJob{WorkflowName: "CI", Repository: "acme/sandbox-1"}, Low},
+		{"first rule wins", github.QueuedJob{WorkflowName: "Nightly build", Labels: []string{"urgent"}}, High},
+		{"unmatched", github.QueuedJob{WorkflowName: "CI", Repository: "acme/api"}, Normal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Classify(tt.job); got != tt.want {
+				t.Errorf("Classify(%+v) = %s, want %s", tt.job, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCounts(t *testing.T) {
+	c := New(config.PriorityConfig{
+		Enabled: true,
+		Rules:   []config.PriorityRule{{Class: High, Workflows: []string{"Release"}}},
+	})
+
+	counts := c.Count([]github.QueuedJob{{WorkflowName: "Release"}, {WorkflowName: "CI"}, {WorkflowName: "CI"}})
+	if counts != (Counts{High: 1, Normal: 2}) || counts.Total() != 3 || counts.Highest() != High {
+		t.Errorf("Count() = %+v", counts)
+	}
+	if (Counts{Low: 1}).Highest() != Low || (Counts{}).Highest() != "" {
+		t.Error("Highest() did not pick the highest class with jobs")
+	}
+	if c.HardMax(10) != 10 {
+		t.Errorf("HardMax(10) = %d, want the soft maximum when no hard maximum is set", c.HardMax(10))
+	}
+}
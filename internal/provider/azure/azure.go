@@ -0,0 +1,603 @@
+package azure
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"Zeno/internal/config"
+	"Zeno/internal/provider"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+	"github.com/google/uuid"
+)
+
+// Tags follow the same scheme as the EC2 provider's
+const (
+	tagPrefix     = "zeno:"
+	tagManagedBy  = tagPrefix + "managed-by"
+	tagRunnerID   = tagPrefix + "runner-id"
+	tagRunnerName = tagPrefix + "runner-name"
+	tagCreatedAt  = tagPrefix + "created-at"
+
+	managedByRunner = "zeno"
+)
+
+type AzureProvider struct {
+	vms   *armcompute.VirtualMachinesClient
+	disks *armcompute.DisksClient
+	nics  *armnetwork.InterfacesClient
+	// resourceGroup is fixed, so it is read without holding mu
+	resourceGroup string
+	config        config.AzureConfig
+	logger        *slog.Logger
+	mu            sync.RWMutex
+}
+
+func init() {
+	provider.Register("azure", func(cfg config.ProviderConfig, logger *slog.Logger) (provider.Provider, error) {
+		return New(cfg.Azure, logger)
+	})
+}
+
+// New creates a new Azure provider. Credentials are resolved by
+// azidentity.DefaultAzureCredential: environment variables, workload or
+// managed identity, or the Azure CLI.
+func New(cfg config.AzureConfig, logger *slog.Logger) (*AzureProvider, error) {
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Azure credentials: %w", err)
+	}
+
+	return newProvider(cfg, cred, nil, logger)
+}
+
+// newProvider builds the ARM clients. Tests pass the transport of a local
+// fake.
+func newProvider(cfg config.AzureConfig, cred azcore.TokenCredential, transport policy.Transporter, logger *slog.Logger) (*AzureProvider, error) {
+	opts := &arm.ClientOptions{}
+	if transport != nil {
+		opts.Transport = transport
+	}
+	if cfg.Endpoint != "" {
+		opts.Cloud = cloud.Configuration{
+			ActiveDirectoryAuthorityHost: cloud.AzurePublic.ActiveDirectoryAuthorityHost,
+			Services: map[cloud.ServiceName]cloud.ServiceConfiguration{
+				cloud.ResourceManager: {
+					Audience: cloud.AzurePublic.Services[cloud.ResourceManager].Audience,
+					Endpoint: cfg.Endpoint,
+				},
+			},
+		}
+	}
+
+	vms, err := armcompute.NewVirtualMachinesClient(cfg.SubscriptionID, cred, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create virtual machines client: %w", err)
+	}
+	disks, err := armcompute.NewDisksClient(cfg.SubscriptionID, cred, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create disks client: %w", err)
+	}
+	nics, err := armnetwork.NewInterfacesClient(cfg.SubscriptionID, cred, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create network interfaces client: %w", err)
+	}
+
+	return &AzureProvider{
+		vms:           vms,
+		disks:         disks,
+		nics:          nics,
+		resourceGroup: cfg.ResourceGroup,
+		config:        cfg,
+		logger:        logger.With("provider", "azure"),
+	}, nil
+}
+
+// Reconfigure applies new VM settings to runners created from now on. The
+// subscription, resource group, location, endpoint and pool are fixed for
+// the life of the provider.
+func (p *AzureProvider) Reconfigure(cfg config.ProviderConfig) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var restart []string
+	next := cfg.Azure
+	provider.KeepFixed(&restart, "provider.azure.subscription_id", &next.SubscriptionID, p.config.SubscriptionID)
+	provider.KeepFixed(&restart, "provider.azure.resource_group", &next.ResourceGroup, p.config.ResourceGroup)
+	provider.KeepFixed(&restart, "provider.azure.location", &next.Location, p.config.Location)
+	provider.KeepFixed(&restart, "provider.azure.endpoint", &next.Endpoint, p.config.Endpoint)
+	provider.KeepFixed(&restart, "provider.azure.pool", &next.Pool, p.config.Pool)
+	p.config = next
+	return restart, nil
+}
+
+func (p *AzureProvider) Name() string {
+	return "azure"
+}
+
+func (p *AzureProvider) ListRunners(ctx context.Context) ([]*provider.Runner, error) {
+	vms, err := p.listVMs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	runners := make([]*provider.Runner, 0, len(vms))
+	for _, vm := range vms {
+		runners = append(runners, p.vmToRunner(vm))
+	}
+	return runners, nil
+}
+
+func (p *AzureProvider) GetRunner(ctx context.Context, id string) (*provider.Runner, error) {
+	vm, err := p.findVM(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return p.vmToRunner(vm), nil
+}
+
+// listVMs returns Zeno's VMs in the resource group with their instance
+// views, which hold the power state
+func (p *AzureProvider) listVMs(ctx context.Context) ([]*armcompute.VirtualMachine, error) {
+	pager := p.vms.NewListPager(p.resourceGroup, &armcompute.VirtualMachinesClientListOptions{
+		Expand: to.Ptr(armcompute.ExpandTypeForListVMsInstanceView),
+	})
+
+	var vms []*armcompute.VirtualMachine
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list virtual machines: %w", err)
+		}
+		for _, vm := range page.Value {
+			if tagValue(vm.Tags, tagManagedBy) == managedByRunner {
+				vms = append(vms, vm)
+			}
+		}
+	}
+	return vms, nil
+}
+
+func (p *AzureProvider) findVM(ctx context.Context, id string) (*armcompute.VirtualMachine, error) {
+	vms, err := p.listVMs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, vm := range vms {
+		if tagValue(vm.Tags, tagRunnerID) == id {
+			return vm, nil
+		}
+	}
+	return nil, fmt.Errorf("runner %s not found", id)
+}
+
+func (p *AzureProvider) CreateRunner(ctx context.Context, req *provider.CreateRunnerRequest) (*provider.Runner, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	runnerID := uuid.New().String()
+	vmName := fmt.Sprintf("zeno-runner-%s", runnerID[:8])
+
+	p.logger.Info("creating Azure VM",
+		"id", runnerID,
+		"name", req.Name,
+		"vm", vmName,
+		"vm_size", p.config.VMSize,
+		"use_spot", p.config.UseSpot,
+	)
+
+	tags := p.buildTags(runnerID, req)
+
+	nicID, err := p.createNIC(ctx, vmName+"-nic", tags)
+	if err != nil {
+		return nil, err
+	}
+
+	vm, err := p.buildVM(vmName, nicID, tags, req)
+	if err != nil {
+		return nil, err
+	}
+	poller, err := p.vms.BeginCreateOrUpdate(ctx, p.resourceGroup, vmName, *vm, nil)
+	if err == nil {
+		_, err = poller.PollUntilDone(ctx, nil)
+	}
+	if err != nil {
+		// RemoveRunner never sees a VM that failed to create, so clean up
+		// its NIC and any disk here
+		cleanupCtx := context.WithoutCancel(ctx)
+		if cleanupErr := errors.Join(p.deleteNIC(cleanupCtx, vmName+"-nic"), p.deleteDisk(cleanupCtx, vmName+"-osdisk")); cleanupErr != nil {
+			p.logger.Warn("failed to clean up after failed VM", "vm", vmName, "error", cleanupErr)
+		}
+		return nil, fmt.Errorf("failed to create virtual machine: %w", err)
+	}
+
+	p.logger.Info("Azure VM created",
+		"id", runnerID,
+		"vm", vmName,
+	)
+
+	metadata := map[string]string{
+		"vm":            vmName,
+		"instance_type": p.config.VMSize,
+		"location":      p.config.Location,
+		"spot":          fmt.Sprintf("%t", p.config.UseSpot),
+		"pool":          p.config.Pool,
+	}
+	for k, v := range req.Metadata {
+		metadata[k] = v
+	}
+
+	return &provider.Runner{
+		ID:         runnerID,
+		Name:       req.Name,
+		Status:     provider.StatusProvisioning,
+		Labels:     req.Labels,
+		Provider:   "azure",
+		ProviderID: vmName,
+		CreatedAt:  time.Now(),
+		Metadata:   metadata,
+	}, nil
+}
+
+// RemoveRunner deletes the VM, then its network interfaces and disks, which
+// Azure keeps unless they were created with a delete option. Azure shuts
+// the guest down before deleting, so graceful and forced removal are the
+// same.
+func (p *AzureProvider) RemoveRunner(ctx context.Context, id string, graceful bool) error {
+	vm, err := p.findVM(ctx, id)
+	if err != nil {
+		return err
+	}
+	vmName := stringValue(vm.Name)
+
+	p.logger.Info("deleting Azure VM",
+		"id", id,
+		"vm", vmName,
+		"graceful", graceful,
+	)
+
+	poller, err := p.vms.BeginDelete(ctx, p.resourceGroup, vmName, nil)
+	if err == nil {
+		_, err = poller.PollUntilDone(ctx, nil)
+	}
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete virtual machine: %w", err)
+	}
+
+	var errs []error
+	for _, nic := range attachedNICs(vm) {
+		if err := p.deleteNIC(ctx, nic); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, disk := range attachedDisks(vm) {
+		if err := p.deleteDisk(ctx, disk); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	p.logger.Info("Azure VM deleted", "id", id)
+	return nil
+}
+
+func (p *AzureProvider) HealthCheck(ctx context.Context) error {
+	pager := p.vms.NewListPager(p.resourceGroup, nil)
+	if _, err := pager.NextPage(ctx); err != nil {
+		return fmt.Errorf("Azure health check failed: %w", err)
+	}
+	return nil
+}
+
+func (p *AzureProvider) Close() error {
+	return nil
+}
+
+func (p *AzureProvider) createNIC(ctx context.Context, name string, tags map[string]*string) (string, error) {
+	nic := armnetwork.Interface{
+		Location: to.Ptr(p.config.Location),
+		Tags:     tags,
+		Properties: &armnetwork.InterfacePropertiesFormat{
+			IPConfigurations: []*armnetwork.InterfaceIPConfiguration{
+				{
+					Name: to.Ptr("ipconfig1"),
+					Properties: &armnetwork.InterfaceIPConfigurationPropertiesFormat{
+						Subnet:                    &armnetwork.Subnet{ID: to.Ptr(p.config.SubnetID)},
+						PrivateIPAllocationMethod: to.Ptr(armnetwork.IPAllocationMethodDynamic),
+					},
+				},
+			},
+		},
+	}
+
+	poller, err := p.nics.BeginCreateOrUpdate(ctx, p.resourceGroup, name, nic, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create network interface: %w", err)
+	}
+	resp, err := poller.PollUntilDone(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create network interface: %w", err)
+	}
+	return stringValue(resp.ID), nil
+}
+
+func (p *AzureProvider) deleteNIC(ctx context.Context, name string) error {
+	poller, err := p.nics.BeginDelete(ctx, p.resourceGroup, name, nil)
+	if err == nil {
+		_, err = poller.PollUntilDone(ctx, nil)
+	}
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete network interface %s: %w", name, err)
+	}
+	return nil
+}
+
+func (p *AzureProvider) deleteDisk(ctx context.Context, name string) error {
+	poller, err := p.disks.BeginDelete(ctx, p.resourceGroup, name, nil)
+	if err == nil {
+		_, err = poller.PollUntilDone(ctx, nil)
+	}
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete disk %s: %w", name, err)
+	}
+	return nil
+}
+
+func (p *AzureProvider) buildVM(name, nicID string, tags map[string]*string, req *provider.CreateRunnerRequest) (*armcompute.VirtualMachine, error) {
+	image, err := imageReference(p.config.Image)
+	if err != nil {
+		return nil, err
+	}
+
+	customData := base64.StdEncoding.EncodeToString([]byte(p.buildCustomData(req)))
+
+	vm := &armcompute.VirtualMachine{
+		Location: to.Ptr(p.config.Location),
+		Tags:     tags,
+		Properties: &armcompute.VirtualMachineProperties{
+			HardwareProfile: &armcompute.HardwareProfile{
+				VMSize: to.Ptr(armcompute.VirtualMachineSizeTypes(p.config.VMSize)),
+			},
+			StorageProfile: &armcompute.StorageProfile{
+				ImageReference: image,
+				OSDisk: &armcompute.OSDisk{
+					Name:         to.Ptr(name + "-osdisk"),
+					CreateOption: to.Ptr(armcompute.DiskCreateOptionTypesFromImage),
+					DeleteOption: to.Ptr(armcompute.DiskDeleteOptionTypesDelete),
+					DiskSizeGB:   to.Ptr(p.config.OSDiskSizeGB),
+					ManagedDisk: &armcompute.ManagedDiskParameters{
+						StorageAccountType: to.Ptr(armcompute.StorageAccountTypes(p.config.OSDiskType)),
+					},
+				},
+			},
+			OSProfile: &armcompute.OSProfile{
+				ComputerName:  to.Ptr(name),
+				AdminUsername: to.Ptr(p.config.AdminUsername),
+				CustomData:    to.Ptr(customData),
+				LinuxConfiguration: &armcompute.LinuxConfiguration{
+					DisablePasswordAuthentication: to.Ptr(true),
+					SSH: &armcompute.SSHConfiguration{
+						PublicKeys: []*armcompute.SSHPublicKey{
+							{
+								Path:    to.Ptr(fmt.Sprintf("/home/%s/.ssh/authorized_keys", p.config.AdminUsername)),
+								KeyData: to.Ptr(p.config.SSHPublicKey),
+							},
+						},
+					},
+				},
+			},
+			NetworkProfile: &armcompute.NetworkProfile{
+				NetworkInterfaces: []*armcompute.NetworkInterfaceReference{
+					{
+						ID: to.Ptr(nicID),
+						Properties: &armcompute.NetworkInterfaceReferenceProperties{
+							Primary:      to.Ptr(true),
+							DeleteOption: to.Ptr(armcompute.DeleteOptionsDelete),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if p.config.UseSpot {
+		// Evicted VMs are deleted along with their NIC and disk
+		vm.Properties.Priority = to.Ptr(armcompute.VirtualMachinePriorityTypesSpot)
+		vm.Properties.EvictionPolicy = to.Ptr(armcompute.VirtualMachineEvictionPolicyTypesDelete)
+		vm.Properties.BillingProfile = &armcompute.BillingProfile{MaxPrice: to.Ptr(p.config.SpotMaxPrice)}
+	}
+
+	return vm, nil
+}
+
+// imageReference accepts an image resource ID or a
+// publisher:offer:sku:version URN
+func imageReference(image string) (*armcompute.ImageReference, error) {
+	if strings.HasPrefix(image, "/") {
+		return &armcompute.ImageReference{ID: to.Ptr(image)}, nil
+	}
+	parts := strings.Split(image, ":")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid image %q: want a resource ID or publisher:offer:sku:version", image)
+	}
+	return &armcompute.ImageReference{
+		Publisher: to.Ptr(parts[0]),
+		Offer:     to.Ptr(parts[1]),
+		SKU:       to.Ptr(parts[2]),
+		Version:   to.Ptr(parts[3]),
+	}, nil
+}
+
+func (p *AzureProvider) buildCustomData(req *provider.CreateRunnerRequest) string {
+	if p.config.CustomDataScript != "" {
+		return provider.ExpandScript(p.config.CustomDataScript, req)
+	}
+	return provider.BootstrapScript(req, "/opt/actions-runner", false)
+}
+
+func (p *AzureProvider) buildTags(runnerID string, req *provider.CreateRunnerRequest) map[string]*string {
+	tags := map[string]*string{
+		tagManagedBy:       to.Ptr(managedByRunner),
+		tagRunnerID:        to.Ptr(runnerID),
+		tagRunnerName:      to.Ptr(req.Name),
+		tagCreatedAt:       to.Ptr(time.Now().Format(time.RFC3339)),
+		tagPrefix + "pool": to.Ptr(p.config.Pool),
+	}
+
+	// Add request metadata
+	for k, v := range req.Metadata {
+		tags[tagPrefix+k] = to.Ptr(v)
+	}
+
+	// Add custom tags from config
+	for k, v := range p.config.Tags {
+		tags[k] = to.Ptr(v)
+	}
+
+	return tags
+}
+
+func (p *AzureProvider) vmToRunner(vm *armcompute.VirtualMachine) *provider.Runner {
+	createdAt := time.Now()
+	if t, err := time.Parse(time.RFC3339, tagValue(vm.Tags, tagCreatedAt)); err == nil {
+		createdAt = t
+	}
+
+	metadata := map[string]string{
+		"vm":       stringValue(vm.Name),
+		"location": stringValue(vm.Location),
+		"spot":     "false",
+	}
+
+	// Request metadata is stored as zeno-prefixed tags
+	for key, value := range vm.Tags {
+		switch key {
+		case tagManagedBy, tagRunnerID, tagRunnerName, tagCreatedAt:
+			continue
+		}
+		if strings.HasPrefix(key, tagPrefix) {
+			metadata[strings.TrimPrefix(key, tagPrefix)] = stringValue(value)
+		}
+	}
+
+	var provisioningState, powerState string
+	if props := vm.Properties; props != nil {
+		if props.HardwareProfile != nil && props.HardwareProfile.VMSize != nil {
+			metadata["instance_type"] = string(*props.HardwareProfile.VMSize)
+		}
+		if props.Priority != nil && *props.Priority == armcompute.VirtualMachinePriorityTypesSpot {
+			metadata["spot"] = "true"
+		}
+		provisioningState = stringValue(props.ProvisioningState)
+		if props.InstanceView != nil {
+			for _, status := range props.InstanceView.Statuses {
+				if code := stringValue(status.Code); strings.HasPrefix(code, "PowerState/") {
+					powerState = strings.TrimPrefix(code, "PowerState/")
+				}
+			}
+		}
+	}
+	metadata["state"] = provisioningState
+	if powerState != "" {
+		metadata["power_state"] = powerState
+	}
+
+	return &provider.Runner{
+		ID:         tagValue(vm.Tags, tagRunnerID),
+		Name:       tagValue(vm.Tags, tagRunnerName),
+		Status:     mapVMState(provisioningState, powerState),
+		Provider:   "azure",
+		ProviderID: stringValue(vm.Name),
+		CreatedAt:  createdAt,
+		Metadata:   metadata,
+	}
+}
+
+// mapVMState combines the provisioning state, which covers creation and
+// deletion, with the power state of a provisioned VM
+func mapVMState(provisioningState, powerState string) provider.RunnerStatus {
+	switch provisioningState {
+	case "Creating":
+		return provider.StatusProvisioning
+	case "Deleting":
+		return provider.StatusTerminating
+	case "Failed":
+		return provider.StatusFailed
+	}
+
+	switch powerState {
+	case "starting", "":
+		return provider.StatusProvisioning
+	case "running":
+		return provider.StatusRunning
+	case "stopping", "deallocating":
+		return provider.StatusTerminating
+	case "stopped", "deallocated":
+		return provider.StatusTerminated
+	default:
+		return provider.StatusFailed
+	}
+}
+
+func attachedNICs(vm *armcompute.VirtualMachine) []string {
+	if vm.Properties == nil || vm.Properties.NetworkProfile == nil {
+		return nil
+	}
+	var names []string
+	for _, nic := range vm.Properties.NetworkProfile.NetworkInterfaces {
+		if nic.ID != nil {
+			names = append(names, path.Base(*nic.ID))
+		}
+	}
+	return names
+}
+
+func attachedDisks(vm *armcompute.VirtualMachine) []string {
+	if vm.Properties == nil || vm.Properties.StorageProfile == nil {
+		return nil
+	}
+	storage := vm.Properties.StorageProfile
+	var names []string
+	if storage.OSDisk != nil && storage.OSDisk.Name != nil {
+		names = append(names, *storage.OSDisk.Name)
+	}
+	for _, disk := range storage.DataDisks {
+		if disk.Name != nil {
+			names = append(names, *disk.Name)
+		}
+	}
+	return names
+}
+
+func tagValue(tags map[string]*string, key string) string {
+	return stringValue(tags[key])
+}
+
+func isNotFound(err error) bool {
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,323 @@
+package azure
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"Zeno/internal/config"
+	"Zeno/internal/provider"
+	"Zeno/internal/provider/providertest"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+)
+
+// fakeARM is a local stand-in for the Azure Resource Manager operations the
+// provider uses, holding VMs, NICs and disks of a single resource group.
+// Every operation completes synchronously.
+type fakeARM struct {
+	mu         sync.Mutex
+	vms        map[string]*armcompute.VirtualMachine
+	nics       map[string]bool
+	disks      map[string]bool
+	powerState string
+
+	// vmErr, when set, is the error code returned for VM creation
+	vmErr string
+}
+
+func newFakeARM(t *testing.T) (*fakeARM, *httptest.Server) {
+	f := &fakeARM{
+		vms:        make(map[string]*armcompute.VirtualMachine),
+		nics:       make(map[string]bool),
+		disks:      make(map[string]bool),
+		powerState: "running",
+	}
+	srv := httptest.NewTLSServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeARM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// /subscriptions/{sub}/resourceGroups/{rg}/providers/{namespace}/{type}[/{name}]
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 7 || parts[0] != "subscriptions" || parts[2] != "resourceGroups" || parts[4] != "providers" {
+		armError(w, http.StatusNotFound, "NotFound")
+		return
+	}
+	kind := parts[5] + "/" + parts[6]
+	name := ""
+	if len(parts) == 8 {
+		name = parts[7]
+	}
+
+	switch {
+	case kind == "Microsoft.Network/networkInterfaces" && r.Method == http.MethodPut:
+		var nic map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&nic)
+		nic["id"] = r.URL.Path
+		nic["name"] = name
+		f.nics[name] = true
+		writeJSON(w, nic)
+
+	case kind == "Microsoft.Compute/virtualMachines" && r.Method == http.MethodPut:
+		if f.vmErr != "" {
+			armError(w, http.StatusConflict, f.vmErr)
+			return
+		}
+		var vm armcompute.VirtualMachine
+		if err := json.NewDecoder(r.Body).Decode(&vm); err != nil {
+			armError(w, http.StatusBadRequest, "InvalidRequestContent")
+			return
+		}
+		vm.ID = to.Ptr(r.URL.Path)
+		vm.Name = to.Ptr(name)
+		vm.Properties.ProvisioningState = to.Ptr("Succeeded")
+		f.vms[name] = &vm
+		f.disks[*vm.Properties.StorageProfile.OSDisk.Name] = true
+		writeJSON(w, &vm)
+
+	case kind == "Microsoft.Compute/virtualMachines" && name == "" && r.Method == http.MethodGet:
+		list := armcompute.VirtualMachineListResult{Value: []*armcompute.VirtualMachine{}}
+		for _, vm := range f.vms {
+			listed := *vm
+			props := *vm.Properties
+			if r.URL.Query().Get("$expand") == "instanceView" {
+				props.InstanceView = &armcompute.VirtualMachineInstanceView{
+					Statuses: []*armcompute.InstanceViewStatus{
+						{Code: to.Ptr("ProvisioningState/succeeded")},
+						{Code: to.Ptr("PowerState/" + f.powerState)},
+					},
+				}
+			}
+			listed.Properties = &props
+			list.Value = append(list.Value, &listed)
+		}
+		writeJSON(w, &list)
+
+	case r.Method == http.MethodDelete:
+		resources := map[string]map[string]bool{
+			"Microsoft.Network/networkInterfaces": f.nics,
+			"Microsoft.Compute/disks":             f.disks,
+		}
+		if kind == "Microsoft.Compute/virtualMachines" {
+			if _, ok := f.vms[name]; !ok {
+				armError(w, http.StatusNotFound, "ResourceNotFound")
+				return
+			}
+			delete(f.vms, name)
+		} else if set, ok := resources[kind]; ok && set[name] {
+			delete(set, name)
+		} else {
+			armError(w, http.StatusNotFound, "ResourceNotFound")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		armError(w, http.StatusNotFound, "NotFound")
+	}
+}
+
+func armError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("x-ms-error-code", code)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{"code": code, "message": code},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+type fakeCredential struct{}
+
+func (fakeCredential) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func newTestProvider(t *testing.T, srv *httptest.Server, useSpot bool) *AzureProvider {
+	t.Helper()
+
+	p, err := newProvider(config.AzureConfig{
+		SubscriptionID: "sub-1",
+		ResourceGroup:  "ci-runners",
+		Location:       "westeurope",
+		Endpoint:       srv.URL,
+		SubnetID:       "/subscriptions/sub-1/resourceGroups/net/providers/Microsoft.Network/virtualNetworks/ci/subnets/runners",
+		VMSize:         "Standard_D4s_v5",
+		Image:          "Canonical:0001-com-ubuntu-server-jammy:22_04-lts-gen2:latest",
+		OSDiskSizeGB:   64,
+		OSDiskType:     "Premium_LRS",
+		AdminUsername:  "zeno",
+		SSHPublicKey:   "ssh-ed25519 AAAA zeno",
+		UseSpot:        useSpot,
+		SpotMaxPrice:   -1,
+		Tags:           map[string]string{"team": "platform"},
+		Pool:           "azure",
+	}, fakeCredential{}, srv.Client(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("newProvider() error = %v", err)
+	}
+	return p
+}
+
+func TestCreateRunnerCreatesSpotVM(t *testing.T) {
+	fake, srv := newFakeARM(t)
+	p := newTestProvider(t, srv, true)
+
+	runner, err := p.CreateRunner(context.Background(), providertest.Request())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	if runner.Metadata["spot"] != "true" || runner.Metadata["instance_type"] != "Standard_D4s_v5" || runner.Metadata[provider.MetadataJobID] != "42" {
+		t.Errorf("runner metadata = %v", runner.Metadata)
+	}
+
+	vm := fake.vms[runner.ProviderID]
+	if vm == nil {
+		t.Fatalf("VM %s not created", runner.ProviderID)
+	}
+	props := vm.Properties
+	if *props.Priority != armcompute.VirtualMachinePriorityTypesSpot || *props.EvictionPolicy != armcompute.VirtualMachineEvictionPolicyTypesDelete || *props.BillingProfile.MaxPrice != -1 {
+		t.Errorf("spot settings = %v %v %+v", *props.Priority, *props.EvictionPolicy, props.BillingProfile)
+	}
+	if image := props.StorageProfile.ImageReference; *image.Publisher != "Canonical" || *image.SKU != "22_04-lts-gen2" {
+		t.Errorf("image = %+v", image)
+	}
+	if !fake.nics[runner.ProviderID+"-nic"] {
+		t.Errorf("NIC not created: %v", fake.nics)
+	}
+
+	for k, want := range map[string]string{
+		tagManagedBy:         managedByRunner,
+		tagRunnerID:          runner.ID,
+		tagRunnerName:        "zeno-runner-1",
+		tagPrefix + "job_id": "42",
+		"team":               "platform",
+	} {
+		if got := tagValue(vm.Tags, k); got != want {
+			t.Errorf("tag %s = %q, want %q", k, got, want)
+		}
+	}
+
+	customData, err := base64.StdEncoding.DecodeString(*props.OSProfile.CustomData)
+	if err != nil {
+		t.Fatalf("custom data is not base64: %v", err)
+	}
+	for _, want := range []string{"--url https://github.com/acme", "--token reg-token", "--name zeno-runner-1", "--ephemeral"} {
+		if !strings.Contains(string(customData), want) {
+			t.Errorf("custom data missing %q:\n%s", want, customData)
+		}
+	}
+}
+
+func TestCreateRunnerCleansUpNICWhenVMFails(t *testing.T) {
+	fake, srv := newFakeARM(t)
+	fake.vmErr = "SkuNotAvailable"
+	p := newTestProvider(t, srv, false)
+
+	_, err := p.CreateRunner(context.Background(), providertest.Request())
+	if err == nil || !strings.Contains(err.Error(), "SkuNotAvailable") {
+		t.Fatalf("CreateRunner() error = %v, want SkuNotAvailable", err)
+	}
+	if len(fake.nics) != 0 {
+		t.Errorf("NICs left after failed create: %v", fake.nics)
+	}
+}
+
+func TestListRunnersMapsPowerState(t *testing.T) {
+	fake, srv := newFakeARM(t)
+	p := newTestProvider(t, srv, false)
+	ctx := context.Background()
+
+	created, err := p.CreateRunner(ctx, providertest.Request())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+
+	for powerState, want := range map[string]provider.RunnerStatus{
+		"running":     provider.StatusRunning,
+		"stopped":     provider.StatusTerminated,
+		"deallocated": provider.StatusTerminated,
+	} {
+		fake.mu.Lock()
+		fake.powerState = powerState
+		fake.mu.Unlock()
+
+		runners, err := p.ListRunners(ctx)
+		if err != nil {
+			t.Fatalf("ListRunners() error = %v", err)
+		}
+		if len(runners) != 1 {
+			t.Fatalf("ListRunners() returned %d runners, want 1", len(runners))
+		}
+		r := runners[0]
+		if r.Status != want {
+			t.Errorf("power state %s: status = %s, want %s", powerState, r.Status, want)
+		}
+		if r.ID != created.ID || r.Name != "zeno-runner-1" || r.Metadata[provider.MetadataJobID] != "42" || r.Metadata["pool"] != "azure" || r.Metadata["instance_type"] != "Standard_D4s_v5" {
+			t.Errorf("listed runner = %+v", r)
+		}
+	}
+}
+
+func TestRemoveRunnerDeletesNICAndDisk(t *testing.T) {
+	fake, srv := newFakeARM(t)
+	p := newTestProvider(t, srv, false)
+	ctx := context.Background()
+
+	runner, err := p.CreateRunner(ctx, providertest.Request())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	if err := p.RemoveRunner(ctx, runner.ID, true); err != nil {
+		t.Fatalf("RemoveRunner() error = %v", err)
+	}
+	if len(fake.vms) != 0 || len(fake.nics) != 0 || len(fake.disks) != 0 {
+		t.Errorf("resources left after removal: vms=%d nics=%v disks=%v", len(fake.vms), fake.nics, fake.disks)
+	}
+	if err := p.RemoveRunner(ctx, runner.ID, true); err == nil {
+		t.Error("RemoveRunner() of a removed runner succeeded")
+	}
+	if err := p.HealthCheck(ctx); err != nil {
+		t.Errorf("HealthCheck() error = %v", err)
+	}
+}
+
+func TestMapVMState(t *testing.T) {
+	tests := []struct {
+		provisioningState, powerState string
+		want                          provider.RunnerStatus
+	}{
+		{"Creating", "", provider.StatusProvisioning},
+		{"Succeeded", "starting", provider.StatusProvisioning},
+		{"Succeeded", "running", provider.StatusRunning},
+		{"Succeeded", "deallocating", provider.StatusTerminating},
+		{"Succeeded", "stopped", provider.StatusTerminated},
+		{"Deleting", "running", provider.StatusTerminating},
+		{"Failed", "running", provider.StatusFailed},
+	}
+	for _, tt := range tests {
+		if got := mapVMState(tt.provisioningState, tt.powerState); got != tt.want {
+			t.Errorf("mapVMState(%q, %q) = %s, want %s", tt.provisioningState, tt.powerState, got, tt.want)
+		}
+	}
+}
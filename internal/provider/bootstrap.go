@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+)
+
+// runnerRelease is the GitHub Actions runner release that bootstrap
+// scripts install
+const runnerRelease = "actions-runner-linux-x64-2.311.0.tar.gz"
+
+// BootstrapScript is the default boot script of providers that start a
+// machine per runner. It installs the runner into dir, then registers and
+// runs it as ConfigureRunnerScript does.
+func BootstrapScript(req *CreateRunnerRequest, dir string, singleUse bool) string {
+	return "#!/bin/bash\nset -e\n\n" + InstallRunnerScript(dir) + "\n" + ConfigureRunnerScript(req, singleUse)
+}
+
+// InstallRunnerScript downloads the GitHub Actions runner into dir and
+// leaves the shell in that directory
+func InstallRunnerScript(dir string) string {
+	return fmt.Sprintf(`# Install GitHub Actions runner
+mkdir -p %[1]s && cd %[1]s
+curl -o %[2]s -L https://github.com/actions/runner/releases/download/v2.311.0/%[2]s
+tar xzf ./%[2]s
+`, dir, runnerRelease)
+}
+
+// ConfigureRunnerScript registers and starts an installed runner from
+// inside its directory. Boot scripts run as root, which config.sh refuses
+// unless told otherwise. A singleUse runner is registered with --ephemeral
+// and takes one job, as ephemeral runners always are; only ephemeral
+// runners power the machine off once their job is done so the controller
+// can collect it as consumed.
+func ConfigureRunnerScript(req *CreateRunnerRequest, singleUse bool) string {
+	flag := ephemeralFlag(req)
+	if singleUse {
+		flag = "--ephemeral"
+	}
+
+	postRun := ""
+	if req.Ephemeral {
+		postRun = "\n# Runner is single-use, power off once it exits\nshutdown -h now\n"
+	}
+
+	return fmt.Sprintf(`# Boot scripts run as root
+export RUNNER_ALLOW_RUNASROOT=1
+
+# Configure runner
+./config.sh --url https://github.com/%s --token %s --name %s --labels %s --unattended %s
+
+# Start runner
+./run.sh
+%s`,
+		registrationScope(req),
+		req.GitHubToken,
+		req.Name,
+		strings.Join(req.Labels, ","),
+		flag,
+		postRun,
+	)
+}
+
+// ExpandScript fills in the placeholders of a boot script supplied in the
+// configuration: {{RUNNER_NAME}}, {{GITHUB_TOKEN}}, {{GITHUB_ORG}},
+// {{GITHUB_REPO}}, {{LABELS}} and {{EPHEMERAL}}
+func ExpandScript(script string, req *CreateRunnerRequest) string {
+	return strings.NewReplacer(
+		"{{RUNNER_NAME}}", req.Name,
+		"{{GITHUB_TOKEN}}", req.GitHubToken,
+		"{{GITHUB_ORG}}", req.GitHubOrg,
+		"{{GITHUB_REPO}}", req.GitHubRepo,
+		"{{LABELS}}", strings.Join(req.Labels, ","),
+		"{{EPHEMERAL}}", ephemeralFlag(req),
+	).Replace(script)
+}
+
+// registrationScope is the organization, or owner/repo for runners scoped
+// to a single repository
+func registrationScope(req *CreateRunnerRequest) string {
+	if req.GitHubOrg != "" {
+		return req.GitHubOrg
+	}
+	return req.GitHubRepo
+}
+
+func ephemeralFlag(req *CreateRunnerRequest) string {
+	if req.Ephemeral {
+		return "--ephemeral"
+	}
+	return ""
+}
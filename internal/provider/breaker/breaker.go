@@ -0,0 +1,267 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"Zeno/internal/config"
+	"Zeno/internal/metrics"
+	"Zeno/internal/provider"
+)
+
+// Operations guarded by their own circuit
+const (
+	OpList   = "list"
+	OpGet    = "get"
+	OpCreate = "create"
+	OpRemove = "remove"
+	OpHealth = "health"
+)
+
+// State is the state of a single circuit
+type State string
+
+const (
+	StateClosed   State = "closed"
+	StateHalfOpen State = "half_open"
+	StateOpen     State = "open"
+)
+
+// ErrOpen is returned without calling the provider while a circuit is open
+var ErrOpen = errors.New("circuit breaker open")
+
+// Reporter is implemented by providers that expose circuit breaker state
+type Reporter interface {
+	CircuitStates() map[string]State
+}
+
+type circuit struct {
+	state    State
+	failures int
+	openedAt time.Time
+	backoff  time.Duration
+	probing  bool
+}
+
+// Provider wraps a provider.Provider and trips a circuit per operation after
+// consecutive failures. An open circuit is probed again once its backoff has
+// elapsed; each failed probe doubles the backoff up to the configured max.
+type Provider struct {
+	next     provider.Provider
+	config   config.CircuitBreakerConfig
+	metrics  *metrics.Metrics
+	logger   *slog.Logger
+	circuits map[string]*circuit
+	now      func() time.Time
+	mu       sync.Mutex
+}
+
+// Wrap puts a circuit breaker around every operation of prov
+func Wrap(prov provider.Provider, cfg config.CircuitBreakerConfig, met *metrics.Metrics, logger *slog.Logger) *Provider {
+	p := &Provider{
+		next:     prov,
+		config:   cfg,
+		metrics:  met,
+		logger:   logger.With("component", "circuit-breaker", "provider", prov.Name()),
+		circuits: make(map[string]*circuit),
+		now:      time.Now,
+	}
+
+	for _, op := range []string{OpList, OpGet, OpCreate, OpRemove, OpHealth} {
+		p.circuits[op] = &circuit{state: StateClosed}
+		p.reportState(op, StateClosed)
+	}
+
+	return p
+}
+
+func (p *Provider) Name() string {
+	return p.next.Name()
+}
+
+func (p *Provider) ListRunners(ctx context.Context) ([]*provider.Runner, error) {
+	var runners []*provider.Runner
+	err := p.call(ctx, OpList, func() error {
+		var err error
+		runners, err = p.next.ListRunners(ctx)
+		return err
+	})
+	return runners, err
+}
+
+func (p *Provider) GetRunner(ctx context.Context, id string) (*provider.Runner, error) {
+	var runner *provider.Runner
+	err := p.call(ctx, OpGet, func() error {
+		var err error
+		runner, err = p.next.GetRunner(ctx, id)
+		return err
+	})
+	return runner, err
+}
+
+func (p *Provider) CreateRunner(ctx context.Context, req *provider.CreateRunnerRequest) (*provider.Runner, error) {
+	var runner *provider.Runner
+	err := p.call(ctx, OpCreate, func() error {
+		var err error
+		runner, err = p.next.CreateRunner(ctx, req)
+		return err
+	})
+	return runner, err
+}
+
+func (p *Provider) RemoveRunner(ctx context.Context, id string, graceful bool) error {
+	return p.call(ctx, OpRemove, func() error {
+		return p.next.RemoveRunner(ctx, id, graceful)
+	})
+}
+
+func (p *Provider) HealthCheck(ctx context.Context) error {
+	return p.call(ctx, OpHealth, func() error {
+		return p.next.HealthCheck(ctx)
+	})
+}
+
+func (p *Provider) Close() error {
+	return p.next.Close()
+}
+
+// Unwrap returns the guarded provider
+func (p *Provider) Unwrap() provider.Provider {
+	return p.next
+}
+
+// State returns the state of the circuit for op. An open circuit whose
+// backoff has elapsed reports half-open, since the next call will probe.
+func (p *Provider) State(op string) State {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.stateLocked(op)
+}
+
+// CircuitStates returns the state of every circuit keyed by operation
+func (p *Provider) CircuitStates() map[string]State {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	states := make(map[string]State, len(p.circuits))
+	for op := range p.circuits {
+		states[op] = p.stateLocked(op)
+	}
+	return states
+}
+
+func (p *Provider) stateLocked(op string) State {
+	c := p.circuits[op]
+	if c.state == StateOpen && !p.now().Before(c.openedAt.Add(c.backoff)) {
+		return StateHalfOpen
+	}
+	return c.state
+}
+
+func (p *Provider) call(ctx context.Context, op string, fn func() error) error {
+	if err := p.before(op); err != nil {
+		return err
+	}
+
+	err := fn()
+
+	// A cancelled caller says nothing about the provider's health
+	if err != nil && ctx.Err() != nil {
+		p.release(op)
+		return err
+	}
+
+	p.after(op, err)
+	return err
+}
+
+// before decides whether a call may proceed and claims the probe slot when
+// a half-open circuit is tested
+func (p *Provider) before(op string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c := p.circuits[op]
+	switch p.stateLocked(op) {
+	case StateOpen:
+		return fmt.Errorf("%w: %s", ErrOpen, op)
+	case StateHalfOpen:
+		if c.probing {
+			return fmt.Errorf("%w: %s (probe in flight)", ErrOpen, op)
+		}
+		c.state = StateHalfOpen
+		c.probing = true
+		p.reportState(op, StateHalfOpen)
+		p.logger.Info("circuit half-open, probing provider", "operation", op)
+	}
+
+	return nil
+}
+
+func (p *Provider) after(op string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c := p.circuits[op]
+	wasProbe := c.probing
+	c.probing = false
+
+	if err == nil {
+		if c.state != StateClosed {
+			p.logger.Info("circuit closed", "operation", op)
+		}
+		c.state = StateClosed
+		c.failures = 0
+		c.backoff = 0
+		p.reportState(op, StateClosed)
+		return
+	}
+
+	c.failures++
+
+	switch {
+	case wasProbe:
+		c.backoff = min(c.backoff*2, p.config.MaxBackoff)
+	case c.state == StateClosed && c.failures >= p.config.FailureThreshold:
+		c.backoff = p.config.BaseBackoff
+	default:
+		return
+	}
+
+	c.state = StateOpen
+	c.openedAt = p.now()
+	p.reportState(op, StateOpen)
+	p.logger.Warn("circuit opened",
+		"operation", op,
+		"consecutive_failures", c.failures,
+		"backoff", c.backoff,
+		"error", err,
+	)
+}
+
+func (p *Provider) release(op string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.circuits[op].probing = false
+}
+
+func (p *Provider) reportState(op string, state State) {
+	if p.metrics == nil {
+		return
+	}
+
+	var value float64
+	switch state {
+	case StateHalfOpen:
+		value = 1
+	case StateOpen:
+		value = 2
+	}
+	p.metrics.ProviderCircuitState.WithLabelValues(p.next.Name(), op).Set(value)
+}
@@ -0,0 +1,153 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"Zeno/internal/config"
+	"Zeno/internal/metrics"
+	"Zeno/internal/provider"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type flakyProvider struct {
+	err   error
+	calls int
+}
+
+func (f *flakyProvider) Name() string { return "flaky" }
+
+func (f *flakyProvider) ListRunners(ctx context.Context) ([]*provider.Runner, error) {
+	f.calls++
+	return nil, f.err
+}
+
+func (f *flakyProvider) GetRunner(ctx context.Context, id string) (*provider.Runner, error) {
+	f.calls++
+	return nil, f.err
+}
+
+func (f *flakyProvider) CreateRunner(ctx context.Context, req *provider.CreateRunnerRequest) (*provider.Runner, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &provider.Runner{ID: "r1"}, nil
+}
+
+func (f *flakyProvider) RemoveRunner(ctx context.Context, id string, graceful bool) error {
+	f.calls++
+	return f.err
+}
+
+func (f *flakyProvider) HealthCheck(ctx context.Context) error {
+	f.calls++
+	return f.err
+}
+
+func (f *flakyProvider) Close() error { return nil }
+
+func newTestBreaker(next provider.Provider) (*Provider, *time.Time) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	p := Wrap(next, config.CircuitBreakerConfig{
+		Enabled:          true,
+		FailureThreshold: 3,
+		BaseBackoff:      time.Minute,
+		MaxBackoff:       3 * time.Minute,
+	}, metrics.NewMetrics(prometheus.NewRegistry()), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	p.now = func() time.Time { return now }
+	return p, &now
+}
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	next := &flakyProvider{err: errors.New("quota exhausted")}
+	p, _ := newTestBreaker(next)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := p.CreateRunner(ctx, &provider.CreateRunnerRequest{}); errors.Is(err, ErrOpen) {
+			t.Fatalf("call %d: circuit opened too early", i+1)
+		}
+	}
+
+	if got := p.State(OpCreate); got != StateOpen {
+		t.Fatalf("State(create) = %s, want %s", got, StateOpen)
+	}
+
+	_, err := p.CreateRunner(ctx, &provider.CreateRunnerRequest{})
+	if !errors.Is(err, ErrOpen) {
+		t.Errorf("CreateRunner() error = %v, want ErrOpen", err)
+	}
+	if next.calls != 3 {
+		t.Errorf("provider called %d times, want 3", next.calls)
+	}
+
+	// Other operations have their own circuit
+	if got := p.State(OpList); got != StateClosed {
+		t.Errorf("State(list) = %s, want %s", got, StateClosed)
+	}
+}
+
+func TestBreakerProbesWithExponentialBackoff(t *testing.T) {
+	next := &flakyProvider{err: errors.New("daemon down")}
+	p, now := newTestBreaker(next)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_ = p.HealthCheck(ctx)
+	}
+
+	// Backoff not yet elapsed
+	*now = now.Add(59 * time.Second)
+	if err := p.HealthCheck(ctx); !errors.Is(err, ErrOpen) {
+		t.Fatalf("HealthCheck() error = %v, want ErrOpen", err)
+	}
+
+	// First probe after 1m fails and doubles the backoff
+	*now = now.Add(time.Second)
+	if got := p.State(OpHealth); got != StateHalfOpen {
+		t.Fatalf("State(health) = %s, want %s", got, StateHalfOpen)
+	}
+	if err := p.HealthCheck(ctx); errors.Is(err, ErrOpen) {
+		t.Fatal("probe was not let through")
+	}
+	if got := p.State(OpHealth); got != StateOpen {
+		t.Fatalf("State(health) after failed probe = %s, want %s", got, StateOpen)
+	}
+
+	*now = now.Add(time.Minute)
+	if err := p.HealthCheck(ctx); !errors.Is(err, ErrOpen) {
+		t.Fatalf("HealthCheck() error = %v, want ErrOpen during doubled backoff", err)
+	}
+
+	// Second probe succeeds and closes the circuit
+	*now = now.Add(time.Minute)
+	next.err = nil
+	if err := p.HealthCheck(ctx); err != nil {
+		t.Fatalf("HealthCheck() error = %v, want nil", err)
+	}
+	if got := p.State(OpHealth); got != StateClosed {
+		t.Errorf("State(health) = %s, want %s", got, StateClosed)
+	}
+}
+
+func TestBreakerIgnoresCancelledContext(t *testing.T) {
+	next := &flakyProvider{err: context.Canceled}
+	p, _ := newTestBreaker(next)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for i := 0; i < 5; i++ {
+		_, _ = p.ListRunners(ctx)
+	}
+
+	if got := p.State(OpList); got != StateClosed {
+		t.Errorf("State(list) = %s, want %s", got, StateClosed)
+	}
+}
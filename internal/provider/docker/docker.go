@@ -23,6 +23,7 @@ const (
 	labelRunnerID     = runnerLabelPrefix + ".id"
 	labelRunnerName   = runnerLabelPrefix + ".name"
 	labelManagedBy    = runnerLabelPrefix + ".managed-by"
+	labelPool         = runnerLabelPrefix + ".pool"
 )
 
 type DockerProvider struct {
@@ -32,6 +33,12 @@ type DockerProvider struct {
 	mu     sync.RWMutex
 }
 
+func init() {
+	provider.Register("docker", func(cfg config.ProviderConfig, logger *slog.Logger) (provider.Provider, error) {
+		return New(cfg.Docker, logger)
+	})
+}
+
 // New creates a new Docker provider
 func New(cfg config.DockerConfig, logger *slog.Logger) (*DockerProvider, error) {
 	cli, err := client.NewClientWithOpts(
@@ -49,6 +56,20 @@ func New(cfg config.DockerConfig, logger *slog.Logger) (*DockerProvider, error)
 	}, nil
 }
 
+// Reconfigure applies new runner settings to runners created from now on.
+// The Docker host and pool are fixed for the life of the provider.
+func (p *DockerProvider) Reconfigure(cfg config.ProviderConfig) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var restart []string
+	next := cfg.Docker
+	provider.KeepFixed(&restart, "provider.docker.host", &next.Host, p.config.Host)
+	provider.KeepFixed(&restart, "provider.docker.pool", &next.Pool, p.config.Pool)
+	p.config = next
+	return restart, nil
+}
+
 func (p *DockerProvider) Name() string {
 	return "docker"
 }
@@ -71,6 +92,11 @@ func (p *DockerProvider) ListRunners(ctx context.Context) ([]*provider.Runner, e
 		}
 
 		status := mapContainerState(c.State)
+		metadata := requestMetadataFromLabels(c.Labels)
+		metadata["container_id"] = c.ID
+		metadata["image"] = c.Image
+		metadata["state"] = c.State
+
 		runners = append(runners, &provider.Runner{
 			ID:         c.Labels[labelRunnerID],
 			Name:       c.Labels[labelRunnerName],
@@ -78,11 +104,7 @@ func (p *DockerProvider) ListRunners(ctx context.Context) ([]*provider.Runner, e
 			Provider:   "docker",
 			ProviderID: c.ID,
 			CreatedAt:  time.Unix(c.Created, 0),
-			Metadata: map[string]string{
-				"container_id": c.ID,
-				"image":        c.Image,
-				"state":        c.State,
-			},
+			Metadata:   metadata,
 		})
 	}
 
@@ -173,6 +195,15 @@ func (p *DockerProvider) CreateRunner(ctx context.Context, req *provider.CreateR
 		"name", req.Name,
 	)
 
+	metadata := map[string]string{
+		"container_id": resp.ID,
+		"image":        p.config.Image,
+		"pool":         p.config.Pool,
+	}
+	for k, v := range req.Metadata {
+		metadata[k] = v
+	}
+
 	return &provider.Runner{
 		ID:         runnerID,
 		Name:       req.Name,
@@ -181,10 +212,7 @@ func (p *DockerProvider) CreateRunner(ctx context.Context, req *provider.CreateR
 		Provider:   "docker",
 		ProviderID: resp.ID,
 		CreatedAt:  time.Now(),
-		Metadata: map[string]string{
-			"container_id": resp.ID,
-			"image":        p.config.Image,
-		},
+		Metadata:   metadata,
 	}, nil
 }
 
@@ -262,27 +290,10 @@ func (p *DockerProvider) pullImage(ctx context.Context) error {
 }
 
 func (p *DockerProvider) buildEnv(req *provider.CreateRunnerRequest) []string {
-	env := []string{
-		fmt.Sprintf("RUNNER_NAME=%s", req.Name),
-		fmt.Sprintf("RUNNER_WORKDIR=%s", p.config.RunnerWorkDir),
+	var env []string
+	for _, v := range provider.ContainerEnv(req, p.config.RunnerWorkDir) {
+		env = append(env, v.Name+"="+v.Value)
 	}
-
-	if req.GitHubToken != "" {
-		env = append(env, fmt.Sprintf("ACCESS_TOKEN=%s", req.GitHubToken))
-	}
-
-	if req.GitHubOrg != "" {
-		env = append(env, fmt.Sprintf("RUNNER_SCOPE=org"))
-		env = append(env, fmt.Sprintf("ORG_NAME=%s", req.GitHubOrg))
-	} else if req.GitHubRepo != "" {
-		env = append(env, fmt.Sprintf("RUNNER_SCOPE=repo"))
-		env = append(env, fmt.Sprintf("REPO_URL=https://github.com/%s", req.GitHubRepo))
-	}
-
-	if len(req.Labels) > 0 {
-		env = append(env, fmt.Sprintf("LABELS=%s", strings.Join(req.Labels, ",")))
-	}
-
 	return env
 }
 
@@ -291,6 +302,7 @@ func (p *DockerProvider) buildLabels(runnerID string, req *provider.CreateRunner
 		labelRunnerID:   runnerID,
 		labelRunnerName: req.Name,
 		labelManagedBy:  "zeno",
+		labelPool:       p.config.Pool,
 	}
 
 	// Merge custom labels from config
@@ -306,6 +318,23 @@ func (p *DockerProvider) buildLabels(runnerID string, req *provider.CreateRunner
 	return labels
 }
 
+// requestMetadataFromLabels recovers the request metadata that buildLabels
+// stored under the runner label prefix
+func requestMetadataFromLabels(labels map[string]string) map[string]string {
+	metadata := make(map[string]string)
+	for k, v := range labels {
+		if !strings.HasPrefix(k, runnerLabelPrefix+".") {
+			continue
+		}
+		switch k {
+		case labelRunnerID, labelRunnerName, labelManagedBy:
+			continue
+		}
+		metadata[strings.TrimPrefix(k, runnerLabelPrefix+".")] = v
+	}
+	return metadata
+}
+
 func mapContainerState(state string) provider.RunnerStatus {
 	switch state {
 	case "running":
@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -20,17 +21,65 @@ import (
 )
 
 const (
-	tagManagedBy  = "zeno:managed-by"
-	tagRunnerID   = "zeno:runner-id"
-	tagRunnerName = "zeno:runner-name"
-	tagCreatedAt  = "zeno:created-at"
+	tagPrefix     = "zeno:"
+	tagManagedBy  = tagPrefix + "managed-by"
+	tagRunnerID   = tagPrefix + "runner-id"
+	tagRunnerName = tagPrefix + "runner-name"
+	tagCreatedAt  = tagPrefix + "created-at"
+
+	// Values of tagManagedBy. Warm pool instances are tagged apart from
+	// runners so they never count as active capacity.
+	managedByRunner   = "zeno"
+	managedByWarmPool = "zeno-warm-pool"
 )
 
+// ec2API is the subset of the EC2 client the provider uses
+type ec2API interface {
+	DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+	RunInstances(ctx context.Context, params *ec2.RunInstancesInput, optFns ...func(*ec2.Options)) (*ec2.RunInstancesOutput, error)
+	StartInstances(ctx context.Context, params *ec2.StartInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error)
+	TerminateInstances(ctx context.Context, params *ec2.TerminateInstancesInput, optFns ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error)
+	ModifyInstanceAttribute(ctx context.Context, params *ec2.ModifyInstanceAttributeInput, optFns ...func(*ec2.Options)) (*ec2.ModifyInstanceAttributeOutput, error)
+	CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error)
+	DescribeRegions(ctx context.Context, params *ec2.DescribeRegionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error)
+	RequestSpotInstances(ctx context.Context, params *ec2.RequestSpotInstancesInput, optFns ...func(*ec2.Options)) (*ec2.RequestSpotInstancesOutput, error)
+	DescribeSpotInstanceRequests(ctx context.Context, params *ec2.DescribeSpotInstanceRequestsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSpotInstanceRequestsOutput, error)
+	CreateFleet(ctx context.Context, params *ec2.CreateFleetInput, optFns ...func(*ec2.Options)) (*ec2.CreateFleetOutput, error)
+	CreateLaunchTemplateVersion(ctx context.Context, params *ec2.CreateLaunchTemplateVersionInput, optFns ...func(*ec2.Options)) (*ec2.CreateLaunchTemplateVersionOutput, error)
+	DescribeLaunchTemplateVersions(ctx context.Context, params *ec2.DescribeLaunchTemplateVersionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeLaunchTemplateVersionsOutput, error)
+	DeleteLaunchTemplateVersions(ctx context.Context, params *ec2.DeleteLaunchTemplateVersionsInput, optFns ...func(*ec2.Options)) (*ec2.DeleteLaunchTemplateVersionsOutput, error)
+}
+
 type EC2Provider struct {
-	client *ec2.Client
+	client ec2API
 	config config.AWSConfig
 	logger *slog.Logger
 	mu     sync.RWMutex
+
+	// undeletedVersions are fleet user data versions of the launch template
+	// that could not be deleted yet. Guarded by mu.
+	undeletedVersions []string
+
+	// Warm pool refill loop, running when the warm pool is enabled
+	refill   chan struct{}
+	stopPool context.CancelFunc
+	poolDone chan struct{}
+
+	// Interruption notices keyed by instance ID, and the endpoint and
+	// queue poller that receive them. watchInterruptions is fixed at
+	// creation, so it can be read without holding mu.
+	watchInterruptions bool
+	noticeMu           sync.Mutex
+	notices            map[string]string
+	stopWatch          context.CancelFunc
+	eventServer        *http.Server
+	watchers           sync.WaitGroup
+}
+
+func init() {
+	provider.Register("ec2", func(cfg config.ProviderConfig, logger *slog.Logger) (provider.Provider, error) {
+		return New(cfg.AWS, logger)
+	})
 }
 
 // New creates a new EC2 provider
@@ -42,11 +91,51 @@ func New(cfg config.AWSConfig, logger *slog.Logger) (*EC2Provider, error) {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
-	return &EC2Provider{
-		client: ec2.NewFromConfig(awsCfg),
-		config: cfg,
-		logger: logger.With("provider", "ec2"),
-	}, nil
+	p := newProvider(ec2.NewFromConfig(awsCfg), cfg, logger)
+
+	if in := cfg.Interruptions; in.Enabled {
+		var queue sqsAPI
+		if in.QueueURL != "" {
+			queue = newQueueClient(awsCfg, in)
+		}
+		if err := p.startInterruptionWatch(in, queue); err != nil {
+			p.Close()
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+func newProvider(client ec2API, cfg config.AWSConfig, logger *slog.Logger) *EC2Provider {
+	p := &EC2Provider{
+		client:             client,
+		config:             cfg,
+		logger:             logger.With("provider", "ec2"),
+		watchInterruptions: cfg.Interruptions.Enabled,
+	}
+
+	if cfg.WarmPool.Enabled {
+		p.startWarmPool()
+	}
+
+	return p
+}
+
+// Reconfigure applies new instance settings to runners created from now on.
+// The region, warm pool and interruption watch are fixed for the life of
+// the provider.
+func (p *EC2Provider) Reconfigure(cfg config.ProviderConfig) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var restart []string
+	next := cfg.AWS
+	provider.KeepFixed(&restart, "provider.aws.region", &next.Region, p.config.Region)
+	provider.KeepFixed(&restart, "provider.aws.warm_pool", &next.WarmPool, p.config.WarmPool)
+	provider.KeepFixed(&restart, "provider.aws.interruptions", &next.Interruptions, p.config.Interruptions)
+	p.config = next
+	return restart, nil
 }
 
 func (p *EC2Provider) Name() string {
@@ -57,11 +146,16 @@ func (p *EC2Provider) ListRunners(ctx context.Context) ([]*provider.Runner, erro
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
+	return p.listRunners(ctx)
+}
+
+// listRunners lists the runners while mu is held
+func (p *EC2Provider) listRunners(ctx context.Context) ([]*provider.Runner, error) {
 	input := &ec2.DescribeInstancesInput{
 		Filters: []types.Filter{
 			{
 				Name:   aws.String("tag:" + tagManagedBy),
-				Values: []string{"zeno"},
+				Values: []string{managedByRunner},
 			},
 			{
 				Name: aws.String("instance-state-name"),
@@ -70,6 +164,7 @@ func (p *EC2Provider) ListRunners(ctx context.Context) ([]*provider.Runner, erro
 					"running",
 					"stopping",
 					"stopped",
+					"shutting-down",
 				},
 			},
 		},
@@ -81,13 +176,24 @@ func (p *EC2Provider) ListRunners(ctx context.Context) ([]*provider.Runner, erro
 	}
 
 	var runners []*provider.Runner
+	listed := make(map[string]bool)
 	for _, reservation := range result.Reservations {
 		for _, instance := range reservation.Instances {
+			// Instances shutting down are only listed when reclaimed, so
+			// the runner is seen draining and replaced
+			if instance.State.Name == types.InstanceStateNameShuttingDown && p.instanceInterruption(&instance) == "" {
+				continue
+			}
+			listed[aws.ToString(instance.InstanceId)] = true
 			runner := p.instanceToRunner(&instance)
 			runners = append(runners, runner)
 		}
 	}
 
+	if p.watchInterruptions {
+		p.pruneNotices(listed)
+	}
+
 	return runners, nil
 }
 
@@ -119,44 +225,66 @@ func (p *EC2Provider) CreateRunner(ctx context.Context, req *provider.CreateRunn
 
 	runnerID := uuid.New().String()
 
+	if p.config.WarmPool.Enabled {
+		if runner, ok := p.claimWarmInstance(ctx, runnerID, req); ok {
+			return runner, nil
+		}
+	}
+
+	spot := p.config.UseSpot
+	if p.config.SpotMix.Enabled {
+		var err error
+		if spot, err = p.mixWantsSpot(ctx); err != nil {
+			return nil, err
+		}
+	}
+
 	p.logger.Info("creating EC2 instance",
 		"id", runnerID,
 		"name", req.Name,
 		"instance_type", p.config.InstanceType,
-		"use_spot", p.config.UseSpot,
+		"use_spot", spot,
 	)
 
 	userData := p.buildUserData(req)
 	userDataB64 := base64.StdEncoding.EncodeToString([]byte(userData))
 
-	tags := p.buildTags(runnerID, req)
-	tagSpecs := []types.TagSpecification{
-		{
-			ResourceType: types.ResourceTypeInstance,
-			Tags:         tags,
-		},
-		{
-			ResourceType: types.ResourceTypeVolume,
-			Tags:         tags,
-		},
-	}
+	if p.config.Fleet.Enabled {
+		instance, err := p.createFleetInstance(ctx, userDataB64, p.buildTags(runnerID, req), spot)
+		if err != nil {
+			return nil, err
+		}
 
-	blockDeviceMappings := []types.BlockDeviceMapping{
-		{
-			DeviceName: aws.String("/dev/sda1"),
-			Ebs: &types.EbsBlockDevice{
-				VolumeSize:          aws.Int32(p.config.VolumeSize),
-				VolumeType:          types.VolumeType(p.config.VolumeType),
-				DeleteOnTermination: aws.Bool(true),
-			},
-		},
+		p.logger.Info("EC2 fleet instance created",
+			"id", runnerID,
+			"instance_id", instance.id,
+			"instance_type", instance.instanceType,
+			"az", instance.az,
+			"spot", instance.spot,
+		)
+
+		runner := p.newRunner(runnerID, instance.id, req, instance.spot)
+		runner.Metadata["instance_type"] = instance.instanceType
+		runner.Metadata["az"] = instance.az
+		if instance.subnetID != "" {
+			runner.Metadata["subnet_id"] = instance.subnetID
+		}
+		return runner, nil
 	}
 
+	tagSpecs := tagSpecifications(p.buildTags(runnerID, req))
+	blockDeviceMappings := p.blockDeviceMappings()
+
 	var instanceID string
 	var err error
 
-	if p.config.UseSpot {
+	if spot {
 		instanceID, err = p.createSpotInstance(ctx, userDataB64, tagSpecs, blockDeviceMappings)
+		if err != nil && p.config.SpotMix.Enabled {
+			p.logger.Warn("spot launch failed, falling back to on-demand", "id", runnerID, "error", err)
+			spot = false
+			instanceID, err = p.createOnDemandInstance(ctx, userDataB64, tagSpecs, blockDeviceMappings)
+		}
 	} else {
 		instanceID, err = p.createOnDemandInstance(ctx, userDataB64, tagSpecs, blockDeviceMappings)
 	}
@@ -170,6 +298,45 @@ func (p *EC2Provider) CreateRunner(ctx context.Context, req *provider.CreateRunn
 		"instance_id", instanceID,
 	)
 
+	return p.newRunner(runnerID, instanceID, req, spot), nil
+}
+
+// mixWantsSpot reports whether the next runner should be spot under the
+// spot mix, given the runners already running. Draining runners are on
+// their way out and do not count.
+func (p *EC2Provider) mixWantsSpot(ctx context.Context) (bool, error) {
+	runners, err := p.listRunners(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	active, spot := 0, 0
+	for _, r := range runners {
+		if r.Status == provider.StatusTerminated || r.Status == provider.StatusDraining {
+			continue
+		}
+		active++
+		if r.Metadata[provider.MetadataLifecycle] == provider.LifecycleSpot {
+			spot++
+		}
+	}
+
+	return spot < p.config.SpotMix.SpotRunners(active+1), nil
+}
+
+// newRunner describes a runner whose instance was just launched or started
+func (p *EC2Provider) newRunner(runnerID, instanceID string, req *provider.CreateRunnerRequest, spot bool) *provider.Runner {
+	metadata := map[string]string{
+		"instance_id":   instanceID,
+		"instance_type": p.config.InstanceType,
+		"region":        p.config.Region,
+		"spot":          fmt.Sprintf("%t", spot),
+	}
+	metadata[provider.MetadataLifecycle] = lifecycle(spot)
+	for k, v := range req.Metadata {
+		metadata[k] = v
+	}
+
 	return &provider.Runner{
 		ID:         runnerID,
 		Name:       req.Name,
@@ -178,13 +345,8 @@ func (p *EC2Provider) CreateRunner(ctx context.Context, req *provider.CreateRunn
 		Provider:   "ec2",
 		ProviderID: instanceID,
 		CreatedAt:  time.Now(),
-		Metadata: map[string]string{
-			"instance_id":   instanceID,
-			"instance_type": p.config.InstanceType,
-			"region":        p.config.Region,
-			"spot":          fmt.Sprintf("%t", p.config.UseSpot),
-		},
-	}, nil
+		Metadata:   metadata,
+	}
 }
 
 func (p *EC2Provider) RemoveRunner(ctx context.Context, id string, graceful bool) error {
@@ -225,8 +387,18 @@ func (p *EC2Provider) HealthCheck(ctx context.Context) error {
 	return nil
 }
 
+// Close stops the warm pool refill loop and the interruption watch. Warm
+// instances are left stopped so a restarted controller can use them.
 func (p *EC2Provider) Close() error {
-	return nil
+	if p.stopPool != nil {
+		p.stopPool()
+		<-p.poolDone
+	}
+	p.stopInterruptionWatch()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.deleteUserDataVersions()
 }
 
 func (p *EC2Provider) createOnDemandInstance(
@@ -235,6 +407,41 @@ func (p *EC2Provider) createOnDemandInstance(
 	tagSpecs []types.TagSpecification,
 	blockDeviceMappings []types.BlockDeviceMapping,
 ) (string, error) {
+	input := p.runInstancesInput(userData, tagSpecs, blockDeviceMappings)
+
+	result, err := p.client.RunInstances(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("failed to run on-demand instance: %w", err)
+	}
+
+	if len(result.Instances) == 0 {
+		return "", fmt.Errorf("no instances created")
+	}
+
+	return *result.Instances[0].InstanceId, nil
+}
+
+func (p *EC2Provider) runInstancesInput(
+	userData string,
+	tagSpecs []types.TagSpecification,
+	blockDeviceMappings []types.BlockDeviceMapping,
+) *ec2.RunInstancesInput {
+	if template := p.launchTemplate(); template != nil {
+		// The template supplies the image, network, storage and anything
+		// else Zeno has no setting for
+		input := &ec2.RunInstancesInput{
+			LaunchTemplate:    template,
+			MinCount:          aws.Int32(1),
+			MaxCount:          aws.Int32(1),
+			UserData:          aws.String(userData),
+			TagSpecifications: tagSpecs,
+		}
+		if p.config.InstanceType != "" {
+			input.InstanceType = types.InstanceType(p.config.InstanceType)
+		}
+		return input
+	}
+
 	input := &ec2.RunInstancesInput{
 		ImageId:             aws.String(p.config.AMI),
 		InstanceType:        types.InstanceType(p.config.InstanceType),
@@ -257,16 +464,54 @@ func (p *EC2Provider) createOnDemandInstance(
 		}
 	}
 
-	result, err := p.client.RunInstances(ctx, input)
-	if err != nil {
-		return "", fmt.Errorf("failed to run on-demand instance: %w", err)
+	return input
+}
+
+// launchTemplate returns the configured launch template, or nil when
+// instances are described by the individual settings
+func (p *EC2Provider) launchTemplate() *types.LaunchTemplateSpecification {
+	lt := p.config.LaunchTemplate
+	if lt.ID == "" && lt.Name == "" {
+		return nil
 	}
 
-	if len(result.Instances) == 0 {
-		return "", fmt.Errorf("no instances created")
+	spec := &types.LaunchTemplateSpecification{}
+	if lt.ID != "" {
+		spec.LaunchTemplateId = aws.String(lt.ID)
+	} else {
+		spec.LaunchTemplateName = aws.String(lt.Name)
+	}
+	if lt.Version != "" {
+		spec.Version = aws.String(lt.Version)
+	}
+	return spec
+}
+
+func (p *EC2Provider) blockDeviceMappings() []types.BlockDeviceMapping {
+	return []types.BlockDeviceMapping{
+		{
+			DeviceName: aws.String("/dev/sda1"),
+			Ebs: &types.EbsBlockDevice{
+				VolumeSize:          aws.Int32(p.config.VolumeSize),
+				VolumeType:          types.VolumeType(p.config.VolumeType),
+				DeleteOnTermination: aws.Bool(true),
+			},
+		},
 	}
+}
 
-	return *result.Instances[0].InstanceId, nil
+// tagSpecifications applies tags to an instance and its volumes
+func tagSpecifications(tags []types.Tag) []types.TagSpecification {
+	return []types.TagSpecification{
+		{
+			ResourceType: types.ResourceTypeInstance,
+			Tags:         tags,
+		},
+		{
+			ResourceType: types.ResourceTypeVolume,
+			Tags:         tags,
+		},
+	}
 }
 
 func (p *EC2Provider) createSpotInstance(
@@ -275,6 +520,10 @@ func (p *EC2Provider) createSpotInstance(
 	tagSpecs []types.TagSpecification,
 	blockDeviceMappings []types.BlockDeviceMapping,
 ) (string, error) {
+	if p.launchTemplate() != nil {
+		return p.createTemplateSpotInstance(ctx, userData, tagSpecs, blockDeviceMappings)
+	}
+
 	launchSpec := &types.RequestSpotLaunchSpecification{
 		ImageId:             aws.String(p.config.AMI),
 		InstanceType:        types.InstanceType(p.config.InstanceType),
@@ -348,46 +597,59 @@ func (p *EC2Provider) createSpotInstance(
 	return instanceID, nil
 }
 
+// createTemplateSpotInstance launches a one-time spot instance from the
+// launch template. Spot requests cannot reference a launch template, so the
+// instance is launched with RunInstances and spot market options instead.
+func (p *EC2Provider) createTemplateSpotInstance(
+	ctx context.Context,
+	userData string,
+	tagSpecs []types.TagSpecification,
+	blockDeviceMappings []types.BlockDeviceMapping,
+) (string, error) {
+	input := p.runInstancesInput(userData, tagSpecs, blockDeviceMappings)
+	input.InstanceMarketOptions = &types.InstanceMarketOptionsRequest{
+		MarketType: types.MarketTypeSpot,
+		SpotOptions: &types.SpotMarketOptions{
+			SpotInstanceType:             types.SpotInstanceTypeOneTime,
+			InstanceInterruptionBehavior: types.InstanceInterruptionBehaviorTerminate,
+		},
+	}
+	if p.config.SpotMaxPrice != "" {
+		input.InstanceMarketOptions.SpotOptions.MaxPrice = aws.String(p.config.SpotMaxPrice)
+	}
+
+	result, err := p.client.RunInstances(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("failed to run spot instance: %w", err)
+	}
+
+	if len(result.Instances) == 0 {
+		return "", fmt.Errorf("no instances created")
+	}
+
+	return *result.Instances[0].InstanceId, nil
+}
+
+// runnerDir is where the default user data installs the runner
+const runnerDir = "/home/ubuntu/actions-runner"
+
+// singleUseRunners keeps the default user data registering runners with
+// --ephemeral outside ephemeral mode too, as it always has, so each runner
+// takes one job. Only ephemeral mode powers the instance off afterwards.
+const singleUseRunners = true
+
 func (p *EC2Provider) buildUserData(req *provider.CreateRunnerRequest) string {
 	if p.config.UserDataScript != "" {
-		// Use custom user data script
-		script := p.config.UserDataScript
-		script = strings.ReplaceAll(script, "{{RUNNER_NAME}}", req.Name)
-		script = strings.ReplaceAll(script, "{{GITHUB_TOKEN}}", req.GitHubToken)
-		script = strings.ReplaceAll(script, "{{GITHUB_ORG}}", req.GitHubOrg)
-		script = strings.ReplaceAll(script, "{{GITHUB_REPO}}", req.GitHubRepo)
-		script = strings.ReplaceAll(script, "{{LABELS}}", strings.Join(req.Labels, ","))
-		return script
-	}
-
-	// Default user data script
-	return fmt.Sprintf(`#!/bin/bash
-set -e
-
-# Install GitHub Actions runner
-cd /home/ubuntu
-mkdir actions-runner && cd actions-runner
-curl -o actions-runner-linux-x64-2.311.0.tar.gz -L https://github.com/actions/runner/releases/download/v2.311.0/actions-runner-linux-x64-2.311.0.tar.gz
-tar xzf ./actions-runner-linux-x64-2.311.0.tar.gz
-
-# Configure runner
-./config.sh --url https://github.com/%s --token %s --name %s --labels %s --unattended --ephemeral
-
-# Start runner
-./run.sh
-`,
-		req.GitHubOrg,
-		req.GitHubToken,
-		req.Name,
-		strings.Join(req.Labels, ","),
-	)
+		return provider.ExpandScript(p.config.UserDataScript, req)
+	}
+	return provider.BootstrapScript(req, runnerDir, singleUseRunners)
 }
 
 func (p *EC2Provider) buildTags(runnerID string, req *provider.CreateRunnerRequest) []types.Tag {
 	tags := []types.Tag{
 		{
 			Key:   aws.String(tagManagedBy),
-			Value: aws.String("zeno"),
+			Value: aws.String(managedByRunner),
 		},
 		{
 			Key:   aws.String(tagRunnerID),
@@ -407,6 +669,14 @@ func (p *EC2Provider) buildTags(runnerID string, req *provider.CreateRunnerReque
 		},
 	}
 
+	// Add request metadata
+	for k, v := range req.Metadata {
+		tags = append(tags, types.Tag{
+			Key:   aws.String(tagPrefix + k),
+			Value: aws.String(v),
+		})
+	}
+
 	// Add custom tags from config
 	for k, v := range p.config.Tags {
 		tags = append(tags, types.Tag{
@@ -445,12 +715,32 @@ func (p *EC2Provider) instanceToRunner(instance *types.Instance) *provider.Runne
 		"az":             *instance.Placement.AvailabilityZone,
 	}
 
+	// Request metadata is stored as zeno-prefixed tags
+	for _, tag := range instance.Tags {
+		key := aws.ToString(tag.Key)
+		switch key {
+		case tagManagedBy, tagRunnerID, tagRunnerName, tagCreatedAt:
+			continue
+		}
+		if strings.HasPrefix(key, tagPrefix) {
+			metadata[strings.TrimPrefix(key, tagPrefix)] = aws.ToString(tag.Value)
+		}
+	}
+
 	if instance.PrivateIpAddress != nil {
 		metadata["private_ip"] = *instance.PrivateIpAddress
 	}
 	if instance.PublicIpAddress != nil {
 		metadata["public_ip"] = *instance.PublicIpAddress
 	}
+	metadata[provider.MetadataLifecycle] = lifecycle(instance.InstanceLifecycle == types.InstanceLifecycleTypeSpot)
+
+	if kind := p.instanceInterruption(instance); kind != "" {
+		metadata[provider.MetadataInterruption] = kind
+		if status != provider.StatusTerminated {
+			status = provider.StatusDraining
+		}
+	}
 
 	return &provider.Runner{
 		ID:         runnerID,
@@ -463,6 +753,13 @@ func (p *EC2Provider) instanceToRunner(instance *types.Instance) *provider.Runne
 	}
 }
 
+func lifecycle(spot bool) string {
+	if spot {
+		return provider.LifecycleSpot
+	}
+	return provider.LifecycleOnDemand
+}
+
 func mapInstanceState(state types.InstanceStateName) provider.RunnerStatus {
 	switch state {
 	case types.InstanceStateNamePending:
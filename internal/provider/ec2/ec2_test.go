@@ -0,0 +1,1043 @@
+package ec2
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"Zeno/internal/config"
+	"Zeno/internal/provider"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// fakeEC2 is an in-memory EC2 API holding instances, their tags and user data
+type fakeEC2 struct {
+	mu        sync.Mutex
+	instances []*types.Instance
+	userData  map[string]string
+	runInputs []*ec2.RunInstancesInput
+	nextID    int
+	launched  time.Time
+
+	startErr error
+
+	// Launch template versions created for fleets, with their user data
+	templateVersions map[int64]string
+	deletedVersions  []string
+	deleteErr        error
+	fleetInputs      []*ec2.CreateFleetInput
+	// noSpotCapacity makes spot fleets launch nothing and spot launches fail
+	noSpotCapacity bool
+}
+
+func newFakeEC2() *fakeEC2 {
+	return &fakeEC2{
+		userData:         make(map[string]string),
+		templateVersions: make(map[int64]string),
+		launched:         time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func (f *fakeEC2) find(id string) *types.Instance {
+	for _, instance := range f.instances {
+		if aws.ToString(instance.InstanceId) == id {
+			return instance
+		}
+	}
+	return nil
+}
+
+// stopAll simulates warm instances finishing preparation and powering off
+func (f *fakeEC2) stopAll() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, instance := range f.instances {
+		if instance.State.Name == types.InstanceStateNamePending {
+			instance.State = &types.InstanceState{Name: types.InstanceStateNameStopped}
+		}
+	}
+}
+
+func (f *fakeEC2) tag(id, key string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, tag := range f.find(id).Tags {
+		if aws.ToString(tag.Key) == key {
+			return aws.ToString(tag.Value)
+		}
+	}
+	return ""
+}
+
+func (f *fakeEC2) count(managedBy string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := 0
+	for _, instance := range f.instances {
+		if instance.State.Name != types.InstanceStateNameTerminated && hasTag(instance, tagManagedBy, managedBy) {
+			n++
+		}
+	}
+	return n
+}
+
+func hasTag(instance *types.Instance, key, value string) bool {
+	for _, tag := range instance.Tags {
+		if aws.ToString(tag.Key) == key && aws.ToString(tag.Value) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func matches(instance *types.Instance, filter types.Filter) bool {
+	name := aws.ToString(filter.Name)
+	for _, value := range filter.Values {
+		switch {
+		case name == "instance-state-name":
+			if string(instance.State.Name) == value {
+				return true
+			}
+		case strings.HasPrefix(name, "tag:"):
+			if hasTag(instance, strings.TrimPrefix(name, "tag:"), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (f *fakeEC2) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var instances []types.Instance
+outer:
+	for _, instance := range f.instances {
+		if len(params.InstanceIds) > 0 && aws.ToString(instance.InstanceId) != params.InstanceIds[0] {
+			continue
+		}
+		for _, filter := range params.Filters {
+			if !matches(instance, filter) {
+				continue outer
+			}
+		}
+		instances = append(instances, *instance)
+	}
+
+	return &ec2.DescribeInstancesOutput{
+		Reservations: []types.Reservation{{Instances: instances}},
+	}, nil
+}
+
+func (f *fakeEC2) RunInstances(ctx context.Context, params *ec2.RunInstancesInput, optFns ...func(*ec2.Options)) (*ec2.RunInstancesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var lifecycle types.InstanceLifecycleType
+	if params.InstanceMarketOptions != nil && params.InstanceMarketOptions.MarketType == types.MarketTypeSpot {
+		if f.noSpotCapacity {
+			return nil, fmt.Errorf("InsufficientInstanceCapacity: no spot capacity")
+		}
+		lifecycle = types.InstanceLifecycleTypeSpot
+	}
+
+	f.nextID++
+	f.launched = f.launched.Add(time.Minute)
+	id := fmt.Sprintf("i-%04d", f.nextID)
+
+	var tags []types.Tag
+	for _, spec := range params.TagSpecifications {
+		if spec.ResourceType == types.ResourceTypeInstance {
+			tags = append(tags, spec.Tags...)
+		}
+	}
+
+	instance := &types.Instance{
+		InstanceId:        aws.String(id),
+		InstanceType:      params.InstanceType,
+		InstanceLifecycle: lifecycle,
+		LaunchTime:        aws.Time(f.launched),
+		Placement:         &types.Placement{AvailabilityZone: aws.String("us-east-1a")},
+		State:             &types.InstanceState{Name: types.InstanceStateNamePending},
+		Tags:              tags,
+		BlockDeviceMappings: []types.InstanceBlockDeviceMapping{{
+			DeviceName: aws.String("/dev/sda1"),
+			Ebs:        &types.EbsInstanceBlockDevice{VolumeId: aws.String("vol-" + id)},
+		}},
+	}
+	f.instances = append(f.instances, instance)
+	f.runInputs = append(f.runInputs, params)
+
+	data, _ := base64.StdEncoding.DecodeString(aws.ToString(params.UserData))
+	f.userData[id] = string(data)
+
+	return &ec2.RunInstancesOutput{Instances: []types.Instance{*instance}}, nil
+}
+
+func (f *fakeEC2) StartInstances(ctx context.Context, params *ec2.StartInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.startErr != nil {
+		return nil, f.startErr
+	}
+	for _, id := range params.InstanceIds {
+		instance := f.find(id)
+		if instance == nil || instance.State.Name != types.InstanceStateNameStopped {
+			return nil, fmt.Errorf("IncorrectInstanceState: %s", id)
+		}
+		instance.State = &types.InstanceState{Name: types.InstanceStateNamePending}
+	}
+	return &ec2.StartInstancesOutput{}, nil
+}
+
+func (f *fakeEC2) TerminateInstances(ctx context.Context, params *ec2.TerminateInstancesInput, optFns ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, id := range params.InstanceIds {
+		if instance := f.find(id); instance != nil {
+			instance.State = &types.InstanceState{Name: types.InstanceStateNameTerminated}
+		}
+	}
+	return &ec2.TerminateInstancesOutput{}, nil
+}
+
+func (f *fakeEC2) ModifyInstanceAttribute(ctx context.Context, params *ec2.ModifyInstanceAttributeInput, optFns ...func(*ec2.Options)) (*ec2.ModifyInstanceAttributeOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := aws.ToString(params.InstanceId)
+	instance := f.find(id)
+	if instance == nil || instance.State.Name != types.InstanceStateNameStopped {
+		return nil, fmt.Errorf("IncorrectInstanceState: %s", id)
+	}
+	if params.UserData != nil {
+		f.userData[id] = string(params.UserData.Value)
+	}
+	return &ec2.ModifyInstanceAttributeOutput{}, nil
+}
+
+func (f *fakeEC2) CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, id := range params.Resources {
+		instance := f.find(id)
+		if instance == nil {
+			continue
+		}
+	tags:
+		for _, tag := range params.Tags {
+			for i, existing := range instance.Tags {
+				if aws.ToString(existing.Key) == aws.ToString(tag.Key) {
+					instance.Tags[i] = tag
+					continue tags
+				}
+			}
+			instance.Tags = append(instance.Tags, tag)
+		}
+	}
+	return &ec2.CreateTagsOutput{}, nil
+}
+
+func (f *fakeEC2) DescribeRegions(ctx context.Context, params *ec2.DescribeRegionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error) {
+	return &ec2.DescribeRegionsOutput{}, nil
+}
+
+func (f *fakeEC2) RequestSpotInstances(ctx context.Context, params *ec2.RequestSpotInstancesInput, optFns ...func(*ec2.Options)) (*ec2.RequestSpotInstancesOutput, error) {
+	return nil, fmt.Errorf("spot requests are not supported by the fake")
+}
+
+func (f *fakeEC2) DescribeSpotInstanceRequests(ctx context.Context, params *ec2.DescribeSpotInstanceRequestsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSpotInstanceRequestsOutput, error) {
+	return nil, fmt.Errorf("spot requests are not supported by the fake")
+}
+
+func (f *fakeEC2) CreateFleet(ctx context.Context, params *ec2.CreateFleetInput, optFns ...func(*ec2.Options)) (*ec2.CreateFleetOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.fleetInputs = append(f.fleetInputs, params)
+	config := params.LaunchTemplateConfigs[0]
+	override := config.Overrides[len(config.Overrides)-1]
+	lifecycle := types.InstanceLifecycleOnDemand
+	if params.TargetCapacitySpecification.DefaultTargetCapacityType == types.DefaultTargetCapacityTypeSpot {
+		lifecycle = types.InstanceLifecycleSpot
+	}
+
+	if lifecycle == types.InstanceLifecycleSpot && f.noSpotCapacity {
+		return &ec2.CreateFleetOutput{Errors: []types.CreateFleetError{{
+			ErrorCode: aws.String("InsufficientInstanceCapacity"),
+			LaunchTemplateAndOverrides: &types.LaunchTemplateAndOverridesResponse{
+				Overrides: &types.FleetLaunchTemplateOverrides{InstanceType: override.InstanceType, SubnetId: override.SubnetId},
+			},
+		}}}, nil
+	}
+
+	// The last pairing stands in for the one EC2 picked
+	f.nextID++
+	id := fmt.Sprintf("i-%04d", f.nextID)
+	version, _ := strconv.ParseInt(aws.ToString(config.LaunchTemplateSpecification.Version), 10, 64)
+	f.userData[id] = f.templateVersions[version]
+	f.instances = append(f.instances, &types.Instance{
+		InstanceId:   aws.String(id),
+		InstanceType: override.InstanceType,
+		Placement:    &types.Placement{AvailabilityZone: aws.String("us-east-1b")},
+		State:        &types.InstanceState{Name: types.InstanceStateNamePending},
+		Tags:         params.TagSpecifications[0].Tags,
+	})
+
+	return &ec2.CreateFleetOutput{Instances: []types.CreateFleetInstance{{
+		InstanceIds:  []string{id},
+		InstanceType: override.InstanceType,
+		Lifecycle:    lifecycle,
+		LaunchTemplateAndOverrides: &types.LaunchTemplateAndOverridesResponse{
+			Overrides: &types.FleetLaunchTemplateOverrides{InstanceType: override.InstanceType, SubnetId: override.SubnetId},
+		},
+	}}}, nil
+}
+
+func (f *fakeEC2) CreateLaunchTemplateVersion(ctx context.Context, params *ec2.CreateLaunchTemplateVersionInput, optFns ...func(*ec2.Options)) (*ec2.CreateLaunchTemplateVersionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if aws.ToString(params.SourceVersion) != "3" {
+		return nil, fmt.Errorf("source version %q is not the default version 3", aws.ToString(params.SourceVersion))
+	}
+	version := int64(10 + len(f.templateVersions))
+	data, _ := base64.StdEncoding.DecodeString(aws.ToString(params.LaunchTemplateData.UserData))
+	f.templateVersions[version] = string(data)
+
+	return &ec2.CreateLaunchTemplateVersionOutput{
+		LaunchTemplateVersion: &types.LaunchTemplateVersion{VersionNumber: aws.Int64(version)},
+	}, nil
+}
+
+func (f *fakeEC2) DescribeLaunchTemplateVersions(ctx context.Context, params *ec2.DescribeLaunchTemplateVersionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeLaunchTemplateVersionsOutput, error) {
+	return &ec2.DescribeLaunchTemplateVersionsOutput{
+		LaunchTemplateVersions: []types.LaunchTemplateVersion{{VersionNumber: aws.Int64(3)}},
+	}, nil
+}
+
+func (f *fakeEC2) DeleteLaunchTemplateVersions(ctx context.Context, params *ec2.DeleteLaunchTemplateVersionsInput, optFns ...func(*ec2.Options)) (*ec2.DeleteLaunchTemplateVersionsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.deleteErr != nil {
+		return nil, f.deleteErr
+	}
+	f.deletedVersions = append(f.deletedVersions, params.Versions...)
+	return &ec2.DeleteLaunchTemplateVersionsOutput{}, nil
+}
+
+func testAWSConfig() config.AWSConfig {
+	return config.AWSConfig{
+		Region:           "us-east-1",
+		InstanceType:     "t3.medium",
+		AMI:              "ami-123",
+		SubnetID:         "subnet-123",
+		SecurityGroupIDs: []string{"sg-123"},
+		VolumeSize:       30,
+		VolumeType:       "gp3",
+		WarmPool: config.WarmPoolConfig{
+			Enabled:        true,
+			Size:           2,
+			RefillInterval: time.Hour,
+		},
+	}
+}
+
+// newTestProvider builds a provider without the background refill loop
+func newTestProvider(fake *fakeEC2, cfg config.AWSConfig) *EC2Provider {
+	return &EC2Provider{
+		client: fake,
+		config: cfg,
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func testRequest() *provider.CreateRunnerRequest {
+	return &provider.CreateRunnerRequest{
+		Name:        "zeno-runner-1",
+		Labels:      []string{"self-hosted", "linux"},
+		GitHubOrg:   "acme",
+		GitHubToken: "reg-token",
+		Metadata:    map[string]string{"pool": "ec2"},
+	}
+}
+
+func TestDefaultUserDataRegistersSingleUseRunners(t *testing.T) {
+	p := newTestProvider(newFakeEC2(), testAWSConfig())
+
+	// Outside ephemeral mode runners still take one job, but the instance
+	// stays up until the controller removes it
+	userData := p.buildUserData(testRequest())
+	if !strings.Contains(userData, "--unattended --ephemeral") || strings.Contains(userData, "shutdown -h now") {
+		t.Errorf("default user data should register a single-use runner without powering off:\n%s", userData)
+	}
+
+	req := testRequest()
+	req.Ephemeral = true
+	userData = p.buildUserData(req)
+	if !strings.Contains(userData, "--unattended --ephemeral") || !strings.Contains(userData, "shutdown -h now") {
+		t.Errorf("ephemeral user data should register a single-use runner and power off:\n%s", userData)
+	}
+}
+
+func TestRefillWarmPool(t *testing.T) {
+	fake := newFakeEC2()
+	p := newTestProvider(fake, testAWSConfig())
+	ctx := context.Background()
+
+	if err := p.refillWarmPool(ctx); err != nil {
+		t.Fatalf("refillWarmPool() error = %v", err)
+	}
+	if got := fake.count(managedByWarmPool); got != 2 {
+		t.Fatalf("warm instances = %d, want 2", got)
+	}
+
+	input := fake.runInputs[0]
+	if input.InstanceInitiatedShutdownBehavior != types.ShutdownBehaviorStop {
+		t.Errorf("shutdown behaviour = %q, want stop", input.InstanceInitiatedShutdownBehavior)
+	}
+	userData := fake.userData["i-0001"]
+	if !strings.Contains(userData, "tar xzf") || !strings.Contains(userData, "shutdown -h now") {
+		t.Errorf("warm user data does not install the runner and stop:\n%s", userData)
+	}
+	if strings.Contains(userData, "config.sh") {
+		t.Errorf("warm user data registers a runner:\n%s", userData)
+	}
+
+	// Instances still preparing count towards the pool
+	if err := p.refillWarmPool(ctx); err != nil {
+		t.Fatalf("refillWarmPool() error = %v", err)
+	}
+	if got := fake.count(managedByWarmPool); got != 2 {
+		t.Errorf("warm instances after second refill = %d, want 2", got)
+	}
+
+	runners, err := p.ListRunners(ctx)
+	if err != nil {
+		t.Fatalf("ListRunners() error = %v", err)
+	}
+	if len(runners) != 0 {
+		t.Errorf("ListRunners() returned %d warm instances as runners", len(runners))
+	}
+}
+
+func TestCreateRunnerStartsWarmInstance(t *testing.T) {
+	fake := newFakeEC2()
+	p := newTestProvider(fake, testAWSConfig())
+	ctx := context.Background()
+
+	if err := p.refillWarmPool(ctx); err != nil {
+		t.Fatalf("refillWarmPool() error = %v", err)
+	}
+	fake.stopAll()
+
+	runner, err := p.CreateRunner(ctx, testRequest())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+
+	// The oldest warm instance is claimed and nothing new is launched
+	if runner.ProviderID != "i-0001" {
+		t.Errorf("ProviderID = %s, want the oldest warm instance i-0001", runner.ProviderID)
+	}
+	if runner.Metadata["warm_pool"] != "true" || runner.Metadata["pool"] != "ec2" {
+		t.Errorf("Metadata = %v", runner.Metadata)
+	}
+	if len(fake.runInputs) != 2 {
+		t.Errorf("RunInstances called %d times, want only the 2 warm launches", len(fake.runInputs))
+	}
+
+	if got := fake.tag("i-0001", tagManagedBy); got != managedByRunner {
+		t.Errorf("managed-by tag = %q, want %q", got, managedByRunner)
+	}
+	if got := fake.tag("i-0001", tagRunnerName); got != "zeno-runner-1" {
+		t.Errorf("runner-name tag = %q", got)
+	}
+
+	userData := fake.userData["i-0001"]
+	if !strings.HasPrefix(userData, "#!/bin/bash\n"+warmClaimMarker+"\n") {
+		t.Errorf("claim user data lacks the marker:\n%s", userData)
+	}
+	if !strings.Contains(userData, "--name zeno-runner-1") || strings.Contains(userData, "tar xzf") {
+		t.Errorf("claim user data should only configure the runner:\n%s", userData)
+	}
+
+	runners, err := p.ListRunners(ctx)
+	if err != nil {
+		t.Fatalf("ListRunners() error = %v", err)
+	}
+	if len(runners) != 1 || runners[0].ID != runner.ID || runners[0].Metadata["warm_pool"] != "true" {
+		t.Errorf("ListRunners() = %+v, want the claimed runner", runners)
+	}
+	if got := fake.count(managedByWarmPool); got != 1 {
+		t.Errorf("warm instances left = %d, want 1", got)
+	}
+}
+
+func TestCreateRunnerColdStartsWithoutStoppedWarmInstance(t *testing.T) {
+	fake := newFakeEC2()
+	cfg := testAWSConfig()
+	p := newTestProvider(fake, cfg)
+	ctx := context.Background()
+
+	// Warm instances still preparing cannot be claimed
+	if err := p.refillWarmPool(ctx); err != nil {
+		t.Fatalf("refillWarmPool() error = %v", err)
+	}
+
+	runner, err := p.CreateRunner(ctx, testRequest())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	if runner.ProviderID != "i-0003" || runner.Metadata["warm_pool"] != "" {
+		t.Errorf("runner = %+v, want a new instance", runner)
+	}
+
+	userData := fake.userData["i-0003"]
+	if !strings.Contains(userData, "tar xzf") || !strings.Contains(userData, "--name zeno-runner-1") {
+		t.Errorf("cold start user data does not install and configure the runner:\n%s", userData)
+	}
+}
+
+func TestCreateRunnerReturnsWarmInstanceWhenStartFails(t *testing.T) {
+	fake := newFakeEC2()
+	p := newTestProvider(fake, testAWSConfig())
+	ctx := context.Background()
+
+	if err := p.refillWarmPool(ctx); err != nil {
+		t.Fatalf("refillWarmPool() error = %v", err)
+	}
+	fake.stopAll()
+	fake.startErr = fmt.Errorf("InsufficientInstanceCapacity")
+
+	runner, err := p.CreateRunner(ctx, testRequest())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	if runner.Metadata["warm_pool"] != "" {
+		t.Errorf("runner = %+v, want a cold start", runner)
+	}
+	if got := fake.count(managedByWarmPool); got != 2 {
+		t.Errorf("warm instances = %d, want both handed back to the pool", got)
+	}
+}
+
+func TestWarmPoolRefillsAfterClaim(t *testing.T) {
+	fake := newFakeEC2()
+	p := newProvider(fake, testAWSConfig(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	defer p.Close()
+
+	waitForWarm := func(want int) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for fake.count(managedByWarmPool) != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("warm instances = %d, want %d", fake.count(managedByWarmPool), want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// Filled at start
+	waitForWarm(2)
+	fake.stopAll()
+
+	if _, err := p.CreateRunner(context.Background(), testRequest()); err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+
+	// Refilled right after the claim, well before the hourly interval
+	waitForWarm(2)
+	if got := fake.count(managedByRunner); got != 1 {
+		t.Errorf("runner instances = %d, want 1", got)
+	}
+}
+
+func TestReconfigureKeepsRegionAndWarmPool(t *testing.T) {
+	fake := newFakeEC2()
+	cfg := testAWSConfig()
+	cfg.WarmPool.Enabled = false
+	p := newTestProvider(fake, cfg)
+
+	next := testAWSConfig()
+	next.Region = "eu-west-1"
+	next.InstanceType = "c6i.large"
+	next.AMI = "ami-456"
+	restart, err := p.Reconfigure(config.ProviderConfig{AWS: next})
+	if err != nil {
+		t.Fatalf("Reconfigure() error = %v", err)
+	}
+	if want := []string{"provider.aws.region", "provider.aws.warm_pool"}; !reflect.DeepEqual(restart, want) {
+		t.Errorf("restart = %v, want %v", restart, want)
+	}
+
+	if _, err := p.CreateRunner(context.Background(), testRequest()); err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	input := fake.runInputs[0]
+	if input.InstanceType != types.InstanceType("c6i.large") || aws.ToString(input.ImageId) != "ami-456" {
+		t.Errorf("launched %s from %s, want the reconfigured c6i.large from ami-456", input.InstanceType, aws.ToString(input.ImageId))
+	}
+	if p.config.Region != "us-east-1" || p.config.WarmPool.Enabled {
+		t.Errorf("region = %s, warm pool = %v; want both unchanged", p.config.Region, p.config.WarmPool.Enabled)
+	}
+}
+
+func TestCreateRunnerFromLaunchTemplate(t *testing.T) {
+	fake := newFakeEC2()
+	cfg := testAWSConfig()
+	cfg.WarmPool.Enabled = false
+	cfg.LaunchTemplate = config.LaunchTemplateConfig{Name: "ci-runners", Version: "$Latest"}
+	cfg.Tags = map[string]string{"team": "ci"}
+	p := newTestProvider(fake, cfg)
+
+	runner, err := p.CreateRunner(context.Background(), testRequest())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+
+	input := fake.runInputs[0]
+	if input.LaunchTemplate == nil || aws.ToString(input.LaunchTemplate.LaunchTemplateName) != "ci-runners" || aws.ToString(input.LaunchTemplate.Version) != "$Latest" {
+		t.Fatalf("LaunchTemplate = %+v, want ci-runners at $Latest", input.LaunchTemplate)
+	}
+	if input.ImageId != nil || input.SubnetId != nil || input.SecurityGroupIds != nil || input.BlockDeviceMappings != nil {
+		t.Errorf("input overrides the template's image, network or storage: %+v", input)
+	}
+	if input.InstanceType != types.InstanceType("t3.medium") {
+		t.Errorf("InstanceType = %s, want the configured t3.medium layered on the template", input.InstanceType)
+	}
+	if input.InstanceMarketOptions != nil {
+		t.Errorf("InstanceMarketOptions = %+v, want on-demand", input.InstanceMarketOptions)
+	}
+
+	if got := fake.tag(runner.ProviderID, tagRunnerName); got != "zeno-runner-1" {
+		t.Errorf("runner-name tag = %q", got)
+	}
+	if got := fake.tag(runner.ProviderID, "team"); got != "ci" {
+		t.Errorf("team tag = %q, want the configured tags", got)
+	}
+	if !strings.Contains(fake.userData[runner.ProviderID], "--name zeno-runner-1") {
+		t.Errorf("user data does not configure the runner:\n%s", fake.userData[runner.ProviderID])
+	}
+}
+
+func TestCreateSpotRunnerFromLaunchTemplate(t *testing.T) {
+	fake := newFakeEC2()
+	cfg := testAWSConfig()
+	cfg.WarmPool.Enabled = false
+	cfg.UseSpot = true
+	cfg.SpotMaxPrice = "0.05"
+	cfg.InstanceType = ""
+	cfg.LaunchTemplate = config.LaunchTemplateConfig{ID: "lt-0123456789abcdef0"}
+	p := newTestProvider(fake, cfg)
+
+	// The fake rejects spot requests, so this only passes through RunInstances
+	if _, err := p.CreateRunner(context.Background(), testRequest()); err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+
+	input := fake.runInputs[0]
+	if aws.ToString(input.LaunchTemplate.LaunchTemplateId) != "lt-0123456789abcdef0" || input.LaunchTemplate.Version != nil {
+		t.Errorf("LaunchTemplate = %+v, want the template's default version by ID", input.LaunchTemplate)
+	}
+	if input.InstanceType != "" {
+		t.Errorf("InstanceType = %s, want the template's with instance_type empty", input.InstanceType)
+	}
+	market := input.InstanceMarketOptions
+	if market == nil || market.MarketType != types.MarketTypeSpot || aws.ToString(market.SpotOptions.MaxPrice) != "0.05" {
+		t.Errorf("InstanceMarketOptions = %+v, want spot capped at 0.05", market)
+	}
+}
+
+func testFleetConfig() config.AWSConfig {
+	cfg := testAWSConfig()
+	cfg.WarmPool.Enabled = false
+	cfg.UseSpot = true
+	cfg.LaunchTemplate = config.LaunchTemplateConfig{Name: "ci-runners"}
+	cfg.Fleet = config.FleetConfig{
+		Enabled:            true,
+		InstanceTypes:      []string{"m6i.large", "c6i.large"},
+		SubnetIDs:          []string{"subnet-a", "subnet-b"},
+		AllocationStrategy: "price-capacity-optimized",
+		OnDemandFallback:   true,
+	}
+	return cfg
+}
+
+func TestCreateRunnerWithFleet(t *testing.T) {
+	fake := newFakeEC2()
+	p := newTestProvider(fake, testFleetConfig())
+
+	runner, err := p.CreateRunner(context.Background(), testRequest())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+
+	input := fake.fleetInputs[0]
+	if input.Type != types.FleetTypeInstant || input.SpotOptions.AllocationStrategy != types.SpotAllocationStrategyPriceCapacityOptimized {
+		t.Errorf("fleet type = %s, spot options = %+v", input.Type, input.SpotOptions)
+	}
+	spec := input.LaunchTemplateConfigs[0]
+	if aws.ToString(spec.LaunchTemplateSpecification.LaunchTemplateName) != "ci-runners" || aws.ToString(spec.LaunchTemplateSpecification.Version) != "10" {
+		t.Errorf("template = %+v, want the user data version of ci-runners", spec.LaunchTemplateSpecification)
+	}
+	if len(spec.Overrides) != 4 {
+		t.Errorf("%d overrides, want every instance type in every subnet", len(spec.Overrides))
+	}
+
+	want := map[string]string{"instance_type": "c6i.large", "subnet_id": "subnet-b", "az": "us-east-1b", "spot": "true", "pool": "ec2"}
+	for k, v := range want {
+		if runner.Metadata[k] != v {
+			t.Errorf("Metadata[%s] = %q, want %q", k, runner.Metadata[k], v)
+		}
+	}
+	if !strings.Contains(fake.userData[runner.ProviderID], "--name zeno-runner-1") {
+		t.Errorf("fleet instance user data does not configure the runner:\n%s", fake.userData[runner.ProviderID])
+	}
+	if got := fake.tag(runner.ProviderID, tagRunnerID); got != runner.ID {
+		t.Errorf("runner-id tag = %q, want %q", got, runner.ID)
+	}
+	if len(fake.deletedVersions) != 1 || fake.deletedVersions[0] != "10" {
+		t.Errorf("deleted template versions = %v, want the user data version removed", fake.deletedVersions)
+	}
+}
+
+func TestFleetRetriesUndeletedTemplateVersions(t *testing.T) {
+	fake := newFakeEC2()
+	fake.deleteErr = errors.New("RequestLimitExceeded")
+	p := newTestProvider(fake, testFleetConfig())
+
+	// The instance is running, so a failed cleanup does not fail the runner
+	if _, err := p.CreateRunner(context.Background(), testRequest()); err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	if len(fake.deletedVersions) != 0 {
+		t.Fatalf("deleted template versions = %v, want none while deletes fail", fake.deletedVersions)
+	}
+
+	fake.deleteErr = nil
+	if _, err := p.CreateRunner(context.Background(), testRequest()); err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	if strings.Join(fake.deletedVersions, ",") != "10,11" {
+		t.Errorf("deleted template versions = %v, want the earlier version retried with the new one", fake.deletedVersions)
+	}
+
+	fake.deleteErr = errors.New("RequestLimitExceeded")
+	if _, err := p.CreateRunner(context.Background(), testRequest()); err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	if err := p.Close(); err == nil {
+		t.Error("Close() succeeded with a version holding a registration token left behind")
+	}
+}
+
+func TestFleetFallsBackToOnDemand(t *testing.T) {
+	fake := newFakeEC2()
+	fake.noSpotCapacity = true
+	p := newTestProvider(fake, testFleetConfig())
+
+	runner, err := p.CreateRunner(context.Background(), testRequest())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	if len(fake.fleetInputs) != 2 || fake.fleetInputs[1].TargetCapacitySpecification.DefaultTargetCapacityType != types.DefaultTargetCapacityTypeOnDemand {
+		t.Errorf("fleet requests = %d, want spot then on-demand", len(fake.fleetInputs))
+	}
+	if runner.Metadata["spot"] != "false" {
+		t.Errorf("Metadata[spot] = %q, want the on-demand fallback recorded", runner.Metadata["spot"])
+	}
+
+	cfg := testFleetConfig()
+	cfg.Fleet.OnDemandFallback = false
+	p = newTestProvider(fake, cfg)
+	if _, err := p.CreateRunner(context.Background(), testRequest()); err == nil || !strings.Contains(err.Error(), "InsufficientInstanceCapacity") {
+		t.Errorf("CreateRunner() error = %v, want the capacity error without fallback", err)
+	}
+}
+
+func testSpotMixConfig() config.AWSConfig {
+	cfg := testAWSConfig()
+	cfg.WarmPool.Enabled = false
+	cfg.LaunchTemplate = config.LaunchTemplateConfig{Name: "ci-runners"}
+	cfg.SpotMix = config.SpotMixConfig{Enabled: true, OnDemandBase: 1, SpotPercentage: 50}
+	return cfg
+}
+
+func TestSpotMixKeepsOnDemandBase(t *testing.T) {
+	fake := newFakeEC2()
+	p := newTestProvider(fake, testSpotMixConfig())
+	ctx := context.Background()
+
+	// One on-demand runner as the base, then every other runner spot
+	want := []string{"on-demand", "on-demand", "spot", "on-demand", "spot"}
+	var got []string
+	for range want {
+		runner, err := p.CreateRunner(ctx, testRequest())
+		if err != nil {
+			t.Fatalf("CreateRunner() error = %v", err)
+		}
+		got = append(got, runner.Metadata[provider.MetadataLifecycle])
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("lifecycles = %v, want %v", got, want)
+	}
+
+	runners, err := p.ListRunners(ctx)
+	if err != nil {
+		t.Fatalf("ListRunners() error = %v", err)
+	}
+	spot := 0
+	for _, r := range runners {
+		if r.Metadata[provider.MetadataLifecycle] == provider.LifecycleSpot {
+			spot++
+		}
+	}
+	if spot != 2 {
+		t.Errorf("ListRunners() has %d spot runners, want 2", spot)
+	}
+}
+
+func TestSpotMixFallsBackToOnDemand(t *testing.T) {
+	fake := newFakeEC2()
+	fake.noSpotCapacity = true
+	cfg := testSpotMixConfig()
+	cfg.SpotMix = config.SpotMixConfig{Enabled: true, SpotPercentage: 100}
+	p := newTestProvider(fake, cfg)
+
+	runner, err := p.CreateRunner(context.Background(), testRequest())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	if runner.Metadata[provider.MetadataLifecycle] != provider.LifecycleOnDemand || runner.Metadata["spot"] != "false" {
+		t.Errorf("Metadata = %v, want the on-demand fallback recorded", runner.Metadata)
+	}
+
+	// use_spot alone does not fall back
+	cfg.SpotMix.Enabled = false
+	cfg.UseSpot = true
+	p = newTestProvider(fake, cfg)
+	if _, err := p.CreateRunner(context.Background(), testRequest()); err == nil {
+		t.Error("CreateRunner() succeeded without spot capacity or the spot mix")
+	}
+}
+
+// fakeQueue is an in-memory SQS queue that hands out every message at once
+type fakeQueue struct {
+	mu       sync.Mutex
+	messages []string
+	deleted  []string
+}
+
+func (q *fakeQueue) ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var messages []sqstypes.Message
+	for i, body := range q.messages {
+		messages = append(messages, sqstypes.Message{
+			MessageId:     aws.String(fmt.Sprintf("msg-%d", i)),
+			ReceiptHandle: aws.String(fmt.Sprintf("receipt-%d", i)),
+			Body:          aws.String(body),
+		})
+	}
+	q.messages = nil
+	return &sqs.ReceiveMessageOutput{Messages: messages}, nil
+}
+
+func (q *fakeQueue) DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.deleted = append(q.deleted, aws.ToString(params.ReceiptHandle))
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func testInterruptionConfig() config.AWSConfig {
+	cfg := testAWSConfig()
+	cfg.UseSpot = false
+	cfg.WarmPool.Enabled = false
+	cfg.Interruptions = config.InterruptionConfig{
+		Enabled:  true,
+		Path:     "/events",
+		Secret:   "s3cret",
+		QueueURL: "http://localhost:9324/queue/zeno-interruptions",
+		WaitTime: time.Second,
+	}
+	return cfg
+}
+
+func interruptionEvent(detailType, instanceID string) string {
+	return fmt.Sprintf(`{"version":"0","source":"aws.ec2","detail-type":%q,"detail":{"instance-id":%q,"instance-action":"terminate"}}`,
+		detailType, instanceID)
+}
+
+func TestInterruptionEventsDrainRunners(t *testing.T) {
+	fake := newFakeEC2()
+	p := newTestProvider(fake, testInterruptionConfig())
+	p.watchInterruptions = true
+	ctx := context.Background()
+
+	flagged, err := p.CreateRunner(ctx, testRequest())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	if _, err := p.CreateRunner(ctx, testRequest()); err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+
+	// A rebalance recommendation posted to the endpoint
+	rec := httptest.NewRecorder()
+	p.handleEvent(rec, httptest.NewRequest(http.MethodPost, "/events",
+		strings.NewReader(interruptionEvent("EC2 Instance Rebalance Recommendation", flagged.ProviderID))))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("handleEvent() status = %d, want 202", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	p.handleEvent(rec, httptest.NewRequest(http.MethodPost, "/events", strings.NewReader("{")))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("handleEvent() status = %d for a malformed event, want 400", rec.Code)
+	}
+
+	// The interruption warning that follows arrives through the queue,
+	// alongside junk and an event about someone else's instance
+	queue := &fakeQueue{messages: []string{
+		interruptionEvent("EC2 Spot Instance Interruption Warning", flagged.ProviderID),
+		"not json",
+		interruptionEvent("EC2 Spot Instance Interruption Warning", "i-elsewhere"),
+	}}
+	if err := p.receiveEvents(ctx, queue, p.config.Interruptions); err != nil {
+		t.Fatalf("receiveEvents() error = %v", err)
+	}
+	if len(queue.deleted) != 3 {
+		t.Errorf("deleted %v, want every message", queue.deleted)
+	}
+
+	runners, err := p.ListRunners(ctx)
+	if err != nil {
+		t.Fatalf("ListRunners() error = %v", err)
+	}
+	if len(runners) != 2 {
+		t.Fatalf("ListRunners() returned %d runners, want 2", len(runners))
+	}
+	for _, r := range runners {
+		if r.ID == flagged.ID {
+			if r.Status != provider.StatusDraining || r.Metadata[provider.MetadataInterruption] != "spot-interruption" {
+				t.Errorf("flagged runner = %s with interruption %q, want draining for spot-interruption",
+					r.Status, r.Metadata[provider.MetadataInterruption])
+			}
+		} else if r.Status == provider.StatusDraining || r.Metadata[provider.MetadataInterruption] != "" {
+			t.Errorf("unflagged runner = %s with interruption %q", r.Status, r.Metadata[provider.MetadataInterruption])
+		}
+	}
+
+	if _, ok := p.notices["i-elsewhere"]; ok {
+		t.Error("notice about an unmanaged instance kept after ListRunners")
+	}
+}
+
+func TestInterruptionEndpointRequiresSecret(t *testing.T) {
+	fake := newFakeEC2()
+	cfg := testInterruptionConfig()
+	p := newTestProvider(fake, cfg)
+	p.watchInterruptions = true
+	ctx := context.Background()
+
+	flagged, err := p.CreateRunner(ctx, testRequest())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+
+	handler := p.authorizeEvent(cfg.Interruptions.Secret, p.handleEvent)
+	body := interruptionEvent("EC2 Spot Instance Interruption Warning", flagged.ProviderID)
+	for _, auth := range []string{"", "s3cret", "Bearer wrong"} {
+		req := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(body))
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("event with Authorization %q: status = %d, want 401", auth, rec.Code)
+		}
+	}
+	if len(p.notices) != 0 {
+		t.Fatalf("unauthorized events recorded notices: %v", p.notices)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+cfg.Interruptions.Secret)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("authorized event: status = %d, want 202", rec.Code)
+	}
+	if _, ok := p.notices[flagged.ProviderID]; !ok {
+		t.Error("authorized event did not record a notice")
+	}
+
+	for address, want := range map[string]string{
+		":8090":          "127.0.0.1:8090",
+		"0.0.0.0:8090":   "0.0.0.0:8090",
+		"10.0.0.5:8090":  "10.0.0.5:8090",
+		"localhost:8090": "localhost:8090",
+	} {
+		if got := listenAddress(address); got != want {
+			t.Errorf("listenAddress(%q) = %q, want %q", address, got, want)
+		}
+	}
+}
+
+func TestSpotStateReasonDrainsRunner(t *testing.T) {
+	fake := newFakeEC2()
+	p := newTestProvider(fake, testInterruptionConfig())
+	p.watchInterruptions = true
+	ctx := context.Background()
+
+	reclaimed, _ := p.CreateRunner(ctx, testRequest())
+	removed, _ := p.CreateRunner(ctx, testRequest())
+	fake.mu.Lock()
+	fake.find(reclaimed.ProviderID).State = &types.InstanceState{Name: types.InstanceStateNameShuttingDown}
+	fake.find(reclaimed.ProviderID).StateReason = &types.StateReason{Code: aws.String("Server.SpotInstanceTermination")}
+	fake.find(removed.ProviderID).State = &types.InstanceState{Name: types.InstanceStateNameShuttingDown}
+	fake.find(removed.ProviderID).StateReason = &types.StateReason{Code: aws.String("Client.UserInitiatedShutdown")}
+	fake.mu.Unlock()
+
+	runners, err := p.ListRunners(ctx)
+	if err != nil {
+		t.Fatalf("ListRunners() error = %v", err)
+	}
+	if len(runners) != 1 || runners[0].ID != reclaimed.ID || runners[0].Status != provider.StatusDraining {
+		t.Fatalf("ListRunners() = %+v, want only the reclaimed runner, draining", runners)
+	}
+
+	// Without the watch, instances shutting down are not listed at all
+	p.watchInterruptions = false
+	if runners, _ := p.ListRunners(ctx); len(runners) != 0 {
+		t.Errorf("ListRunners() returned %d runners without the watch, want 0", len(runners))
+	}
+}
@@ -0,0 +1,266 @@
+package ec2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// errNoCapacity is returned when a fleet request succeeded but EC2 launched
+// nothing, typically for lack of capacity in every pool
+var errNoCapacity = errors.New("fleet launched no instance")
+
+// fleetInstance is the instance an instant fleet launched, with the type,
+// subnet and AZ EC2 picked for it
+type fleetInstance struct {
+	id           string
+	instanceType string
+	subnetID     string
+	az           string
+	spot         bool
+}
+
+// createFleetInstance launches one instance with an instant fleet across the
+// configured instance types and subnets. A spot fleet that gets no capacity
+// is retried as on-demand when fallback or the spot mix is enabled.
+//
+// CreateFleet cannot override user data, so the runner's user data goes
+// into a new version of the launch template, which is deleted once the
+// instance has launched. The caller holds p.mu.
+func (p *EC2Provider) createFleetInstance(ctx context.Context, userData string, tags []types.Tag, spot bool) (*fleetInstance, error) {
+	version, err := p.createUserDataVersion(ctx, userData)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := p.deleteUserDataVersions(version); err != nil {
+			p.logger.Error("launch template versions holding registration tokens were not deleted, retrying with the next fleet launch", "error", err)
+		}
+	}()
+
+	instance, err := p.runFleet(ctx, version, tags, spot)
+	if errors.Is(err, errNoCapacity) && spot && (p.config.Fleet.OnDemandFallback || p.config.SpotMix.Enabled) {
+		p.logger.Warn("spot fleet got no capacity, falling back to on-demand", "error", err)
+		instance, err = p.runFleet(ctx, version, tags, false)
+	}
+	return instance, err
+}
+
+func (p *EC2Provider) runFleet(ctx context.Context, version string, tags []types.Tag, spot bool) (*fleetInstance, error) {
+	template := p.launchTemplate()
+	input := &ec2.CreateFleetInput{
+		Type: types.FleetTypeInstant,
+		LaunchTemplateConfigs: []types.FleetLaunchTemplateConfigRequest{{
+			LaunchTemplateSpecification: &types.FleetLaunchTemplateSpecificationRequest{
+				LaunchTemplateId:   template.LaunchTemplateId,
+				LaunchTemplateName: template.LaunchTemplateName,
+				Version:            aws.String(version),
+			},
+			Overrides: p.fleetOverrides(spot),
+		}},
+		TargetCapacitySpecification: &types.TargetCapacitySpecificationRequest{
+			TotalTargetCapacity:       aws.Int32(1),
+			DefaultTargetCapacityType: types.DefaultTargetCapacityTypeOnDemand,
+		},
+		OnDemandOptions: &types.OnDemandOptionsRequest{
+			AllocationStrategy: types.FleetOnDemandAllocationStrategyLowestPrice,
+		},
+		// Instant fleets can only tag the instances they launch
+		TagSpecifications: []types.TagSpecification{{
+			ResourceType: types.ResourceTypeInstance,
+			Tags:         tags,
+		}},
+	}
+	if spot {
+		input.TargetCapacitySpecification.DefaultTargetCapacityType = types.DefaultTargetCapacityTypeSpot
+		input.SpotOptions = &types.SpotOptionsRequest{
+			AllocationStrategy: types.SpotAllocationStrategy(p.config.Fleet.AllocationStrategy),
+		}
+	}
+
+	result, err := p.client.CreateFleet(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fleet: %w", err)
+	}
+
+	for _, launched := range result.Instances {
+		if len(launched.InstanceIds) == 0 {
+			continue
+		}
+		instance := &fleetInstance{
+			id:           launched.InstanceIds[0],
+			instanceType: string(launched.InstanceType),
+			spot:         launched.Lifecycle == types.InstanceLifecycleSpot,
+		}
+		if lto := launched.LaunchTemplateAndOverrides; lto != nil && lto.Overrides != nil {
+			instance.subnetID = aws.ToString(lto.Overrides.SubnetId)
+			instance.az = aws.ToString(lto.Overrides.AvailabilityZone)
+		}
+		if instance.az == "" {
+			instance.az = p.instanceAZ(ctx, instance.id)
+		}
+		return instance, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", errNoCapacity, fleetErrors(result.Errors))
+}
+
+// fleetOverrides pairs every instance type with every subnet. Without
+// subnets, the template's network is used.
+func (p *EC2Provider) fleetOverrides(spot bool) []types.FleetLaunchTemplateOverridesRequest {
+	subnets := p.config.Fleet.SubnetIDs
+	if len(subnets) == 0 {
+		subnets = []string{""}
+	}
+
+	var overrides []types.FleetLaunchTemplateOverridesRequest
+	for _, instanceType := range p.config.Fleet.InstanceTypes {
+		for _, subnet := range subnets {
+			override := types.FleetLaunchTemplateOverridesRequest{
+				InstanceType: types.InstanceType(instanceType),
+			}
+			if subnet != "" {
+				override.SubnetId = aws.String(subnet)
+			}
+			if spot && p.config.SpotMaxPrice != "" {
+				override.MaxPrice = aws.String(p.config.SpotMaxPrice)
+			}
+			overrides = append(overrides, override)
+		}
+	}
+	return overrides
+}
+
+// createUserDataVersion adds a version with the runner's user data to the
+// launch template, based on the configured version
+func (p *EC2Provider) createUserDataVersion(ctx context.Context, userData string) (string, error) {
+	template := p.launchTemplate()
+
+	source, err := p.templateVersionNumber(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := p.client.CreateLaunchTemplateVersion(ctx, &ec2.CreateLaunchTemplateVersionInput{
+		LaunchTemplateId:   template.LaunchTemplateId,
+		LaunchTemplateName: template.LaunchTemplateName,
+		SourceVersion:      aws.String(source),
+		VersionDescription: aws.String("zeno runner user data"),
+		LaunchTemplateData: &types.RequestLaunchTemplateData{
+			UserData: aws.String(userData),
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create launch template version: %w", err)
+	}
+	if result.LaunchTemplateVersion == nil || result.LaunchTemplateVersion.VersionNumber == nil {
+		return "", fmt.Errorf("launch template version has no number")
+	}
+
+	return strconv.FormatInt(*result.LaunchTemplateVersion.VersionNumber, 10), nil
+}
+
+// templateVersionNumber resolves the configured version, which may be
+// "$Latest", "$Default" or empty, to the number a new version is based on
+func (p *EC2Provider) templateVersionNumber(ctx context.Context) (string, error) {
+	version := p.config.LaunchTemplate.Version
+	if version == "" {
+		version = "$Default"
+	}
+	if !strings.HasPrefix(version, "$") {
+		return version, nil
+	}
+
+	template := p.launchTemplate()
+	result, err := p.client.DescribeLaunchTemplateVersions(ctx, &ec2.DescribeLaunchTemplateVersionsInput{
+		LaunchTemplateId:   template.LaunchTemplateId,
+		LaunchTemplateName: template.LaunchTemplateName,
+		Versions:           []string{version},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe launch template version: %w", err)
+	}
+	if len(result.LaunchTemplateVersions) == 0 || result.LaunchTemplateVersions[0].VersionNumber == nil {
+		return "", fmt.Errorf("launch template version %s not found", version)
+	}
+
+	return strconv.FormatInt(*result.LaunchTemplateVersions[0].VersionNumber, 10), nil
+}
+
+// deleteUserDataVersions removes user data versions once they have been
+// used, together with any that earlier attempts failed to remove. Each holds
+// a runner registration token, so versions that cannot be deleted are kept
+// and retried with the next fleet launch and on Close. It runs after the
+// request's context may have ended. The caller holds p.mu.
+func (p *EC2Provider) deleteUserDataVersions(versions ...string) error {
+	p.undeletedVersions = append(p.undeletedVersions, versions...)
+	if len(p.undeletedVersions) == 0 {
+		return nil
+	}
+
+	template := p.launchTemplate()
+	result, err := p.client.DeleteLaunchTemplateVersions(context.Background(), &ec2.DeleteLaunchTemplateVersionsInput{
+		LaunchTemplateId:   template.LaunchTemplateId,
+		LaunchTemplateName: template.LaunchTemplateName,
+		Versions:           p.undeletedVersions,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete launch template versions %s: %w", strings.Join(p.undeletedVersions, ", "), err)
+	}
+
+	var failed, reasons []string
+	for _, item := range result.UnsuccessfullyDeletedLaunchTemplateVersions {
+		version := strconv.FormatInt(aws.ToInt64(item.VersionNumber), 10)
+		reason := "no reason given"
+		if item.ResponseError != nil {
+			reason = string(item.ResponseError.Code)
+		}
+		failed = append(failed, version)
+		reasons = append(reasons, version+": "+reason)
+	}
+	p.undeletedVersions = failed
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to delete launch template versions %s", strings.Join(reasons, ", "))
+	}
+	return nil
+}
+
+// instanceAZ looks up the AZ of an instance whose fleet override named only
+// a subnet. The AZ is informational, so failures leave it empty.
+func (p *EC2Provider) instanceAZ(ctx context.Context, instanceID string) string {
+	result, err := p.client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: []string{instanceID},
+	})
+	if err != nil || len(result.Reservations) == 0 || len(result.Reservations[0].Instances) == 0 {
+		return ""
+	}
+
+	placement := result.Reservations[0].Instances[0].Placement
+	if placement == nil {
+		return ""
+	}
+	return aws.ToString(placement.AvailabilityZone)
+}
+
+// fleetErrors summarizes why a fleet launched nothing
+func fleetErrors(errs []types.CreateFleetError) string {
+	if len(errs) == 0 {
+		return "no errors reported"
+	}
+
+	var parts []string
+	for _, e := range errs {
+		part := aws.ToString(e.ErrorCode)
+		if lto := e.LaunchTemplateAndOverrides; lto != nil && lto.Overrides != nil {
+			part += fmt.Sprintf(" (%s in %s)", lto.Overrides.InstanceType, aws.ToString(lto.Overrides.SubnetId))
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
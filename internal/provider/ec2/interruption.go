@@ -0,0 +1,260 @@
+package ec2
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"Zeno/internal/config"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// Values of the interruption metadata of a draining runner. A spot
+// interruption outranks a rebalance recommendation for the same instance.
+const (
+	interruptionSpot      = "spot-interruption"
+	interruptionRebalance = "rebalance-recommendation"
+)
+
+// EventBridge detail types of the events that flag an instance
+var eventKinds = map[string]string{
+	"EC2 Spot Instance Interruption Warning": interruptionSpot,
+	"EC2 Instance Rebalance Recommendation":  interruptionRebalance,
+}
+
+// maxEventSize bounds the body of an event posted to the HTTP endpoint
+const maxEventSize = 1 << 20
+
+// event is the part of an EventBridge event the provider reads
+type event struct {
+	DetailType string `json:"detail-type"`
+	Detail     struct {
+		InstanceID string `json:"instance-id"`
+	} `json:"detail"`
+}
+
+// sqsAPI is the subset of the SQS client the interruption poller uses
+type sqsAPI interface {
+	ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
+}
+
+// newQueueClient creates the SQS client for cfg, at its endpoint if set
+func newQueueClient(awsCfg aws.Config, cfg config.InterruptionConfig) sqsAPI {
+	return sqs.NewFromConfig(awsCfg, func(o *sqs.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+	})
+}
+
+// startInterruptionWatch starts the HTTP endpoint and the queue poller that
+// feed interruption notices. Either may be left out of the configuration.
+func (p *EC2Provider) startInterruptionWatch(cfg config.InterruptionConfig, queue sqsAPI) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.stopWatch = cancel
+
+	if cfg.ListenAddress != "" {
+		listener, err := net.Listen("tcp", listenAddress(cfg.ListenAddress))
+		if err != nil {
+			cancel()
+			return fmt.Errorf("failed to listen for interruption events: %w", err)
+		}
+
+		mux := http.NewServeMux()
+		mux.HandleFunc(cfg.Path, p.authorizeEvent(cfg.Secret, p.handleEvent))
+		p.eventServer = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+		p.watchers.Add(1)
+		go func() {
+			defer p.watchers.Done()
+			if err := p.eventServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				p.logger.Error("interruption event endpoint failed", "error", err)
+			}
+		}()
+		p.logger.Info("listening for interruption events", "address", listener.Addr().String(), "path", cfg.Path)
+	}
+
+	if queue != nil {
+		p.watchers.Add(1)
+		go func() {
+			defer p.watchers.Done()
+			p.pollQueue(ctx, queue, cfg)
+		}()
+		p.logger.Info("polling queue for interruption events", "queue_url", cfg.QueueURL)
+	}
+
+	return nil
+}
+
+// listenAddress binds an address without a host, such as ":8090", to
+// localhost only. Listening on every interface takes an explicit host.
+func listenAddress(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || host != "" {
+		return address
+	}
+	return net.JoinHostPort("127.0.0.1", port)
+}
+
+// authorizeEvent passes only posts that carry secret as a bearer token, as
+// EventBridge API destinations send it with API key authorization
+func (p *EC2Provider) authorizeEvent(secret string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			p.logger.Warn("rejected unauthorized interruption event", "remote_addr", r.RemoteAddr)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// stopInterruptionWatch stops the endpoint and the poller
+func (p *EC2Provider) stopInterruptionWatch() {
+	if p.stopWatch == nil {
+		return
+	}
+
+	p.stopWatch()
+	if p.eventServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		p.eventServer.Shutdown(ctx)
+	}
+	p.watchers.Wait()
+}
+
+// handleEvent takes one EventBridge event, e.g. from an API destination
+func (p *EC2Provider) handleEvent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var ev event
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxEventSize)).Decode(&ev); err != nil {
+		http.Error(w, fmt.Sprintf("invalid event: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	p.recordEvent(ev)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// pollQueue long-polls the queue until ctx ends, pausing after failures
+func (p *EC2Provider) pollQueue(ctx context.Context, queue sqsAPI, cfg config.InterruptionConfig) {
+	for ctx.Err() == nil {
+		err := p.receiveEvents(ctx, queue, cfg)
+		if err == nil || ctx.Err() != nil {
+			continue
+		}
+
+		p.logger.Warn("failed to receive interruption events", "error", err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(cfg.WaitTime):
+		}
+	}
+}
+
+// receiveEvents reads one batch of events from the queue. Every message is
+// deleted once read, including malformed and unrelated ones, since a notice
+// is worthless once it is stale.
+func (p *EC2Provider) receiveEvents(ctx context.Context, queue sqsAPI, cfg config.InterruptionConfig) error {
+	result, err := queue.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(cfg.QueueURL),
+		MaxNumberOfMessages: 10,
+		WaitTimeSeconds:     int32(cfg.WaitTime / time.Second),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to receive messages: %w", err)
+	}
+
+	for _, msg := range result.Messages {
+		var ev event
+		if err := json.Unmarshal([]byte(aws.ToString(msg.Body)), &ev); err != nil {
+			p.logger.Warn("ignoring malformed interruption event", "message_id", aws.ToString(msg.MessageId), "error", err)
+		} else {
+			p.recordEvent(ev)
+		}
+
+		_, err := queue.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+			QueueUrl:      aws.String(cfg.QueueURL),
+			ReceiptHandle: msg.ReceiptHandle,
+		})
+		if err != nil {
+			p.logger.Warn("failed to delete interruption event", "message_id", aws.ToString(msg.MessageId), "error", err)
+		}
+	}
+
+	return nil
+}
+
+// recordEvent flags the instance an interruption or rebalance event is
+// about. Other events are ignored. The events cover every instance in the
+// account, so notices about instances Zeno does not manage are dropped on
+// the next ListRunners.
+func (p *EC2Provider) recordEvent(ev event) {
+	kind, ok := eventKinds[ev.DetailType]
+	if !ok || ev.Detail.InstanceID == "" {
+		return
+	}
+
+	p.noticeMu.Lock()
+	defer p.noticeMu.Unlock()
+
+	if prev := p.notices[ev.Detail.InstanceID]; prev == kind || prev == interruptionSpot {
+		return
+	}
+	if p.notices == nil {
+		p.notices = make(map[string]string)
+	}
+	p.notices[ev.Detail.InstanceID] = kind
+
+	p.logger.Warn("instance flagged for interruption", "instance_id", ev.Detail.InstanceID, "interruption", kind)
+}
+
+// instanceInterruption returns why an instance is about to be reclaimed,
+// from a received notice or the reason EC2 gives for its last state change,
+// or an empty string when it is not or interruptions are not watched
+func (p *EC2Provider) instanceInterruption(instance *types.Instance) string {
+	if !p.watchInterruptions {
+		return ""
+	}
+
+	if instance.StateReason != nil {
+		switch aws.ToString(instance.StateReason.Code) {
+		case "Server.SpotInstanceShutdown", "Server.SpotInstanceTermination":
+			return interruptionSpot
+		}
+	}
+
+	p.noticeMu.Lock()
+	defer p.noticeMu.Unlock()
+
+	return p.notices[aws.ToString(instance.InstanceId)]
+}
+
+// pruneNotices drops notices about instances that are not runners, or no
+// longer are
+func (p *EC2Provider) pruneNotices(listed map[string]bool) {
+	p.noticeMu.Lock()
+	defer p.noticeMu.Unlock()
+
+	for id := range p.notices {
+		if !listed[id] {
+			delete(p.notices, id)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package ec2
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"Zeno/internal/provider"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/google/uuid"
+)
+
+// warmClaimMarker identifies user data written for a claimed warm instance.
+// The boot hook installed while preparing a warm instance only runs user
+// data carrying it, so the preparation script never runs twice.
+const warmClaimMarker = "# zeno-warm-claim"
+
+// warmPrepareHook runs claim user data on every boot after preparation
+const warmPrepareHook = `# Run claim user data on the next boot
+mkdir -p /var/lib/cloud/scripts/per-boot
+cat > /var/lib/cloud/scripts/per-boot/zeno-claim.sh <<'HOOK'
+#!/bin/bash
+TOKEN=$(curl -s -X PUT http://169.254.169.254/latest/api/token -H "X-aws-ec2-metadata-token-ttl-seconds: 300")
+curl -s -H "X-aws-ec2-metadata-token: $TOKEN" -o /run/zeno-user-data http://169.254.169.254/latest/user-data
+if grep -q '^` + warmClaimMarker + `' /run/zeno-user-data; then
+  exec bash /run/zeno-user-data
+fi
+HOOK
+chmod +x /var/lib/cloud/scripts/per-boot/zeno-claim.sh
+
+# Stay stopped until claimed
+shutdown -h now
+`
+
+func (p *EC2Provider) startWarmPool() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.refill = make(chan struct{}, 1)
+	p.stopPool = cancel
+	p.poolDone = make(chan struct{})
+
+	go p.runWarmPool(ctx, p.config.WarmPool.RefillInterval)
+}
+
+// runWarmPool tops the pool up at start, on every refill interval and
+// whenever an instance is claimed
+func (p *EC2Provider) runWarmPool(ctx context.Context, interval time.Duration) {
+	defer close(p.poolDone)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.refillWarmPool(ctx); err != nil && ctx.Err() == nil {
+			p.logger.Error("failed to refill warm pool", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.refill:
+		}
+	}
+}
+
+// triggerRefill wakes the refill loop without waiting for it
+func (p *EC2Provider) triggerRefill() {
+	select {
+	case p.refill <- struct{}{}:
+	default:
+	}
+}
+
+// refillWarmPool launches instances until the pool, counting instances
+// still being prepared, reaches its configured size. The config lock is
+// only held while reading settings, never across AWS calls.
+func (p *EC2Provider) refillWarmPool(ctx context.Context) error {
+	p.mu.RLock()
+	size := p.config.WarmPool.Size
+	p.mu.RUnlock()
+
+	instances, err := p.describeWarmInstances(ctx,
+		types.InstanceStateNamePending,
+		types.InstanceStateNameRunning,
+		types.InstanceStateNameStopping,
+		types.InstanceStateNameStopped,
+	)
+	if err != nil {
+		return err
+	}
+
+	for missing := size - len(instances); missing > 0; missing-- {
+		instanceID, err := p.launchWarmInstance(ctx)
+		if err != nil {
+			return err
+		}
+		p.logger.Info("launched warm pool instance", "instance_id", instanceID)
+	}
+
+	return nil
+}
+
+// launchWarmInstance starts an on-demand instance that installs the runner
+// and powers itself off. Stopping on shutdown is what keeps it in the pool;
+// one-time spot instances cannot be stopped, so warm instances never use spot.
+func (p *EC2Provider) launchWarmInstance(ctx context.Context) (string, error) {
+	p.mu.RLock()
+	userData := base64.StdEncoding.EncodeToString([]byte(p.buildWarmUserData()))
+	input := p.runInstancesInput(userData, tagSpecifications(p.buildWarmTags()), p.blockDeviceMappings())
+	p.mu.RUnlock()
+
+	input.InstanceInitiatedShutdownBehavior = types.ShutdownBehaviorStop
+
+	result, err := p.client.RunInstances(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("failed to run warm instance: %w", err)
+	}
+
+	if len(result.Instances) == 0 {
+		return "", fmt.Errorf("no instances created")
+	}
+
+	return *result.Instances[0].InstanceId, nil
+}
+
+// claimWarmInstance turns the oldest stopped warm instance into a runner.
+// It reports false when no instance could be claimed, leaving the caller to
+// launch one from scratch.
+func (p *EC2Provider) claimWarmInstance(ctx context.Context, runnerID string, req *provider.CreateRunnerRequest) (*provider.Runner, bool) {
+	instances, err := p.describeWarmInstances(ctx, types.InstanceStateNameStopped)
+	if err != nil {
+		p.logger.Warn("failed to list warm pool, launching a new instance", "error", err)
+		return nil, false
+	}
+	if len(instances) == 0 {
+		p.logger.Info("warm pool empty, launching a new instance")
+		return nil, false
+	}
+
+	defer p.triggerRefill()
+
+	sort.Slice(instances, func(i, j int) bool {
+		return aws.ToTime(instances[i].LaunchTime).Before(aws.ToTime(instances[j].LaunchTime))
+	})
+
+	for i := range instances {
+		instance := &instances[i]
+		instanceID := aws.ToString(instance.InstanceId)
+
+		if err := p.startWarmInstance(ctx, instance, runnerID, req); err != nil {
+			p.logger.Warn("failed to claim warm instance",
+				"instance_id", instanceID,
+				"error", err,
+			)
+			continue
+		}
+
+		p.logger.Info("claimed warm instance",
+			"id", runnerID,
+			"name", req.Name,
+			"instance_id", instanceID,
+		)
+
+		runner := p.newRunner(runnerID, instanceID, req, false)
+		runner.Metadata["warm_pool"] = "true"
+		return runner, true
+	}
+
+	return nil, false
+}
+
+// startWarmInstance hands a stopped warm instance its runner user data and
+// tags, then starts it
+func (p *EC2Provider) startWarmInstance(ctx context.Context, instance *types.Instance, runnerID string, req *provider.CreateRunnerRequest) error {
+	instanceID := aws.ToString(instance.InstanceId)
+
+	_, err := p.client.ModifyInstanceAttribute(ctx, &ec2.ModifyInstanceAttributeInput{
+		InstanceId: aws.String(instanceID),
+		UserData:   &types.BlobAttributeValue{Value: []byte(p.buildClaimUserData(req))},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set user data: %w", err)
+	}
+
+	resources := []string{instanceID}
+	for _, bdm := range instance.BlockDeviceMappings {
+		if bdm.Ebs != nil && bdm.Ebs.VolumeId != nil {
+			resources = append(resources, *bdm.Ebs.VolumeId)
+		}
+	}
+
+	// The warm_pool tag surfaces as runner metadata, like request metadata
+	tags := append(p.buildTags(runnerID, req), types.Tag{
+		Key:   aws.String(tagPrefix + "warm_pool"),
+		Value: aws.String("true"),
+	})
+
+	_, err = p.client.CreateTags(ctx, &ec2.CreateTagsInput{
+		Resources: resources,
+		Tags:      tags,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to tag instance: %w", err)
+	}
+
+	_, err = p.client.StartInstances(ctx, &ec2.StartInstancesInput{
+		InstanceIds: []string{instanceID},
+	})
+	if err != nil {
+		// Hand the instance back to the pool
+		_, tagErr := p.client.CreateTags(ctx, &ec2.CreateTagsInput{
+			Resources: resources,
+			Tags: []types.Tag{{
+				Key:   aws.String(tagManagedBy),
+				Value: aws.String(managedByWarmPool),
+			}},
+		})
+		if tagErr != nil {
+			p.logger.Warn("failed to return instance to warm pool",
+				"instance_id", instanceID,
+				"error", tagErr,
+			)
+		}
+		return fmt.Errorf("failed to start instance: %w", err)
+	}
+
+	return nil
+}
+
+func (p *EC2Provider) describeWarmInstances(ctx context.Context, states ...types.InstanceStateName) ([]types.Instance, error) {
+	stateValues := make([]string, 0, len(states))
+	for _, state := range states {
+		stateValues = append(stateValues, string(state))
+	}
+
+	result, err := p.client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("tag:" + tagManagedBy),
+				Values: []string{managedByWarmPool},
+			},
+			{
+				Name:   aws.String("instance-state-name"),
+				Values: stateValues,
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe warm instances: %w", err)
+	}
+
+	var instances []types.Instance
+	for _, reservation := range result.Reservations {
+		instances = append(instances, reservation.Instances...)
+	}
+	return instances, nil
+}
+
+// buildWarmUserData prepares a warm instance. A custom user data script is
+// self-contained, so only the default script gets the runner pre-installed.
+func (p *EC2Provider) buildWarmUserData() string {
+	var script strings.Builder
+	script.WriteString("#!/bin/bash\nset -e\n\n")
+	if p.config.UserDataScript == "" {
+		script.WriteString(provider.InstallRunnerScript(runnerDir))
+		script.WriteString("\n")
+	}
+	script.WriteString(warmPrepareHook)
+	return script.String()
+}
+
+// buildClaimUserData is run by the boot hook when a warm instance starts
+func (p *EC2Provider) buildClaimUserData(req *provider.CreateRunnerRequest) string {
+	header := "#!/bin/bash\n" + warmClaimMarker + "\n"
+	if p.config.UserDataScript != "" {
+		return header + p.buildUserData(req)
+	}
+	return header + "set -e\n\ncd " + runnerDir + "\n\n" + provider.ConfigureRunnerScript(req, singleUseRunners)
+}
+
+func (p *EC2Provider) buildWarmTags() []types.Tag {
+	tags := []types.Tag{
+		{
+			Key:   aws.String(tagManagedBy),
+			Value: aws.String(managedByWarmPool),
+		},
+		{
+			Key:   aws.String(tagCreatedAt),
+			Value: aws.String(time.Now().Format(time.RFC3339)),
+		},
+		{
+			Key:   aws.String("Name"),
+			Value: aws.String(fmt.Sprintf("zeno-warm-%s", uuid.New().String()[:8])),
+		},
+	}
+
+	for k, v := range p.config.Tags {
+		tags = append(tags, types.Tag{
+			Key:   aws.String(k),
+			Value: aws.String(v),
+		})
+	}
+
+	return tags
+}
@@ -0,0 +1,49 @@
+package provider
+
+import "strings"
+
+// EnvVar is an environment variable passed to a runner container
+type EnvVar struct {
+	Name  string
+	Value string
+}
+
+// ContainerTokenEnv is the variable of ContainerEnv that holds the
+// registration token
+const ContainerTokenEnv = "ACCESS_TOKEN"
+
+// ContainerEnv returns the variables that register a runner from the
+// runner container image. The Docker and Kubernetes providers both pass
+// them, so both can run the same image.
+func ContainerEnv(req *CreateRunnerRequest, workDir string) []EnvVar {
+	env := []EnvVar{
+		{Name: "RUNNER_NAME", Value: req.Name},
+		{Name: "RUNNER_WORKDIR", Value: workDir},
+	}
+
+	if req.GitHubToken != "" {
+		env = append(env, EnvVar{Name: ContainerTokenEnv, Value: req.GitHubToken})
+	}
+
+	if req.GitHubOrg != "" {
+		env = append(env,
+			EnvVar{Name: "RUNNER_SCOPE", Value: "org"},
+			EnvVar{Name: "ORG_NAME", Value: req.GitHubOrg},
+		)
+	} else if req.GitHubRepo != "" {
+		env = append(env,
+			EnvVar{Name: "RUNNER_SCOPE", Value: "repo"},
+			EnvVar{Name: "REPO_URL", Value: "https://github.com/" + req.GitHubRepo},
+		)
+	}
+
+	if len(req.Labels) > 0 {
+		env = append(env, EnvVar{Name: "LABELS", Value: strings.Join(req.Labels, ",")})
+	}
+
+	if req.Ephemeral {
+		env = append(env, EnvVar{Name: "EPHEMERAL", Value: "1"})
+	}
+
+	return env
+}
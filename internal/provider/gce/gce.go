@@ -0,0 +1,489 @@
+package gce
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"Zeno/internal/config"
+	"Zeno/internal/provider"
+
+	"github.com/google/uuid"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+)
+
+const (
+	// Labels identify Zeno's instances and are what ListRunners filters on.
+	// Label values are restricted, so the exact runner name is also kept in
+	// instance metadata.
+	labelManagedBy  = "zeno-managed-by"
+	labelRunnerID   = "zeno-runner-id"
+	labelRunnerName = "zeno-runner-name"
+	managedByRunner = "zeno"
+
+	// Metadata keys. Request metadata is stored under metadataPrefix.
+	metadataPrefix        = "zeno-"
+	metadataRunnerName    = metadataPrefix + "runner-name"
+	metadataCreatedAt     = metadataPrefix + "created-at"
+	metadataStartupScript = "startup-script"
+)
+
+type GCEProvider struct {
+	service *compute.Service
+	// project and zone are fixed, so they are read without holding mu
+	project string
+	zone    string
+	config  config.GCEConfig
+	logger  *slog.Logger
+	mu      sync.RWMutex
+}
+
+func init() {
+	provider.Register("gce", func(cfg config.ProviderConfig, logger *slog.Logger) (provider.Provider, error) {
+		return New(cfg.GCE, logger)
+	})
+}
+
+// New creates a new GCE provider. Credentials come from CredentialsFile or,
+// when it is empty, from Application Default Credentials.
+func New(cfg config.GCEConfig, logger *slog.Logger) (*GCEProvider, error) {
+	return newWithOptions(cfg, logger)
+}
+
+// newWithOptions lets tests add client options, such as dropping
+// authentication when talking to a local stand-in
+func newWithOptions(cfg config.GCEConfig, logger *slog.Logger, opts ...option.ClientOption) (*GCEProvider, error) {
+	if cfg.CredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(cfg.CredentialsFile))
+	}
+	if cfg.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(cfg.Endpoint))
+	}
+
+	service, err := compute.NewService(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compute client: %w", err)
+	}
+
+	return &GCEProvider{
+		service: service,
+		project: cfg.Project,
+		zone:    cfg.Zone,
+		config:  cfg,
+		logger:  logger.With("provider", "gce"),
+	}, nil
+}
+
+// Reconfigure applies new instance settings to runners created from now on.
+// The project, zone, credentials, endpoint and pool are fixed for the life of
+// the provider.
+func (p *GCEProvider) Reconfigure(cfg config.ProviderConfig) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var restart []string
+	next := cfg.GCE
+	provider.KeepFixed(&restart, "provider.gce.project", &next.Project, p.config.Project)
+	provider.KeepFixed(&restart, "provider.gce.zone", &next.Zone, p.config.Zone)
+	provider.KeepFixed(&restart, "provider.gce.credentials_file", &next.CredentialsFile, p.config.CredentialsFile)
+	provider.KeepFixed(&restart, "provider.gce.endpoint", &next.Endpoint, p.config.Endpoint)
+	provider.KeepFixed(&restart, "provider.gce.pool", &next.Pool, p.config.Pool)
+	p.config = next
+	return restart, nil
+}
+
+func (p *GCEProvider) Name() string {
+	return "gce"
+}
+
+func (p *GCEProvider) ListRunners(ctx context.Context) ([]*provider.Runner, error) {
+	return p.listRunners(ctx, fmt.Sprintf("labels.%s = %s", labelManagedBy, managedByRunner))
+}
+
+func (p *GCEProvider) GetRunner(ctx context.Context, id string) (*provider.Runner, error) {
+	runners, err := p.listRunners(ctx, fmt.Sprintf("labels.%s = %s", labelRunnerID, id))
+	if err != nil {
+		return nil, err
+	}
+	if len(runners) == 0 {
+		return nil, fmt.Errorf("runner %s not found", id)
+	}
+	return runners[0], nil
+}
+
+func (p *GCEProvider) listRunners(ctx context.Context, filter string) ([]*provider.Runner, error) {
+	var runners []*provider.Runner
+	err := p.service.Instances.List(p.project, p.zone).
+		Filter(filter).
+		Pages(ctx, func(page *compute.InstanceList) error {
+			for _, instance := range page.Items {
+				runners = append(runners, p.instanceToRunner(instance))
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instances: %w", err)
+	}
+	return runners, nil
+}
+
+func (p *GCEProvider) CreateRunner(ctx context.Context, req *provider.CreateRunnerRequest) (*provider.Runner, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	runnerID := uuid.New().String()
+	name := fmt.Sprintf("zeno-runner-%s", runnerID[:8])
+
+	p.logger.Info("creating GCE instance",
+		"id", runnerID,
+		"name", req.Name,
+		"instance", name,
+		"machine_type", p.config.MachineType,
+		"provisioning", p.config.Provisioning,
+	)
+
+	instance := p.buildInstance(name, runnerID, req)
+
+	call := p.service.Instances.Insert(p.project, p.zone, instance).Context(ctx)
+	if p.config.InstanceTemplate != "" {
+		call = call.SourceInstanceTemplate(p.templateURL())
+	}
+	op, err := call.Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert instance: %w", err)
+	}
+	// Capacity and quota errors are only reported on the operation
+	if err := p.waitForOperation(ctx, op); err != nil {
+		return nil, fmt.Errorf("failed to create instance: %w", err)
+	}
+
+	p.logger.Info("GCE instance created",
+		"id", runnerID,
+		"instance", name,
+	)
+
+	metadata := map[string]string{
+		"instance":      name,
+		"instance_type": p.config.MachineType,
+		"zone":          p.zone,
+		"provisioning":  p.provisioning(),
+		"pool":          p.config.Pool,
+	}
+	for k, v := range req.Metadata {
+		metadata[k] = v
+	}
+
+	return &provider.Runner{
+		ID:         runnerID,
+		Name:       req.Name,
+		Status:     provider.StatusProvisioning,
+		Labels:     req.Labels,
+		Provider:   "gce",
+		ProviderID: name,
+		CreatedAt:  time.Now(),
+		Metadata:   metadata,
+	}, nil
+}
+
+// RemoveRunner deletes the instance. Compute Engine shuts the guest down
+// before deleting it, so graceful and forced removal are the same.
+func (p *GCEProvider) RemoveRunner(ctx context.Context, id string, graceful bool) error {
+	runner, err := p.GetRunner(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	p.logger.Info("deleting GCE instance",
+		"id", id,
+		"instance", runner.ProviderID,
+		"graceful", graceful,
+	)
+
+	op, err := p.service.Instances.Delete(p.project, p.zone, runner.ProviderID).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to delete instance: %w", err)
+	}
+	if err := operationError(op); err != nil {
+		return fmt.Errorf("failed to delete instance: %w", err)
+	}
+
+	p.logger.Info("GCE instance deletion initiated", "id", id)
+	return nil
+}
+
+func (p *GCEProvider) HealthCheck(ctx context.Context) error {
+	if _, err := p.service.Zones.Get(p.project, p.zone).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("GCE health check failed: %w", err)
+	}
+	return nil
+}
+
+func (p *GCEProvider) Close() error {
+	return nil
+}
+
+// buildInstance describes the instance to insert. With an instance template
+// only the settings given in the config are set, so the template supplies
+// the rest.
+func (p *GCEProvider) buildInstance(name, runnerID string, req *provider.CreateRunnerRequest) *compute.Instance {
+	instance := &compute.Instance{
+		Name:     name,
+		Labels:   p.buildLabels(runnerID, req),
+		Metadata: p.buildMetadata(req),
+	}
+
+	if p.config.MachineType != "" {
+		instance.MachineType = fmt.Sprintf("zones/%s/machineTypes/%s", p.zone, p.config.MachineType)
+	}
+
+	if p.config.Image != "" {
+		instance.Disks = []*compute.AttachedDisk{
+			{
+				Boot:       true,
+				AutoDelete: true,
+				InitializeParams: &compute.AttachedDiskInitializeParams{
+					SourceImage: p.config.Image,
+					DiskSizeGb:  p.config.DiskSizeGB,
+					DiskType:    fmt.Sprintf("zones/%s/diskTypes/%s", p.zone, p.config.DiskType),
+					Labels:      instance.Labels,
+				},
+			},
+		}
+	}
+
+	if p.config.Network != "" || p.config.Subnetwork != "" || p.config.InstanceTemplate == "" {
+		network := p.config.Network
+		if network == "" && p.config.Subnetwork == "" {
+			network = "global/networks/default"
+		}
+		instance.NetworkInterfaces = []*compute.NetworkInterface{
+			{
+				Network:    network,
+				Subnetwork: p.config.Subnetwork,
+				AccessConfigs: []*compute.AccessConfig{
+					{Name: "External NAT", Type: "ONE_TO_ONE_NAT"},
+				},
+			},
+		}
+	}
+
+	if p.config.ServiceAccount != "" {
+		instance.ServiceAccounts = []*compute.ServiceAccount{
+			{
+				Email:  p.config.ServiceAccount,
+				Scopes: []string{compute.CloudPlatformScope},
+			},
+		}
+	}
+
+	switch p.config.Provisioning {
+	case "preemptible":
+		instance.Scheduling = &compute.Scheduling{
+			Preemptible:       true,
+			AutomaticRestart:  new(bool),
+			OnHostMaintenance: "TERMINATE",
+		}
+	case "spot":
+		instance.Scheduling = &compute.Scheduling{
+			ProvisioningModel: "SPOT",
+			AutomaticRestart:  new(bool),
+			OnHostMaintenance: "TERMINATE",
+		}
+	case "standard":
+		instance.Scheduling = &compute.Scheduling{ProvisioningModel: "STANDARD"}
+	}
+
+	return instance
+}
+
+func (p *GCEProvider) buildLabels(runnerID string, req *provider.CreateRunnerRequest) map[string]string {
+	labels := map[string]string{
+		labelManagedBy:  managedByRunner,
+		labelRunnerID:   runnerID,
+		labelRunnerName: labelValue(req.Name),
+	}
+
+	// Add custom labels from config
+	for k, v := range p.config.Labels {
+		labels[k] = v
+	}
+
+	return labels
+}
+
+// buildMetadata passes the bootstrap script as startup-script and keeps the
+// runner name, creation time and request metadata for instanceToRunner
+func (p *GCEProvider) buildMetadata(req *provider.CreateRunnerRequest) *compute.Metadata {
+	items := []*compute.MetadataItems{
+		metadataItem(metadataStartupScript, p.buildStartupScript(req)),
+		metadataItem(metadataRunnerName, req.Name),
+		metadataItem(metadataCreatedAt, time.Now().Format(time.RFC3339)),
+		metadataItem(metadataPrefix+"pool", p.config.Pool),
+	}
+	for k, v := range req.Metadata {
+		items = append(items, metadataItem(metadataPrefix+k, v))
+	}
+	return &compute.Metadata{Items: items}
+}
+
+func metadataItem(key, value string) *compute.MetadataItems {
+	return &compute.MetadataItems{Key: key, Value: &value}
+}
+
+func (p *GCEProvider) buildStartupScript(req *provider.CreateRunnerRequest) string {
+	if p.config.StartupScript != "" {
+		return provider.ExpandScript(p.config.StartupScript, req)
+	}
+	return provider.BootstrapScript(req, "/opt/actions-runner", false)
+}
+
+// templateURL accepts a template name or a full or partial resource URL
+func (p *GCEProvider) templateURL() string {
+	if strings.Contains(p.config.InstanceTemplate, "/") {
+		return p.config.InstanceTemplate
+	}
+	return fmt.Sprintf("projects/%s/global/instanceTemplates/%s", p.project, p.config.InstanceTemplate)
+}
+
+// provisioning is the configured provisioning model, or "template" when the
+// instance template decides
+func (p *GCEProvider) provisioning() string {
+	if p.config.Provisioning != "" {
+		return p.config.Provisioning
+	}
+	if p.config.InstanceTemplate != "" {
+		return "template"
+	}
+	return "standard"
+}
+
+// waitForOperation blocks until a zonal operation is done and returns its
+// error, if any
+func (p *GCEProvider) waitForOperation(ctx context.Context, op *compute.Operation) error {
+	for op.Status != "DONE" {
+		var err error
+		op, err = p.service.ZoneOperations.Wait(p.project, p.zone, op.Name).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("failed to wait for operation: %w", err)
+		}
+	}
+	return operationError(op)
+}
+
+func operationError(op *compute.Operation) error {
+	if op.Error == nil || len(op.Error.Errors) == 0 {
+		return nil
+	}
+	e := op.Error.Errors[0]
+	return fmt.Errorf("%s: %s", e.Code, e.Message)
+}
+
+func (p *GCEProvider) instanceToRunner(instance *compute.Instance) *provider.Runner {
+	runnerName := instance.Labels[labelRunnerName]
+	createdAt := time.Now()
+	if t, err := time.Parse(time.RFC3339, instance.CreationTimestamp); err == nil {
+		createdAt = t
+	}
+
+	metadata := map[string]string{
+		"instance":      instance.Name,
+		"instance_type": path.Base(instance.MachineType),
+		"zone":          path.Base(instance.Zone),
+		"state":         instance.Status,
+		"provisioning":  instanceProvisioning(instance.Scheduling),
+	}
+
+	// Request metadata is stored as zeno-prefixed metadata items
+	if instance.Metadata != nil {
+		for _, item := range instance.Metadata.Items {
+			if item.Value == nil {
+				continue
+			}
+			switch item.Key {
+			case metadataRunnerName:
+				runnerName = *item.Value
+			case metadataCreatedAt:
+				if t, err := time.Parse(time.RFC3339, *item.Value); err == nil {
+					createdAt = t
+				}
+			default:
+				if strings.HasPrefix(item.Key, metadataPrefix) {
+					metadata[strings.TrimPrefix(item.Key, metadataPrefix)] = *item.Value
+				}
+			}
+		}
+	}
+
+	if len(instance.NetworkInterfaces) > 0 {
+		nic := instance.NetworkInterfaces[0]
+		if nic.NetworkIP != "" {
+			metadata["private_ip"] = nic.NetworkIP
+		}
+		if len(nic.AccessConfigs) > 0 && nic.AccessConfigs[0].NatIP != "" {
+			metadata["public_ip"] = nic.AccessConfigs[0].NatIP
+		}
+	}
+
+	return &provider.Runner{
+		ID:         instance.Labels[labelRunnerID],
+		Name:       runnerName,
+		Status:     mapInstanceStatus(instance.Status),
+		Provider:   "gce",
+		ProviderID: instance.Name,
+		CreatedAt:  createdAt,
+		Metadata:   metadata,
+	}
+}
+
+func instanceProvisioning(s *compute.Scheduling) string {
+	switch {
+	case s == nil:
+		return "standard"
+	case s.ProvisioningModel == "SPOT":
+		return "spot"
+	case s.Preemptible:
+		return "preemptible"
+	default:
+		return "standard"
+	}
+}
+
+func mapInstanceStatus(status string) provider.RunnerStatus {
+	switch status {
+	case "PROVISIONING", "STAGING":
+		return provider.StatusProvisioning
+	case "RUNNING":
+		return provider.StatusRunning
+	case "STOPPING", "SUSPENDING":
+		return provider.StatusTerminating
+	case "TERMINATED", "SUSPENDED":
+		return provider.StatusTerminated
+	case "REPAIRING":
+		return provider.StatusPending
+	default:
+		return provider.StatusFailed
+	}
+}
+
+// labelValue fits s to the label value rules: at most 63 lowercase
+// letters, digits, dashes and underscores
+func labelValue(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if b.Len() == 63 {
+			break
+		}
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
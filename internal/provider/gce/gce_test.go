@@ -0,0 +1,365 @@
+package gce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"Zeno/internal/config"
+	"Zeno/internal/provider"
+	"Zeno/internal/provider/providertest"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+)
+
+// fakeCompute is a local stand-in for the zonal parts of the Compute Engine
+// REST API that the provider uses
+type fakeCompute struct {
+	mu        sync.Mutex
+	instances map[string]*compute.Instance
+	templates map[string]string // instance name to sourceInstanceTemplate
+	ops       map[string]*compute.Operation
+	nextOp    int
+
+	// insertErr is reported on the insert operation, as capacity errors are
+	insertErr *compute.OperationErrorErrors
+}
+
+func newFakeCompute(t *testing.T) (*fakeCompute, *httptest.Server) {
+	f := &fakeCompute{
+		instances: make(map[string]*compute.Instance),
+		templates: make(map[string]string),
+		ops:       make(map[string]*compute.Operation),
+	}
+	srv := httptest.NewServer(http.StripPrefix("/compute/v1", f))
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeCompute) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// projects/{project}/zones/{zone}[/{collection}[/{name}[/{verb}]]]
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 4 || parts[0] != "projects" || parts[2] != "zones" {
+		http.NotFound(w, r)
+		return
+	}
+	zone := parts[3]
+
+	switch {
+	case len(parts) == 4 && r.Method == http.MethodGet:
+		writeJSON(w, &compute.Zone{Name: zone, Status: "UP"})
+
+	case len(parts) == 5 && parts[4] == "instances" && r.Method == http.MethodGet:
+		list := &compute.InstanceList{}
+		for _, instance := range f.instances {
+			if matchesFilter(instance, r.URL.Query().Get("filter")) {
+				list.Items = append(list.Items, instance)
+			}
+		}
+		writeJSON(w, list)
+
+	case len(parts) == 5 && parts[4] == "instances" && r.Method == http.MethodPost:
+		var instance compute.Instance
+		if err := json.NewDecoder(r.Body).Decode(&instance); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		op := f.operation(zone)
+		if f.insertErr != nil {
+			op.Error = &compute.OperationError{Errors: []*compute.OperationErrorErrors{f.insertErr}}
+		} else {
+			instance.Status = "PROVISIONING"
+			instance.Zone = "https://www.googleapis.com/compute/v1/projects/" + parts[1] + "/zones/" + zone
+			instance.CreationTimestamp = "2024-06-01T12:00:00.000-07:00"
+			f.instances[instance.Name] = &instance
+			f.templates[instance.Name] = r.URL.Query().Get("sourceInstanceTemplate")
+		}
+		// Report the operation as still running so the provider has to wait
+		pending := *op
+		pending.Status = "RUNNING"
+		pending.Error = nil
+		f.ops[op.Name] = op
+		writeJSON(w, &pending)
+
+	case len(parts) == 6 && parts[4] == "instances" && r.Method == http.MethodDelete:
+		if _, ok := f.instances[parts[5]]; !ok {
+			http.Error(w, `{"error": {"code": 404, "message": "not found"}}`, http.StatusNotFound)
+			return
+		}
+		delete(f.instances, parts[5])
+		writeJSON(w, f.operation(zone))
+
+	case len(parts) == 7 && parts[4] == "operations" && parts[6] == "wait" && r.Method == http.MethodPost:
+		op, ok := f.ops[parts[5]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, op)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeCompute) operation(zone string) *compute.Operation {
+	f.nextOp++
+	return &compute.Operation{Name: fmt.Sprintf("operation-%d", f.nextOp), Zone: zone, Status: "DONE"}
+}
+
+func (f *fakeCompute) setStatus(status string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, instance := range f.instances {
+		instance.Status = status
+	}
+}
+
+func (f *fakeCompute) only(t *testing.T) (*compute.Instance, string) {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.instances) != 1 {
+		t.Fatalf("fake holds %d instances, want 1", len(f.instances))
+	}
+	for name, instance := range f.instances {
+		return instance, f.templates[name]
+	}
+	return nil, ""
+}
+
+// matchesFilter understands the single "labels.key = value" filters the
+// provider sends
+func matchesFilter(instance *compute.Instance, filter string) bool {
+	if filter == "" {
+		return true
+	}
+	key, value, ok := strings.Cut(filter, " = ")
+	if !ok || !strings.HasPrefix(key, "labels.") {
+		return false
+	}
+	return instance.Labels[strings.TrimPrefix(key, "labels.")] == value
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func newTestProvider(t *testing.T, srv *httptest.Server, cfg config.GCEConfig) *GCEProvider {
+	t.Helper()
+
+	cfg.Project = "acme-ci"
+	cfg.Zone = "europe-west1-b"
+	cfg.Endpoint = srv.URL + "/compute/v1/"
+	cfg.Pool = "gce"
+	p, err := newWithOptions(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("newWithOptions() error = %v", err)
+	}
+	return p
+}
+
+func metadataValue(instance *compute.Instance, key string) string {
+	for _, item := range instance.Metadata.Items {
+		if item.Key == key && item.Value != nil {
+			return *item.Value
+		}
+	}
+	return ""
+}
+
+func TestCreateRunnerFromMachineTypeAndImage(t *testing.T) {
+	fake, srv := newFakeCompute(t)
+	p := newTestProvider(t, srv, config.GCEConfig{
+		MachineType:  "e2-standard-4",
+		Image:        "projects/ubuntu-os-cloud/global/images/family/ubuntu-2204-lts",
+		DiskSizeGB:   50,
+		DiskType:     "pd-ssd",
+		Provisioning: "spot",
+		Labels:       map[string]string{"team": "platform"},
+	})
+
+	// Instance labels only take lowercase letters, digits, - and _
+	req := providertest.Request()
+	req.Name = "Zeno.Runner-1"
+	runner, err := p.CreateRunner(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	if runner.Metadata["provisioning"] != "spot" || runner.Metadata["instance_type"] != "e2-standard-4" || runner.Metadata[provider.MetadataJobID] != "42" {
+		t.Errorf("runner metadata = %v", runner.Metadata)
+	}
+
+	instance, template := fake.only(t)
+	if template != "" {
+		t.Errorf("sourceInstanceTemplate = %q, want none", template)
+	}
+	if instance.MachineType != "zones/europe-west1-b/machineTypes/e2-standard-4" {
+		t.Errorf("machine type = %q", instance.MachineType)
+	}
+	if len(instance.Disks) != 1 || instance.Disks[0].InitializeParams.DiskSizeGb != 50 || !instance.Disks[0].AutoDelete {
+		t.Errorf("disks = %+v", instance.Disks)
+	}
+	if s := instance.Scheduling; s == nil || s.ProvisioningModel != "SPOT" || s.OnHostMaintenance != "TERMINATE" {
+		t.Errorf("scheduling = %+v", s)
+	}
+
+	wantLabels := map[string]string{
+		labelManagedBy:  managedByRunner,
+		labelRunnerID:   runner.ID,
+		labelRunnerName: "zeno-runner-1",
+		"team":          "platform",
+	}
+	for k, v := range wantLabels {
+		if instance.Labels[k] != v {
+			t.Errorf("label %s = %q, want %q", k, instance.Labels[k], v)
+		}
+	}
+
+	script := metadataValue(instance, metadataStartupScript)
+	for _, want := range []string{"--url https://github.com/acme", "--token reg-token", "--name Zeno.Runner-1", "--ephemeral", "shutdown -h now"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("startup script missing %q:\n%s", want, script)
+		}
+	}
+}
+
+func TestCreateRunnerFromInstanceTemplate(t *testing.T) {
+	fake, srv := newFakeCompute(t)
+	p := newTestProvider(t, srv, config.GCEConfig{
+		InstanceTemplate: "zeno-runner",
+		Provisioning:     "preemptible",
+	})
+
+	if _, err := p.CreateRunner(context.Background(), providertest.Request()); err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+
+	instance, template := fake.only(t)
+	if template != "projects/acme-ci/global/instanceTemplates/zeno-runner" {
+		t.Errorf("sourceInstanceTemplate = %q", template)
+	}
+	// Everything not configured comes from the template
+	if instance.MachineType != "" || len(instance.Disks) != 0 || len(instance.NetworkInterfaces) != 0 {
+		t.Errorf("instance overrides template settings: %+v", instance)
+	}
+	if s := instance.Scheduling; s == nil || !s.Preemptible {
+		t.Errorf("scheduling = %+v, want preemptible", s)
+	}
+	if metadataValue(instance, metadataStartupScript) == "" {
+		t.Error("startup script not set")
+	}
+}
+
+func TestCreateRunnerReportsOperationError(t *testing.T) {
+	fake, srv := newFakeCompute(t)
+	fake.insertErr = &compute.OperationErrorErrors{
+		Code:    "ZONE_RESOURCE_POOL_EXHAUSTED",
+		Message: "The zone does not have enough resources available",
+	}
+	p := newTestProvider(t, srv, config.GCEConfig{MachineType: "e2-standard-4", Image: "ubuntu", DiskSizeGB: 30, DiskType: "pd-balanced"})
+
+	_, err := p.CreateRunner(context.Background(), providertest.Request())
+	if err == nil || !strings.Contains(err.Error(), "ZONE_RESOURCE_POOL_EXHAUSTED") {
+		t.Fatalf("CreateRunner() error = %v, want the operation's error", err)
+	}
+}
+
+func TestListRunnersReadsLabelsAndMetadata(t *testing.T) {
+	fake, srv := newFakeCompute(t)
+	p := newTestProvider(t, srv, config.GCEConfig{MachineType: "e2-standard-4", Image: "ubuntu", DiskSizeGB: 30, DiskType: "pd-balanced"})
+	ctx := context.Background()
+
+	created, err := p.CreateRunner(ctx, providertest.Request())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	instance, _ := fake.only(t)
+	// The API returns the machine type as a full URL
+	instance.MachineType = "https://www.googleapis.com/compute/v1/projects/acme-ci/zones/europe-west1-b/machineTypes/e2-standard-4"
+	fake.setStatus("RUNNING")
+
+	runners, err := p.ListRunners(ctx)
+	if err != nil {
+		t.Fatalf("ListRunners() error = %v", err)
+	}
+	if len(runners) != 1 {
+		t.Fatalf("ListRunners() returned %d runners, want 1", len(runners))
+	}
+	r := runners[0]
+	if r.ID != created.ID || r.Name != "zeno-runner-1" || r.Status != provider.StatusRunning || r.ProviderID != created.ProviderID {
+		t.Errorf("listed runner = %+v", r)
+	}
+	for k, want := range map[string]string{
+		provider.MetadataJobID: "42",
+		"pool":                 "gce",
+		"instance_type":        "e2-standard-4",
+		"zone":                 "europe-west1-b",
+		"provisioning":         "standard",
+	} {
+		if r.Metadata[k] != want {
+			t.Errorf("metadata %s = %q, want %q", k, r.Metadata[k], want)
+		}
+	}
+	// The creation time kept in metadata wins over the API's timestamp
+	if d := r.CreatedAt.Sub(created.CreatedAt); d < -time.Second || d > time.Second {
+		t.Errorf("CreatedAt = %v, want %v", r.CreatedAt, created.CreatedAt)
+	}
+}
+
+func TestRemoveRunnerDeletesInstance(t *testing.T) {
+	fake, srv := newFakeCompute(t)
+	p := newTestProvider(t, srv, config.GCEConfig{MachineType: "e2-standard-4", Image: "ubuntu", DiskSizeGB: 30, DiskType: "pd-balanced"})
+	ctx := context.Background()
+
+	runner, err := p.CreateRunner(ctx, providertest.Request())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	if err := p.RemoveRunner(ctx, runner.ID, true); err != nil {
+		t.Fatalf("RemoveRunner() error = %v", err)
+	}
+	if len(fake.instances) != 0 {
+		t.Errorf("%d instances left after removal", len(fake.instances))
+	}
+	if err := p.RemoveRunner(ctx, runner.ID, true); err == nil {
+		t.Error("RemoveRunner() of a removed runner succeeded")
+	}
+	if err := p.HealthCheck(ctx); err != nil {
+		t.Errorf("HealthCheck() error = %v", err)
+	}
+}
+
+func TestMapInstanceStatus(t *testing.T) {
+	tests := map[string]provider.RunnerStatus{
+		"PROVISIONING": provider.StatusProvisioning,
+		"STAGING":      provider.StatusProvisioning,
+		"RUNNING":      provider.StatusRunning,
+		"STOPPING":     provider.StatusTerminating,
+		"SUSPENDING":   provider.StatusTerminating,
+		"TERMINATED":   provider.StatusTerminated,
+		"SUSPENDED":    provider.StatusTerminated,
+		"REPAIRING":    provider.StatusPending,
+		"UNKNOWN":      provider.StatusFailed,
+	}
+	for status, want := range tests {
+		if got := mapInstanceStatus(status); got != want {
+			t.Errorf("mapInstanceStatus(%q) = %s, want %s", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,458 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"Zeno/internal/config"
+	"Zeno/internal/provider"
+
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	runnerLabelPrefix = "zeno.runner"
+	labelRunnerID     = runnerLabelPrefix + ".id"
+	labelManagedBy    = runnerLabelPrefix + ".managed-by"
+	labelPool         = runnerLabelPrefix + ".pool"
+
+	// Label values cannot hold runner names or repositories, so the name
+	// and request metadata are kept in annotations
+	annotationRunnerName = runnerLabelPrefix + ".name"
+
+	runnerContainer = "runner"
+
+	// secretTokenKey holds the registration token in a runner's Secret
+	secretTokenKey = "token"
+)
+
+type KubernetesProvider struct {
+	client kubernetes.Interface
+	config config.KubernetesConfig
+	logger *slog.Logger
+	mu     sync.RWMutex
+}
+
+func init() {
+	provider.Register("kubernetes", func(cfg config.ProviderConfig, logger *slog.Logger) (provider.Provider, error) {
+		return New(cfg.Kubernetes, logger)
+	})
+}
+
+// New creates a new Kubernetes provider. Without a kubeconfig path it uses
+// the service account of the pod Zeno runs in.
+func New(cfg config.KubernetesConfig, logger *slog.Logger) (*KubernetesProvider, error) {
+	var restCfg *rest.Config
+	var err error
+	if cfg.Kubeconfig != "" {
+		restCfg, err = clientcmd.BuildConfigFromFlags("", cfg.Kubeconfig)
+	} else {
+		restCfg, err = rest.InClusterConfig()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubernetes config: %w", err)
+	}
+
+	client, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+
+	return newProvider(client, cfg, logger)
+}
+
+func newProvider(client kubernetes.Interface, cfg config.KubernetesConfig, logger *slog.Logger) (*KubernetesProvider, error) {
+	if _, err := buildResources(cfg); err != nil {
+		return nil, err
+	}
+
+	return &KubernetesProvider{
+		client: client,
+		config: cfg,
+		logger: logger.With("provider", "kubernetes"),
+	}, nil
+}
+
+// Reconfigure applies a new pod template to runners created from now on.
+// The cluster, namespace and pool are fixed for the life of the provider.
+func (p *KubernetesProvider) Reconfigure(cfg config.ProviderConfig) ([]string, error) {
+	next := cfg.Kubernetes
+	if _, err := buildResources(next); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var restart []string
+	provider.KeepFixed(&restart, "provider.kubernetes.kubeconfig", &next.Kubeconfig, p.config.Kubeconfig)
+	provider.KeepFixed(&restart, "provider.kubernetes.namespace", &next.Namespace, p.config.Namespace)
+	provider.KeepFixed(&restart, "provider.kubernetes.pool", &next.Pool, p.config.Pool)
+	p.config = next
+	return restart, nil
+}
+
+func (p *KubernetesProvider) Name() string {
+	return "kubernetes"
+}
+
+func (p *KubernetesProvider) ListRunners(ctx context.Context) ([]*provider.Runner, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.listRunners(ctx)
+}
+
+// listRunners lists the pods of this pool only, so Zeno deployments sharing
+// a namespace do not count each other's runners
+func (p *KubernetesProvider) listRunners(ctx context.Context) ([]*provider.Runner, error) {
+	pods, err := p.client.CoreV1().Pods(p.config.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=zeno,%s=%s", labelManagedBy, labelPool, p.config.Pool),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	runners := make([]*provider.Runner, 0, len(pods.Items))
+	for i := range pods.Items {
+		runners = append(runners, podToRunner(&pods.Items[i]))
+	}
+
+	return runners, nil
+}
+
+func (p *KubernetesProvider) GetRunner(ctx context.Context, id string) (*provider.Runner, error) {
+	runners, err := p.ListRunners(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range runners {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+
+	return nil, fmt.Errorf("runner %s not found", id)
+}
+
+// CreateRunner creates the runner's pod. The registration token is kept in
+// a Secret the pod reads it from, so it is not in the pod spec for anyone
+// who can get pods, and the Secret is owned by the pod so it is deleted
+// with it.
+func (p *KubernetesProvider) CreateRunner(ctx context.Context, req *provider.CreateRunnerRequest) (*provider.Runner, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	runnerID := uuid.New().String()
+
+	p.logger.Info("creating runner", "id", runnerID, "name", req.Name)
+
+	pod, err := p.buildPod(runnerID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := p.client.CoreV1().Secrets(p.config.Namespace)
+	secret, err := secrets.Create(ctx, p.buildSecret(runnerID, req), metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token secret: %w", err)
+	}
+
+	created, err := p.client.CoreV1().Pods(p.config.Namespace).Create(ctx, pod, metav1.CreateOptions{})
+	if err != nil {
+		p.deleteSecret(ctx, secret.Name)
+		return nil, fmt.Errorf("failed to create pod: %w", err)
+	}
+
+	secret.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Name:       created.Name,
+		UID:        created.UID,
+	}}
+	if _, err := secrets.Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		// RemoveRunner still deletes the Secret
+		p.logger.Warn("failed to hand token secret to its pod", "id", runnerID, "secret", secret.Name, "error", err)
+	}
+
+	p.logger.Info("runner created successfully",
+		"id", runnerID,
+		"pod", created.Name,
+		"name", req.Name,
+	)
+
+	runner := podToRunner(created)
+	runner.Status = provider.StatusProvisioning
+	runner.Labels = req.Labels
+	runner.CreatedAt = time.Now()
+	return runner, nil
+}
+
+// RemoveRunner deletes the runner's pod. A graceful removal lets the runner
+// finish within the pod's termination grace period; otherwise the pod is
+// killed straight away.
+func (p *KubernetesProvider) RemoveRunner(ctx context.Context, id string, graceful bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pod, err := p.findPod(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	p.logger.Info("removing runner",
+		"id", id,
+		"pod", pod.Name,
+		"graceful", graceful,
+	)
+
+	var opts metav1.DeleteOptions
+	if !graceful {
+		var immediate int64
+		opts.GracePeriodSeconds = &immediate
+	}
+
+	if err := p.client.CoreV1().Pods(p.config.Namespace).Delete(ctx, pod.Name, opts); err != nil {
+		return fmt.Errorf("failed to delete pod: %w", err)
+	}
+	// The container has read the token by now; the Secret normally goes with
+	// the pod, but not if it could not be given its owner
+	p.deleteSecret(ctx, pod.Name)
+
+	p.logger.Info("runner removed successfully", "id", id)
+	return nil
+}
+
+func (p *KubernetesProvider) HealthCheck(ctx context.Context) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	// Listing pods also checks the service account may manage them
+	_, err := p.client.CoreV1().Pods(p.config.Namespace).List(ctx, metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return fmt.Errorf("kubernetes health check failed: %w", err)
+	}
+	return nil
+}
+
+func (p *KubernetesProvider) Close() error {
+	return nil
+}
+
+func (p *KubernetesProvider) findPod(ctx context.Context, id string) (*corev1.Pod, error) {
+	pods, err := p.client.CoreV1().Pods(p.config.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=zeno,%s=%s,%s=%s", labelManagedBy, labelPool, p.config.Pool, labelRunnerID, id),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	if len(pods.Items) == 0 {
+		return nil, fmt.Errorf("runner %s not found", id)
+	}
+	return &pods.Items[0], nil
+}
+
+// deleteSecret deletes a runner's token Secret, if it is still there
+func (p *KubernetesProvider) deleteSecret(ctx context.Context, name string) {
+	err := p.client.CoreV1().Secrets(p.config.Namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		p.logger.Warn("failed to delete token secret", "secret", name, "error", err)
+	}
+}
+
+// runnerName names a runner's pod and its token Secret
+func runnerName(runnerID string) string {
+	return fmt.Sprintf("zeno-runner-%s", runnerID[:8])
+}
+
+// runnerLabels are the labels of a runner's pod and Secret
+func (p *KubernetesProvider) runnerLabels(runnerID string) map[string]string {
+	return map[string]string{
+		labelRunnerID:  runnerID,
+		labelManagedBy: "zeno",
+		labelPool:      p.config.Pool,
+	}
+}
+
+func (p *KubernetesProvider) buildSecret(runnerID string, req *provider.CreateRunnerRequest) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      runnerName(runnerID),
+			Namespace: p.config.Namespace,
+			Labels:    p.runnerLabels(runnerID),
+		},
+		Type:       corev1.SecretTypeOpaque,
+		StringData: map[string]string{secretTokenKey: req.GitHubToken},
+	}
+}
+
+func (p *KubernetesProvider) buildPod(runnerID string, req *provider.CreateRunnerRequest) (*corev1.Pod, error) {
+	resources, err := buildResources(p.config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Zeno's own labels go last, since finding and counting runners relies
+	// on them and a configured label must not replace them
+	labels := make(map[string]string, len(p.config.Labels)+3)
+	for k, v := range p.config.Labels {
+		labels[k] = v
+	}
+	for k, v := range p.runnerLabels(runnerID) {
+		labels[k] = v
+	}
+
+	annotations := map[string]string{
+		annotationRunnerName: req.Name,
+	}
+	for k, v := range req.Metadata {
+		annotations[runnerLabelPrefix+"."+k] = v
+	}
+
+	gracePeriod := int64(p.config.TerminationGracePeriod / time.Second)
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        runnerName(runnerID),
+			Namespace:   p.config.Namespace,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy:                 corev1.RestartPolicyNever,
+			ServiceAccountName:            p.config.ServiceAccount,
+			NodeSelector:                  p.config.NodeSelector,
+			Tolerations:                   buildTolerations(p.config.Tolerations),
+			TerminationGracePeriodSeconds: &gracePeriod,
+			Containers: []corev1.Container{{
+				Name:      runnerContainer,
+				Image:     p.config.Image,
+				Env:       p.buildEnv(runnerID, req),
+				Resources: resources,
+			}},
+		},
+	}, nil
+}
+
+// buildEnv passes the runner container environment, with the token read
+// from the runner's Secret
+func (p *KubernetesProvider) buildEnv(runnerID string, req *provider.CreateRunnerRequest) []corev1.EnvVar {
+	var env []corev1.EnvVar
+	for _, v := range provider.ContainerEnv(req, p.config.RunnerWorkDir) {
+		if v.Name == provider.ContainerTokenEnv {
+			env = append(env, corev1.EnvVar{
+				Name: v.Name,
+				ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: runnerName(runnerID)},
+					Key:                  secretTokenKey,
+				}},
+			})
+			continue
+		}
+		env = append(env, corev1.EnvVar{Name: v.Name, Value: v.Value})
+	}
+	return env
+}
+
+func buildResources(cfg config.KubernetesConfig) (corev1.ResourceRequirements, error) {
+	var resources corev1.ResourceRequirements
+
+	quantities := []struct {
+		field string
+		value string
+		list  *corev1.ResourceList
+		name  corev1.ResourceName
+	}{
+		{"cpu_request", cfg.CPURequest, &resources.Requests, corev1.ResourceCPU},
+		{"memory_request", cfg.MemoryRequest, &resources.Requests, corev1.ResourceMemory},
+		{"cpu_limit", cfg.CPULimit, &resources.Limits, corev1.ResourceCPU},
+		{"memory_limit", cfg.MemoryLimit, &resources.Limits, corev1.ResourceMemory},
+	}
+	for _, q := range quantities {
+		if q.value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(q.value)
+		if err != nil {
+			return resources, fmt.Errorf("invalid provider.kubernetes.%s %q: %w", q.field, q.value, err)
+		}
+		if *q.list == nil {
+			*q.list = corev1.ResourceList{}
+		}
+		(*q.list)[q.name] = quantity
+	}
+
+	return resources, nil
+}
+
+func buildTolerations(configs []config.TolerationConfig) []corev1.Toleration {
+	tolerations := make([]corev1.Toleration, 0, len(configs))
+	for _, t := range configs {
+		tolerations = append(tolerations, corev1.Toleration{
+			Key:      t.Key,
+			Operator: corev1.TolerationOperator(t.Operator),
+			Value:    t.Value,
+			Effect:   corev1.TaintEffect(t.Effect),
+		})
+	}
+	return tolerations
+}
+
+func podToRunner(pod *corev1.Pod) *provider.Runner {
+	metadata := map[string]string{
+		"pod":       pod.Name,
+		"namespace": pod.Namespace,
+		"phase":     string(pod.Status.Phase),
+		"pool":      pod.Labels[labelPool],
+	}
+	if pod.Spec.NodeName != "" {
+		metadata["node"] = pod.Spec.NodeName
+	}
+	for k, v := range pod.Annotations {
+		if k == annotationRunnerName || !strings.HasPrefix(k, runnerLabelPrefix+".") {
+			continue
+		}
+		metadata[strings.TrimPrefix(k, runnerLabelPrefix+".")] = v
+	}
+
+	return &provider.Runner{
+		ID:         pod.Labels[labelRunnerID],
+		Name:       pod.Annotations[annotationRunnerName],
+		Status:     mapPodStatus(pod),
+		Provider:   "kubernetes",
+		ProviderID: pod.Name,
+		CreatedAt:  pod.CreationTimestamp.Time,
+		Metadata:   metadata,
+	}
+}
+
+func mapPodStatus(pod *corev1.Pod) provider.RunnerStatus {
+	if pod.DeletionTimestamp != nil {
+		return provider.StatusTerminating
+	}
+
+	switch pod.Status.Phase {
+	case corev1.PodPending:
+		return provider.StatusProvisioning
+	case corev1.PodRunning:
+		return provider.StatusRunning
+	case corev1.PodSucceeded:
+		return provider.StatusTerminated
+	case corev1.PodFailed:
+		return provider.StatusFailed
+	default:
+		return provider.StatusPending
+	}
+}
@@ -0,0 +1,274 @@
+package kubernetes
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"Zeno/internal/config"
+	"Zeno/internal/provider"
+	"Zeno/internal/provider/providertest"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func testConfig() config.KubernetesConfig {
+	return config.KubernetesConfig{
+		Namespace:      "runners",
+		Image:          "runner:latest",
+		ServiceAccount: "github-runner",
+		RunnerWorkDir:  "/runner/_work",
+		CPURequest:     "500m",
+		MemoryLimit:    "2Gi",
+		NodeSelector:   map[string]string{"kubernetes.io/arch": "amd64"},
+		Tolerations: []config.TolerationConfig{
+			{Key: "dedicated", Operator: "Equal", Value: "ci", Effect: "NoSchedule"},
+		},
+		TerminationGracePeriod: 45 * time.Second,
+		Pool:                   "k8s",
+	}
+}
+
+func newTestProvider(t *testing.T, cfg config.KubernetesConfig) (*KubernetesProvider, *fake.Clientset) {
+	t.Helper()
+
+	client := fake.NewSimpleClientset()
+	p, err := newProvider(client, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("newProvider() error = %v", err)
+	}
+	return p, client
+}
+
+func TestCreateRunnerBuildsPodFromTemplate(t *testing.T) {
+	p, client := newTestProvider(t, testConfig())
+	ctx := context.Background()
+
+	runner, err := p.CreateRunner(ctx, providertest.Request())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+
+	pod, err := client.CoreV1().Pods("runners").Get(ctx, runner.ProviderID, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("pod %s not created: %v", runner.ProviderID, err)
+	}
+
+	if pod.Labels[labelRunnerID] != runner.ID || pod.Labels[labelManagedBy] != "zeno" || pod.Labels[labelPool] != "k8s" {
+		t.Errorf("pod labels = %v", pod.Labels)
+	}
+	if pod.Spec.ServiceAccountName != "github-runner" || pod.Spec.RestartPolicy != corev1.RestartPolicyNever {
+		t.Errorf("pod spec = %+v", pod.Spec)
+	}
+	if pod.Spec.NodeSelector["kubernetes.io/arch"] != "amd64" {
+		t.Errorf("node selector = %v", pod.Spec.NodeSelector)
+	}
+	if len(pod.Spec.Tolerations) != 1 || pod.Spec.Tolerations[0].Effect != corev1.TaintEffectNoSchedule {
+		t.Errorf("tolerations = %v", pod.Spec.Tolerations)
+	}
+	if *pod.Spec.TerminationGracePeriodSeconds != 45 {
+		t.Errorf("termination grace period = %d, want 45", *pod.Spec.TerminationGracePeriodSeconds)
+	}
+
+	c := pod.Spec.Containers[0]
+	if c.Image != "runner:latest" {
+		t.Errorf("image = %s", c.Image)
+	}
+	if got := c.Resources.Requests.Cpu().String(); got != "500m" {
+		t.Errorf("cpu request = %s, want 500m", got)
+	}
+	if got := c.Resources.Limits.Memory().String(); got != "2Gi" {
+		t.Errorf("memory limit = %s, want 2Gi", got)
+	}
+	env := make(map[string]string)
+	for _, e := range c.Env {
+		env[e.Name] = e.Value
+	}
+	if env["RUNNER_SCOPE"] != "org" || env["ORG_NAME"] != "acme" || env["EPHEMERAL"] != "1" || env["LABELS"] != "self-hosted,linux" {
+		t.Errorf("env = %v", env)
+	}
+
+	// The token is read from a Secret owned by the pod, not kept in its spec
+	for _, e := range c.Env {
+		if e.Value == "reg-token" {
+			t.Errorf("env %s holds the registration token in the pod spec", e.Name)
+		}
+		if e.Name == provider.ContainerTokenEnv {
+			if ref := e.ValueFrom; ref == nil || ref.SecretKeyRef == nil || ref.SecretKeyRef.Name != pod.Name {
+				t.Errorf("%s = %+v, want it read from secret %s", e.Name, e.ValueFrom, pod.Name)
+			}
+		}
+	}
+	secret, err := client.CoreV1().Secrets("runners").Get(ctx, pod.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("token secret not created: %v", err)
+	}
+	if secret.StringData[secretTokenKey] != "reg-token" {
+		t.Errorf("secret data = %v, want the registration token", secret.StringData)
+	}
+	if owners := secret.OwnerReferences; len(owners) != 1 || owners[0].Kind != "Pod" || owners[0].Name != pod.Name {
+		t.Errorf("secret owners = %+v, want the runner pod", owners)
+	}
+
+	runners, err := p.ListRunners(ctx)
+	if err != nil {
+		t.Fatalf("ListRunners() error = %v", err)
+	}
+	if len(runners) != 1 {
+		t.Fatalf("ListRunners() returned %d runners, want 1", len(runners))
+	}
+	if r := runners[0]; r.ID != runner.ID || r.Name != "zeno-runner-1" || r.Metadata[provider.MetadataJobID] != "42" {
+		t.Errorf("listed runner = %+v", r)
+	}
+}
+
+func TestConfiguredLabelsCannotReplaceZenoLabels(t *testing.T) {
+	cfg := testConfig()
+	cfg.Labels = map[string]string{labelManagedBy: "someone-else", labelPool: "other", "team": "ci"}
+	p, _ := newTestProvider(t, cfg)
+
+	pod, err := p.buildPod("11111111-2222", providertest.Request())
+	if err != nil {
+		t.Fatalf("buildPod() error = %v", err)
+	}
+	if pod.Labels[labelManagedBy] != "zeno" || pod.Labels[labelPool] != "k8s" || pod.Labels["team"] != "ci" {
+		t.Errorf("pod labels = %v, want zeno labels kept alongside team=ci", pod.Labels)
+	}
+}
+
+func TestListRunnersIgnoresUnmanagedPods(t *testing.T) {
+	p, client := newTestProvider(t, testConfig())
+	ctx := context.Background()
+
+	other := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "runners"}}
+	if _, err := client.CoreV1().Pods("runners").Create(ctx, other, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	runners, err := p.ListRunners(ctx)
+	if err != nil {
+		t.Fatalf("ListRunners() error = %v", err)
+	}
+	if len(runners) != 0 {
+		t.Errorf("ListRunners() returned %d runners, want unmanaged pods ignored", len(runners))
+	}
+}
+
+func TestListRunnersIgnoresOtherPools(t *testing.T) {
+	p, client := newTestProvider(t, testConfig())
+	otherCfg := testConfig()
+	otherCfg.Pool = "k8s-gpu"
+	other, err := newProvider(client, otherCfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("newProvider() error = %v", err)
+	}
+	ctx := context.Background()
+
+	theirs, err := other.CreateRunner(ctx, providertest.Request())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+
+	runners, err := p.ListRunners(ctx)
+	if err != nil {
+		t.Fatalf("ListRunners() error = %v", err)
+	}
+	if len(runners) != 0 {
+		t.Errorf("ListRunners() returned %d runners, want the other pool's pod ignored", len(runners))
+	}
+	if err := p.RemoveRunner(ctx, theirs.ID, false); err == nil {
+		t.Error("RemoveRunner() removed a runner of another pool")
+	}
+}
+
+func TestRemoveRunnerGracePeriod(t *testing.T) {
+	tests := []struct {
+		name     string
+		graceful bool
+		want     *int64
+	}{
+		{"graceful uses the pod grace period", true, nil},
+		{"forced kills immediately", false, new(int64)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, client := newTestProvider(t, testConfig())
+			ctx := context.Background()
+
+			var deleted *metav1.DeleteOptions
+			client.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				opts := action.(k8stesting.DeleteActionImpl).GetDeleteOptions()
+				deleted = &opts
+				return false, nil, nil
+			})
+
+			runner, err := p.CreateRunner(ctx, providertest.Request())
+			if err != nil {
+				t.Fatalf("CreateRunner() error = %v", err)
+			}
+			if err := p.RemoveRunner(ctx, runner.ID, tt.graceful); err != nil {
+				t.Fatalf("RemoveRunner() error = %v", err)
+			}
+
+			if deleted == nil {
+				t.Fatal("pod was not deleted")
+			}
+			got := deleted.GracePeriodSeconds
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("grace period = %v, want %v", got, tt.want)
+			}
+
+			runners, _ := p.ListRunners(ctx)
+			if len(runners) != 0 {
+				t.Errorf("%d runners left after removal", len(runners))
+			}
+			if secrets, _ := client.CoreV1().Secrets("runners").List(ctx, metav1.ListOptions{}); len(secrets.Items) != 0 {
+				t.Errorf("%d token secrets left after removal", len(secrets.Items))
+			}
+		})
+	}
+}
+
+func TestMapPodStatus(t *testing.T) {
+	now := metav1.Now()
+	tests := []struct {
+		pod  corev1.Pod
+		want provider.RunnerStatus
+	}{
+		{corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodPending}}, provider.StatusProvisioning},
+		{corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning}}, provider.StatusRunning},
+		{corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodSucceeded}}, provider.StatusTerminated},
+		{corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodFailed}}, provider.StatusFailed},
+		{corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodUnknown}}, provider.StatusPending},
+		{corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &now},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}, provider.StatusTerminating},
+	}
+
+	for _, tt := range tests {
+		if got := mapPodStatus(&tt.pod); got != tt.want {
+			t.Errorf("mapPodStatus(%s) = %s, want %s", tt.pod.Status.Phase, got, tt.want)
+		}
+	}
+}
+
+func TestInvalidResourcesRejected(t *testing.T) {
+	cfg := testConfig()
+	cfg.CPULimit = "lots"
+	if _, err := newProvider(fake.NewSimpleClientset(), cfg, slog.New(slog.NewTextHandler(io.Discard, nil))); err == nil {
+		t.Error("newProvider() accepted an invalid cpu limit")
+	}
+
+	p, _ := newTestProvider(t, testConfig())
+	if _, err := p.Reconfigure(config.ProviderConfig{Kubernetes: cfg}); err == nil {
+		t.Error("Reconfigure() accepted an invalid cpu limit")
+	}
+}
@@ -0,0 +1,185 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+
+	"Zeno/internal/config"
+	"Zeno/internal/provider"
+	"Zeno/pkg/providerplugin"
+)
+
+func init() {
+	provider.Register("plugin", func(cfg config.ProviderConfig, logger *slog.Logger) (provider.Provider, error) {
+		return New(cfg.Plugin, logger)
+	})
+}
+
+// PluginProvider runs a provider plugin program and forwards provider calls
+// to it over the plugin protocol
+type PluginProvider struct {
+	client *providerplugin.Client
+	config config.PluginConfig
+	logger *slog.Logger
+}
+
+// New starts the plugin and waits for it to finish the handshake
+func New(cfg config.PluginConfig, logger *slog.Logger) (*PluginProvider, error) {
+	settings, err := json.Marshal(cfg.Settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode plugin settings: %w", err)
+	}
+
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Env = os.Environ()
+	keys := make([]string, 0, len(cfg.Env))
+	for k := range cfg.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		cmd.Env = append(cmd.Env, k+"="+cfg.Env[k])
+	}
+	cmd.Stderr = &logWriter{logger: logger.With("plugin", cfg.Command)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.StartTimeout)
+	defer cancel()
+
+	client, err := providerplugin.Start(ctx, cmd, settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", cfg.Command, err)
+	}
+
+	logger.Info("started provider plugin", "command", cfg.Command, "name", client.Name())
+
+	return &PluginProvider{client: client, config: cfg, logger: logger}, nil
+}
+
+// Name returns the name the plugin reported
+func (p *PluginProvider) Name() string {
+	return p.client.Name()
+}
+
+// ListRunners returns all runners managed by the plugin
+func (p *PluginProvider) ListRunners(ctx context.Context) ([]*provider.Runner, error) {
+	runners, err := p.client.ListRunners(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runners: %w", err)
+	}
+
+	result := make([]*provider.Runner, 0, len(runners))
+	for _, r := range runners {
+		result = append(result, p.fromWire(r))
+	}
+	return result, nil
+}
+
+// GetRunner returns a specific runner by ID
+func (p *PluginProvider) GetRunner(ctx context.Context, id string) (*provider.Runner, error) {
+	r, err := p.client.GetRunner(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get runner: %w", err)
+	}
+	return p.fromWire(r), nil
+}
+
+// CreateRunner asks the plugin to provision a new runner
+func (p *PluginProvider) CreateRunner(ctx context.Context, req *provider.CreateRunnerRequest) (*provider.Runner, error) {
+	r, err := p.client.CreateRunner(ctx, &providerplugin.CreateRunnerRequest{
+		Name:          req.Name,
+		Labels:        req.Labels,
+		GitHubToken:   req.GitHubToken,
+		GitHubOrg:     req.GitHubOrg,
+		GitHubRepo:    req.GitHubRepo,
+		RunnerVersion: req.RunnerVersion,
+		Ephemeral:     req.Ephemeral,
+		Metadata:      req.Metadata,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create runner: %w", err)
+	}
+
+	p.logger.Info("created runner", "id", r.ID, "name", r.Name)
+
+	return p.fromWire(r), nil
+}
+
+// RemoveRunner asks the plugin to terminate and remove a runner
+func (p *PluginProvider) RemoveRunner(ctx context.Context, id string, graceful bool) error {
+	if err := p.client.RemoveRunner(ctx, id, graceful); err != nil {
+		return fmt.Errorf("failed to remove runner: %w", err)
+	}
+
+	p.logger.Info("removed runner", "id", id)
+
+	return nil
+}
+
+// HealthCheck asks the plugin to check its platform
+func (p *PluginProvider) HealthCheck(ctx context.Context) error {
+	if err := p.client.HealthCheck(ctx); err != nil {
+		return fmt.Errorf("plugin health check failed: %w", err)
+	}
+	return nil
+}
+
+// Reconfigure applies nothing: the plugin reads its settings once, during
+// the handshake, so any change to provider.plugin needs a restart
+func (p *PluginProvider) Reconfigure(cfg config.ProviderConfig) ([]string, error) {
+	var restart []string
+	provider.KeepFixed(&restart, "provider.plugin", &cfg.Plugin, p.config)
+	return restart, nil
+}
+
+// Close stops the plugin
+func (p *PluginProvider) Close() error {
+	return p.client.Close()
+}
+
+func (p *PluginProvider) fromWire(r *providerplugin.Runner) *provider.Runner {
+	return &provider.Runner{
+		ID:         r.ID,
+		Name:       r.Name,
+		Status:     provider.RunnerStatus(r.Status),
+		Labels:     r.Labels,
+		Provider:   p.client.Name(),
+		ProviderID: r.ProviderID,
+		CreatedAt:  r.CreatedAt,
+		LastSeen:   r.LastSeen,
+		Metadata:   r.Metadata,
+	}
+}
+
+// logWriter logs each line the plugin writes to stderr
+type logWriter struct {
+	logger *slog.Logger
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *logWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(data)
+	for {
+		line, err := w.buf.ReadBytes('\n')
+		if err != nil {
+			// Keep the partial line for the next write
+			w.buf.Write(line)
+			break
+		}
+		if line = bytes.TrimRight(line, "\r\n"); len(line) > 0 {
+			w.logger.Info(string(line))
+		}
+	}
+	return len(data), nil
+}
@@ -0,0 +1,254 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"Zeno/internal/config"
+	"Zeno/internal/provider"
+	"Zeno/pkg/providerplugin"
+)
+
+// TestMain lets the test binary stand in for a plugin when ZENO_TEST_PLUGIN
+// is set
+func TestMain(m *testing.M) {
+	if os.Getenv("ZENO_TEST_PLUGIN") == "1" {
+		if err := providerplugin.Serve(newFakePlugin); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+type fakeSettings struct {
+	Fail    string `json:"fail"`
+	Message string `json:"message"`
+}
+
+// fakePlugin keeps one runner per create request and misbehaves as told
+// by its settings
+type fakePlugin struct {
+	settings fakeSettings
+
+	mu      sync.Mutex
+	runners map[string]*providerplugin.Runner
+}
+
+func newFakePlugin(raw json.RawMessage) (providerplugin.Provider, error) {
+	var settings fakeSettings
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		return nil, err
+	}
+	if settings.Fail == "init" {
+		return nil, fmt.Errorf("bad settings")
+	}
+	fmt.Fprintf(os.Stderr, "starting with message %s\n", settings.Message)
+	return &fakePlugin{settings: settings, runners: make(map[string]*providerplugin.Runner)}, nil
+}
+
+func (p *fakePlugin) Name() string { return "fake" }
+
+func (p *fakePlugin) ListRunners(ctx context.Context) ([]*providerplugin.Runner, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var runners []*providerplugin.Runner
+	for _, r := range p.runners {
+		runners = append(runners, r)
+	}
+	return runners, nil
+}
+
+func (p *fakePlugin) GetRunner(ctx context.Context, id string) (*providerplugin.Runner, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r, ok := p.runners[id]
+	if !ok {
+		return nil, fmt.Errorf("runner not found: %s", id)
+	}
+	return r, nil
+}
+
+func (p *fakePlugin) CreateRunner(ctx context.Context, req *providerplugin.CreateRunnerRequest) (*providerplugin.Runner, error) {
+	if p.settings.Fail == "create" {
+		os.Exit(3)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r := &providerplugin.Runner{
+		ID:         "fake-" + req.Name,
+		Name:       req.Name,
+		Status:     providerplugin.StatusProvisioning,
+		Labels:     req.Labels,
+		ProviderID: req.GitHubOrg + "/" + req.GitHubToken,
+		CreatedAt:  time.Now(),
+		Metadata:   req.Metadata,
+	}
+	p.runners[r.ID] = r
+	return r, nil
+}
+
+func (p *fakePlugin) RemoveRunner(ctx context.Context, id string, graceful bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !graceful {
+		return fmt.Errorf("forced removal is not supported")
+	}
+	delete(p.runners, id)
+	return nil
+}
+
+func (p *fakePlugin) HealthCheck(ctx context.Context) error {
+	if p.settings.Fail == "health" {
+		return fmt.Errorf("platform is down")
+	}
+	return nil
+}
+
+func (p *fakePlugin) Close() error { return nil }
+
+// syncBuffer collects log output written from the plugin's stderr copier
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func newTestProvider(t *testing.T, settings map[string]interface{}) (*PluginProvider, *syncBuffer, error) {
+	t.Helper()
+
+	logs := &syncBuffer{}
+	p, err := New(config.PluginConfig{
+		Command:      os.Args[0],
+		Env:          map[string]string{"ZENO_TEST_PLUGIN": "1"},
+		Settings:     settings,
+		StartTimeout: 10 * time.Second,
+	}, slog.New(slog.NewTextHandler(logs, nil)))
+	if err == nil {
+		t.Cleanup(func() { p.Close() })
+	}
+	return p, logs, err
+}
+
+func TestRunnerRoundTrip(t *testing.T) {
+	p, logs, err := newTestProvider(t, map[string]interface{}{"message": "hello"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx := context.Background()
+
+	if p.Name() != "fake" {
+		t.Errorf("Name() = %q, want the name reported by the plugin", p.Name())
+	}
+	if err := p.HealthCheck(ctx); err != nil {
+		t.Errorf("HealthCheck() error = %v", err)
+	}
+
+	runner, err := p.CreateRunner(ctx, &provider.CreateRunnerRequest{
+		Name:        "zeno-runner-1",
+		Labels:      []string{"self-hosted"},
+		GitHubOrg:   "acme",
+		GitHubToken: "reg-token",
+		Metadata:    map[string]string{provider.MetadataJobID: "42"},
+	})
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	if runner.ID != "fake-zeno-runner-1" || runner.Status != provider.StatusProvisioning || runner.Provider != "fake" {
+		t.Errorf("CreateRunner() = %+v", runner)
+	}
+	if runner.ProviderID != "acme/reg-token" {
+		t.Errorf("ProviderID = %q, want the request fields passed to the plugin", runner.ProviderID)
+	}
+
+	runners, err := p.ListRunners(ctx)
+	if err != nil {
+		t.Fatalf("ListRunners() error = %v", err)
+	}
+	if len(runners) != 1 || runners[0].Metadata[provider.MetadataJobID] != "42" || runners[0].Labels[0] != "self-hosted" {
+		t.Errorf("ListRunners() = %+v", runners)
+	}
+
+	if err := p.RemoveRunner(ctx, runner.ID, false); err == nil || !strings.Contains(err.Error(), "forced removal is not supported") {
+		t.Errorf("RemoveRunner(graceful=false) error = %v, want the plugin's error", err)
+	}
+	if err := p.RemoveRunner(ctx, runner.ID, true); err != nil {
+		t.Fatalf("RemoveRunner() error = %v", err)
+	}
+	if _, err := p.GetRunner(ctx, runner.ID); err == nil {
+		t.Error("GetRunner() of a removed runner succeeded")
+	}
+
+	if !strings.Contains(logs.String(), "starting with message hello") {
+		t.Errorf("plugin stderr was not logged:\n%s", logs)
+	}
+}
+
+func TestPluginErrors(t *testing.T) {
+	if _, _, err := newTestProvider(t, map[string]interface{}{"fail": "init"}); err == nil || !strings.Contains(err.Error(), "bad settings") {
+		t.Errorf("New() error = %v, want the plugin's init error", err)
+	}
+
+	p, _, err := newTestProvider(t, map[string]interface{}{"fail": "health"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := p.HealthCheck(context.Background()); err == nil || !strings.Contains(err.Error(), "platform is down") {
+		t.Errorf("HealthCheck() error = %v, want the plugin's error", err)
+	}
+}
+
+func TestPluginExit(t *testing.T) {
+	p, _, err := newTestProvider(t, map[string]interface{}{"fail": "create"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := p.CreateRunner(ctx, &provider.CreateRunnerRequest{Name: "zeno-runner-1"}); err == nil || !strings.Contains(err.Error(), "exit status 3") {
+		t.Errorf("CreateRunner() error = %v, want the plugin's exit", err)
+	}
+	if _, err := p.ListRunners(ctx); err == nil {
+		t.Error("ListRunners() succeeded after the plugin exited")
+	}
+}
+
+func TestStartTimeout(t *testing.T) {
+	start := time.Now()
+	_, err := New(config.PluginConfig{
+		Command:      "sleep",
+		Args:         []string{"10"},
+		StartTimeout: 200 * time.Millisecond,
+	}, slog.New(slog.NewTextHandler(&syncBuffer{}, nil)))
+	if err == nil {
+		t.Fatal("New() succeeded with a plugin that never answers")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("New() took %v, want it to give up after the start timeout", elapsed)
+	}
+}
@@ -0,0 +1,475 @@
+package process
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"Zeno/internal/config"
+	"Zeno/internal/provider"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// stateFile in each runner directory lets a restarted Zeno find
+	// runners started by an earlier process
+	stateFile = ".zeno-runner.json"
+	logFile   = "runner.log"
+)
+
+// runnerState is written to stateFile when a runner starts
+type runnerState struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	PID       int               `json:"pid"`
+	Labels    []string          `json:"labels"`
+	CreatedAt time.Time         `json:"created_at"`
+	Metadata  map[string]string `json:"metadata"`
+	// StartTime tells the runner's process apart from a later process
+	// given the same PID. It is empty where start times are not available.
+	StartTime string `json:"start_time,omitempty"`
+}
+
+// runnerProcess is a run.sh started by this process
+type runnerProcess struct {
+	state    runnerState
+	done     chan struct{}
+	exitCode int
+	stopping bool
+}
+
+type ProcessProvider struct {
+	config config.ProcessConfig
+	logger *slog.Logger
+	mu     sync.RWMutex
+
+	// processes holds runners started by this process, keyed by runner ID
+	processes map[string]*runnerProcess
+}
+
+func init() {
+	provider.Register("process", func(cfg config.ProviderConfig, logger *slog.Logger) (provider.Provider, error) {
+		return New(cfg.Process, logger)
+	})
+}
+
+// New creates a new process provider
+func New(cfg config.ProcessConfig, logger *slog.Logger) (*ProcessProvider, error) {
+	if err := os.MkdirAll(cfg.WorkRoot, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create work root: %w", err)
+	}
+
+	return &ProcessProvider{
+		config:    cfg,
+		logger:    logger.With("provider", "process"),
+		processes: make(map[string]*runnerProcess),
+	}, nil
+}
+
+// Reconfigure switches the runner directory and stop timeout for runners
+// created from now on. The work root and pool are fixed.
+func (p *ProcessProvider) Reconfigure(cfg config.ProviderConfig) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var restart []string
+	next := cfg.Process
+	provider.KeepFixed(&restart, "provider.process.work_root", &next.WorkRoot, p.config.WorkRoot)
+	provider.KeepFixed(&restart, "provider.process.pool", &next.Pool, p.config.Pool)
+	p.config = next
+	return restart, nil
+}
+
+func (p *ProcessProvider) Name() string {
+	return "process"
+}
+
+// ListRunners returns runners started by this process and live runners
+// left in the work root by an earlier one
+func (p *ProcessProvider) ListRunners(ctx context.Context) ([]*provider.Runner, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	entries, err := os.ReadDir(p.config.WorkRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read work root: %w", err)
+	}
+
+	var runners []*provider.Runner
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(p.config.WorkRoot, entry.Name())
+		state, err := readState(dir)
+		if err != nil {
+			continue
+		}
+
+		if proc, ok := p.processes[state.ID]; ok {
+			runners = append(runners, p.toRunner(proc.state, dir, proc.status()))
+			continue
+		}
+
+		// A runner from an earlier process can only be watched by its PID
+		status := provider.StatusTerminated
+		if running(state) {
+			status = provider.StatusRunning
+		}
+		runners = append(runners, p.toRunner(state, dir, status))
+	}
+
+	return runners, nil
+}
+
+func (p *ProcessProvider) GetRunner(ctx context.Context, id string) (*provider.Runner, error) {
+	runners, err := p.ListRunners(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range runners {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+
+	return nil, fmt.Errorf("runner %s not found", id)
+}
+
+// CreateRunner copies the runner directory, registers the runner with
+// config.sh and starts run.sh. Output of both goes to the runner's log file.
+// The provider is not locked while config.sh talks to GitHub, so listing and
+// removing runners carry on meanwhile.
+func (p *ProcessProvider) CreateRunner(ctx context.Context, req *provider.CreateRunnerRequest) (*provider.Runner, error) {
+	p.mu.RLock()
+	cfg := p.config
+	p.mu.RUnlock()
+
+	runnerID := uuid.New().String()
+	dir := filepath.Join(cfg.WorkRoot, fmt.Sprintf("zeno-runner-%s", runnerID[:8]))
+
+	p.logger.Info("creating runner", "id", runnerID, "name", req.Name, "dir", dir)
+
+	if err := copyDir(cfg.RunnerDir, dir); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to copy runner directory: %w", err)
+	}
+
+	logOut, err := os.OpenFile(filepath.Join(dir, logFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to open runner log: %w", err)
+	}
+
+	configure := exec.CommandContext(ctx, "./config.sh", provider.ConfigArgs(req)...)
+	configure.Dir = dir
+	configure.Env = append(os.Environ(), provider.TokenEnv+"="+req.GitHubToken)
+	configure.Stdout = logOut
+	configure.Stderr = logOut
+	if err := configure.Run(); err != nil {
+		logOut.Close()
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to configure runner: %w", err)
+	}
+
+	run := exec.Command("./run.sh")
+	run.Dir = dir
+	run.Stdout = logOut
+	run.Stderr = logOut
+	// run.sh starts the listener as a child; signals go to the whole group
+	setProcessGroup(run)
+	if err := run.Start(); err != nil {
+		logOut.Close()
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to start runner: %w", err)
+	}
+
+	metadata := map[string]string{"pool": cfg.Pool}
+	for k, v := range req.Metadata {
+		metadata[k] = v
+	}
+
+	proc := &runnerProcess{
+		state: runnerState{
+			ID:        runnerID,
+			Name:      req.Name,
+			PID:       run.Process.Pid,
+			Labels:    req.Labels,
+			CreatedAt: time.Now(),
+			Metadata:  metadata,
+		},
+		done: make(chan struct{}),
+	}
+	if started, err := processStartTime(proc.state.PID); err == nil {
+		proc.state.StartTime = started
+	}
+
+	// Registered before the state file is written, so ListRunners never
+	// mistakes the new runner for one left by an earlier process
+	p.mu.Lock()
+	p.processes[runnerID] = proc
+	p.mu.Unlock()
+
+	if err := writeState(dir, proc.state); err != nil {
+		p.logger.Warn("failed to write runner state", "id", runnerID, "error", err)
+	}
+
+	go func() {
+		defer close(proc.done)
+		defer logOut.Close()
+
+		err := run.Wait()
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			proc.exitCode = exitErr.ExitCode()
+		}
+		p.logger.Info("runner process exited", "id", runnerID, "exit_code", proc.exitCode)
+	}()
+
+	p.logger.Info("runner created successfully",
+		"id", runnerID,
+		"pid", proc.state.PID,
+		"name", req.Name,
+	)
+
+	runner := p.toRunner(proc.state, dir, provider.StatusProvisioning)
+	runner.Labels = req.Labels
+	return runner, nil
+}
+
+// RemoveRunner stops the runner and deletes its directory. A graceful stop
+// sends SIGINT, which lets the runner finish its job, and falls back to
+// SIGKILL after the stop timeout.
+func (p *ProcessProvider) RemoveRunner(ctx context.Context, id string, graceful bool) error {
+	runner, err := p.GetRunner(ctx, id)
+	if err != nil {
+		return err
+	}
+	dir := runner.Metadata["dir"]
+
+	p.mu.Lock()
+	proc := p.processes[id]
+	if proc != nil {
+		proc.stopping = true
+	}
+	timeout := p.config.StopTimeout
+	p.mu.Unlock()
+
+	p.logger.Info("removing runner",
+		"id", id,
+		"pid", runner.ProviderID,
+		"graceful", graceful,
+	)
+
+	pid, _ := strconv.Atoi(runner.ProviderID)
+	exited := func() bool {
+		state, err := readState(dir)
+		return err != nil || !running(state)
+	}
+	if proc != nil {
+		exited = func() bool {
+			select {
+			case <-proc.done:
+				return true
+			default:
+				return false
+			}
+		}
+	}
+
+	if graceful && !exited() {
+		if err := signalGroup(pid, interruptSignal); err != nil {
+			p.logger.Warn("failed to interrupt runner", "id", id, "error", err)
+		}
+		waitFor(ctx, exited, timeout)
+		if !exited() {
+			p.logger.Warn("runner did not stop in time, killing it", "id", id)
+		}
+	}
+	if !exited() {
+		if err := signalGroup(pid, killSignal); err != nil && !exited() {
+			return fmt.Errorf("failed to kill runner process: %w", err)
+		}
+		waitFor(ctx, exited, timeout)
+	}
+
+	p.mu.Lock()
+	delete(p.processes, id)
+	p.mu.Unlock()
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove runner directory: %w", err)
+	}
+
+	p.logger.Info("runner removed successfully", "id", id)
+	return nil
+}
+
+func (p *ProcessProvider) HealthCheck(ctx context.Context) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, script := range []string{"config.sh", "run.sh"} {
+		if _, err := os.Stat(filepath.Join(p.config.RunnerDir, script)); err != nil {
+			return fmt.Errorf("process health check failed: %w", err)
+		}
+	}
+	if _, err := os.Stat(p.config.WorkRoot); err != nil {
+		return fmt.Errorf("process health check failed: %w", err)
+	}
+	return nil
+}
+
+// Close leaves runners running, like the other providers; the shutdown
+// policy decides whether they are removed
+func (p *ProcessProvider) Close() error {
+	return nil
+}
+
+func (p *ProcessProvider) toRunner(state runnerState, dir string, status provider.RunnerStatus) *provider.Runner {
+	metadata := map[string]string{
+		"dir": dir,
+		"log": filepath.Join(dir, logFile),
+	}
+	for k, v := range state.Metadata {
+		metadata[k] = v
+	}
+
+	return &provider.Runner{
+		ID:         state.ID,
+		Name:       state.Name,
+		Status:     status,
+		Labels:     state.Labels,
+		Provider:   "process",
+		ProviderID: fmt.Sprint(state.PID),
+		CreatedAt:  state.CreatedAt,
+		Metadata:   metadata,
+	}
+}
+
+// status maps the process state to a runner status. The caller holds p.mu.
+func (r *runnerProcess) status() provider.RunnerStatus {
+	select {
+	case <-r.done:
+		if r.exitCode != 0 && !r.stopping {
+			return provider.StatusFailed
+		}
+		return provider.StatusTerminated
+	default:
+	}
+
+	if r.stopping {
+		return provider.StatusTerminating
+	}
+	return provider.StatusRunning
+}
+
+// running reports whether the process recorded in state is still alive. A
+// PID may have been given to an unrelated process since the state was
+// written, so a recorded start time has to match too.
+func running(state runnerState) bool {
+	if !processAlive(state.PID) {
+		return false
+	}
+	if state.StartTime == "" {
+		return true
+	}
+	started, err := processStartTime(state.PID)
+	return err == nil && started == state.StartTime
+}
+
+// waitFor polls done until it reports true, the timeout passes or ctx ends
+func waitFor(ctx context.Context, done func() bool, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for !done() && time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func readState(dir string) (runnerState, error) {
+	var state runnerState
+	data, err := os.ReadFile(filepath.Join(dir, stateFile))
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(data, &state)
+	return state, err
+}
+
+func writeState(dir string, state runnerState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, stateFile), data, 0o644)
+}
+
+// copyDir copies the runner directory tree, keeping file modes so the
+// scripts stay executable
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			return copyFile(path, target, info.Mode().Perm())
+		}
+	})
+}
+
+func copyFile(src, dst string, mode fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
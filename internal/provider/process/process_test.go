@@ -0,0 +1,249 @@
+//go:build unix
+
+package process
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"Zeno/internal/config"
+	"Zeno/internal/provider"
+	"Zeno/internal/provider/providertest"
+)
+
+func newTestProvider(t *testing.T, runnerDir string) *ProcessProvider {
+	t.Helper()
+
+	p, err := New(config.ProcessConfig{
+		RunnerDir:   runnerDir,
+		WorkRoot:    filepath.Join(t.TempDir(), "work"),
+		StopTimeout: 500 * time.Millisecond,
+		Pool:        "process",
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return p
+}
+
+// waitForStatus polls until the runner reaches want
+func waitForStatus(t *testing.T, p *ProcessProvider, id string, want provider.RunnerStatus) *provider.Runner {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		runner, err := p.GetRunner(context.Background(), id)
+		if err != nil {
+			t.Fatalf("GetRunner() error = %v", err)
+		}
+		if runner.Status == want {
+			return runner
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("runner status = %s, want %s", runner.Status, want)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func readLog(t *testing.T, runner *provider.Runner) string {
+	t.Helper()
+
+	data, err := os.ReadFile(runner.Metadata["log"])
+	if err != nil {
+		t.Fatalf("failed to read runner log: %v", err)
+	}
+	return string(data)
+}
+
+// waitForListening polls until run.sh has set up its trap and started
+func waitForListening(t *testing.T, runner *provider.Runner) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(readLog(t, runner), "listening") {
+		if time.Now().After(deadline) {
+			t.Fatalf("run.sh did not start:\n%s", readLog(t, runner))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestCreateRunnerConfiguresAndRuns(t *testing.T) {
+	p := newTestProvider(t, providertest.NewRunnerDir(t, providertest.RunScript("")))
+	ctx := context.Background()
+
+	runner, err := p.CreateRunner(ctx, providertest.Request())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	defer p.RemoveRunner(ctx, runner.ID, false)
+
+	listed := waitForStatus(t, p, runner.ID, provider.StatusRunning)
+	if listed.Name != "zeno-runner-1" || listed.Metadata[provider.MetadataJobID] != "42" {
+		t.Errorf("listed runner = %+v", listed)
+	}
+
+	args, err := os.ReadFile(filepath.Join(listed.Metadata["dir"], ".runner"))
+	if err != nil {
+		t.Fatalf("config.sh did not run: %v", err)
+	}
+	for _, want := range []string{"--url https://github.com/acme", "token=reg-token", "--name zeno-runner-1", "--labels self-hosted,linux", "--ephemeral"} {
+		if !strings.Contains(string(args), want) {
+			t.Errorf("config.sh args %q missing %q", args, want)
+		}
+	}
+	if strings.Contains(string(args), "--token") {
+		t.Errorf("config.sh args %q carry the token on the command line", args)
+	}
+
+	waitForListening(t, listed)
+	if log := readLog(t, listed); !strings.Contains(log, "configured") {
+		t.Errorf("runner log does not hold config.sh output:\n%s", log)
+	}
+}
+
+func TestRemoveRunnerInterruptsGracefully(t *testing.T) {
+	p := newTestProvider(t, providertest.NewRunnerDir(t, providertest.RunScript(`trap 'echo "finishing job"; exit 0' INT`)))
+	ctx := context.Background()
+
+	runner, err := p.CreateRunner(ctx, providertest.Request())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	listed := waitForStatus(t, p, runner.ID, provider.StatusRunning)
+	waitForListening(t, listed)
+
+	start := time.Now()
+	if err := p.RemoveRunner(ctx, runner.ID, true); err != nil {
+		t.Fatalf("RemoveRunner() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 500*time.Millisecond {
+		t.Errorf("graceful removal took %v, want the runner to stop on SIGINT", elapsed)
+	}
+	if _, err := os.Stat(listed.Metadata["dir"]); !os.IsNotExist(err) {
+		t.Errorf("runner directory left behind: %v", err)
+	}
+	if runners, _ := p.ListRunners(ctx); len(runners) != 0 {
+		t.Errorf("%d runners left after removal", len(runners))
+	}
+}
+
+func TestRemoveRunnerKillsAfterStopTimeout(t *testing.T) {
+	// The runner ignores SIGINT, so only SIGKILL stops it
+	p := newTestProvider(t, providertest.NewRunnerDir(t, providertest.RunScript(`trap '' INT`)))
+	ctx := context.Background()
+
+	runner, err := p.CreateRunner(ctx, providertest.Request())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	waitForListening(t, waitForStatus(t, p, runner.ID, provider.StatusRunning))
+
+	start := time.Now()
+	if err := p.RemoveRunner(ctx, runner.ID, true); err != nil {
+		t.Fatalf("RemoveRunner() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("removal took %v, want it to wait for the stop timeout before killing", elapsed)
+	}
+	if runners, _ := p.ListRunners(ctx); len(runners) != 0 {
+		t.Errorf("%d runners left after removal", len(runners))
+	}
+}
+
+func TestExitCodeMapsToStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		run  string
+		want provider.RunnerStatus
+	}{
+		{"clean exit", "#!/bin/sh\nexit 0\n", provider.StatusTerminated},
+		{"crash", "#!/bin/sh\nexit 3\n", provider.StatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProvider(t, providertest.NewRunnerDir(t, tt.run))
+
+			runner, err := p.CreateRunner(context.Background(), providertest.Request())
+			if err != nil {
+				t.Fatalf("CreateRunner() error = %v", err)
+			}
+			waitForStatus(t, p, runner.ID, tt.want)
+		})
+	}
+}
+
+func TestCreateRunnerFailsWhenConfigFails(t *testing.T) {
+	dir := providertest.NewRunnerDir(t, providertest.RunScript(""))
+	if err := os.WriteFile(filepath.Join(dir, "config.sh"), []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	p := newTestProvider(t, dir)
+
+	if _, err := p.CreateRunner(context.Background(), providertest.Request()); err == nil {
+		t.Fatal("CreateRunner() succeeded with a failing config.sh")
+	}
+	entries, _ := os.ReadDir(p.config.WorkRoot)
+	if len(entries) != 0 {
+		t.Errorf("work root holds %d entries after a failed create, want none", len(entries))
+	}
+}
+
+func TestListRunnersFindsRunnersFromEarlierProcess(t *testing.T) {
+	runnerDir := providertest.NewRunnerDir(t, providertest.RunScript(""))
+	p := newTestProvider(t, runnerDir)
+	ctx := context.Background()
+
+	runner, err := p.CreateRunner(ctx, providertest.Request())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	waitForStatus(t, p, runner.ID, provider.StatusRunning)
+
+	// A new provider over the same work root stands in for a restart
+	restarted, err := New(p.config, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	adopted := waitForStatus(t, restarted, runner.ID, provider.StatusRunning)
+	if adopted.Name != "zeno-runner-1" {
+		t.Errorf("adopted runner = %+v", adopted)
+	}
+
+	if err := restarted.RemoveRunner(ctx, runner.ID, false); err != nil {
+		t.Fatalf("RemoveRunner() error = %v", err)
+	}
+	// The original provider still reaps its child
+	<-p.processes[runner.ID].done
+}
+
+func TestListRunnersIgnoresReusedPID(t *testing.T) {
+	p := newTestProvider(t, providertest.NewRunnerDir(t, providertest.RunScript("")))
+
+	// The test process stands in for an unrelated process that was given
+	// the PID of a runner from an earlier Zeno process
+	dir := filepath.Join(p.config.WorkRoot, "zeno-runner-old")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	state := runnerState{ID: "old", Name: "zeno-runner-old", PID: os.Getpid(), StartTime: "1"}
+	if err := writeState(dir, state); err != nil {
+		t.Fatal(err)
+	}
+
+	runner, err := p.GetRunner(context.Background(), "old")
+	if err != nil {
+		t.Fatalf("GetRunner() error = %v", err)
+	}
+	if runner.Status != provider.StatusTerminated {
+		t.Errorf("status = %s, want %s for a reused PID", runner.Status, provider.StatusTerminated)
+	}
+}
@@ -0,0 +1,31 @@
+//go:build linux
+
+package process
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// processStartTime returns when pid started, in clock ticks since boot, as
+// the kernel reports it in /proc/<pid>/stat
+func processStartTime(pid int) (string, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return "", err
+	}
+
+	// The command name is in parentheses and may contain spaces, so fields
+	// are counted from its closing parenthesis, which is followed by field 3
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return "", fmt.Errorf("malformed stat for pid %d", pid)
+	}
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 20 {
+		return "", fmt.Errorf("malformed stat for pid %d", pid)
+	}
+	return fields[19], nil
+}
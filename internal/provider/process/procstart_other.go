@@ -0,0 +1,11 @@
+//go:build !linux
+
+package process
+
+import "errors"
+
+// processStartTime is only available on Linux; elsewhere runners left by an
+// earlier process are recognised by their PID alone
+func processStartTime(pid int) (string, error) {
+	return "", errors.ErrUnsupported
+}
@@ -0,0 +1,32 @@
+//go:build !unix
+
+package process
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+var (
+	interruptSignal = os.Interrupt
+	killSignal      = os.Kill
+)
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// signalGroup signals only pid; process groups are a Unix feature
+func signalGroup(pid int, sig os.Signal) error {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	if sig == os.Interrupt {
+		return errors.New("interrupting a runner is not supported on this platform")
+	}
+	return proc.Signal(sig)
+}
+
+func processAlive(pid int) bool {
+	return false
+}
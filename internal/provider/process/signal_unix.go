@@ -0,0 +1,36 @@
+//go:build unix
+
+package process
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+var (
+	interruptSignal = syscall.SIGINT
+	killSignal      = syscall.SIGKILL
+)
+
+// setProcessGroup starts cmd in its own process group
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// signalGroup signals every process in the group led by pid
+func signalGroup(pid int, sig syscall.Signal) error {
+	if pid <= 0 {
+		return errors.New("invalid pid")
+	}
+	return syscall.Kill(-pid, sig)
+}
+
+// processAlive reports whether pid is a running process
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -18,6 +18,28 @@ type Runner struct {
 	Metadata    map[string]string
 }
 
+// MetadataJobID is the metadata key holding the ID of the workflow job an
+// ephemeral runner was created for
+const MetadataJobID = "job_id"
+
+// MetadataRepository is the metadata key holding the repository a runner
+// was registered to when runners are shared out between repositories
+const MetadataRepository = "repository"
+
+// MetadataInterruption is the metadata key holding why a draining runner's
+// machine is about to be reclaimed, e.g. "spot-interruption"
+const MetadataInterruption = "interruption"
+
+// MetadataLifecycle is the metadata key holding whether a runner runs on
+// spot or on-demand capacity, for providers that offer both
+const MetadataLifecycle = "lifecycle"
+
+// Values of MetadataLifecycle
+const (
+	LifecycleSpot     = "spot"
+	LifecycleOnDemand = "on-demand"
+)
+
 // RunnerStatus represents the state of a runner
 type RunnerStatus string
 
@@ -27,6 +49,7 @@ const (
 	StatusRunning      RunnerStatus = "running"
 	StatusIdle         RunnerStatus = "idle"
 	StatusBusy         RunnerStatus = "busy"
+	StatusDraining     RunnerStatus = "draining" // About to be reclaimed, no longer capacity
 	StatusTerminating  RunnerStatus = "terminating"
 	StatusTerminated   RunnerStatus = "terminated"
 	StatusFailed       RunnerStatus = "failed"
@@ -40,6 +63,7 @@ type CreateRunnerRequest struct {
 	GitHubOrg      string
 	GitHubRepo     string
 	RunnerVersion  string
+	Ephemeral      bool
 	Metadata       map[string]string
 }
 
@@ -48,7 +72,11 @@ type Provider interface {
 	// Name returns the provider name
 	Name() string
 
-	// ListRunners returns all runners managed by this provider
+	// ListRunners returns all runners managed by this provider. Scale-down
+	// removes idle runners in list order, only moving spot runners ahead of
+	// on-demand runners with the same Provider field, so a provider that
+	// pools others lists each pooled provider's runners together, the ones
+	// to give up first at the front.
 	ListRunners(ctx context.Context) ([]*Runner, error)
 
 	// GetRunner returns a specific runner by ID
@@ -66,6 +94,3 @@ type Provider interface {
 	// Close releases any resources held by the provider
 	Close() error
 }
-
-// ProviderFactory creates a provider instance based on configuration
-type ProviderFactory func(config interface{}) (Provider, error)
@@ -0,0 +1,63 @@
+// Package providertest provides fixtures for testing providers that start
+// runners from a copy of a runner directory
+package providertest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"Zeno/internal/provider"
+)
+
+// configScript stands in for config.sh and records its arguments and the
+// registration token it was given in .runner
+const configScript = `#!/bin/sh
+echo "configured $*"
+echo "$* token=$ACTIONS_RUNNER_INPUT_TOKEN" > .runner
+`
+
+// runScript stands in for run.sh; %s is replaced by the trap that decides
+// how it reacts to SIGINT
+const runScript = `#!/bin/sh
+%s
+echo "listening"
+while true; do sleep 0.05; done
+`
+
+// RunScript returns a run.sh that prints "listening" and runs until it is
+// signalled. trap is a shell trap statement that sets how it reacts to
+// SIGINT; an empty trap leaves the default.
+func RunScript(trap string) string {
+	return strings.Replace(runScript, "%s", trap, 1)
+}
+
+// NewRunnerDir creates a runner directory with a stub config.sh, the given
+// run.sh and an empty bin subdirectory
+func NewRunnerDir(t *testing.T, run string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{"config.sh": configScript, "run.sh": run} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// Request returns an ephemeral runner request for an organization
+func Request() *provider.CreateRunnerRequest {
+	return &provider.CreateRunnerRequest{
+		Name:        "zeno-runner-1",
+		Labels:      []string{"self-hosted", "linux"},
+		GitHubOrg:   "acme",
+		GitHubToken: "reg-token",
+		Ephemeral:   true,
+		Metadata:    map[string]string{provider.MetadataJobID: "42"},
+	}
+}
@@ -0,0 +1,24 @@
+package provider
+
+import (
+	"reflect"
+
+	"Zeno/internal/config"
+)
+
+// Reconfigurable is implemented by providers that can change runner image
+// or instance settings without being recreated. Reconfigure applies cfg to
+// runners created from now on and returns the settings that cfg changes but
+// that only take effect after a restart, such as "provider.aws.region".
+type Reconfigurable interface {
+	Reconfigure(cfg config.ProviderConfig) ([]string, error)
+}
+
+// KeepFixed puts back a setting that only takes effect after a restart. If
+// next changed it, name is added to restart.
+func KeepFixed[T any](restart *[]string, name string, next *T, current T) {
+	if !reflect.DeepEqual(*next, current) {
+		*restart = append(*restart, name)
+		*next = current
+	}
+}
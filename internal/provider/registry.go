@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"Zeno/internal/config"
+)
+
+// Validate accepts exactly the registered provider types
+func init() {
+	config.SetProviderTypes(Registered)
+}
+
+// ProviderFactory creates a provider instance from the provider section of
+// the configuration
+type ProviderFactory func(cfg config.ProviderConfig, logger *slog.Logger) (Provider, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]ProviderFactory)
+)
+
+// Register makes a provider available under name, the value of
+// provider.type that selects it. Providers register themselves from init,
+// so a binary offers the providers it imports. Register panics if name is
+// registered twice.
+func Register(name string, factory ProviderFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if factory == nil {
+		panic("provider: Register factory is nil for " + name)
+	}
+	if _, dup := factories[name]; dup {
+		panic("provider: Register called twice for " + name)
+	}
+	factories[name] = factory
+}
+
+// New creates the provider registered under name
+func New(name string, cfg config.ProviderConfig, logger *slog.Logger) (Provider, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[name]
+	factoriesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown provider type: %s", name)
+	}
+	return factory(cfg, logger)
+}
+
+// Registered returns the names of the registered providers in sorted order
+func Registered() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
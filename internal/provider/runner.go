@@ -0,0 +1,33 @@
+package provider
+
+import "strings"
+
+// TokenEnv is the environment variable config.sh reads the registration
+// token from. Passing it there rather than as --token keeps it out of the
+// process list, where every user on the host can read it.
+const TokenEnv = "ACTIONS_RUNNER_INPUT_TOKEN"
+
+// ConfigArgs returns the arguments that register req's runner with the
+// runner's config.sh, for providers that run it from a runner directory.
+// The token is left out; config.sh is given it in TokenEnv.
+func ConfigArgs(req *CreateRunnerRequest) []string {
+	url := "https://github.com/" + req.GitHubOrg
+	if req.GitHubOrg == "" {
+		url = "https://github.com/" + req.GitHubRepo
+	}
+
+	args := []string{
+		"--unattended",
+		"--replace",
+		"--url", url,
+		"--name", req.Name,
+		"--work", "_work",
+	}
+	if len(req.Labels) > 0 {
+		args = append(args, "--labels", strings.Join(req.Labels, ","))
+	}
+	if req.Ephemeral {
+		args = append(args, "--ephemeral")
+	}
+	return args
+}
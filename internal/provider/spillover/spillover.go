@@ -0,0 +1,355 @@
+package spillover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"Zeno/internal/config"
+	"Zeno/internal/provider"
+	"Zeno/internal/provider/breaker"
+)
+
+func init() {
+	provider.Register("spillover", func(cfg config.ProviderConfig, logger *slog.Logger) (provider.Provider, error) {
+		return New(cfg, logger)
+	})
+}
+
+// member is one provider of the pool
+type member struct {
+	name       string
+	prov       provider.Provider
+	maxRunners int
+	cost       float64
+}
+
+// SpilloverProvider fills its providers in priority order. A runner is
+// created on the first provider that is below its maximum and whose create
+// circuit is not open. Runners are listed most expensive provider first,
+// grouped by provider and with Provider set to the member's name, so
+// scale-down, which removes runners in list order within that grouping,
+// empties the expensive providers before the cheap ones.
+type SpilloverProvider struct {
+	members []*member
+	// removal lists the members in the order their runners are listed
+	removal []*member
+	// config is the provider.spillover section the members were created from
+	config config.SpilloverConfig
+	logger *slog.Logger
+
+	mu sync.Mutex
+	// owners maps runner IDs to the member that created them
+	owners map[string]*member
+	// terminated holds the runners the last ListRunners saw terminated,
+	// which no longer take up room
+	terminated map[string]bool
+}
+
+// New creates every provider listed in provider.spillover from its own
+// section of cfg
+func New(cfg config.ProviderConfig, logger *slog.Logger) (*SpilloverProvider, error) {
+	var members []*member
+	for _, m := range cfg.Spillover.Providers {
+		prov, err := provider.New(m.Type, cfg, logger)
+		if err != nil {
+			for _, created := range members {
+				created.prov.Close()
+			}
+			return nil, fmt.Errorf("failed to create %s provider: %w", m.Type, err)
+		}
+		members = append(members, &member{name: m.Type, prov: prov, maxRunners: m.MaxRunners, cost: m.Cost})
+	}
+
+	p := newProvider(members, logger)
+	p.config = cfg.Spillover
+	return p, nil
+}
+
+func newProvider(members []*member, logger *slog.Logger) *SpilloverProvider {
+	// Most expensive first; among equally priced providers, the one filled
+	// last is emptied first
+	removal := make([]*member, len(members))
+	for i, m := range members {
+		removal[len(members)-1-i] = m
+	}
+	sort.SliceStable(removal, func(i, j int) bool { return removal[i].cost > removal[j].cost })
+
+	return &SpilloverProvider{
+		members:    members,
+		removal:    removal,
+		logger:     logger.With("component", "spillover"),
+		owners:     make(map[string]*member),
+		terminated: make(map[string]bool),
+	}
+}
+
+// WrapMembers replaces every provider with wrap(provider), so that each gets
+// its own circuit breaker and an open circuit on one spills over to the next
+func (p *SpilloverProvider) WrapMembers(wrap func(provider.Provider) provider.Provider) {
+	for _, m := range p.members {
+		m.prov = wrap(m.prov)
+	}
+}
+
+func (p *SpilloverProvider) Name() string {
+	return "spillover"
+}
+
+// ListRunners returns the runners of every provider, most expensive provider
+// first. It fails if any provider cannot be listed, since a partial list
+// would look like missing capacity.
+func (p *SpilloverProvider) ListRunners(ctx context.Context) ([]*provider.Runner, error) {
+	var all []*provider.Runner
+	owners := make(map[string]*member)
+	terminated := make(map[string]bool)
+
+	for _, m := range p.removal {
+		runners, err := m.prov.ListRunners(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s runners: %w", m.name, err)
+		}
+		for _, r := range runners {
+			r.Provider = m.name
+			owners[r.ID] = m
+			if r.Status == provider.StatusTerminated {
+				terminated[r.ID] = true
+			}
+		}
+		all = append(all, runners...)
+	}
+
+	p.mu.Lock()
+	p.owners = owners
+	p.terminated = terminated
+	p.mu.Unlock()
+
+	return all, nil
+}
+
+func (p *SpilloverProvider) GetRunner(ctx context.Context, id string) (*provider.Runner, error) {
+	if m := p.owner(id); m != nil {
+		runner, err := m.prov.GetRunner(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		runner.Provider = m.name
+		return runner, nil
+	}
+
+	// Not seen yet, e.g. created before a restart and not listed since
+	for _, m := range p.members {
+		runner, err := m.prov.GetRunner(ctx, id)
+		if err != nil {
+			continue
+		}
+		runner.Provider = m.name
+		p.setOwner(id, m)
+		return runner, nil
+	}
+	return nil, fmt.Errorf("runner not found: %s", id)
+}
+
+// CreateRunner creates the runner on the first provider with room, counted
+// from the most recent ListRunners and the creates and removals since. A
+// provider that fails the create is skipped like a full one. It returns
+// breaker.ErrOpen if every provider's create circuit is open.
+func (p *SpilloverProvider) CreateRunner(ctx context.Context, req *provider.CreateRunnerRequest) (*provider.Runner, error) {
+	open := 0
+	var errs []error
+	for _, m := range p.members {
+		if createCircuitOpen(m.prov) {
+			p.logger.Debug("provider circuit open, spilling over", "provider", m.name)
+			open++
+			continue
+		}
+
+		if active := p.activeRunners(m); active >= m.maxRunners {
+			p.logger.Debug("provider at maximum, spilling over", "provider", m.name, "max_runners", m.maxRunners)
+			continue
+		}
+
+		runner, err := m.prov.CreateRunner(ctx, req)
+		if errors.Is(err, breaker.ErrOpen) {
+			open++
+			continue
+		}
+		if err != nil {
+			p.logger.Warn("failed to create runner, spilling over", "provider", m.name, "error", err)
+			errs = append(errs, fmt.Errorf("failed to create runner on %s: %w", m.name, err))
+			continue
+		}
+
+		runner.Provider = m.name
+		p.setOwner(runner.ID, m)
+		return runner, nil
+	}
+
+	if open == len(p.members) {
+		return nil, fmt.Errorf("%w: create on every provider", breaker.ErrOpen)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return nil, fmt.Errorf("no provider has room for another runner")
+}
+
+func (p *SpilloverProvider) RemoveRunner(ctx context.Context, id string, graceful bool) error {
+	m := p.owner(id)
+	if m == nil {
+		// GetRunner finds and records the owner
+		if _, err := p.GetRunner(ctx, id); err != nil {
+			return err
+		}
+		m = p.owner(id)
+	}
+
+	if err := m.prov.RemoveRunner(ctx, id, graceful); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	delete(p.owners, id)
+	delete(p.terminated, id)
+	p.mu.Unlock()
+
+	return nil
+}
+
+// HealthCheck fails only when every provider fails; runners can still be
+// created while one provider is down
+func (p *SpilloverProvider) HealthCheck(ctx context.Context) error {
+	var errs []error
+	for _, m := range p.members {
+		if err := m.prov.HealthCheck(ctx); err != nil {
+			p.logger.Warn("provider health check failed", "provider", m.name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", m.name, err))
+		}
+	}
+	if len(errs) == len(p.members) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+func (p *SpilloverProvider) Close() error {
+	var errs []error
+	for _, m := range p.members {
+		if err := m.prov.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", m.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Reconfigure hands cfg to every provider that supports reconfiguration and
+// collects the settings they need a restart for. The providers themselves
+// are created once, so provider.spillover never changes at runtime.
+func (p *SpilloverProvider) Reconfigure(cfg config.ProviderConfig) ([]string, error) {
+	var restart []string
+	provider.KeepFixed(&restart, "provider.spillover", &cfg.Spillover, p.config)
+
+	for _, m := range p.members {
+		prov := m.prov
+		for prov != nil {
+			if rc, ok := prov.(provider.Reconfigurable); ok {
+				memberRestart, err := rc.Reconfigure(cfg)
+				if err != nil {
+					return nil, fmt.Errorf("failed to reconfigure %s: %w", m.name, err)
+				}
+				restart = append(restart, memberRestart...)
+				break
+			}
+			wrapper, ok := prov.(interface{ Unwrap() provider.Provider })
+			if !ok {
+				break
+			}
+			prov = wrapper.Unwrap()
+		}
+	}
+	return restart, nil
+}
+
+// CircuitStates combines the circuits of the providers. Create, remove and
+// health take the best state, since another provider can stand in. List and
+// get take the worst, since every provider is needed to answer them.
+func (p *SpilloverProvider) CircuitStates() map[string]breaker.State {
+	states := make(map[string]breaker.State)
+	for i, m := range p.members {
+		// A provider without a breaker is always closed
+		var memberStates map[string]breaker.State
+		if reporter, ok := m.prov.(breaker.Reporter); ok {
+			memberStates = reporter.CircuitStates()
+		}
+
+		for _, op := range []string{breaker.OpList, breaker.OpGet, breaker.OpCreate, breaker.OpRemove, breaker.OpHealth} {
+			state, ok := memberStates[op]
+			if !ok {
+				state = breaker.StateClosed
+			}
+
+			switch {
+			case i == 0:
+				states[op] = state
+			case op == breaker.OpList || op == breaker.OpGet:
+				if severity(state) > severity(states[op]) {
+					states[op] = state
+				}
+			default:
+				if severity(state) < severity(states[op]) {
+					states[op] = state
+				}
+			}
+		}
+	}
+	return states
+}
+
+func (p *SpilloverProvider) owner(id string) *member {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.owners[id]
+}
+
+func (p *SpilloverProvider) setOwner(id string, m *member) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.owners[id] = m
+}
+
+func createCircuitOpen(prov provider.Provider) bool {
+	reporter, ok := prov.(breaker.Reporter)
+	if !ok {
+		return false
+	}
+	return reporter.CircuitStates()[breaker.OpCreate] == breaker.StateOpen
+}
+
+// activeRunners counts the runners that take up room on m
+func (p *SpilloverProvider) activeRunners(m *member) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	active := 0
+	for id, owner := range p.owners {
+		if owner == m && !p.terminated[id] {
+			active++
+		}
+	}
+	return active
+}
+
+func severity(state breaker.State) int {
+	switch state {
+	case breaker.StateHalfOpen:
+		return 1
+	case breaker.StateOpen:
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,279 @@
+package spillover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"Zeno/internal/config"
+	"Zeno/internal/provider"
+	"Zeno/internal/provider/breaker"
+)
+
+// fakeProvider keeps runners in memory and fails creates while createErr
+// is set
+type fakeProvider struct {
+	name      string
+	createErr error
+
+	mu      sync.Mutex
+	lists   int
+	nextID  int
+	runners []*provider.Runner
+}
+
+func (f *fakeProvider) Name() string { return f.name }
+
+func (f *fakeProvider) ListRunners(ctx context.Context) ([]*provider.Runner, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lists++
+	runners := make([]*provider.Runner, 0, len(f.runners))
+	for _, r := range f.runners {
+		copied := *r
+		runners = append(runners, &copied)
+	}
+	return runners, nil
+}
+
+func (f *fakeProvider) GetRunner(ctx context.Context, id string) (*provider.Runner, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, r := range f.runners {
+		if r.ID == id {
+			copied := *r
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("runner not found: %s", id)
+}
+
+func (f *fakeProvider) CreateRunner(ctx context.Context, req *provider.CreateRunnerRequest) (*provider.Runner, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.createErr != nil {
+		return nil, f.createErr
+	}
+	f.nextID++
+	r := &provider.Runner{
+		ID:       fmt.Sprintf("%s-%d", f.name, f.nextID),
+		Name:     req.Name,
+		Status:   provider.StatusIdle,
+		Provider: f.name,
+	}
+	f.runners = append(f.runners, r)
+	copied := *r
+	return &copied, nil
+}
+
+func (f *fakeProvider) RemoveRunner(ctx context.Context, id string, graceful bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, r := range f.runners {
+		if r.ID == id {
+			f.runners = append(f.runners[:i], f.runners[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("runner not found: %s", id)
+}
+
+func (f *fakeProvider) HealthCheck(ctx context.Context) error { return nil }
+
+func (f *fakeProvider) Close() error { return nil }
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func createRunners(t *testing.T, p *SpilloverProvider, n int) []string {
+	t.Helper()
+
+	var placed []string
+	for i := 0; i < n; i++ {
+		r, err := p.CreateRunner(context.Background(), &provider.CreateRunnerRequest{Name: fmt.Sprintf("zeno-runner-%d", i)})
+		if err != nil {
+			t.Fatalf("CreateRunner() #%d error = %v", i+1, err)
+		}
+		placed = append(placed, r.Provider)
+	}
+	return placed
+}
+
+func TestCreateRunnerFillsProvidersInOrder(t *testing.T) {
+	docker := &fakeProvider{name: "docker"}
+	ec2 := &fakeProvider{name: "ec2"}
+	p := newProvider([]*member{
+		{name: "docker", prov: docker, maxRunners: 2, cost: 0.01},
+		{name: "ec2", prov: ec2, maxRunners: 2, cost: 0.10},
+	}, testLogger())
+
+	placed := createRunners(t, p, 4)
+	want := []string{"docker", "docker", "ec2", "ec2"}
+	for i := range want {
+		if placed[i] != want[i] {
+			t.Fatalf("runners placed on %v, want %v", placed, want)
+		}
+	}
+
+	if _, err := p.CreateRunner(context.Background(), &provider.CreateRunnerRequest{Name: "zeno-runner-5"}); err == nil {
+		t.Error("CreateRunner() succeeded with every provider at its maximum")
+	}
+
+	// Removing a Docker runner makes room on the primary again
+	if err := p.RemoveRunner(context.Background(), "docker-1", true); err != nil {
+		t.Fatalf("RemoveRunner() error = %v", err)
+	}
+	if placed := createRunners(t, p, 1); placed[0] != "docker" {
+		t.Errorf("runner placed on %s after freeing the primary, want docker", placed[0])
+	}
+}
+
+func TestCreateRunnerSpillsOverOpenCircuit(t *testing.T) {
+	docker := &fakeProvider{name: "docker", createErr: errors.New("daemon unreachable")}
+	ec2 := &fakeProvider{name: "ec2"}
+	wrapped := breaker.Wrap(docker, config.CircuitBreakerConfig{
+		Enabled:          true,
+		FailureThreshold: 1,
+		BaseBackoff:      time.Hour,
+		MaxBackoff:       time.Hour,
+	}, nil, testLogger())
+	p := newProvider([]*member{
+		{name: "docker", prov: wrapped, maxRunners: 5},
+		{name: "ec2", prov: ec2, maxRunners: 5},
+	}, testLogger())
+	ctx := context.Background()
+
+	// The failure opens Docker's circuit and the runner spills over
+	if placed := createRunners(t, p, 3); placed[0] != "ec2" || placed[1] != "ec2" || placed[2] != "ec2" {
+		t.Errorf("runners placed on %v with the primary's circuit open, want ec2", placed)
+	}
+	if state := p.CircuitStates()[breaker.OpCreate]; state != breaker.StateClosed {
+		t.Errorf("combined create circuit = %s, want closed while ec2 can take runners", state)
+	}
+
+	ec2.createErr = errors.New("insufficient capacity")
+	p.members[1].prov = breaker.Wrap(ec2, config.CircuitBreakerConfig{
+		Enabled:          true,
+		FailureThreshold: 1,
+		BaseBackoff:      time.Hour,
+		MaxBackoff:       time.Hour,
+	}, nil, testLogger())
+	p.CreateRunner(ctx, &provider.CreateRunnerRequest{Name: "zeno-runner-3"})
+
+	if _, err := p.CreateRunner(ctx, &provider.CreateRunnerRequest{Name: "zeno-runner-4"}); !errors.Is(err, breaker.ErrOpen) {
+		t.Errorf("CreateRunner() error = %v, want ErrOpen with every circuit open", err)
+	}
+	if state := p.CircuitStates()[breaker.OpCreate]; state != breaker.StateOpen {
+		t.Errorf("combined create circuit = %s, want open", state)
+	}
+}
+
+func TestCreateRunnerSpillsOverCreateError(t *testing.T) {
+	ec2 := &fakeProvider{name: "ec2", createErr: errors.New("InsufficientInstanceCapacity")}
+	gce := &fakeProvider{name: "gce"}
+	p := newProvider([]*member{
+		{name: "ec2", prov: ec2, maxRunners: 5},
+		{name: "gce", prov: gce, maxRunners: 5},
+	}, testLogger())
+
+	if placed := createRunners(t, p, 2); placed[0] != "gce" || placed[1] != "gce" {
+		t.Errorf("runners placed on %v with ec2 out of capacity, want gce", placed)
+	}
+
+	gce.createErr = errors.New("quota exceeded")
+	_, err := p.CreateRunner(context.Background(), &provider.CreateRunnerRequest{Name: "zeno-runner-2"})
+	if err == nil || !strings.Contains(err.Error(), "InsufficientInstanceCapacity") || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("CreateRunner() error = %v, want both providers' errors", err)
+	}
+}
+
+func TestCreateRunnerCountsFromLastList(t *testing.T) {
+	docker := &fakeProvider{name: "docker"}
+	ec2 := &fakeProvider{name: "ec2"}
+	p := newProvider([]*member{
+		{name: "docker", prov: docker, maxRunners: 2},
+		{name: "ec2", prov: ec2, maxRunners: 2},
+	}, testLogger())
+	ctx := context.Background()
+
+	// Runners from before a restart are only known once listed
+	docker.runners = []*provider.Runner{
+		{ID: "docker-old-1", Status: provider.StatusBusy},
+		{ID: "docker-old-2", Status: provider.StatusTerminated},
+	}
+	docker.nextID = 2
+	if _, err := p.ListRunners(ctx); err != nil {
+		t.Fatalf("ListRunners() error = %v", err)
+	}
+
+	placed := createRunners(t, p, 3)
+	want := []string{"docker", "ec2", "ec2"}
+	for i := range want {
+		if placed[i] != want[i] {
+			t.Fatalf("runners placed on %v, want %v", placed, want)
+		}
+	}
+	if docker.lists != 1 || ec2.lists != 1 {
+		t.Errorf("providers listed docker=%d ec2=%d times, want once each by ListRunners", docker.lists, ec2.lists)
+	}
+}
+
+func TestListRunnersMostExpensiveFirst(t *testing.T) {
+	docker := &fakeProvider{name: "docker"}
+	ec2 := &fakeProvider{name: "ec2"}
+	p := newProvider([]*member{
+		{name: "docker", prov: docker, maxRunners: 1, cost: 0.01},
+		{name: "ec2", prov: ec2, maxRunners: 2, cost: 0.10},
+	}, testLogger())
+	createRunners(t, p, 3)
+
+	runners, err := p.ListRunners(context.Background())
+	if err != nil {
+		t.Fatalf("ListRunners() error = %v", err)
+	}
+	var got []string
+	for _, r := range runners {
+		got = append(got, r.Provider)
+	}
+	want := []string{"ec2", "ec2", "docker"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("ListRunners() providers = %v, want %v", got, want)
+	}
+}
+
+func TestRemoveRunnerFindsOwnerAfterRestart(t *testing.T) {
+	docker := &fakeProvider{name: "docker"}
+	ec2 := &fakeProvider{name: "ec2"}
+	members := []*member{
+		{name: "docker", prov: docker, maxRunners: 1},
+		{name: "ec2", prov: ec2, maxRunners: 1},
+	}
+	createRunners(t, newProvider(members, testLogger()), 2)
+
+	// A new instance has not seen the runners yet
+	p := newProvider(members, testLogger())
+	r, err := p.GetRunner(context.Background(), "ec2-1")
+	if err != nil {
+		t.Fatalf("GetRunner() error = %v", err)
+	}
+	if r.Provider != "ec2" {
+		t.Errorf("GetRunner() Provider = %q, want ec2", r.Provider)
+	}
+	if err := p.RemoveRunner(context.Background(), "docker-1", true); err != nil {
+		t.Fatalf("RemoveRunner() error = %v", err)
+	}
+	if runners, _ := docker.ListRunners(context.Background()); len(runners) != 0 {
+		t.Errorf("docker has %d runners after removal, want 0", len(runners))
+	}
+}
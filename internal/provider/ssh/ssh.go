@@ -0,0 +1,524 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"Zeno/internal/config"
+	"Zeno/internal/provider"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	// Files kept in each runner directory on the host
+	stateFile    = ".zeno-runner.json"
+	pidFile      = "runner.pid"
+	exitCodeFile = "exit_code"
+	logFile      = "runner.log"
+
+	runnerDirPrefix = "zeno-runner-"
+)
+
+// aliveFunc is prepended to scripts that check on runner processes. A
+// zombie left for a non-reaping init still answers kill -0, so ps decides.
+const aliveFunc = `alive() { kill -0 "$1" 2>/dev/null && [ "$(ps -o stat= -p "$1" 2>/dev/null | cut -c1)" != Z ]; }
+`
+
+// runnerState is written to stateFile when a runner is created
+type runnerState struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Labels    []string          `json:"labels"`
+	CreatedAt time.Time         `json:"created_at"`
+	Metadata  map[string]string `json:"metadata"`
+}
+
+type host struct {
+	config.SSHHostConfig
+	clientConfig *ssh.ClientConfig
+}
+
+type SSHProvider struct {
+	config config.SSHConfig
+	hosts  []*host
+	logger *slog.Logger
+	mu     sync.Mutex
+
+	// workRoot is fixed at creation, so it is read without holding mu
+	workRoot string
+
+	// occupancy counts runner directories per host as of the last listing,
+	// creation or removal, and reserved the slots taken by runners still
+	// being created. Remote commands run without holding mu.
+	occupancy map[string]int
+	reserved  map[string]int
+}
+
+func init() {
+	provider.Register("ssh", func(cfg config.ProviderConfig, logger *slog.Logger) (provider.Provider, error) {
+		return New(cfg.SSH, logger)
+	})
+}
+
+// New creates a new SSH provider
+func New(cfg config.SSHConfig, logger *slog.Logger) (*SSHProvider, error) {
+	key, err := os.ReadFile(cfg.PrivateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	hostKeyCallback := ssh.InsecureIgnoreHostKey()
+	if !cfg.InsecureIgnoreHostKey {
+		hostKeyCallback, err = knownhosts.New(cfg.KnownHostsPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load known hosts: %w", err)
+		}
+	}
+
+	return newProvider(cfg, ssh.PublicKeys(signer), hostKeyCallback, logger), nil
+}
+
+func newProvider(cfg config.SSHConfig, auth ssh.AuthMethod, hostKeyCallback ssh.HostKeyCallback, logger *slog.Logger) *SSHProvider {
+	p := &SSHProvider{
+		config:    cfg,
+		logger:    logger.With("provider", "ssh"),
+		workRoot:  cfg.WorkRoot,
+		occupancy: make(map[string]int),
+		reserved:  make(map[string]int),
+	}
+
+	for _, hc := range cfg.Hosts {
+		user := hc.User
+		if user == "" {
+			user = cfg.User
+		}
+		p.hosts = append(p.hosts, &host{
+			SSHHostConfig: hc,
+			clientConfig: &ssh.ClientConfig{
+				User:            user,
+				Auth:            []ssh.AuthMethod{auth},
+				HostKeyCallback: hostKeyCallback,
+				Timeout:         cfg.ConnectTimeout,
+			},
+		})
+	}
+
+	return p
+}
+
+// Reconfigure switches the runner directory and stop timeout for runners
+// created from now on. Hosts and credentials are fixed.
+func (p *SSHProvider) Reconfigure(cfg config.ProviderConfig) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var restart []string
+	next := cfg.SSH
+	provider.KeepFixed(&restart, "provider.ssh.user", &next.User, p.config.User)
+	provider.KeepFixed(&restart, "provider.ssh.private_key_path", &next.PrivateKeyPath, p.config.PrivateKeyPath)
+	provider.KeepFixed(&restart, "provider.ssh.known_hosts_path", &next.KnownHostsPath, p.config.KnownHostsPath)
+	provider.KeepFixed(&restart, "provider.ssh.insecure_ignore_host_key", &next.InsecureIgnoreHostKey, p.config.InsecureIgnoreHostKey)
+	provider.KeepFixed(&restart, "provider.ssh.hosts", &next.Hosts, p.config.Hosts)
+	provider.KeepFixed(&restart, "provider.ssh.work_root", &next.WorkRoot, p.config.WorkRoot)
+	provider.KeepFixed(&restart, "provider.ssh.connect_timeout", &next.ConnectTimeout, p.config.ConnectTimeout)
+	provider.KeepFixed(&restart, "provider.ssh.pool", &next.Pool, p.config.Pool)
+	p.config = next
+	return restart, nil
+}
+
+func (p *SSHProvider) Name() string {
+	return "ssh"
+}
+
+// ListRunners queries every host. Hosts that cannot be reached are logged
+// and skipped, so one broken machine does not hide the others' runners; an
+// error is returned only when no host answered.
+func (p *SSHProvider) ListRunners(ctx context.Context) ([]*provider.Runner, error) {
+	var runners []*provider.Runner
+	var lastErr error
+	reached := 0
+
+	for _, h := range p.hosts {
+		hostRunners, err := p.listHost(ctx, h)
+		if err != nil {
+			p.logger.Warn("failed to list runners on host", "host", h.Address, "error", err)
+			lastErr = err
+			continue
+		}
+		reached++
+		p.mu.Lock()
+		p.occupancy[h.Address] = len(hostRunners)
+		p.mu.Unlock()
+		runners = append(runners, hostRunners...)
+	}
+
+	if reached == 0 && lastErr != nil {
+		return nil, fmt.Errorf("failed to list runners on any host: %w", lastErr)
+	}
+	return runners, nil
+}
+
+func (p *SSHProvider) listHost(ctx context.Context, h *host) ([]*provider.Runner, error) {
+	script := aliveFunc + fmt.Sprintf(`cd %s 2>/dev/null || exit 0
+for d in %s*; do
+  [ -f "$d/%s" ] || continue
+  pid=$(cat "$d/%s" 2>/dev/null)
+  if [ -n "$pid" ] && alive "$pid"; then
+    status=running
+  elif [ -f "$d/%s" ]; then
+    status="exited:$(cat "$d/%s")"
+  else
+    status=exited
+  fi
+  printf '%%s %%s %%s\n' "$d" "$status" "$(cat "$d/%s")"
+done
+`, shellQuote(p.workRoot), runnerDirPrefix, stateFile, pidFile, exitCodeFile, exitCodeFile, stateFile)
+
+	out, err := p.run(ctx, h, script)
+	if err != nil {
+		return nil, err
+	}
+
+	var runners []*provider.Runner
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), " ", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		var state runnerState
+		if err := json.Unmarshal([]byte(fields[2]), &state); err != nil {
+			p.logger.Warn("ignoring unreadable runner state", "host", h.Address, "dir", fields[0], "error", err)
+			continue
+		}
+		runners = append(runners, p.toRunner(h, fields[0], state, mapProcessStatus(fields[1])))
+	}
+
+	return runners, nil
+}
+
+func (p *SSHProvider) GetRunner(ctx context.Context, id string) (*provider.Runner, error) {
+	runners, err := p.ListRunners(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range runners {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+
+	return nil, fmt.Errorf("runner %s not found", id)
+}
+
+// CreateRunner places the runner on the least-loaded host with a free slot,
+// copies the runner directory there, registers it with config.sh and starts
+// run.sh in its own session so it outlives the SSH connection. The slot is
+// reserved before the remote commands run, so the provider lock is not held
+// while config.sh registers the runner.
+func (p *SSHProvider) CreateRunner(ctx context.Context, req *provider.CreateRunnerRequest) (*provider.Runner, error) {
+	// Refresh occupancy; runners may have been removed behind our back
+	if _, err := p.ListRunners(ctx); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	h := p.placeRunner()
+	if h == nil {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("no free runner slots on any host")
+	}
+	p.reserved[h.Address]++
+	cfg := p.config
+	p.mu.Unlock()
+
+	created := false
+	defer func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		p.reserved[h.Address]--
+		if created {
+			p.occupancy[h.Address]++
+		}
+	}()
+
+	runnerID := uuid.New().String()
+	dir := runnerDirPrefix + runnerID[:8]
+
+	p.logger.Info("creating runner", "id", runnerID, "name", req.Name, "host", h.Address)
+
+	metadata := map[string]string{"pool": cfg.Pool}
+	for k, v := range req.Metadata {
+		metadata[k] = v
+	}
+	state := runnerState{
+		ID:        runnerID,
+		Name:      req.Name,
+		Labels:    req.Labels,
+		CreatedAt: time.Now(),
+		Metadata:  metadata,
+	}
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode runner state: %w", err)
+	}
+
+	// run.sh is detached with setsid -f rather than a background job, since
+	// sh starts background jobs with SIGINT ignored and the runner could then
+	// never be interrupted gracefully. Only util-linux's setsid has -f, so
+	// hosts without it are refused before anything is copied. The token
+	// arrives on stdin and reaches config.sh in its environment, so it is in
+	// no command line that ps shows.
+	script := fmt.Sprintf(`set -e
+IFS= read -r token
+if ! setsid -f true > /dev/null 2>&1; then
+  echo "setsid -f is not available; runner hosts need util-linux" >&2
+  exit 1
+fi
+mkdir -p %[1]s
+cd %[1]s
+cp -R %[2]s %[3]s
+cd %[3]s
+printf '%%s' %[4]s > %[5]s
+if ! %[10]s="$token" ./config.sh %[6]s < /dev/null > %[7]s 2>&1; then
+  cd .. && rm -rf %[3]s
+  echo "config.sh failed" >&2
+  exit 1
+fi
+setsid -f sh -c 'echo $$ > %[9]s; ./run.sh >> %[7]s 2>&1; echo $? > %[8]s' < /dev/null > /dev/null 2>&1
+while [ ! -s %[9]s ]; do sleep 0.01; done
+`, shellQuote(p.workRoot), shellQuote(cfg.RunnerDir), dir, shellQuote(string(stateJSON)),
+		stateFile, shellJoin(provider.ConfigArgs(req)), logFile, exitCodeFile, pidFile, provider.TokenEnv)
+
+	if _, err := p.runInput(ctx, h, script, req.GitHubToken+"\n"); err != nil {
+		return nil, fmt.Errorf("failed to start runner on %s: %w", h.Address, err)
+	}
+	created = true
+
+	p.logger.Info("runner created successfully",
+		"id", runnerID,
+		"host", h.Address,
+		"name", req.Name,
+	)
+
+	return p.toRunner(h, dir, state, provider.StatusProvisioning), nil
+}
+
+// RemoveRunner stops the runner and deletes its directory. A graceful stop
+// sends SIGINT, which lets the runner finish its job, and falls back to
+// SIGKILL after the stop timeout. The provider lock is not held while
+// waiting for the runner to stop.
+func (p *SSHProvider) RemoveRunner(ctx context.Context, id string, graceful bool) error {
+	runners, err := p.ListRunners(ctx)
+	if err != nil {
+		return err
+	}
+	var runner *provider.Runner
+	for _, r := range runners {
+		if r.ID == id {
+			runner = r
+		}
+	}
+	if runner == nil {
+		return fmt.Errorf("runner %s not found", id)
+	}
+	h := p.host(runner.Metadata["host"])
+
+	p.logger.Info("removing runner",
+		"id", id,
+		"host", h.Address,
+		"graceful", graceful,
+	)
+
+	// Wait in tenths of a second for the runner to stop after SIGINT
+	waits := 0
+	if graceful {
+		p.mu.Lock()
+		stopTimeout := p.config.StopTimeout
+		p.mu.Unlock()
+		waits = int(stopTimeout / (100 * time.Millisecond))
+	}
+
+	script := aliveFunc + fmt.Sprintf(`cd %s || exit 0
+pid=$(cat %s/%s 2>/dev/null)
+if [ -n "$pid" ] && alive "$pid"; then
+  if [ %d -gt 0 ]; then
+    kill -INT "-$pid" 2>/dev/null
+    i=0
+    while alive "$pid" && [ $i -lt %d ]; do sleep 0.1; i=$((i+1)); done
+  fi
+  if alive "$pid"; then
+    kill -KILL "-$pid" 2>/dev/null
+  fi
+fi
+rm -rf %s
+`, shellQuote(p.workRoot), runner.ProviderID, pidFile, waits, waits, runner.ProviderID)
+
+	if _, err := p.run(ctx, h, script); err != nil {
+		return fmt.Errorf("failed to remove runner on %s: %w", h.Address, err)
+	}
+	p.mu.Lock()
+	if p.occupancy[h.Address] > 0 {
+		p.occupancy[h.Address]--
+	}
+	p.mu.Unlock()
+
+	p.logger.Info("runner removed successfully", "id", id)
+	return nil
+}
+
+// HealthCheck fails only when no host can be reached
+func (p *SSHProvider) HealthCheck(ctx context.Context) error {
+	var lastErr error
+	for _, h := range p.hosts {
+		if _, err := p.run(ctx, h, "true"); err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+	return fmt.Errorf("ssh health check failed: %w", lastErr)
+}
+
+func (p *SSHProvider) Close() error {
+	return nil
+}
+
+// placeRunner returns the host with a free slot and the lowest share of its
+// slots in use or reserved, or nil when every host is full. Ties go to the
+// host listed first. The caller holds p.mu.
+func (p *SSHProvider) placeRunner() *host {
+	var best *host
+	var bestLoad float64
+	for _, h := range p.hosts {
+		used, ok := p.occupancy[h.Address]
+		used += p.reserved[h.Address]
+		if !ok || used >= h.Slots {
+			// Hosts that did not answer the last listing are skipped
+			continue
+		}
+		load := float64(used) / float64(h.Slots)
+		if best == nil || load < bestLoad {
+			best, bestLoad = h, load
+		}
+	}
+	return best
+}
+
+func (p *SSHProvider) host(address string) *host {
+	for _, h := range p.hosts {
+		if h.Address == address {
+			return h
+		}
+	}
+	return nil
+}
+
+// run executes script with sh on the host and returns its output
+func (p *SSHProvider) run(ctx context.Context, h *host, script string) ([]byte, error) {
+	return p.runInput(ctx, h, script, "")
+}
+
+// runInput runs script as run does, with input on its stdin
+func (p *SSHProvider) runInput(ctx context.Context, h *host, script, input string) ([]byte, error) {
+	dialer := net.Dialer{Timeout: h.clientConfig.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", h.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, h.Address, h.clientConfig)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session: %w", err)
+	}
+	defer session.Close()
+
+	// Closing the client unblocks the command when ctx ends
+	stop := context.AfterFunc(ctx, func() { client.Close() })
+	defer stop()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdin = strings.NewReader(input)
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	if err := session.Run("sh -c " + shellQuote(script)); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+func (p *SSHProvider) toRunner(h *host, dir string, state runnerState, status provider.RunnerStatus) *provider.Runner {
+	metadata := map[string]string{
+		"host": h.Address,
+		"log":  path.Join(p.workRoot, dir, logFile),
+	}
+	for k, v := range state.Metadata {
+		metadata[k] = v
+	}
+
+	return &provider.Runner{
+		ID:         state.ID,
+		Name:       state.Name,
+		Status:     status,
+		Labels:     state.Labels,
+		Provider:   "ssh",
+		ProviderID: dir,
+		CreatedAt:  state.CreatedAt,
+		Metadata:   metadata,
+	}
+}
+
+// mapProcessStatus maps the state reported by the listing script
+func mapProcessStatus(status string) provider.RunnerStatus {
+	switch status {
+	case "running":
+		return provider.StatusRunning
+	case "exited", "exited:0":
+		return provider.StatusTerminated
+	default:
+		return provider.StatusFailed
+	}
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = shellQuote(a)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,401 @@
+//go:build unix
+
+package ssh
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"Zeno/internal/config"
+	"Zeno/internal/provider"
+	"Zeno/internal/provider/providertest"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testServer is an in-process SSH server that runs exec requests with sh
+// in its own home directory, standing in for one build machine
+type testServer struct {
+	addr     string
+	home     string
+	path     string // searched for commands before PATH, when set
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	commands []string // every command run, as the host's process list shows it
+}
+
+func newTestServer(t *testing.T, clientKey ssh.PublicKey) *testServer {
+	t.Helper()
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(clientKey.Marshal()) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, nil
+		},
+	}
+	cfg.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{addr: listener.Addr().String(), home: t.TempDir(), listener: listener}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.serve(conn, cfg)
+			}()
+		}
+	}()
+	t.Cleanup(func() {
+		listener.Close()
+		s.wg.Wait()
+	})
+
+	return s
+}
+
+func (s *testServer) serve(conn net.Conn, cfg *ssh.ServerConfig) {
+	defer conn.Close()
+
+	_, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go s.session(channel, requests)
+	}
+}
+
+func (s *testServer) session(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+
+	for req := range requests {
+		if req.Type != "exec" {
+			req.Reply(false, nil)
+			continue
+		}
+		req.Reply(true, nil)
+
+		command := string(req.Payload[4:])
+		s.mu.Lock()
+		s.commands = append(s.commands, command)
+		s.mu.Unlock()
+
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Stdin = channel
+		cmd.Dir = s.home
+		if s.path != "" {
+			cmd.Env = append(os.Environ(), "PATH="+s.path+string(os.PathListSeparator)+os.Getenv("PATH"))
+		}
+		cmd.Stdout = channel
+		cmd.Stderr = channel.Stderr()
+
+		status := uint32(0)
+		if err := cmd.Run(); err != nil {
+			status = 1
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				status = uint32(exitErr.ExitCode())
+			}
+		}
+
+		payload := make([]byte, 4)
+		binary.BigEndian.PutUint32(payload, status)
+		channel.SendRequest("exit-status", false, payload)
+		return
+	}
+}
+
+// newTestProvider starts one in-process SSH server per slot count
+func newTestProvider(t *testing.T, runnerDir string, slots ...int) (*SSHProvider, []*testServer) {
+	t.Helper()
+
+	_, clientPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(clientPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.SSHConfig{
+		User:           "runner",
+		RunnerDir:      runnerDir,
+		WorkRoot:       "zeno-runners",
+		ConnectTimeout: 5 * time.Second,
+		StopTimeout:    500 * time.Millisecond,
+		Pool:           "ssh",
+	}
+	var servers []*testServer
+	for _, n := range slots {
+		s := newTestServer(t, signer.PublicKey())
+		servers = append(servers, s)
+		cfg.Hosts = append(cfg.Hosts, config.SSHHostConfig{Address: s.addr, Slots: n})
+	}
+
+	p := newProvider(cfg, ssh.PublicKeys(signer), ssh.InsecureIgnoreHostKey(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.Cleanup(func() {
+		// Stop anything a failed test left running
+		runners, _ := p.ListRunners(context.Background())
+		for _, r := range runners {
+			p.RemoveRunner(context.Background(), r.ID, false)
+		}
+	})
+	return p, servers
+}
+
+// waitForLog polls until the runner's log on its host contains want
+func waitForLog(t *testing.T, server *testServer, runner *provider.Runner, want string) {
+	t.Helper()
+
+	path := filepath.Join(server.home, runner.Metadata["log"])
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, _ := os.ReadFile(path)
+		if strings.Contains(string(data), want) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("runner log %s does not contain %q:\n%s", path, want, data)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestCreateRunnerPrefersLeastLoadedHost(t *testing.T) {
+	p, servers := newTestProvider(t, providertest.NewRunnerDir(t, providertest.RunScript("")), 1, 2)
+	ctx := context.Background()
+
+	perHost := make(map[string]int)
+	for i := 0; i < 3; i++ {
+		runner, err := p.CreateRunner(ctx, providertest.Request())
+		if err != nil {
+			t.Fatalf("CreateRunner() #%d error = %v", i+1, err)
+		}
+		perHost[runner.Metadata["host"]]++
+	}
+
+	if perHost[servers[0].addr] != 1 || perHost[servers[1].addr] != 2 {
+		t.Errorf("runners per host = %v, want every slot used once", perHost)
+	}
+	if _, err := p.CreateRunner(ctx, providertest.Request()); err == nil {
+		t.Error("CreateRunner() succeeded with every slot taken")
+	}
+
+	runners, err := p.ListRunners(ctx)
+	if err != nil {
+		t.Fatalf("ListRunners() error = %v", err)
+	}
+	if len(runners) != 3 {
+		t.Fatalf("ListRunners() returned %d runners, want 3", len(runners))
+	}
+	for _, r := range runners {
+		if r.Status != provider.StatusRunning || r.Name != "zeno-runner-1" || r.Metadata[provider.MetadataJobID] != "42" {
+			t.Errorf("listed runner = %+v", r)
+		}
+	}
+
+	home := servers[0].home
+	if runners[0].Metadata["host"] == servers[1].addr {
+		home = servers[1].home
+	}
+	args, err := os.ReadFile(filepath.Join(home, "zeno-runners", runners[0].ProviderID, ".runner"))
+	if err != nil {
+		t.Fatalf("config.sh did not run: %v", err)
+	}
+	for _, want := range []string{"--url https://github.com/acme", "token=reg-token", "--name zeno-runner-1", "--labels self-hosted,linux"} {
+		if !strings.Contains(string(args), want) {
+			t.Errorf("config.sh args %q missing %q", args, want)
+		}
+	}
+	if strings.Contains(string(args), "--token") {
+		t.Errorf("config.sh args %q carry the token on the command line", args)
+	}
+	for _, server := range servers {
+		server.mu.Lock()
+		for _, command := range server.commands {
+			if strings.Contains(command, "reg-token") {
+				t.Errorf("command line %q carries the registration token", command)
+			}
+		}
+		server.mu.Unlock()
+	}
+}
+
+func TestRemoveRunnerFreesSlot(t *testing.T) {
+	p, servers := newTestProvider(t, providertest.NewRunnerDir(t, providertest.RunScript(`trap 'echo "finishing job"; exit 0' INT`)), 1)
+	ctx := context.Background()
+
+	runner, err := p.CreateRunner(ctx, providertest.Request())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	waitForLog(t, servers[0], runner, "listening")
+
+	start := time.Now()
+	if err := p.RemoveRunner(ctx, runner.ID, true); err != nil {
+		t.Fatalf("RemoveRunner() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 500*time.Millisecond {
+		t.Errorf("graceful removal took %v, want the runner to stop on SIGINT", elapsed)
+	}
+
+	if runners, _ := p.ListRunners(ctx); len(runners) != 0 {
+		t.Errorf("%d runners left after removal", len(runners))
+	}
+	if _, err := p.CreateRunner(ctx, providertest.Request()); err != nil {
+		t.Errorf("CreateRunner() error = %v, want the freed slot reused", err)
+	}
+}
+
+func TestRemoveRunnerKillsAfterStopTimeout(t *testing.T) {
+	p, servers := newTestProvider(t, providertest.NewRunnerDir(t, providertest.RunScript(`trap '' INT`)), 1)
+	ctx := context.Background()
+
+	runner, err := p.CreateRunner(ctx, providertest.Request())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	waitForLog(t, servers[0], runner, "listening")
+
+	start := time.Now()
+	removed := make(chan error, 1)
+	go func() { removed <- p.RemoveRunner(ctx, runner.ID, true) }()
+
+	// Listing does not wait for the removal to finish
+	time.Sleep(100 * time.Millisecond)
+	if _, err := p.ListRunners(ctx); err != nil {
+		t.Fatalf("ListRunners() error = %v", err)
+	}
+	select {
+	case <-removed:
+		t.Fatal("ListRunners() was blocked until the removal finished")
+	default:
+	}
+
+	if err := <-removed; err != nil {
+		t.Fatalf("RemoveRunner() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("removal took %v, want it to wait for the stop timeout before killing", elapsed)
+	}
+	if runners, _ := p.ListRunners(ctx); len(runners) != 0 {
+		t.Errorf("%d runners left after removal", len(runners))
+	}
+}
+
+func TestUnreachableHostIsSkipped(t *testing.T) {
+	p, servers := newTestProvider(t, providertest.NewRunnerDir(t, providertest.RunScript("")), 1, 1)
+	ctx := context.Background()
+
+	servers[1].listener.Close()
+
+	runner, err := p.CreateRunner(ctx, providertest.Request())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+	if runner.Metadata["host"] != servers[0].addr {
+		t.Errorf("runner placed on %s, want the reachable host", runner.Metadata["host"])
+	}
+	if _, err := p.CreateRunner(ctx, providertest.Request()); err == nil {
+		t.Error("CreateRunner() succeeded with only an unreachable host left")
+	}
+
+	runners, err := p.ListRunners(ctx)
+	if err != nil {
+		t.Fatalf("ListRunners() error = %v", err)
+	}
+	if len(runners) != 1 {
+		t.Errorf("ListRunners() returned %d runners, want the reachable host's runner", len(runners))
+	}
+}
+
+func TestExitCodeMapsToStatus(t *testing.T) {
+	p, _ := newTestProvider(t, providertest.NewRunnerDir(t, "#!/bin/sh\nexit 3\n"), 1)
+	ctx := context.Background()
+
+	runner, err := p.CreateRunner(ctx, providertest.Request())
+	if err != nil {
+		t.Fatalf("CreateRunner() error = %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, err := p.GetRunner(ctx, runner.ID)
+		if err != nil {
+			t.Fatalf("GetRunner() error = %v", err)
+		}
+		if got.Status == provider.StatusFailed {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("runner status = %s, want failed", got.Status)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestCreateRunnerRefusesHostWithoutSetsidFork(t *testing.T) {
+	p, servers := newTestProvider(t, providertest.NewRunnerDir(t, providertest.RunScript("")), 1)
+
+	// A setsid without -f, as on hosts without util-linux
+	bin := t.TempDir()
+	script := "#!/bin/sh\necho 'setsid: illegal option -- f' >&2\nexit 1\n"
+	if err := os.WriteFile(filepath.Join(bin, "setsid"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	servers[0].path = bin
+
+	_, err := p.CreateRunner(context.Background(), providertest.Request())
+	if err == nil || !strings.Contains(err.Error(), "util-linux") {
+		t.Fatalf("CreateRunner() error = %v, want the missing setsid reported", err)
+	}
+	if _, err := os.Stat(filepath.Join(servers[0].home, "zeno-runners")); !os.IsNotExist(err) {
+		t.Errorf("work root created on a refused host: %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+// Package reload applies configuration file changes to a running controller
+package reload
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"Zeno/internal/config"
+	"Zeno/internal/metrics"
+	"Zeno/internal/notify"
+	"Zeno/internal/provider"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ConfigUpdater receives configurations that passed validation
+type ConfigUpdater interface {
+	UpdateConfig(cfg *config.Config)
+}
+
+// Reloader re-reads the configuration and hands the settings that are safe
+// to change at runtime to the controller, provider and notifier. Anything
+// else that changed is logged as needing a restart.
+type Reloader struct {
+	path     string
+	current  *config.Config
+	target   ConfigUpdater
+	provider provider.Provider
+	notifier *notify.Notifier
+	metrics  *metrics.Metrics
+	logger   *slog.Logger
+	mu       sync.Mutex
+}
+
+// New creates a reloader for the configuration loaded from path
+func New(
+	path string,
+	cfg *config.Config,
+	target ConfigUpdater,
+	prov provider.Provider,
+	notifier *notify.Notifier,
+	met *metrics.Metrics,
+	logger *slog.Logger,
+) *Reloader {
+	return &Reloader{
+		path:     path,
+		current:  cfg,
+		target:   target,
+		provider: prov,
+		notifier: notifier,
+		metrics:  met,
+		logger:   logger.With("component", "reload"),
+	}
+}
+
+// Run reloads whenever a signal arrives on signals or, when there is a
+// config file, whenever the file changes, until ctx is cancelled
+func (r *Reloader) Run(ctx context.Context, signals <-chan os.Signal) {
+	var changed <-chan struct{}
+	if r.path != "" {
+		var err error
+		changed, err = r.watch(ctx)
+		if err != nil {
+			r.logger.Warn("failed to watch configuration file, reloading on signals only", "error", err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-signals:
+			r.logger.Info("reloading configuration", "signal", sig.String())
+		case <-changed:
+			r.logger.Info("reloading configuration", "reason", "file_changed")
+		}
+
+		// Errors are logged and counted by Reload
+		_ = r.Reload()
+	}
+}
+
+// watch reports changes to the configuration file until ctx is cancelled.
+// It watches the file's directory, since editors and mounted ConfigMaps
+// replace the file, or the symlink to it, rather than writing to it.
+func (r *Reloader) watch(ctx context.Context) (<-chan struct{}, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create watcher: %w", err)
+	}
+
+	path := filepath.Clean(r.path)
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch %s: %w", filepath.Dir(path), err)
+	}
+	target, _ := filepath.EvalSymlinks(path)
+
+	changed := make(chan struct{}, 1)
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				written := filepath.Clean(event.Name) == path && event.Has(fsnotify.Write|fsnotify.Create)
+				current, _ := filepath.EvalSymlinks(path)
+				if !written && (current == "" || current == target) {
+					continue
+				}
+				target = current
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				r.logger.Warn("configuration watcher error", "error", err)
+			}
+		}
+	}()
+
+	return changed, nil
+}
+
+// Reload reads and validates the configuration and applies the reloadable
+// sections. A configuration that fails validation, or that a component
+// refuses, is rejected and nothing is changed.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := config.Load(r.path)
+	if err != nil {
+		return r.reject(err)
+	}
+
+	merged, restart := r.current.Reloadable(next)
+	if err := merged.Validate(); err != nil {
+		return r.reject(fmt.Errorf("config validation failed: %w", err))
+	}
+
+	if err := r.notifier.Reconfigure(merged.Notifications); err != nil {
+		return r.reject(fmt.Errorf("failed to reconfigure notifications: %w", err))
+	}
+	providerRestart, err := r.reconfigureProvider(merged)
+	if err != nil {
+		// Put the notifier back so the rejected file leaves no trace
+		if rollbackErr := r.notifier.Reconfigure(r.current.Notifications); rollbackErr != nil {
+			r.logger.Error("failed to restore notifications", "error", rollbackErr)
+		}
+		return r.reject(fmt.Errorf("failed to reconfigure provider: %w", err))
+	}
+
+	restart = append(restart, providerRestart...)
+
+	r.target.UpdateConfig(merged)
+	r.current = merged
+	r.metrics.ConfigReloads.WithLabelValues("applied").Inc()
+
+	if len(restart) > 0 {
+		r.logger.Warn("configuration changes need a restart to take effect", "sections", restart)
+	}
+	r.logger.Info("configuration reloaded")
+	return nil
+}
+
+func (r *Reloader) reject(err error) error {
+	r.metrics.ConfigReloads.WithLabelValues("rejected").Inc()
+	r.logger.Error("configuration reload rejected", "error", err)
+	return err
+}
+
+// reconfigureProvider passes the provider settings in cfg to the first
+// provider in the wrapper chain that accepts them and returns the settings
+// it needs a restart for. A provider that accepts none keeps its running
+// settings, which are put back into cfg.
+func (r *Reloader) reconfigureProvider(cfg *config.Config) ([]string, error) {
+	prov := r.provider
+	for prov != nil {
+		if rc, ok := prov.(provider.Reconfigurable); ok {
+			return rc.Reconfigure(cfg.Provider)
+		}
+		wrapper, ok := prov.(interface{ Unwrap() provider.Provider })
+		if !ok {
+			break
+		}
+		prov = wrapper.Unwrap()
+	}
+
+	r.logger.Debug("provider does not support reconfiguration")
+	if reflect.DeepEqual(cfg.Provider, r.current.Provider) {
+		return nil, nil
+	}
+	cfg.Provider = r.current.Provider
+	return []string{"provider"}, nil
+}
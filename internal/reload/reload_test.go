@@ -0,0 +1,174 @@
+package reload
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"Zeno/internal/config"
+	"Zeno/internal/metrics"
+	"Zeno/internal/provider"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const baseConfig = `
+github:
+  token: test-token
+  organization: acme
+provider:
+  type: docker
+  docker:
+    image: runner:1
+scaling:
+  min_runners: 1
+  max_runners: %d
+`
+
+// recordingTarget keeps every configuration handed to the controller
+type recordingTarget struct {
+	mu      sync.Mutex
+	configs []*config.Config
+}
+
+func (r *recordingTarget) UpdateConfig(cfg *config.Config) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.configs = append(r.configs, cfg)
+}
+
+func (r *recordingTarget) updates() []*config.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*config.Config(nil), r.configs...)
+}
+
+// reconfigurableProvider records provider settings it is given
+type reconfigurableProvider struct {
+	provider.Provider
+	images []string
+}
+
+func (p *reconfigurableProvider) Reconfigure(cfg config.ProviderConfig) ([]string, error) {
+	p.images = append(p.images, cfg.Docker.Image)
+	return nil, nil
+}
+
+// wrappedProvider hides the provider behind an Unwrap method
+type wrappedProvider struct {
+	provider.Provider
+	next provider.Provider
+}
+
+func (p *wrappedProvider) Unwrap() provider.Provider {
+	return p.next
+}
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+}
+
+func newTestReloader(t *testing.T, content string) (*Reloader, *recordingTarget, *reconfigurableProvider, *metrics.Metrics, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, content)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	target := &recordingTarget{}
+	prov := &reconfigurableProvider{}
+	met := metrics.NewMetrics(prometheus.NewRegistry())
+	r := New(path, cfg, target, &wrappedProvider{next: prov}, nil, met, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	return r, target, prov, met, path
+}
+
+func TestReloadAppliesValidConfig(t *testing.T) {
+	r, target, prov, met, path := newTestReloader(t, fmt.Sprintf(baseConfig, 5))
+
+	writeConfig(t, path, `
+github:
+  token: test-token
+  organization: acme
+provider:
+  type: docker
+  docker:
+    image: runner:2
+scaling:
+  min_runners: 1
+  max_runners: 10
+server:
+  port: 9090
+`)
+
+	if err := r.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	updates := target.updates()
+	if len(updates) != 1 || updates[0].Scaling.MaxRunners != 10 {
+		t.Fatalf("controller updates = %v, want one with max_runners 10", updates)
+	}
+	if updates[0].Server.Port != 8080 {
+		t.Errorf("server port = %d, want the running port until restart", updates[0].Server.Port)
+	}
+	if len(prov.images) != 1 || prov.images[0] != "runner:2" {
+		t.Errorf("provider images = %v, want the wrapped provider reconfigured with runner:2", prov.images)
+	}
+	if got := testutil.ToFloat64(met.ConfigReloads.WithLabelValues("applied")); got != 1 {
+		t.Errorf("applied reloads = %v, want 1", got)
+	}
+}
+
+func TestReloadRejectsInvalidConfig(t *testing.T) {
+	r, target, prov, met, path := newTestReloader(t, fmt.Sprintf(baseConfig, 5))
+
+	// max_runners below min_runners fails validation
+	writeConfig(t, path, fmt.Sprintf(baseConfig, 0))
+
+	if err := r.Reload(); err == nil {
+		t.Fatal("Reload() accepted an invalid configuration")
+	}
+	if len(target.updates()) != 0 || len(prov.images) != 0 {
+		t.Error("rejected configuration was applied")
+	}
+	if got := testutil.ToFloat64(met.ConfigReloads.WithLabelValues("rejected")); got != 1 {
+		t.Errorf("rejected reloads = %v, want 1", got)
+	}
+}
+
+// Either the file watch or the signal may trigger the reload
+func TestRunReloadsChangedConfig(t *testing.T) {
+	r, target, _, _, path := newTestReloader(t, fmt.Sprintf(baseConfig, 5))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	go r.Run(ctx, signals)
+
+	writeConfig(t, path, fmt.Sprintf(baseConfig, 7))
+	signals <- os.Interrupt
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, cfg := range target.updates() {
+			if cfg.Scaling.MaxRunners == 7 {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("configuration not reloaded")
+}
@@ -1,23 +1,39 @@
 package store
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
 	"sync"
 	"time"
 )
 
+// stateRefresh is how often unchanged controller state is written anyway,
+// so SavedAt tells a restarted controller how recent the state is
+const stateRefresh = time.Minute
+
 type Store struct {
-	config   StoreConfig
-	events   []ScaleEvent
-	mu       sync.RWMutex
+	config    StoreConfig
+	events    []ScaleEvent
+	decisions []DecisionRecord
+	pending   []DecisionRecord // decisions recorded since the last write
+	logged    int              // records in the decision log
+	compact   bool             // the decision log must be rewritten
+	overrides Overrides
+	state     ControllerState
+	mu        sync.RWMutex
 }
 
 type StoreConfig struct {
-	Enabled   bool
-	Path      string
-	MaxEvents int
+	Enabled      bool
+	Path         string
+	MaxEvents    int
+	MaxDecisions int
 }
 
 type ScaleEvent struct {
@@ -27,6 +43,102 @@ type ScaleEvent struct {
 	QueueDepth    int       `json:"queue_depth"`
 	RunnersBefore int       `json:"runners_before"`
 	RunnersAfter  int       `json:"runners_after"`
+	JobID         int64     `json:"job_id,omitempty"`
+}
+
+// Overrides are operator controls that take precedence over autoscaling.
+// Zero expiry times mean the corresponding override is not set.
+type Overrides struct {
+	Paused            bool      `json:"paused"`
+	PausedAt          time.Time `json:"paused_at,omitempty"`
+	PinnedCount       int       `json:"pinned_count,omitempty"`
+	PinnedUntil       time.Time `json:"pinned_until,omitempty"`
+	BoostedMinRunners int       `json:"boosted_min_runners,omitempty"`
+	BoostedUntil      time.Time `json:"boosted_until,omitempty"`
+}
+
+// ControllerState is the scaling state a controller needs to pick up where
+// a previous process, or a previous leader, left off
+type ControllerState struct {
+	SavedAt           time.Time               `json:"saved_at"`
+	LastScaleUpTime   time.Time               `json:"last_scale_up_time,omitempty"`
+	LastScaleDownTime time.Time               `json:"last_scale_down_time,omitempty"`
+	ScaleUpCounter    int                     `json:"scale_up_counter"`
+	ScaleDownCounter  int                     `json:"scale_down_counter"`
+	QueueHistory      []int                   `json:"queue_history,omitempty"`
+	Runners           map[string]RunnerOrigin `json:"runners,omitempty"`
+	Replacements      map[string]string       `json:"replacements,omitempty"`
+	Budget            *BudgetSpend            `json:"budget,omitempty"`
+}
+
+// BudgetSpend is the spend accrued in the current budget windows
+type BudgetSpend struct {
+	HourStart   time.Time            `json:"hour_start"`
+	DayStart    time.Time            `json:"day_start"`
+	Hourly      float64              `json:"hourly"`
+	Daily       float64              `json:"daily"`
+	Total       float64              `json:"total"`
+	LastAccrued map[string]time.Time `json:"last_accrued,omitempty"`
+}
+
+// RunnerOrigin records why a runner was created, keyed by runner ID
+type RunnerOrigin struct {
+	Name      string    `json:"name"`
+	Reason    string    `json:"reason"`
+	JobID     int64     `json:"job_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DecisionRecord captures a scaling decision together with the inputs it
+// was made from and the outcome of acting on it
+type DecisionRecord struct {
+	Timestamp           time.Time          `json:"timestamp"`
+	Action              string             `json:"action"`
+	Reason              string             `json:"reason"`
+	QueueDepth          int                `json:"queue_depth"`
+	PredictedQueueDepth int                `json:"predicted_queue_depth,omitempty"`
+	CurrentCount        int                `json:"current_count"`
+	DesiredCount        int                `json:"desired_count"`
+	RunnersByStatus     map[string]int     `json:"runners_by_status"`
+	HysteresisHit       bool               `json:"hysteresis_hit"`
+	JobIDs              []int64            `json:"job_ids,omitempty"`
+	PriorityClass       string             `json:"priority_class,omitempty"`
+	Thresholds          DecisionThresholds `json:"thresholds"`
+	DryRun              bool               `json:"dry_run"`
+	Executed            bool               `json:"executed"`
+	Succeeded           bool               `json:"succeeded"`
+	RunnersChanged      int                `json:"runners_changed"`
+	Error               string             `json:"error,omitempty"`
+}
+
+// DecisionThresholds are the scaling settings in effect for a decision
+type DecisionThresholds struct {
+	MinRunners          int    `json:"min_runners"`
+	MaxRunners          int    `json:"max_runners"`
+	ScaleUpThreshold    int    `json:"scale_up_threshold"`
+	ScaleDownThreshold  int    `json:"scale_down_threshold"`
+	ScaleUpHysteresis   int    `json:"scale_up_hysteresis"`
+	ScaleDownHysteresis int    `json:"scale_down_hysteresis"`
+	CooldownPeriod      string `json:"cooldown_period"`
+	Ephemeral           bool   `json:"ephemeral,omitempty"`
+}
+
+// DecisionQuery filters decision records. Zero values match everything.
+type DecisionQuery struct {
+	Since  time.Time
+	Until  time.Time
+	Action string
+	Limit  int
+}
+
+// storeFile is the on-disk layout of the store. Decisions are appended to a
+// separate log, see decisionLogPath; Decisions is only read, from files
+// written by older versions.
+type storeFile struct {
+	Events    []ScaleEvent     `json:"events"`
+	Decisions []DecisionRecord `json:"decisions,omitempty"`
+	Overrides Overrides        `json:"overrides"`
+	State     *ControllerState `json:"state,omitempty"`
 }
 
 // New creates a new store instance
@@ -38,7 +150,7 @@ func New(cfg StoreConfig) (*Store, error) {
 
 	// Load existing events if file exists
 	if cfg.Enabled && cfg.Path != "" {
-		if err := s.load(); err != nil && !os.IsNotExist(err) {
+		if err := s.load(); err != nil {
 			return nil, fmt.Errorf("failed to load store: %w", err)
 		}
 	}
@@ -86,23 +198,300 @@ func (s *Store) GetAllEvents() []ScaleEvent {
 	return append([]ScaleEvent(nil), s.events...)
 }
 
+// RecordDecision records a scaling decision, whether or not it led to
+// action. Decisions are made on every reconcile, so they are only kept in
+// memory until the next write, or an explicit Flush, and are then appended
+// to the decision log rather than rewriting the store file.
+func (s *Store) RecordDecision(record DecisionRecord) error {
+	if !s.config.Enabled {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.decisions = append(s.decisions, record)
+	s.pending = append(s.pending, record)
+
+	if len(s.decisions) > s.config.MaxDecisions {
+		s.decisions = s.decisions[len(s.decisions)-s.config.MaxDecisions:]
+	}
+
+	return nil
+}
+
+// Flush writes decisions recorded since the last write
+func (s *Store) Flush() error {
+	if !s.config.Enabled {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.flushDecisions()
+}
+
+// QueryDecisions returns decision records matching q, oldest first. When
+// q.Limit is set only the most recent matches are returned.
+func (s *Store) QueryDecisions(q DecisionQuery) []DecisionRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matches []DecisionRecord
+	for _, d := range s.decisions {
+		if !q.Since.IsZero() && d.Timestamp.Before(q.Since) {
+			continue
+		}
+		if !q.Until.IsZero() && d.Timestamp.After(q.Until) {
+			continue
+		}
+		if q.Action != "" && d.Action != q.Action {
+			continue
+		}
+		matches = append(matches, d)
+	}
+
+	if q.Limit > 0 && len(matches) > q.Limit {
+		matches = matches[len(matches)-q.Limit:]
+	}
+
+	return matches
+}
+
+// GetOverrides returns the persisted operator overrides
+func (s *Store) GetOverrides() Overrides {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.overrides
+}
+
+// SetOverrides replaces the operator overrides. They are kept in memory
+// even when the store is disabled, but only persisted when it is enabled.
+func (s *Store) SetOverrides(overrides Overrides) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.overrides = overrides
+
+	if !s.config.Enabled {
+		return nil
+	}
+	return s.persist()
+}
+
+// GetControllerState returns the last saved controller state. SavedAt is
+// zero when none was saved.
+func (s *Store) GetControllerState() ControllerState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.state
+}
+
+// SaveControllerState replaces the saved controller state and writes the
+// decisions recorded since the last write. The store file is only rewritten
+// when the state changed, or to refresh SavedAt every stateRefresh.
+func (s *Store) SaveControllerState(state ControllerState) error {
+	if !s.config.Enabled {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sameState(state, s.state) && state.SavedAt.Sub(s.state.SavedAt) < stateRefresh {
+		return s.flushDecisions()
+	}
+
+	s.state = state
+	return s.persist()
+}
+
+// sameState reports whether a and b differ in no more than SavedAt
+func sameState(a, b ControllerState) bool {
+	a.SavedAt, b.SavedAt = time.Time{}, time.Time{}
+	return reflect.DeepEqual(a, b)
+}
+
 func (s *Store) load() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	var file storeFile
 	data, err := os.ReadFile(s.config.Path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	case bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")):
+		// Older versions stored a bare array of events
+		if err := json.Unmarshal(bytes.TrimSpace(data), &s.events); err != nil {
+			return err
+		}
+	default:
+		if err := json.Unmarshal(data, &file); err != nil {
+			return err
+		}
+	}
+
+	if file.Events != nil {
+		s.events = file.Events
+	}
+	s.overrides = file.Overrides
+	if file.State != nil {
+		s.state = *file.State
+	}
+
+	found, err := s.loadDecisions()
 	if err != nil {
 		return err
 	}
+	if !found && len(file.Decisions) > 0 {
+		// Move decisions kept in the store file by older versions to the log
+		s.decisions = file.Decisions
+		s.compact = true
+	}
+
+	return nil
+}
+
+// decisionLogPath is the decision log next to the store file, one JSON
+// record per line
+func (s *Store) decisionLogPath() string {
+	return s.config.Path + ".decisions"
+}
+
+// loadDecisions reads the most recent decisions from the decision log,
+// reporting whether there is one. A line that does not parse, such as one
+// cut short by a crash, is skipped and the log is rewritten on the next
+// write.
+func (s *Store) loadDecisions() (bool, error) {
+	f, err := os.Open(s.decisionLogPath())
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
 
-	return json.Unmarshal(data, &s.events)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var record DecisionRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			s.compact = true
+			continue
+		}
+		s.decisions = append(s.decisions, record)
+		s.logged++
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read decision log: %w", err)
+	}
+
+	if limit := s.config.MaxDecisions; limit > 0 && len(s.decisions) > limit {
+		s.decisions = s.decisions[len(s.decisions)-limit:]
+	}
+	return true, nil
 }
 
+// persist rewrites the store file and writes pending decisions
 func (s *Store) persist() error {
-	data, err := json.MarshalIndent(s.events, "", "  ")
+	file := storeFile{
+		Events:    s.events,
+		Overrides: s.overrides,
+	}
+	if !s.state.SavedAt.IsZero() {
+		file.State = &s.state
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal store: %w", err)
+	}
+
+	if err := writeFileAtomic(s.config.Path, data); err != nil {
+		return err
+	}
+	return s.flushDecisions()
+}
+
+// flushDecisions appends the decisions recorded since the last write to the
+// decision log. Once the log holds twice the retained decisions, or after
+// a damaged log was read, it is rewritten with only the retained ones.
+func (s *Store) flushDecisions() error {
+	if len(s.pending) == 0 && !s.compact {
+		return nil
+	}
+
+	rewrite := s.compact || s.logged+len(s.pending) > 2*s.config.MaxDecisions
+	records := s.pending
+	if rewrite {
+		records = s.decisions
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return fmt.Errorf("failed to marshal decision: %w", err)
+		}
+	}
+
+	if rewrite {
+		if err := writeFileAtomic(s.decisionLogPath(), buf.Bytes()); err != nil {
+			return fmt.Errorf("failed to write decision log: %w", err)
+		}
+		s.logged = 0
+	} else if err := appendFile(s.decisionLogPath(), buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write decision log: %w", err)
+	}
+
+	s.logged += len(records)
+	s.pending = nil
+	s.compact = false
+	return nil
+}
+
+// writeFileAtomic replaces path with data through a synced temporary file
+// in the same directory, so a crash leaves either the old or the new file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to marshal events: %w", err)
+		return err
 	}
 
-	return os.WriteFile(s.config.Path, data, 0644)
+	return os.Rename(tmp.Name(), path)
+}
+
+// appendFile appends data to path and syncs it
+func appendFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	return errors.Join(err, f.Close())
 }
@@ -0,0 +1,293 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadLegacyEventArray(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.json")
+	legacy := `[{"timestamp": "2024-06-01T12:00:00Z", "action": "scale_up", "reason": "queue_above_threshold", "queue_depth": 7, "runners_before": 1, "runners_after": 2}]`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := New(StoreConfig{Enabled: true, Path: path, MaxEvents: 10})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	events := st.GetAllEvents()
+	if len(events) != 1 || events[0].Action != "scale_up" || events[0].RunnersAfter != 2 {
+		t.Errorf("GetAllEvents() = %+v, want the legacy scale_up event", events)
+	}
+}
+
+func TestPersistEventsAndOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	cfg := StoreConfig{Enabled: true, Path: path, MaxEvents: 2}
+
+	st, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := st.RecordScaleEvent(ScaleEvent{Action: "scale_up", RunnersAfter: i}); err != nil {
+			t.Fatalf("RecordScaleEvent() error = %v", err)
+		}
+	}
+
+	until := time.Date(2024, 6, 1, 13, 0, 0, 0, time.UTC)
+	if err := st.SetOverrides(Overrides{Paused: true, PinnedCount: 4, PinnedUntil: until}); err != nil {
+		t.Fatalf("SetOverrides() error = %v", err)
+	}
+
+	reopened, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	events := reopened.GetAllEvents()
+	if len(events) != 2 || events[0].RunnersAfter != 1 {
+		t.Errorf("GetAllEvents() = %+v, want the 2 most recent events", events)
+	}
+
+	overrides := reopened.GetOverrides()
+	if !overrides.Paused || overrides.PinnedCount != 4 || !overrides.PinnedUntil.Equal(until) {
+		t.Errorf("GetOverrides() = %+v, want persisted overrides", overrides)
+	}
+}
+
+func TestQueryDecisions(t *testing.T) {
+	st, err := New(StoreConfig{Enabled: true, Path: filepath.Join(t.TempDir(), "store.json"), MaxDecisions: 3})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	base := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i, action := range []string{"none", "up", "none", "down", "up"} {
+		record := DecisionRecord{Timestamp: base.Add(time.Duration(i) * time.Minute), Action: action}
+		if err := st.RecordDecision(record); err != nil {
+			t.Fatalf("RecordDecision() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query DecisionQuery
+		want  int
+	}{
+		{name: "all retained", query: DecisionQuery{}, want: 3},
+		{name: "by action", query: DecisionQuery{Action: "up"}, want: 1},
+		{name: "since", query: DecisionQuery{Since: base.Add(3 * time.Minute)}, want: 2},
+		{name: "until", query: DecisionQuery{Until: base.Add(3 * time.Minute)}, want: 2},
+		{name: "limit", query: DecisionQuery{Limit: 1}, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := st.QueryDecisions(tt.query); len(got) != tt.want {
+				t.Errorf("QueryDecisions() returned %d records, want %d", len(got), tt.want)
+			}
+		})
+	}
+
+	if latest := st.QueryDecisions(DecisionQuery{Limit: 1}); latest[0].Action != "up" {
+		t.Errorf("limit kept %+v, want the most recent decision", latest[0])
+	}
+}
+
+func TestDecisionsWrittenInBatches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	st, err := New(StoreConfig{Enabled: true, Path: path, MaxDecisions: 10})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := st.RecordDecision(DecisionRecord{Action: "none"}); err != nil {
+			t.Fatalf("RecordDecision() error = %v", err)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("store written before flush, stat error = %v", err)
+	}
+
+	if err := st.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	reloaded, err := New(StoreConfig{Enabled: true, Path: path, MaxDecisions: 10})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got := len(reloaded.QueryDecisions(DecisionQuery{})); got != 3 {
+		t.Errorf("reloaded %d decisions, want 3", got)
+	}
+}
+
+func TestPersistControllerState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	cfg := StoreConfig{Enabled: true, Path: path, MaxEvents: 10}
+
+	st, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got := st.GetControllerState(); !got.SavedAt.IsZero() {
+		t.Fatalf("GetControllerState() = %+v before any save", got)
+	}
+
+	saved := ControllerState{
+		SavedAt:         time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+		LastScaleUpTime: time.Date(2024, 6, 1, 11, 58, 0, 0, time.UTC),
+		ScaleUpCounter:  2,
+		QueueHistory:    []int{3, 7, 9},
+		Runners: map[string]RunnerOrigin{
+			"r1": {Name: "zeno-runner-1", Reason: "queue_above_threshold", JobID: 42},
+		},
+	}
+	if err := st.SaveControllerState(saved); err != nil {
+		t.Fatalf("SaveControllerState() error = %v", err)
+	}
+
+	reloaded, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	got := reloaded.GetControllerState()
+	if !got.SavedAt.Equal(saved.SavedAt) || !got.LastScaleUpTime.Equal(saved.LastScaleUpTime) || got.ScaleUpCounter != 2 {
+		t.Errorf("reloaded state = %+v, want %+v", got, saved)
+	}
+	if len(got.QueueHistory) != 3 || got.QueueHistory[2] != 9 {
+		t.Errorf("QueueHistory = %v, want [3 7 9]", got.QueueHistory)
+	}
+	if origin := got.Runners["r1"]; origin.Reason != "queue_above_threshold" || origin.JobID != 42 {
+		t.Errorf("Runners[r1] = %+v", origin)
+	}
+}
+
+func TestDecisionLogSkipsTruncatedRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	cfg := StoreConfig{Enabled: true, Path: path, MaxDecisions: 10}
+
+	st, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		st.RecordDecision(DecisionRecord{Action: "none", QueueDepth: i})
+	}
+	if err := st.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	// A crash in the middle of an append leaves half a record
+	f, err := os.OpenFile(path+".decisions", os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"timestamp":"2024-06-01T12:`)
+	f.Close()
+
+	reloaded, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v after a truncated decision", err)
+	}
+	reloaded.RecordDecision(DecisionRecord{Action: "up", QueueDepth: 2})
+	if err := reloaded.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	again, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	decisions := again.QueryDecisions(DecisionQuery{})
+	if len(decisions) != 3 || decisions[2].Action != "up" {
+		t.Errorf("reloaded decisions = %+v, want the two flushed ones and the new one", decisions)
+	}
+}
+
+func TestLoadLegacyDecisions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	legacy := `{"events": [], "decisions": [{"timestamp": "2024-06-01T12:00:00Z", "action": "up"}], "overrides": {}}`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := StoreConfig{Enabled: true, Path: path, MaxDecisions: 10}
+
+	st, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := st.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	reloaded, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if decisions := reloaded.QueryDecisions(DecisionQuery{}); len(decisions) != 1 || decisions[0].Action != "up" {
+		t.Errorf("decisions = %+v, want the legacy up decision", decisions)
+	}
+}
+
+func TestUnchangedControllerStateNotRewritten(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "store.json")
+	cfg := StoreConfig{Enabled: true, Path: path, MaxEvents: 10}
+
+	st, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	saved := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	state := ControllerState{SavedAt: saved, ScaleUpCounter: 1}
+	if err := st.SaveControllerState(state); err != nil {
+		t.Fatalf("SaveControllerState() error = %v", err)
+	}
+
+	savedAt := func() time.Time {
+		t.Helper()
+		reloaded, err := New(cfg)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		return reloaded.GetControllerState().SavedAt
+	}
+
+	state.SavedAt = saved.Add(30 * time.Second)
+	st.SaveControllerState(state)
+	if got := savedAt(); !got.Equal(saved) {
+		t.Errorf("unchanged state rewritten, SavedAt = %v", got)
+	}
+
+	state.SavedAt = saved.Add(stateRefresh)
+	st.SaveControllerState(state)
+	if got := savedAt(); !got.Equal(state.SavedAt) {
+		t.Errorf("SavedAt = %v, want it refreshed after %s", got, stateRefresh)
+	}
+
+	state.SavedAt = state.SavedAt.Add(time.Second)
+	state.ScaleUpCounter = 2
+	st.SaveControllerState(state)
+	if got := savedAt(); !got.Equal(state.SavedAt) {
+		t.Errorf("SavedAt = %v, want the changed state written", got)
+	}
+
+	// Writes go through a temporary file that is renamed into place
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("store directory holds %d files, want only the store file", len(entries))
+	}
+}
@@ -0,0 +1,271 @@
+package providerplugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// closeTimeout is how long Close waits for the plugin to exit before
+// killing it
+const closeTimeout = 10 * time.Second
+
+// ErrClosed is returned by calls made after Close
+var ErrClosed = errors.New("plugin is closed")
+
+// Client runs a plugin program and calls it. It implements Provider, so a
+// plugin can be used wherever one is expected. It is safe for concurrent
+// use; calls are sent to the plugin as they are made.
+type Client struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	name  string
+
+	writeMu sync.Mutex
+	enc     *json.Encoder
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan *Response
+	closed  bool
+
+	// done is closed once the plugin has exited, and exitErr says why
+	done      chan struct{}
+	exitErr   error
+	closeOnce sync.Once
+}
+
+// Start runs cmd and performs the init handshake, passing settings to the
+// plugin. cmd must not have Stdin or Stdout set; set Stderr to collect the
+// plugin's log. ctx bounds the handshake only, the plugin keeps running
+// until Close.
+func Start(ctx context.Context, cmd *exec.Cmd, settings json.RawMessage) (*Client, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin: %w", err)
+	}
+
+	c := &Client{
+		cmd:     cmd,
+		stdin:   stdin,
+		enc:     json.NewEncoder(stdin),
+		pending: make(map[uint64]chan *Response),
+		done:    make(chan struct{}),
+	}
+	go c.readResponses(stdout)
+
+	var result InitResult
+	if err := c.call(ctx, MethodInit, InitParams{ProtocolVersion: ProtocolVersion, Settings: settings}, &result); err != nil {
+		c.kill()
+		return nil, fmt.Errorf("plugin handshake failed: %w", err)
+	}
+	if result.ProtocolVersion != ProtocolVersion {
+		c.kill()
+		return nil, fmt.Errorf("plugin speaks protocol version %d, want %d", result.ProtocolVersion, ProtocolVersion)
+	}
+	if result.Name == "" {
+		c.kill()
+		return nil, fmt.Errorf("plugin did not report a name")
+	}
+	c.name = result.Name
+
+	return c, nil
+}
+
+// Name returns the name the plugin reported during the handshake
+func (c *Client) Name() string {
+	return c.name
+}
+
+// ListRunners returns all runners managed by the plugin
+func (c *Client) ListRunners(ctx context.Context) ([]*Runner, error) {
+	var runners []*Runner
+	if err := c.call(ctx, MethodListRunners, nil, &runners); err != nil {
+		return nil, err
+	}
+	return runners, nil
+}
+
+// GetRunner returns a specific runner by ID
+func (c *Client) GetRunner(ctx context.Context, id string) (*Runner, error) {
+	var runner Runner
+	if err := c.call(ctx, MethodGetRunner, GetRunnerParams{ID: id}, &runner); err != nil {
+		return nil, err
+	}
+	return &runner, nil
+}
+
+// CreateRunner asks the plugin to provision a new runner
+func (c *Client) CreateRunner(ctx context.Context, req *CreateRunnerRequest) (*Runner, error) {
+	var runner Runner
+	if err := c.call(ctx, MethodCreateRunner, req, &runner); err != nil {
+		return nil, err
+	}
+	return &runner, nil
+}
+
+// RemoveRunner asks the plugin to terminate and remove a runner
+func (c *Client) RemoveRunner(ctx context.Context, id string, graceful bool) error {
+	return c.call(ctx, MethodRemoveRunner, RemoveRunnerParams{ID: id, Graceful: graceful}, nil)
+}
+
+// HealthCheck asks the plugin whether its platform is reachable
+func (c *Client) HealthCheck(ctx context.Context) error {
+	return c.call(ctx, MethodHealthCheck, nil, nil)
+}
+
+// Close sends close, waits for the plugin to exit and kills it if it does
+// not exit in time. Calls still in flight fail with ErrClosed.
+func (c *Client) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+		defer cancel()
+
+		err = c.call(ctx, MethodClose, nil, nil)
+
+		c.mu.Lock()
+		c.closed = true
+		c.mu.Unlock()
+		c.stdin.Close()
+
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			c.kill()
+		}
+		if errors.Is(err, ErrClosed) {
+			// The plugin exited before answering, which is as good
+			err = nil
+		}
+	})
+	return err
+}
+
+// call sends a request and decodes the result into result, which may be nil
+// for methods without one. If ctx ends first the call is abandoned; the
+// plugin is not told and its late response is dropped.
+func (c *Client) call(ctx context.Context, method string, params, result interface{}) error {
+	req := Request{Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s params: %w", method, err)
+		}
+		req.Params = data
+	}
+
+	ch := make(chan *Response, 1)
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	c.nextID++
+	req.ID = c.nextID
+	c.pending[req.ID] = ch
+	c.mu.Unlock()
+
+	c.writeMu.Lock()
+	err := c.enc.Encode(req)
+	c.writeMu.Unlock()
+	if err != nil {
+		c.forget(req.ID)
+		select {
+		case <-c.done:
+			return c.exitError()
+		default:
+		}
+		return fmt.Errorf("failed to send %s request: %w", method, err)
+	}
+
+	var resp *Response
+	select {
+	case resp = <-ch:
+	case <-c.done:
+		// The response is handed over before done is closed, so one
+		// sent just before the plugin exited is still waiting here
+		select {
+		case resp = <-ch:
+		default:
+			return c.exitError()
+		}
+	case <-ctx.Done():
+		c.forget(req.ID)
+		return ctx.Err()
+	}
+
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	if result != nil {
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("failed to decode %s result: %w", method, err)
+		}
+	}
+	return nil
+}
+
+func (c *Client) forget(id uint64) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+// readResponses hands responses to their callers until the plugin closes
+// stdout, then reaps the plugin
+func (c *Client) readResponses(stdout io.Reader) {
+	dec := json.NewDecoder(stdout)
+	for {
+		var resp Response
+		if err := dec.Decode(&resp); err != nil {
+			break
+		}
+
+		c.mu.Lock()
+		ch := c.pending[resp.ID]
+		delete(c.pending, resp.ID)
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- &resp
+		}
+	}
+
+	err := c.cmd.Wait()
+
+	c.mu.Lock()
+	if c.closed {
+		c.exitErr = ErrClosed
+	} else if err != nil {
+		c.exitErr = fmt.Errorf("plugin exited: %w", err)
+	} else {
+		c.exitErr = errors.New("plugin exited")
+	}
+	c.closed = true
+	c.mu.Unlock()
+	close(c.done)
+}
+
+func (c *Client) exitError() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.exitErr
+}
+
+func (c *Client) kill() {
+	c.cmd.Process.Kill()
+	c.stdin.Close()
+	<-c.done
+}
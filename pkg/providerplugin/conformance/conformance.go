@@ -0,0 +1,244 @@
+// Package conformance checks that a provider plugin behaves the way Zeno
+// expects. Plugin authors call Run from a test in their own module:
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.Run(t, func() *exec.Cmd {
+//			return exec.Command("./my-plugin")
+//		}, json.RawMessage(`{"region": "test"}`))
+//	}
+//
+// The suite creates and removes real runners, so point the plugin at a
+// test environment.
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"Zeno/pkg/providerplugin"
+)
+
+// callTimeout bounds every call the suite makes
+const callTimeout = 2 * time.Minute
+
+// Run starts the plugin returned by cmd with settings and runs the suite
+// against it as subtests of t
+func Run(t *testing.T, cmd func() *exec.Cmd, settings json.RawMessage) {
+	t.Helper()
+
+	start := func(t *testing.T) *providerplugin.Client {
+		t.Helper()
+
+		c := cmd()
+		if c.Stderr == nil {
+			c.Stderr = os.Stderr
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+		defer cancel()
+
+		client, err := providerplugin.Start(ctx, c, settings)
+		if err != nil {
+			t.Fatalf("Start() error = %v", err)
+		}
+		t.Cleanup(func() { client.Close() })
+		return client
+	}
+
+	t.Run("Handshake", func(t *testing.T) {
+		client := start(t)
+		if client.Name() == "" {
+			t.Error("plugin reported an empty name")
+		}
+	})
+
+	t.Run("HealthCheck", func(t *testing.T) {
+		client := start(t)
+		ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+		defer cancel()
+
+		if err := client.HealthCheck(ctx); err != nil {
+			t.Errorf("HealthCheck() error = %v", err)
+		}
+	})
+
+	t.Run("RunnerLifecycle", func(t *testing.T) {
+		client := start(t)
+		ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+		defer cancel()
+
+		req := testRequest("conformance-runner-1")
+		created, err := client.CreateRunner(ctx, req)
+		if err != nil {
+			t.Fatalf("CreateRunner() error = %v", err)
+		}
+		checkRunner(t, "CreateRunner()", created, req)
+
+		got, err := client.GetRunner(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("GetRunner() error = %v", err)
+		}
+		if got.ID != created.ID {
+			t.Errorf("GetRunner() ID = %q, want %q", got.ID, created.ID)
+		}
+		checkRunner(t, "GetRunner()", got, req)
+
+		runners, err := client.ListRunners(ctx)
+		if err != nil {
+			t.Fatalf("ListRunners() error = %v", err)
+		}
+		listed := findRunner(runners, created.ID)
+		if listed == nil {
+			t.Fatalf("ListRunners() does not include runner %s", created.ID)
+		}
+		checkRunner(t, "ListRunners()", listed, req)
+
+		if err := client.RemoveRunner(ctx, created.ID, true); err != nil {
+			t.Fatalf("RemoveRunner() error = %v", err)
+		}
+		runners, err = client.ListRunners(ctx)
+		if err != nil {
+			t.Fatalf("ListRunners() after removal error = %v", err)
+		}
+		if r := findRunner(runners, created.ID); r != nil && !gone(r.Status) {
+			t.Errorf("removed runner is listed with status %q, want it gone or terminating", r.Status)
+		}
+	})
+
+	t.Run("UnknownRunner", func(t *testing.T) {
+		client := start(t)
+		ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+		defer cancel()
+
+		if _, err := client.GetRunner(ctx, "zeno-conformance-does-not-exist"); err == nil {
+			t.Error("GetRunner() of an unknown runner succeeded")
+		}
+	})
+
+	t.Run("ConcurrentCalls", func(t *testing.T) {
+		client := start(t)
+		ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+		defer cancel()
+
+		const n = 4
+		ids := make([]string, n)
+		var wg sync.WaitGroup
+		errs := make(chan error, n)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				r, err := client.CreateRunner(ctx, testRequest(fmt.Sprintf("conformance-runner-%d", i+2)))
+				if err != nil {
+					errs <- err
+					return
+				}
+				ids[i] = r.ID
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Errorf("concurrent CreateRunner() error = %v", err)
+		}
+
+		runners, err := client.ListRunners(ctx)
+		if err != nil {
+			t.Fatalf("ListRunners() error = %v", err)
+		}
+		seen := make(map[string]bool)
+		for _, id := range ids {
+			if id == "" {
+				continue
+			}
+			if seen[id] {
+				t.Errorf("runner ID %s returned for two runners", id)
+			}
+			seen[id] = true
+			if findRunner(runners, id) == nil {
+				t.Errorf("ListRunners() does not include runner %s", id)
+			}
+		}
+
+		for _, id := range ids {
+			if id == "" {
+				continue
+			}
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				if err := client.RemoveRunner(ctx, id, false); err != nil {
+					t.Errorf("concurrent RemoveRunner(%s) error = %v", id, err)
+				}
+			}(id)
+		}
+		wg.Wait()
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		client := start(t)
+		if err := client.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		if err := client.HealthCheck(context.Background()); err == nil {
+			t.Error("HealthCheck() after Close() succeeded")
+		}
+	})
+}
+
+func testRequest(name string) *providerplugin.CreateRunnerRequest {
+	return &providerplugin.CreateRunnerRequest{
+		Name:        name,
+		Labels:      []string{"self-hosted", "conformance"},
+		GitHubToken: "conformance-token",
+		GitHubOrg:   "zeno-conformance",
+		Ephemeral:   true,
+		Metadata:    map[string]string{"job_id": "42"},
+	}
+}
+
+// checkRunner checks the fields Zeno relies on to track a runner
+func checkRunner(t *testing.T, call string, r *providerplugin.Runner, req *providerplugin.CreateRunnerRequest) {
+	t.Helper()
+
+	if r.ID == "" {
+		t.Errorf("%s returned a runner without an ID", call)
+	}
+	if r.Name != req.Name {
+		t.Errorf("%s Name = %q, want %q", call, r.Name, req.Name)
+	}
+	if !known(r.Status) {
+		t.Errorf("%s Status = %q, want one of the protocol's states", call, r.Status)
+	}
+	if r.Metadata["job_id"] != req.Metadata["job_id"] {
+		t.Errorf("%s Metadata[job_id] = %q, want the request's metadata kept", call, r.Metadata["job_id"])
+	}
+}
+
+func findRunner(runners []*providerplugin.Runner, id string) *providerplugin.Runner {
+	for _, r := range runners {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+func known(status string) bool {
+	switch status {
+	case providerplugin.StatusPending, providerplugin.StatusProvisioning, providerplugin.StatusRunning,
+		providerplugin.StatusIdle, providerplugin.StatusBusy, providerplugin.StatusTerminating,
+		providerplugin.StatusTerminated, providerplugin.StatusFailed:
+		return true
+	}
+	return false
+}
+
+func gone(status string) bool {
+	return status == providerplugin.StatusTerminating || status == providerplugin.StatusTerminated
+}
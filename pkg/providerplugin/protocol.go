@@ -0,0 +1,141 @@
+// Package providerplugin lets runner providers live in their own binaries.
+//
+// Zeno starts the plugin program and talks to it over the plugin's stdin and
+// stdout. Each message is one JSON object on its own line. Zeno sends
+// requests and the plugin answers every request with a response carrying
+// the same id. Requests may be answered out of order, so a plugin can work
+// on several at once. Anything the plugin writes to stderr ends up in Zeno's
+// log.
+//
+// The first request is always "init", which carries the protocol version
+// and the plugin's settings from the Zeno configuration. The remaining
+// methods mirror the provider interface in Zeno. The last request is
+// "close", after which Zeno closes stdin and waits for the plugin to exit.
+//
+// Plugin authors implement Provider and call Serve from main. Client is the
+// other end of the protocol, used by Zeno and by the conformance tests.
+package providerplugin
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// ProtocolVersion is the version of the protocol implemented by this
+// package. A plugin answers init with the version it speaks and Zeno refuses
+// to use plugins that speak a different one.
+const ProtocolVersion = 1
+
+// Methods of the protocol
+const (
+	MethodInit         = "init"
+	MethodListRunners  = "list_runners"
+	MethodGetRunner    = "get_runner"
+	MethodCreateRunner = "create_runner"
+	MethodRemoveRunner = "remove_runner"
+	MethodHealthCheck  = "health_check"
+	MethodClose        = "close"
+)
+
+// Request is a message from Zeno to the plugin
+type Request struct {
+	ID     uint64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a message from the plugin to Zeno. A response with a
+// non-empty Error is a failed call.
+type Response struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// InitParams are the parameters of init
+type InitParams struct {
+	ProtocolVersion int             `json:"protocol_version"`
+	Settings        json.RawMessage `json:"settings,omitempty"`
+}
+
+// InitResult is the result of init
+type InitResult struct {
+	ProtocolVersion int    `json:"protocol_version"`
+	Name            string `json:"name"`
+}
+
+// GetRunnerParams are the parameters of get_runner
+type GetRunnerParams struct {
+	ID string `json:"id"`
+}
+
+// RemoveRunnerParams are the parameters of remove_runner
+type RemoveRunnerParams struct {
+	ID       string `json:"id"`
+	Graceful bool   `json:"graceful"`
+}
+
+// Runner states understood by Zeno
+const (
+	StatusPending      = "pending"
+	StatusProvisioning = "provisioning"
+	StatusRunning      = "running"
+	StatusIdle         = "idle"
+	StatusBusy         = "busy"
+	StatusTerminating  = "terminating"
+	StatusTerminated   = "terminated"
+	StatusFailed       = "failed"
+)
+
+// Runner is a runner managed by the plugin. ID is chosen by the plugin and
+// identifies the runner in later calls.
+type Runner struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Status     string            `json:"status"`
+	Labels     []string          `json:"labels,omitempty"`
+	ProviderID string            `json:"provider_id,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	LastSeen   time.Time         `json:"last_seen"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+// CreateRunnerRequest is the parameters of create_runner. The runner should
+// register with GitHub using GitHubToken under Name and Labels, and Metadata
+// should be reported back on the runner.
+type CreateRunnerRequest struct {
+	Name          string            `json:"name"`
+	Labels        []string          `json:"labels,omitempty"`
+	GitHubToken   string            `json:"github_token"`
+	GitHubOrg     string            `json:"github_org,omitempty"`
+	GitHubRepo    string            `json:"github_repo,omitempty"`
+	RunnerVersion string            `json:"runner_version,omitempty"`
+	Ephemeral     bool              `json:"ephemeral"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+}
+
+// Provider is implemented by plugins. It mirrors the provider interface in
+// Zeno, using the wire types of this package.
+type Provider interface {
+	// Name returns the provider name
+	Name() string
+
+	// ListRunners returns all runners managed by this provider
+	ListRunners(ctx context.Context) ([]*Runner, error)
+
+	// GetRunner returns a specific runner by ID
+	GetRunner(ctx context.Context, id string) (*Runner, error)
+
+	// CreateRunner provisions a new runner
+	CreateRunner(ctx context.Context, req *CreateRunnerRequest) (*Runner, error)
+
+	// RemoveRunner terminates and removes a runner
+	RemoveRunner(ctx context.Context, id string, graceful bool) error
+
+	// HealthCheck performs a health check on the provider
+	HealthCheck(ctx context.Context) error
+
+	// Close releases any resources held by the provider
+	Close() error
+}
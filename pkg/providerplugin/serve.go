@@ -0,0 +1,149 @@
+package providerplugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Factory creates the plugin's provider from the settings sent with init.
+// Settings is null when the Zeno configuration has none.
+type Factory func(settings json.RawMessage) (Provider, error)
+
+// Serve answers requests from Zeno on stdin and stdout until Zeno sends
+// close or closes stdin. Plugins call it from main and must not write
+// anything else to stdout.
+func Serve(factory Factory) error {
+	return ServeConn(os.Stdin, os.Stdout, factory)
+}
+
+// ServeConn is Serve over an arbitrary reader and writer
+func ServeConn(r io.Reader, w io.Writer, factory Factory) error {
+	s := &server{enc: json.NewEncoder(w)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dec := json.NewDecoder(r)
+	for {
+		var req Request
+		if err := dec.Decode(&req); err != nil {
+			// Zeno went away without close. Let calls in flight see a
+			// cancelled context before the provider is closed under them.
+			cancel()
+			s.wg.Wait()
+			if s.prov != nil {
+				s.prov.Close()
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to read request: %w", err)
+		}
+
+		switch {
+		case req.Method == MethodInit:
+			result, err := s.init(req.Params, factory)
+			if err := s.reply(req.ID, result, err); err != nil {
+				return err
+			}
+		case s.prov == nil:
+			if err := s.reply(req.ID, nil, fmt.Errorf("%s called before init", req.Method)); err != nil {
+				return err
+			}
+		case req.Method == MethodClose:
+			s.wg.Wait()
+			err := s.prov.Close()
+			return s.reply(req.ID, nil, err)
+		default:
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				result, err := s.call(ctx, req)
+				s.reply(req.ID, result, err)
+			}()
+		}
+	}
+}
+
+type server struct {
+	prov Provider
+	wg   sync.WaitGroup
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (s *server) init(params json.RawMessage, factory Factory) (interface{}, error) {
+	if s.prov != nil {
+		return nil, fmt.Errorf("plugin is already initialized")
+	}
+
+	var p InitParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, fmt.Errorf("invalid init params: %w", err)
+	}
+	if p.ProtocolVersion != ProtocolVersion {
+		return nil, fmt.Errorf("unsupported protocol version %d, plugin speaks %d", p.ProtocolVersion, ProtocolVersion)
+	}
+
+	prov, err := factory(p.Settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+	s.prov = prov
+	return InitResult{ProtocolVersion: ProtocolVersion, Name: prov.Name()}, nil
+}
+
+func (s *server) call(ctx context.Context, req Request) (interface{}, error) {
+	switch req.Method {
+	case MethodListRunners:
+		return s.prov.ListRunners(ctx)
+	case MethodGetRunner:
+		var p GetRunnerParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, fmt.Errorf("invalid %s params: %w", req.Method, err)
+		}
+		return s.prov.GetRunner(ctx, p.ID)
+	case MethodCreateRunner:
+		var p CreateRunnerRequest
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, fmt.Errorf("invalid %s params: %w", req.Method, err)
+		}
+		return s.prov.CreateRunner(ctx, &p)
+	case MethodRemoveRunner:
+		var p RemoveRunnerParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, fmt.Errorf("invalid %s params: %w", req.Method, err)
+		}
+		return nil, s.prov.RemoveRunner(ctx, p.ID, p.Graceful)
+	case MethodHealthCheck:
+		return nil, s.prov.HealthCheck(ctx)
+	default:
+		return nil, fmt.Errorf("unknown method: %s", req.Method)
+	}
+}
+
+func (s *server) reply(id uint64, result interface{}, callErr error) error {
+	resp := Response{ID: id}
+	if callErr != nil {
+		resp.Error = callErr.Error()
+	} else if result != nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			resp.Error = fmt.Sprintf("failed to encode result: %v", err)
+		} else {
+			resp.Result = data
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.enc.Encode(resp); err != nil {
+		return fmt.Errorf("failed to write response: %w", err)
+	}
+	return nil
+}